@@ -0,0 +1,303 @@
+package g722
+
+// 64 kbit/s G.722 sub-band ADPCM (ITU-T G.722 mode 1), integer-exact with the
+// ITU reference: a 24-tap QMF splits 16 kHz audio into two 8 kHz bands, the
+// lower one coded with 6 bits and the upper one with 2 bits per sample.
+
+var (
+	qmfCoeffs = [12]int{3, -11, 12, 32, -210, 951, 3876, -805, 362, -156, 53, -11}
+
+	q6 = [32]int{
+		0, 35, 72, 110, 150, 190, 233, 276,
+		323, 370, 422, 473, 530, 587, 650, 714,
+		786, 858, 940, 1023, 1121, 1219, 1339, 1458,
+		1612, 1765, 1980, 2195, 2557, 2919, 0, 0,
+	}
+	iln = [32]int{
+		0, 63, 62, 31, 30, 29, 28, 27,
+		26, 25, 24, 23, 22, 21, 20, 19,
+		18, 17, 16, 15, 14, 13, 12, 11,
+		10, 9, 8, 7, 6, 5, 4, 0,
+	}
+	ilp = [32]int{
+		0, 61, 60, 59, 58, 57, 56, 55,
+		54, 53, 52, 51, 50, 49, 48, 47,
+		46, 45, 44, 43, 42, 41, 40, 39,
+		38, 37, 36, 35, 34, 33, 32, 0,
+	}
+	wl   = [8]int{-60, -30, 58, 172, 334, 538, 1198, 3042}
+	rl42 = [16]int{0, 7, 6, 5, 4, 3, 2, 1, 7, 6, 5, 4, 3, 2, 1, 0}
+	ilb  = [32]int{
+		2048, 2093, 2139, 2186, 2233, 2282, 2332, 2383,
+		2435, 2489, 2543, 2599, 2656, 2714, 2774, 2834,
+		2896, 2960, 3025, 3091, 3158, 3228, 3298, 3371,
+		3444, 3520, 3597, 3676, 3756, 3838, 3922, 4008,
+	}
+	qm4 = [16]int{
+		0, -20456, -12896, -8968, -6288, -4240, -2584, -1200,
+		20456, 12896, 8968, 6288, 4240, 2584, 1200, 0,
+	}
+	qm6 = [64]int{
+		-136, -136, -136, -136, -24808, -21904, -19008, -16704,
+		-14984, -13512, -12280, -11192, -10232, -9360, -8576, -7856,
+		-7192, -6576, -6000, -5456, -4944, -4464, -4008, -3576,
+		-3168, -2776, -2400, -2032, -1688, -1360, -1040, -728,
+		24808, 21904, 19008, 16704, 14984, 13512, 12280, 11192,
+		10232, 9360, 8576, 7856, 7192, 6576, 6000, 5456,
+		4944, 4464, 4008, 3576, 3168, 2776, 2400, 2032,
+		1688, 1360, 1040, 728, 432, 136, -432, -136,
+	}
+	qm2 = [4]int{-7408, -1616, 7408, 1616}
+	ihn = [3]int{0, 1, 0}
+	ihp = [3]int{0, 3, 2}
+	wh  = [3]int{0, -214, 798}
+	rh2 = [4]int{2, 1, 2, 1}
+)
+
+const (
+	maxNBLow  = 18432
+	maxNBHigh = 22528
+)
+
+// band is the adaptive predictor and quantizer state of one sub-band.
+type band struct {
+	s, sp, sz int
+	r         [3]int
+	a, ap     [3]int
+	p         [3]int
+	d         [7]int
+	b, bp     [7]int
+	sg        [7]int
+	nb        int
+	det       int
+}
+
+func saturate(v int) int {
+	return max(-32768, min(32767, v)) //nolint:mnd
+}
+
+// scale updates the log scale factor nb with wd and derives det (blocks
+// 3L/3H, LOGSCL and SCALEL).
+func (b *band) scale(wd, maxNB, shift int) {
+	b.nb = max(0, min(maxNB, (b.nb*127)>>7+wd)) //nolint:mnd
+	wd1 := (b.nb >> 6) & 31                     //nolint:mnd
+	wd2 := shift - b.nb>>11                     //nolint:mnd
+	var wd3 int
+	if wd2 < 0 {
+		wd3 = ilb[wd1] << -wd2
+	} else {
+		wd3 = ilb[wd1] >> wd2
+	}
+	b.det = wd3 << 2 //nolint:mnd
+}
+
+// update adapts the pole-zero predictor to the quantized difference d
+// (block 4).
+//
+//nolint:mnd,gocognit,cyclop,funlen // transcription of the ITU block diagram
+func (b *band) update(d int) {
+	// RECONS, PARREC
+	b.d[0] = d
+	b.r[0] = saturate(b.s + d)
+	b.p[0] = saturate(b.sz + d)
+
+	// UPPOL2
+	for i := range 3 {
+		b.sg[i] = b.p[i] >> 15
+	}
+	wd1 := saturate(b.a[1] << 2)
+	wd2 := wd1
+	if b.sg[0] == b.sg[1] {
+		wd2 = -wd1
+	}
+	wd2 = min(wd2, 32767)
+	wd3 := wd2 >> 7
+	if b.sg[0] == b.sg[2] {
+		wd3 += 128
+	} else {
+		wd3 -= 128
+	}
+	wd3 += (b.a[2] * 32512) >> 15
+	b.ap[2] = max(-12288, min(12288, wd3))
+
+	// UPPOL1
+	b.sg[0] = b.p[0] >> 15
+	b.sg[1] = b.p[1] >> 15
+	wd1 = -192
+	if b.sg[0] == b.sg[1] {
+		wd1 = 192
+	}
+	wd2 = (b.a[1] * 32640) >> 15
+	b.ap[1] = saturate(wd1 + wd2)
+	wd3 = saturate(15360 - b.ap[2])
+	b.ap[1] = max(-wd3, min(wd3, b.ap[1]))
+
+	// UPZERO
+	wd1 = 128
+	if d == 0 {
+		wd1 = 0
+	}
+	b.sg[0] = d >> 15
+	for i := 1; i < 7; i++ {
+		b.sg[i] = b.d[i] >> 15
+		wd2 = -wd1
+		if b.sg[i] == b.sg[0] {
+			wd2 = wd1
+		}
+		wd3 = (b.b[i] * 32640) >> 15
+		b.bp[i] = saturate(wd2 + wd3)
+	}
+
+	// DELAYA
+	for i := 6; i > 0; i-- {
+		b.d[i] = b.d[i-1]
+		b.b[i] = b.bp[i]
+	}
+	for i := 2; i > 0; i-- {
+		b.r[i] = b.r[i-1]
+		b.p[i] = b.p[i-1]
+		b.a[i] = b.ap[i]
+	}
+
+	// FILTEP
+	wd1 = (b.a[1] * saturate(b.r[1]+b.r[1])) >> 15
+	wd2 = (b.a[2] * saturate(b.r[2]+b.r[2])) >> 15
+	b.sp = saturate(wd1 + wd2)
+
+	// FILTEZ
+	b.sz = 0
+	for i := 6; i > 0; i-- {
+		b.sz += (b.b[i] * saturate(b.d[i]+b.d[i])) >> 15
+	}
+	b.sz = saturate(b.sz)
+
+	// PREDIC
+	b.s = saturate(b.sp + b.sz)
+}
+
+type state struct {
+	band [2]band
+	x    [24]int // QMF delay line
+}
+
+func newState() state {
+	var s state
+	s.band[0].det = 32
+	s.band[1].det = 8
+	return s
+}
+
+// Encoder codes 16 kHz mono S16 samples, one byte per pair of samples.
+type Encoder struct {
+	state
+}
+
+func NewEncoder() *Encoder {
+	return &Encoder{state: newState()}
+}
+
+// Encode appends the code of every pair of samples to dst. An odd trailing
+// sample is ignored.
+//
+//nolint:mnd // G.722 quantizer constants
+func (e *Encoder) Encode(dst []byte, samples []int16) []byte {
+	low, high := &e.band[0], &e.band[1]
+	for j := 0; j+1 < len(samples); j += 2 {
+		// Transmit QMF
+		copy(e.x[:22], e.x[2:])
+		e.x[22], e.x[23] = int(samples[j]), int(samples[j+1])
+		var sumEven, sumOdd int
+		for i := range 12 {
+			sumOdd += e.x[2*i] * qmfCoeffs[i]
+			sumEven += e.x[2*i+1] * qmfCoeffs[11-i]
+		}
+		xlow := (sumEven + sumOdd) >> 14
+		xhigh := (sumEven - sumOdd) >> 14
+
+		// Lower band: SUBTRA, QUANTL, INVQAL
+		el := saturate(xlow - low.s)
+		wd := el
+		if el < 0 {
+			wd = -(el + 1)
+		}
+		i := 1
+		for ; i < 30; i++ {
+			if wd < (q6[i]*low.det)>>12 {
+				break
+			}
+		}
+		ilow := ilp[i]
+		if el < 0 {
+			ilow = iln[i]
+		}
+		ril := ilow >> 2
+		dlow := (low.det * qm4[ril]) >> 15
+		low.scale(wl[rl42[ril]], maxNBLow, 8)
+		low.update(dlow)
+
+		// Higher band: SUBTRA, QUANTH, INVQAH
+		eh := saturate(xhigh - high.s)
+		wd = eh
+		if eh < 0 {
+			wd = -(eh + 1)
+		}
+		mih := 1
+		if wd >= (564*high.det)>>12 {
+			mih = 2
+		}
+		ihigh := ihp[mih]
+		if eh < 0 {
+			ihigh = ihn[mih]
+		}
+		dhigh := (high.det * qm2[ihigh]) >> 15
+		high.scale(wh[rh2[ihigh]], maxNBHigh, 10)
+		high.update(dhigh)
+
+		dst = append(dst, byte(ihigh<<6|ilow)) //nolint:gosec // 2+6 bits
+	}
+	return dst
+}
+
+// Decoder turns G.722 codes back into 16 kHz mono S16 samples.
+type Decoder struct {
+	state
+}
+
+func NewDecoder() *Decoder {
+	return &Decoder{state: newState()}
+}
+
+// Decode appends two samples per byte of data to dst.
+//
+//nolint:mnd // G.722 quantizer constants
+func (d *Decoder) Decode(dst []int16, data []byte) []int16 {
+	low, high := &d.band[0], &d.band[1]
+	for _, code := range data {
+		ilow := int(code & 0x3f)
+		ihigh := int(code >> 6)
+
+		// Lower band: INVQBL, RECONS, LIMIT
+		rlow := max(-16384, min(16383, low.s+(low.det*qm6[ilow])>>15))
+		ril := ilow >> 2
+		dlow := (low.det * qm4[ril]) >> 15
+		low.scale(wl[rl42[ril]], maxNBLow, 8)
+		low.update(dlow)
+
+		// Higher band: INVQAH, RECONS, LIMIT
+		dhigh := (high.det * qm2[ihigh]) >> 15
+		rhigh := max(-16384, min(16383, dhigh+high.s))
+		high.scale(wh[rh2[ihigh]], maxNBHigh, 10)
+		high.update(dhigh)
+
+		// Receive QMF
+		copy(d.x[:22], d.x[2:])
+		d.x[22], d.x[23] = rlow+rhigh, rlow-rhigh
+		var out1, out2 int
+		for i := range 12 {
+			out2 += d.x[2*i] * qmfCoeffs[i]
+			out1 += d.x[2*i+1] * qmfCoeffs[11-i]
+		}
+		dst = append(dst, int16(saturate(out1>>11)), int16(saturate(out2>>11))) //nolint:gosec // saturated
+	}
+	return dst
+}
@@ -0,0 +1,96 @@
+package g722
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// tone returns n samples of a sum of two sines at rate, one per band.
+func tone(n, rate int) []int16 {
+	s := make([]int16, n)
+	for i := range s {
+		t := float64(i) / float64(rate)
+		s[i] = int16(8000*math.Sin(2*math.Pi*440*t) + 3000*math.Sin(2*math.Pi*5500*t))
+	}
+	return s
+}
+
+// snr returns the best signal-to-noise ratio in dB of got against want over
+// small delays, skipping the adaptation at the start.
+func snr(want, got []int16, maxDelay int) (float64, int) {
+	best, bestDelay := math.Inf(-1), 0
+	for delay := range maxDelay {
+		var sig, noise float64
+		for i := len(want) / 4; i < len(want)-maxDelay; i++ {
+			w, g := float64(want[i]), float64(got[i+delay])
+			sig += w * w
+			noise += (w - g) * (w - g)
+		}
+		if v := 10 * math.Log10(sig/noise); v > best {
+			best, bestDelay = v, delay
+		}
+	}
+	return best, bestDelay
+}
+
+func TestRoundTrip(t *testing.T) {
+	in := tone(SampleRate/2, SampleRate)
+	codes := NewEncoder().Encode(nil, in)
+	require.Len(t, codes, len(in)/2)
+
+	out := NewDecoder().Decode(nil, codes)
+	require.Len(t, out, len(in))
+	v, delay := snr(in, out, 48)
+	require.Greater(t, v, 30.0, "SNR %.1f dB at delay %d", v, delay)
+	require.Equal(t, 22, delay, "QMF delay")
+}
+
+// noise returns n samples of a deterministic full-band signal.
+func noise(n int) []int16 {
+	s := make([]int16, n)
+	x := uint32(1)
+	for i := range s {
+		x = x*1664525 + 1013904223
+		s[i] = int16(x>>16) / 4
+	}
+	return s
+}
+
+// Codes and samples of noise(64) from the spandsp G.722 codec (64 kbit/s,
+// 16 kHz), which follows the ITU-T reference arithmetic.
+var (
+	refCodes = []byte{
+		0x9b, 0x24, 0x84, 0x20, 0x84, 0x20, 0x8f, 0x09, 0xae, 0x06, 0x3a, 0xba, 0x1b, 0xb9, 0x9b, 0x14,
+		0xb3, 0x2e, 0x91, 0xb1, 0x1a, 0xef, 0x35, 0x37, 0x7b, 0x13, 0x35, 0xbc, 0x2c, 0x9f, 0x3e, 0x75,
+	}
+	refSamples = []int16{
+		-1, 0, 0, -1, -1, 0, 0, -3, -1, 9, -1, -32, 0, 99, 4, -226,
+		13, 428, -24, -806, -143, 1272, 1423, -140, -2505, -3672, -952, 2672, 410, -5667, -5826, 38,
+		3438, 1344, -1437, -1879, -416, 1174, 1135, -548, -2887, -3293, -1382, 1155, 3877, 5179, 1159, -5617,
+		-4721, 4414, 4623, -2815, -2705, 3419, 5465, 1897, 932, -177, 1958, 1495, -1017, -5299, -1960, 1006,
+	}
+)
+
+func TestReferenceVectors(t *testing.T) {
+	require.Equal(t, refCodes, NewEncoder().Encode(nil, noise(64)))
+	require.Equal(t, refSamples, NewDecoder().Decode(nil, refCodes))
+}
+
+func TestSilence(t *testing.T) {
+	codes := NewEncoder().Encode(nil, make([]int16, 320))
+	out := NewDecoder().Decode(nil, codes)
+	for _, s := range out {
+		require.InDelta(t, 0, s, 4)
+	}
+}
+
+func TestParameters(t *testing.T) {
+	par := NewCodecParameters(2)
+	require.Equal(t, uint64(16000), par.SampleRate())
+	require.Equal(t, uint(64000), par.Bitrate())
+	require.Equal(t, uint8(2), par.StreamIndex())
+	require.Equal(t, 20*time.Millisecond, Duration(160))
+}
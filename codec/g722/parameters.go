@@ -0,0 +1,49 @@
+// Package g722 holds the parameters, packets and a pure-Go codec for
+// 64 kbit/s G.722 wideband audio.
+package g722
+
+import (
+	"github.com/ugparu/gomedia"
+	"github.com/ugparu/gomedia/codec"
+)
+
+const (
+	// SampleRate is the audio sample rate of G.722.
+	SampleRate = 16000
+	// ClockRate is the RTP clock rate: RFC 3551 §4.5.2 keeps 8000 for
+	// historical reasons, so one RTP tick is one code byte.
+	ClockRate = 8000
+	// Bitrate is the only bitrate supported here (mode 1).
+	Bitrate = 64000
+)
+
+type CodecParameters struct {
+	codec.BaseParameters
+}
+
+// NewCodecParameters describes a mono 64 kbit/s G.722 stream.
+func NewCodecParameters(index uint8) *CodecParameters {
+	return &CodecParameters{
+		BaseParameters: codec.BaseParameters{
+			Index:     index,
+			BRate:     Bitrate,
+			CodecType: gomedia.G722,
+		},
+	}
+}
+
+func (p *CodecParameters) SampleFormat() gomedia.SampleFormat {
+	return gomedia.S16
+}
+
+func (p *CodecParameters) SampleRate() uint64 {
+	return SampleRate
+}
+
+func (p *CodecParameters) Channels() uint8 {
+	return 1
+}
+
+func (p *CodecParameters) Tag() string {
+	return "g722"
+}
@@ -0,0 +1,503 @@
+package g726
+
+import (
+	"errors"
+	"fmt"
+)
+
+// G.726 ADPCM at 16, 24, 32 and 40 kbit/s, integer-exact with the ITU
+// reference (derived from the public-domain Sun implementation, extended
+// with the 16 kbit/s tables of the 1990 recommendation).
+
+var ErrBitrate = errors.New("g726: bitrate must be 16000, 24000, 32000 or 40000")
+
+type rateTables struct {
+	bits   int   // code word size
+	states int   // quantizer output levels, see quantize
+	qtab   []int // decision levels
+	dqln   []int // log of the reconstructed difference, by code
+	wi     []int // scale factor multiplier, by code
+	fi     []int // speed control input, by code
+}
+
+var tables = map[int]*rateTables{
+	2: { //nolint:mnd
+		bits: 2, states: 4,
+		qtab: []int{261},
+		dqln: []int{116, 365, 365, 116},
+		wi:   []int{-704, 14048, 14048, -704},
+		fi:   []int{0, 0xe00, 0xe00, 0},
+	},
+	3: { //nolint:mnd
+		bits: 3, states: 7,
+		qtab: []int{8, 218, 331},
+		dqln: []int{-2048, 135, 273, 373, 373, 273, 135, -2048},
+		wi:   []int{-128, 960, 4384, 18624, 18624, 4384, 960, -128},
+		fi:   []int{0, 0x200, 0x400, 0xe00, 0xe00, 0x400, 0x200, 0},
+	},
+	4: { //nolint:mnd
+		bits: 4, states: 15,
+		qtab: []int{-124, 80, 178, 246, 300, 349, 400},
+		dqln: []int{-2048, 4, 135, 213, 273, 323, 373, 425, 425, 373, 323, 273, 213, 135, 4, -2048},
+		wi:   []int{-384, 576, 1312, 2048, 3584, 6336, 11360, 35904, 35904, 11360, 6336, 3584, 2048, 1312, 576, -384},
+		fi:   []int{0, 0, 0, 0x200, 0x200, 0x200, 0x600, 0xe00, 0xe00, 0x600, 0x200, 0x200, 0x200, 0, 0, 0},
+	},
+	5: { //nolint:mnd
+		bits: 5, states: 31,
+		qtab: []int{-122, -16, 68, 139, 198, 250, 298, 339, 378, 413, 445, 475, 502, 528, 553},
+		dqln: []int{
+			-2048, -66, 28, 104, 169, 224, 274, 318, 358, 395, 429, 459, 488, 514, 539, 566,
+			566, 539, 514, 488, 459, 429, 395, 358, 318, 274, 224, 169, 104, 28, -66, -2048,
+		},
+		wi: []int{
+			448, 448, 768, 1248, 1280, 1312, 1856, 3200, 4512, 5728, 7008, 8960, 11456, 14080, 16928, 22272,
+			22272, 16928, 14080, 11456, 8960, 7008, 5728, 4512, 3200, 1856, 1312, 1280, 1248, 768, 448, 448,
+		},
+		fi: []int{
+			0, 0, 0, 0, 0, 0x200, 0x200, 0x200, 0x200, 0x200, 0x400, 0x600, 0x800, 0xa00, 0xc00, 0xc00,
+			0xc00, 0xc00, 0xa00, 0x800, 0x600, 0x400, 0x200, 0x200, 0x200, 0x200, 0x200, 0, 0, 0, 0, 0,
+		},
+	},
+}
+
+// BitsPerSample returns the code word size for a bitrate in bit/s.
+func BitsPerSample(bitrate int) (int, error) {
+	bits := bitrate / SampleRate
+	if _, ok := tables[bits]; !ok || bitrate%SampleRate != 0 {
+		return 0, fmt.Errorf("%w: %d", ErrBitrate, bitrate)
+	}
+	return bits, nil
+}
+
+// quan returns the index of the first table entry above val.
+func quan(val int, table []int) int {
+	for i, t := range table {
+		if val < t {
+			return i
+		}
+	}
+	return len(table)
+}
+
+// log2Floor is quan over the powers of two 1..0x4000.
+func log2Floor(val int) int {
+	for i := range 15 {
+		if val < 1<<i {
+			return i
+		}
+	}
+	return 15 //nolint:mnd
+}
+
+// fmult multiplies a predictor coefficient by a sample in the 4-bit
+// exponent, 6-bit mantissa format of the reference.
+func fmult(an, srn int) int {
+	anmag := an
+	if an <= 0 {
+		anmag = -an & 0x1fff
+	}
+	anexp := log2Floor(anmag) - 6 //nolint:mnd
+	var anmant int
+	switch {
+	case anmag == 0:
+		anmant = 32
+	case anexp >= 0:
+		anmant = anmag >> anexp
+	default:
+		anmant = anmag << -anexp
+	}
+	wanexp := anexp + (srn>>6)&0xf - 13 //nolint:mnd
+	wanmant := (anmant*(srn&0x3f) + 0x30) >> 4
+	var ret int
+	if wanexp >= 0 {
+		ret = (wanmant << wanexp) & 0x7fff
+	} else {
+		ret = wanmant >> -wanexp
+	}
+	if (an ^ srn) < 0 {
+		return -ret
+	}
+	return ret
+}
+
+// floatFormat converts a sign-magnitude or signed value to the 11-bit float
+// format used for the predictor history.
+func floatFormat(mag int, negative bool) int {
+	if mag == 0 {
+		if negative {
+			return -992 // 0xfc20 as a 16-bit value
+		}
+		return 0x20
+	}
+	exp := log2Floor(mag)
+	v := exp<<6 + (mag<<6)>>exp
+	if negative {
+		v -= 0x400
+	}
+	return v
+}
+
+// state is the adaptive predictor and quantizer state, shared by encoder
+// and decoder.
+type state struct {
+	*rateTables
+	yl       int // locked (steady state) step size multiplier
+	yu       int // unlocked (non-steady state) step size multiplier
+	dms, dml int // short and long term energy estimates
+	ap       int // speed control
+	a        [2]int
+	b        [6]int
+	pk       [2]int
+	dq       [6]int
+	sr       [2]int
+	td       bool // tone detected
+}
+
+func newState(bitrate int) (state, error) {
+	bits, err := BitsPerSample(bitrate)
+	if err != nil {
+		return state{}, err
+	}
+	s := state{rateTables: tables[bits], yl: 34816, yu: 544} //nolint:mnd
+	for i := range s.sr {
+		s.sr[i] = 32
+	}
+	for i := range s.dq {
+		s.dq[i] = 32
+	}
+	return s, nil
+}
+
+func (s *state) predictZero() int {
+	var sezi int
+	for i := range s.b {
+		sezi += fmult(s.b[i]>>2, s.dq[i])
+	}
+	return sezi
+}
+
+func (s *state) predictPole() int {
+	return fmult(s.a[1]>>2, s.sr[1]) + fmult(s.a[0]>>2, s.sr[0])
+}
+
+func (s *state) stepSize() int {
+	if s.ap >= 256 { //nolint:mnd
+		return s.yu
+	}
+	y := s.yl >> 6
+	dif := s.yu - y
+	al := s.ap >> 2
+	switch {
+	case dif > 0:
+		y += (dif * al) >> 6
+	case dif < 0:
+		y += (dif*al + 0x3f) >> 6
+	}
+	return y
+}
+
+// quantize returns the code of the difference d at step size y.
+func (s *state) quantize(d, y int) int {
+	dqm := max(d, -d)
+	exp := log2Floor(dqm >> 1)
+	mant := ((dqm << 7) >> exp) & 0x7f
+	dl := exp<<7 + mant
+	dln := dl - y>>2
+
+	size := (s.states - 1) >> 1
+	i := quan(dln, s.qtab[:size])
+	switch {
+	case d < 0:
+		return size<<1 + 1 - i
+	case i == 0 && s.states&1 == 1:
+		return s.states // zero is coded as the one's complement
+	default:
+		return i
+	}
+}
+
+// reconstruct returns the quantized difference in sign-magnitude form.
+func reconstruct(sign bool, dqln, y int) int {
+	dql := dqln + y>>2
+	if dql < 0 {
+		if sign {
+			return -0x8000
+		}
+		return 0
+	}
+	dex := (dql >> 7) & 15         //nolint:mnd
+	dqt := 128 + dql&127           //nolint:mnd
+	dq := (dqt << 7) >> (14 - dex) //nolint:mnd
+	if sign {
+		return dq - 0x8000
+	}
+	return dq
+}
+
+// step runs the shared part of encoding and decoding for code i with the
+// given estimates, and returns the reconstructed signal.
+func (s *state) step(i, se, sez, y int) int {
+	sign := i&(1<<(s.bits-1)) != 0
+	dq := reconstruct(sign, s.dqln[i], y)
+	var sr int
+	switch {
+	case dq >= 0:
+		sr = se + dq
+	case s.bits == 5: //nolint:mnd
+		sr = se - dq&0x7fff
+	default:
+		sr = se - dq&0x3fff
+	}
+	// The reference keeps both in 16-bit registers.
+	sr = int(int16(sr))                //nolint:gosec
+	dqsez := int(int16(sr + sez - se)) //nolint:gosec
+	s.update(y, s.wi[i], s.fi[i], dq, sr, dqsez)
+	return sr
+}
+
+// update adapts the state after one sample.
+//
+//nolint:mnd,gocognit,cyclop,funlen // transcription of the ITU block diagram
+func (s *state) update(y, wi, fi, dq, sr, dqsez int) {
+	pk0 := 0
+	if dqsez < 0 {
+		pk0 = 1
+	}
+	mag := dq & 0x7fff
+
+	// TRANS
+	ylint := s.yl >> 15
+	ylfrac := (s.yl >> 10) & 0x1f
+	thr := (32 + ylfrac) << ylint
+	if ylint > 9 {
+		thr = 31 << 10
+	}
+	dqthr := (thr + thr>>1) >> 1
+	tr := s.td && mag > dqthr
+
+	// FUNCTW, FILTD, LIMB, FILTE
+	s.yu = max(544, min(5120, y+(wi-y)>>5))
+	s.yl += s.yu + (-s.yl)>>6
+
+	a2p := 0
+	if tr {
+		s.a = [2]int{}
+		s.b = [6]int{}
+	} else {
+		pks1 := pk0 ^ s.pk[0]
+
+		// UPA2
+		a2p = s.a[1] - s.a[1]>>7
+		if dqsez != 0 {
+			fa1 := -s.a[0]
+			if pks1 != 0 {
+				fa1 = s.a[0]
+			}
+			switch {
+			case fa1 < -8191:
+				a2p -= 0x100
+			case fa1 > 8191:
+				a2p += 0xff
+			default:
+				a2p += fa1 >> 5
+			}
+			if pk0^s.pk[1] != 0 {
+				switch {
+				case a2p <= -12160:
+					a2p = -12288
+				case a2p >= 12416:
+					a2p = 12288
+				default:
+					a2p -= 0x80
+				}
+			} else {
+				switch {
+				case a2p <= -12416:
+					a2p = -12288
+				case a2p >= 12160:
+					a2p = 12288
+				default:
+					a2p += 0x80
+				}
+			}
+		}
+		s.a[1] = a2p
+
+		// UPA1, LIMD
+		s.a[0] -= s.a[0] >> 8
+		if dqsez != 0 {
+			if pks1 == 0 {
+				s.a[0] += 192
+			} else {
+				s.a[0] -= 192
+			}
+		}
+		a1ul := 15360 - a2p
+		s.a[0] = max(-a1ul, min(a1ul, s.a[0]))
+
+		// UPB
+		for i := range s.b {
+			if s.bits == 5 {
+				s.b[i] -= s.b[i] >> 9
+			} else {
+				s.b[i] -= s.b[i] >> 8
+			}
+			if mag != 0 {
+				if (dq ^ s.dq[i]) >= 0 {
+					s.b[i] += 128
+				} else {
+					s.b[i] -= 128
+				}
+			}
+		}
+	}
+
+	// FLOAT A, FLOAT B
+	copy(s.dq[1:], s.dq[:5])
+	s.dq[0] = floatFormat(mag, dq < 0)
+	s.sr[1] = s.sr[0]
+	switch {
+	case sr > -32768:
+		s.sr[0] = floatFormat(max(sr, -sr), sr < 0)
+	default:
+		s.sr[0] = -992
+	}
+
+	// DELAY A, TONE
+	s.pk[1], s.pk[0] = s.pk[0], pk0
+	s.td = !tr && a2p < -11776
+
+	// FILTA, FILTB, SUBTC
+	s.dms += (fi - s.dms) >> 5
+	s.dml += (fi<<2 - s.dml) >> 7
+	switch {
+	case tr:
+		s.ap = 256
+	case y < 1536, s.td, max(s.dms<<2-s.dml, s.dml-s.dms<<2) >= s.dml>>3:
+		s.ap += (0x200 - s.ap) >> 4
+	default:
+		s.ap += (-s.ap) >> 4
+	}
+}
+
+// bitPacker carries code words that do not fill a whole byte yet.
+type bitPacker struct {
+	packing Packing
+	acc     uint32
+	n       int
+}
+
+func (p *bitPacker) put(dst []byte, code, bits int) []byte {
+	if p.packing == AAL2 {
+		p.acc = p.acc<<bits | uint32(code) //nolint:gosec
+		p.n += bits
+		for p.n >= 8 { //nolint:mnd
+			p.n -= 8
+			dst = append(dst, byte(p.acc>>p.n))
+			p.acc &= 1<<p.n - 1
+		}
+		return dst
+	}
+	p.acc |= uint32(code) << p.n //nolint:gosec
+	p.n += bits
+	for p.n >= 8 { //nolint:mnd
+		dst = append(dst, byte(p.acc))
+		p.acc >>= 8
+		p.n -= 8
+	}
+	return dst
+}
+
+// get feeds one byte and calls fn for every complete code word.
+func (p *bitPacker) get(b byte, bits int, fn func(code int)) {
+	mask := uint32(1)<<bits - 1
+	if p.packing == AAL2 {
+		p.acc = p.acc<<8 | uint32(b)
+		p.n += 8
+		for p.n >= bits {
+			p.n -= bits
+			fn(int(p.acc >> p.n & mask))
+			p.acc &= 1<<p.n - 1
+		}
+		return
+	}
+	p.acc |= uint32(b) << p.n
+	p.n += 8
+	for p.n >= bits {
+		fn(int(p.acc & mask))
+		p.acc >>= bits
+		p.n -= bits
+	}
+}
+
+// Encoder codes 8 kHz mono S16 samples into packed G.726 code words.
+type Encoder struct {
+	state
+	bitPacker
+}
+
+// NewEncoder returns an encoder for a bitrate of 16000, 24000, 32000 or
+// 40000 bit/s.
+func NewEncoder(bitrate int, packing Packing) (*Encoder, error) {
+	s, err := newState(bitrate)
+	if err != nil {
+		return nil, err
+	}
+	return &Encoder{state: s, bitPacker: bitPacker{packing: packing}}, nil
+}
+
+// Encode appends the packed code words of samples to dst. Code words that
+// do not complete a byte are kept for the next call.
+func (e *Encoder) Encode(dst []byte, samples []int16) []byte {
+	for _, sample := range samples {
+		dst = e.put(dst, e.encodeSample(sample), e.bits)
+	}
+	return dst
+}
+
+func (e *Encoder) encodeSample(sample int16) int {
+	sl := int(sample) >> 2 // 14-bit dynamic range
+	sezi := e.predictZero()
+	sez := sezi >> 1
+	se := (sezi + e.predictPole()) >> 1
+	y := e.stepSize()
+	i := e.quantize(sl-se, y)
+	e.step(i, se, sez, y)
+	return i
+}
+
+// Decoder turns packed G.726 code words back into 8 kHz mono S16 samples.
+type Decoder struct {
+	state
+	bitPacker
+}
+
+// NewDecoder returns a decoder for a bitrate of 16000, 24000, 32000 or
+// 40000 bit/s.
+func NewDecoder(bitrate int, packing Packing) (*Decoder, error) {
+	s, err := newState(bitrate)
+	if err != nil {
+		return nil, err
+	}
+	return &Decoder{state: s, bitPacker: bitPacker{packing: packing}}, nil
+}
+
+// Decode appends the samples of the code words in data to dst. Bits of an
+// incomplete code word are kept for the next call.
+func (d *Decoder) Decode(dst []int16, data []byte) []int16 {
+	for _, b := range data {
+		d.get(b, d.bits, func(code int) {
+			dst = append(dst, d.decodeSample(code))
+		})
+	}
+	return dst
+}
+
+func (d *Decoder) decodeSample(i int) int16 {
+	sezi := d.predictZero()
+	sez := sezi >> 1
+	se := (sezi + d.predictPole()) >> 1
+	y := d.stepSize()
+	sr := d.step(i, se, sez, y)
+	return int16(max(-32768, min(32767, sr<<2))) //nolint:gosec,mnd // clamped
+}
@@ -0,0 +1,123 @@
+package g726
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func tone(n int) []int16 {
+	s := make([]int16, n)
+	for i := range s {
+		t := float64(i) / SampleRate
+		s[i] = int16(6000*math.Sin(2*math.Pi*300*t) + 3000*math.Sin(2*math.Pi*1100*t))
+	}
+	return s
+}
+
+// snr skips the first quarter, where the quantizer is still adapting.
+func snr(want, got []int16) float64 {
+	var sig, noise float64
+	for i := len(want) / 4; i < len(want); i++ {
+		w, g := float64(want[i]), float64(got[i])
+		sig += w * w
+		noise += (w - g) * (w - g)
+	}
+	return 10 * math.Log10(sig/noise)
+}
+
+func TestRoundTrip(t *testing.T) {
+	in := tone(SampleRate / 2)
+	for bitrate, minSNR := range map[int]float64{16000: 15, 24000: 23, 32000: 30, 40000: 37} {
+		for _, packing := range []Packing{RFC3551, AAL2} {
+			enc, err := NewEncoder(bitrate, packing)
+			require.NoError(t, err)
+			dec, err := NewDecoder(bitrate, packing)
+			require.NoError(t, err)
+
+			// Odd chunk sizes exercise the carried partial bytes.
+			var codes []byte
+			for i := 0; i < len(in); i += 7 {
+				codes = enc.Encode(codes, in[i:min(i+7, len(in))])
+			}
+			require.Len(t, codes, len(in)*bitrate/SampleRate/8)
+			var out []int16
+			for i := 0; i < len(codes); i += 3 {
+				out = dec.Decode(out, codes[i:min(i+3, len(codes))])
+			}
+			require.Len(t, out, len(in))
+			v := snr(in, out)
+			require.Greater(t, v, minSNR, "%d %v: SNR %.1f dB", bitrate, packing, v)
+		}
+	}
+}
+
+// noise returns n samples of a deterministic full-band signal.
+func noise(n int) []int16 {
+	s := make([]int16, n)
+	x := uint32(1)
+	for i := range s {
+		x = x*1664525 + 1013904223
+		s[i] = int16(x>>16) / 4
+	}
+	return s
+}
+
+// Codes and samples of noise(64) at 32 kbit/s from the public-domain Sun
+// reference (g721_encoder/g721_decoder, 16-bit linear), packed RFC 3551 style.
+var (
+	refCodes32 = []byte{
+		0x77, 0x88, 0x77, 0x88, 0x81, 0x47, 0xdb, 0xf2, 0x5d, 0x9a, 0x12, 0x52, 0xb2, 0x49, 0xc3, 0x2e,
+		0xf6, 0xa6, 0xe6, 0xbe, 0xed, 0x62, 0x3b, 0x26, 0xdb, 0xf4, 0x41, 0x2b, 0x16, 0x26, 0x6d, 0xa1,
+	}
+	refSamples32 = []int16{
+		88, 104, -128, -180, 232, 372, -552, -1012, 208, -1172, 3908, 7024, -7104, -3492, 3580, 204,
+		-2440, 5260, -5552, -8268, 4320, 1996, 732, 5756, 2064, -4820, -6824, 5960, 4472, -4464, -1300, 3000,
+		5680, -564, 5720, -6660, 9136, -932, -2772, -5552, -1296, -944, 2212, 6332, -5164, 2496, 8716, 2288,
+		-7120, -2124, 5964, -32, -668, 3556, -3108, 804, 5712, 376, 4984, 2580, -2556, 6364, 1920, -7016,
+	}
+)
+
+func TestReferenceVectors(t *testing.T) {
+	enc, err := NewEncoder(32000, RFC3551)
+	require.NoError(t, err)
+	require.Equal(t, refCodes32, enc.Encode(nil, noise(64)))
+
+	dec, err := NewDecoder(32000, RFC3551)
+	require.NoError(t, err)
+	require.Equal(t, refSamples32, dec.Decode(nil, refCodes32))
+}
+
+func TestPacking(t *testing.T) {
+	// Codes 1, 2, 3, 4 at 32 kbit/s.
+	for packing, want := range map[Packing][]byte{RFC3551: {0x21, 0x43}, AAL2: {0x12, 0x34}} {
+		p := bitPacker{packing: packing}
+		var got []byte
+		for code := 1; code <= 4; code++ {
+			got = p.put(got, code, 4)
+		}
+		require.Equal(t, want, got, packing.String())
+
+		var codes []int
+		for _, b := range got {
+			p.get(b, 4, func(code int) { codes = append(codes, code) })
+		}
+		require.Equal(t, []int{1, 2, 3, 4}, codes)
+	}
+}
+
+func TestCodecParameters(t *testing.T) {
+	par, err := NewCodecParameters(1, 24000, AAL2)
+	require.NoError(t, err)
+	require.Equal(t, 3, par.BitsPerSample())
+	require.Equal(t, "AAL2-G726-24", par.EncodingName())
+	require.Equal(t, uint64(8000), par.SampleRate())
+	require.Equal(t, 20*time.Millisecond, par.Duration(60))
+
+	_, err = NewCodecParameters(0, 64000, RFC3551)
+	require.ErrorIs(t, err, ErrBitrate)
+	_, err = NewEncoder(12345, RFC3551)
+	require.ErrorIs(t, err, ErrBitrate)
+}
@@ -0,0 +1,96 @@
+// Package g726 holds the parameters, packets and a pure-Go codec for G.726
+// ADPCM at 16, 24, 32 and 40 kbit/s.
+package g726
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ugparu/gomedia"
+	"github.com/ugparu/gomedia/codec"
+)
+
+// SampleRate is the sample rate and RTP clock rate of G.726.
+const SampleRate = 8000
+
+// Packing is the order of code words within a byte.
+type Packing uint8
+
+const (
+	// RFC3551 packs the first code word into the least significant bits
+	// (SDP encoding names G726-16 to G726-40).
+	RFC3551 Packing = iota
+	// AAL2 packs the first code word into the most significant bits, as
+	// ITU-T I.366.2 (SDP encoding names AAL2-G726-16 to AAL2-G726-40).
+	AAL2
+)
+
+func (p Packing) String() string {
+	if p == AAL2 {
+		return "AAL2"
+	}
+	return "RFC3551"
+}
+
+type CodecParameters struct {
+	codec.BaseParameters
+	bitsPerSample int
+	packing       Packing
+}
+
+// NewCodecParameters describes a mono G.726 stream of the given bitrate
+// (16000, 24000, 32000 or 40000 bit/s).
+func NewCodecParameters(index uint8, bitrate int, packing Packing) (*CodecParameters, error) {
+	bits, err := BitsPerSample(bitrate)
+	if err != nil {
+		return nil, err
+	}
+	return &CodecParameters{
+		BaseParameters: codec.BaseParameters{
+			Index:     index,
+			BRate:     uint(bitrate), //nolint:gosec // validated above
+			CodecType: gomedia.G726,
+		},
+		bitsPerSample: bits,
+		packing:       packing,
+	}, nil
+}
+
+func (p *CodecParameters) SampleFormat() gomedia.SampleFormat {
+	return gomedia.S16
+}
+
+func (p *CodecParameters) SampleRate() uint64 {
+	return SampleRate
+}
+
+func (p *CodecParameters) Channels() uint8 {
+	return 1
+}
+
+// BitsPerSample returns the code word size, 2 to 5 bits.
+func (p *CodecParameters) BitsPerSample() int {
+	return p.bitsPerSample
+}
+
+func (p *CodecParameters) Packing() Packing {
+	return p.packing
+}
+
+// EncodingName returns the SDP encoding name, e.g. G726-32.
+func (p *CodecParameters) EncodingName() string {
+	name := fmt.Sprintf("G726-%d", p.bitsPerSample*SampleRate/1000) //nolint:mnd
+	if p.packing == AAL2 {
+		return "AAL2-" + name
+	}
+	return name
+}
+
+func (p *CodecParameters) Tag() string {
+	return "g726"
+}
+
+// Duration returns the play time of n bytes of code words.
+func (p *CodecParameters) Duration(n int) time.Duration {
+	return time.Duration(n*8/p.bitsPerSample) * time.Second / SampleRate //nolint:mnd
+}
@@ -9,8 +9,9 @@ const bitPerSample = 16
 
 type CodecParameters struct {
 	codec.BaseParameters
-	chCount    uint8
-	sampleRate uint64
+	chCount      uint8
+	sampleRate   uint64
+	sampleFormat gomedia.SampleFormat
 }
 
 func NewCodecParameters(index uint8, ct gomedia.CodecType, channelCount uint8, sr uint64) *CodecParameters {
@@ -20,13 +21,26 @@ func NewCodecParameters(index uint8, ct gomedia.CodecType, channelCount uint8, s
 			BRate:     uint(sr) * bitPerSample * uint(channelCount),
 			CodecType: ct,
 		},
-		sampleRate: sr,
-		chCount:    channelCount,
+		sampleRate:   sr,
+		chCount:      channelCount,
+		sampleFormat: gomedia.S16,
 	}
 }
 
+// NewRawCodecParameters describes gomedia.PCM audio in any sample format,
+// little-endian; planar formats store the channel planes one after another.
+func NewRawCodecParameters(index uint8, sf gomedia.SampleFormat, channelCount uint8, sr uint64) *CodecParameters {
+	p := NewCodecParameters(index, gomedia.PCM, channelCount, sr)
+	p.sampleFormat = sf
+	p.BRate = uint(sr) * uint(sf.BytesPerSample()) * 8 * uint(channelCount) //nolint:mnd,gosec // bits per byte
+	return p
+}
+
 func (p *CodecParameters) SampleFormat() gomedia.SampleFormat {
-	return gomedia.S16
+	if p.sampleFormat == 0 {
+		return gomedia.S16
+	}
+	return p.sampleFormat
 }
 
 func (p *CodecParameters) SampleRate() uint64 {
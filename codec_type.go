@@ -32,6 +32,8 @@ var (
 	NELLYMOSER = makeAudioCodecType(avCodecTypeMagic + 5) //nolint:mnd
 	PCM        = makeAudioCodecType(avCodecTypeMagic + 6) //nolint:mnd
 	OPUS       = makeAudioCodecType(avCodecTypeMagic + 7) //nolint:mnd
+	G722       = makeAudioCodecType(avCodecTypeMagic + 8) //nolint:mnd
+	G726       = makeAudioCodecType(avCodecTypeMagic + 9) //nolint:mnd
 )
 
 const (
@@ -67,6 +69,10 @@ func (ct CodecType) String() string {
 		return "PCM"
 	case OPUS:
 		return "OPUS"
+	case G722:
+		return "G722"
+	case G726:
+		return "G726"
 	}
 	return "UNKNOWN"
 }
@@ -0,0 +1,87 @@
+// Package adpcm contains pure-Go decoders for the G.722 and G.726 ADPCM
+// codecs. Both produce mono S16LE PCM.
+package adpcm
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/ugparu/gomedia"
+	"github.com/ugparu/gomedia/codec/g722"
+	"github.com/ugparu/gomedia/codec/g726"
+	"github.com/ugparu/gomedia/decoder"
+	"github.com/ugparu/gomedia/utils/buffer"
+)
+
+type g722Decoder struct {
+	dec     *g722.Decoder
+	samples []int16
+}
+
+func NewG722Decoder() decoder.InnerAudioDecoder {
+	return new(g722Decoder)
+}
+
+func (d *g722Decoder) Init(_ gomedia.AudioCodecParameters) error {
+	d.dec = g722.NewDecoder()
+	return nil
+}
+
+func (d *g722Decoder) Decode(inData []byte, ring *buffer.GrowingRingAlloc) ([]byte, *buffer.SlotHandle, error) {
+	d.samples = d.dec.Decode(d.samples[:0], inData)
+	out, slot := s16le(d.samples, ring)
+	return out, slot, nil
+}
+
+func (d *g722Decoder) Close() {
+	d.dec = nil
+	d.samples = nil
+}
+
+type g726Decoder struct {
+	dec     *g726.Decoder
+	samples []int16
+}
+
+// NewG726Decoder returns a G.726 decoder. The bitrate and packing are taken
+// from the *g726.CodecParameters passed to Init.
+func NewG726Decoder() decoder.InnerAudioDecoder {
+	return new(g726Decoder)
+}
+
+func (d *g726Decoder) Init(params gomedia.AudioCodecParameters) (err error) {
+	par, ok := params.(*g726.CodecParameters)
+	if !ok {
+		return fmt.Errorf("g726 decoder: unexpected codec parameters %T", params)
+	}
+	d.dec, err = g726.NewDecoder(int(par.Bitrate()), par.Packing()) //nolint:gosec // validated by NewCodecParameters
+	return err
+}
+
+func (d *g726Decoder) Decode(inData []byte, ring *buffer.GrowingRingAlloc) ([]byte, *buffer.SlotHandle, error) {
+	d.samples = d.dec.Decode(d.samples[:0], inData)
+	out, slot := s16le(d.samples, ring)
+	return out, slot, nil
+}
+
+func (d *g726Decoder) Close() {
+	d.dec = nil
+	d.samples = nil
+}
+
+// s16le serializes samples, into ring memory when available.
+func s16le(samples []int16, ring *buffer.GrowingRingAlloc) ([]byte, *buffer.SlotHandle) {
+	size := len(samples) * 2 //nolint:mnd
+	var out []byte
+	var slot *buffer.SlotHandle
+	if ring != nil {
+		out, slot = ring.Alloc(size)
+	}
+	if out == nil {
+		out = make([]byte, size)
+	}
+	for i, s := range samples {
+		binary.LittleEndian.PutUint16(out[2*i:], uint16(s)) //nolint:gosec // two's complement
+	}
+	return out, slot
+}
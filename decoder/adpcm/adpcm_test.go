@@ -0,0 +1,69 @@
+//nolint:mnd // Test file uses many literal values for expected results
+package adpcm
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/ugparu/gomedia/codec/g722"
+	"github.com/ugparu/gomedia/codec/g726"
+	"github.com/ugparu/gomedia/utils/buffer"
+)
+
+func tone(rate, n int) []int16 {
+	out := make([]int16, n)
+	for i := range out {
+		out[i] = int16(8000 * math.Sin(2*math.Pi*440*float64(i)/float64(rate)))
+	}
+	return out
+}
+
+func TestG722Decoder(t *testing.T) {
+	code := g722.NewEncoder().Encode(nil, tone(g722.SampleRate, 320))
+	require.Len(t, code, 160)
+
+	dec := NewG722Decoder()
+	require.NoError(t, dec.Init(g722.NewCodecParameters(0)))
+	defer dec.Close()
+
+	ring := buffer.NewGrowingRingAlloc(4096)
+	out, slot, err := dec.Decode(code, ring)
+	require.NoError(t, err)
+	require.NotNil(t, slot)
+	require.Len(t, out, 640)
+
+	want := g722.NewDecoder().Decode(nil, code)
+	for i, s := range want {
+		require.Equal(t, s, int16(binary.LittleEndian.Uint16(out[2*i:]))) //nolint:gosec
+	}
+}
+
+func TestG726Decoder(t *testing.T) {
+	par, err := g726.NewCodecParameters(0, 24000, g726.AAL2)
+	require.NoError(t, err)
+	enc, err := g726.NewEncoder(24000, g726.AAL2)
+	require.NoError(t, err)
+	code := enc.Encode(nil, tone(g726.SampleRate, 160))
+	require.Len(t, code, 60)
+
+	dec := NewG726Decoder()
+	require.NoError(t, dec.Init(par))
+	defer dec.Close()
+
+	out, slot, err := dec.Decode(code, nil)
+	require.NoError(t, err)
+	require.Nil(t, slot)
+	require.Len(t, out, 320)
+
+	ref, err := g726.NewDecoder(24000, g726.AAL2)
+	require.NoError(t, err)
+	for i, s := range ref.Decode(nil, code) {
+		require.Equal(t, s, int16(binary.LittleEndian.Uint16(out[2*i:]))) //nolint:gosec
+	}
+}
+
+func TestG726Decoder_WrongParameters(t *testing.T) {
+	require.Error(t, NewG726Decoder().Init(g722.NewCodecParameters(0)))
+}
@@ -1,7 +1,7 @@
 #include <libavutil/imgutils.h>
 #include "decoder_ffmpeg_cpu.h"
 
-int init_cpu_decoder(cpuDecoder *dec, AVCodecParameters *par) {
+int init_cpu_decoder(cpuDecoder *dec, AVCodecParameters *par, enum AVPixelFormat out_format) {
     dec->packet = av_packet_alloc();
     if (!dec->packet) {
         return AVERROR(ENOMEM);
@@ -42,39 +42,71 @@ int init_cpu_decoder(cpuDecoder *dec, AVCodecParameters *par) {
         return ret;
     }
 
-    int width = par->width;
-    int height = par->height;
+    dec->out_format = out_format;
+    return configure_cpu_output(dec, 0, 0, 0, 0, 0, 0);
+}
 
-	float wScale = (float)(MAX_WIDTH) / (float)(width);
-	float hScale = (float)(MAX_HEIGHT) / (float)(height);
-	float scale = wScale;
-    if (hScale < scale) {
-        scale = hScale;
-    } 
-	if (scale < 1) {
-		width = scale * width;
-		height = scale * height;
-	}
+// configure_cpu_output (re)creates the scaler for the crop rectangle and
+// output size. A zero crop size selects the whole picture, a zero output
+// size the crop size limited to MAX_WIDTH x MAX_HEIGHT.
+int configure_cpu_output(cpuDecoder *dec, int crop_x, int crop_y, int crop_w, int crop_h, int out_w, int out_h) {
+    if (crop_w <= 0 || crop_h <= 0) {
+        crop_x = 0;
+        crop_y = 0;
+        crop_w = dec->ctxt->width;
+        crop_h = dec->ctxt->height;
+    }
+    if (crop_x < 0 || crop_y < 0 || crop_x + crop_w > dec->ctxt->width || crop_y + crop_h > dec->ctxt->height) {
+        return AVERROR(EINVAL);
+    }
+
+    int width = out_w;
+    int height = out_h;
+    if (width <= 0 || height <= 0) {
+        width = crop_w;
+        height = crop_h;
+
+        float wScale = (float)(MAX_WIDTH) / (float)(width);
+        float hScale = (float)(MAX_HEIGHT) / (float)(height);
+        float scale = wScale;
+        if (hScale < scale) {
+            scale = hScale;
+        }
+        if (scale < 1) {
+            width = scale * width;
+            height = scale * height;
+        }
+    }
 
-    dec->scale_ctxt = sws_getContext(par->width, par->height, par->format, width, height,
-                                     AV_PIX_FMT_RGB24, SWS_FAST_BILINEAR, NULL, NULL, NULL);
+    if (dec->scale_ctxt) {
+        sws_freeContext(dec->scale_ctxt);
+    }
+    dec->scale_ctxt = sws_getContext(crop_w, crop_h, dec->ctxt->pix_fmt, width, height,
+                                     dec->out_format, SWS_FAST_BILINEAR, NULL, NULL, NULL);
     if (!dec->scale_ctxt) {
         return AVERROR(ENOMEM);
     }
 
+    if (dec->rgb_frame) {
+        av_frame_free(&dec->rgb_frame);
+    }
     dec->rgb_frame = av_frame_alloc();
     if (!dec->rgb_frame) {
         return AVERROR(ENOMEM);
     }
-	dec->rgb_frame->width = width;
-	dec->rgb_frame->height = height;
-	dec->rgb_frame->format = AV_PIX_FMT_RGB24;
+    dec->rgb_frame->width = width;
+    dec->rgb_frame->height = height;
+    dec->rgb_frame->format = dec->out_format;
 
-    ret = av_frame_get_buffer(dec->rgb_frame, 0);
-	if (ret < 0) {
-		return ret;
-	}
+    int ret = av_frame_get_buffer(dec->rgb_frame, 0);
+    if (ret < 0) {
+        return ret;
+    }
 
+    dec->crop_x = crop_x;
+    dec->crop_y = crop_y;
+    dec->crop_w = crop_w;
+    dec->crop_h = crop_h;
     return 0;
 }
 
@@ -101,13 +133,24 @@ int decode_cpu_packet(cpuDecoder *dec, uint8_t *buffer) {
     }
 
     dec->frame->pts = dec->frame->best_effort_timestamp;
+    if (dec->crop_w != dec->frame->width || dec->crop_h != dec->frame->height) {
+        dec->frame->crop_left = dec->crop_x;
+        dec->frame->crop_top = dec->crop_y;
+        dec->frame->crop_right = dec->frame->width - dec->crop_x - dec->crop_w;
+        dec->frame->crop_bottom = dec->frame->height - dec->crop_y - dec->crop_h;
+        ret = av_frame_apply_cropping(dec->frame, AV_FRAME_CROP_UNALIGNED);
+        if (ret < 0) {
+            return ret;
+        }
+    }
     ret = sws_scale_frame(dec->scale_ctxt, dec->rgb_frame, dec->frame);
 
     if (ret<0) {
         return ret;
     }
 
-    ret = av_image_copy_to_buffer(buffer, dec->rgb_frame->width*dec->rgb_frame->height*3, 
+    int size = av_image_get_buffer_size(dec->rgb_frame->format, dec->rgb_frame->width, dec->rgb_frame->height, 1);
+    ret = av_image_copy_to_buffer(buffer, size,
         (const uint8_t * const*)dec->rgb_frame->data, (const int*)dec->rgb_frame->linesize,
 		dec->rgb_frame->format, dec->rgb_frame->width, dec->rgb_frame->height, 1);
     if (ret < 0) {
@@ -4,17 +4,25 @@ package cpu
 //#include "decoder_ffmpeg_cpu.h"
 import "C"
 import (
+	"fmt"
+	"image"
 	"unsafe"
 
 	"github.com/ugparu/gomedia"
 	"github.com/ugparu/gomedia/decoder"
 	"github.com/ugparu/gomedia/decoder/video"
 	"github.com/ugparu/gomedia/frame/rgb"
+	"github.com/ugparu/gomedia/frame/yuv"
 )
 
 type ffmpegCPUDecoder struct {
-	dcd  *C.cpuDecoder
-	pool *rgb.FramePool
+	dcd      *C.cpuDecoder
+	pixFmt   gomedia.PixelFormat
+	pool     *rgb.FramePool
+	i420Pool *yuv.I420Pool
+	nv12Pool *yuv.NV12Pool
+	output   gomedia.VideoOutput
+	ready    bool // init_cpu_decoder succeeded, the scaler can be reconfigured
 }
 
 func NewFFmpegCPUDecoder() decoder.InnerVideoDecoder {
@@ -31,16 +39,86 @@ func (dcd *ffmpegCPUDecoder) Init(codecPar gomedia.VideoCodecParameters) (err er
 		return err
 	}
 
+	outFmt := C.enum_AVPixelFormat(C.AV_PIX_FMT_RGB24)
+	switch dcd.pixFmt {
+	case gomedia.I420:
+		outFmt = C.AV_PIX_FMT_YUV420P
+	case gomedia.NV12:
+		outFmt = C.AV_PIX_FMT_NV12
+	}
+
+	dcd.ready = false
 	dcd.dcd = new(C.cpuDecoder)
-	if ret := C.init_cpu_decoder(dcd.dcd, cPar); ret < 0 {
+	if ret := C.init_cpu_decoder(dcd.dcd, cPar, outFmt); ret < 0 {
 		return video.NewFFmpegError("can not init cpu decoder", int(ret))
 	}
+	dcd.ready = true
+
+	if !dcd.output.IsZero() {
+		return dcd.configureOutput()
+	}
+	dcd.newPools()
+	return
+}
+
+// SetOutput implements decoder.OutputSetter: cropping and scaling happen in
+// the same swscale pass as the pixel format conversion.
+func (dcd *ffmpegCPUDecoder) SetOutput(out gomedia.VideoOutput) error {
+	dcd.output = out
+	if !dcd.ready {
+		return nil
+	}
+	if err := dcd.configureOutput(); err != nil {
+		// Fall back to full frames so the caller's RGB scaling starts from
+		// the whole picture.
+		dcd.output = gomedia.VideoOutput{}
+		_ = dcd.configureOutput()
+		return err
+	}
+	return nil
+}
+
+func (dcd *ffmpegCPUDecoder) configureOutput() error {
+	var crop image.Rectangle
+	var size image.Point
+	if !dcd.output.IsZero() {
+		crop, size = dcd.output.Resolve(image.Rect(0, 0, int(dcd.dcd.ctxt.width), int(dcd.dcd.ctxt.height)))
+	}
+	if ret := C.configure_cpu_output(dcd.dcd,
+		C.int(crop.Min.X), C.int(crop.Min.Y), C.int(crop.Dx()), C.int(crop.Dy()),
+		C.int(size.X), C.int(size.Y)); ret < 0 {
+		return video.NewFFmpegError("can not configure cpu decoder output", int(ret))
+	}
+	dcd.newPools()
+	return nil
+}
 
+// newPools sizes the frame pool of the current pixel format to the scaler
+// output.
+func (dcd *ffmpegCPUDecoder) newPools() {
 	w := int(dcd.dcd.rgb_frame.width)
 	h := int(dcd.dcd.rgb_frame.height)
-	dcd.pool = rgb.NewFramePool(w, h)
+	dcd.pool, dcd.i420Pool, dcd.nv12Pool = nil, nil, nil
+	switch dcd.pixFmt {
+	case gomedia.I420:
+		dcd.i420Pool = yuv.NewI420Pool(w, h)
+	case gomedia.NV12:
+		dcd.nv12Pool = yuv.NewNV12Pool(w, h)
+	default:
+		dcd.pool = rgb.NewFramePool(w, h)
+	}
+}
 
-	return
+// SetPixelFormat implements decoder.PixelFormatSetter. swscale converts
+// straight into the planes of the pooled yuv frames.
+func (dcd *ffmpegCPUDecoder) SetPixelFormat(pf gomedia.PixelFormat) error {
+	switch pf {
+	case gomedia.RGB24, gomedia.I420, gomedia.NV12:
+		dcd.pixFmt = pf
+		return nil
+	default:
+		return fmt.Errorf("unsupported pixel format: %v", pf)
+	}
 }
 
 func (dcd *ffmpegCPUDecoder) Feed(pkt gomedia.VideoPacket) (err error) {
@@ -60,8 +138,22 @@ func (dcd *ffmpegCPUDecoder) Decode(pkt gomedia.VideoPacket) (rgb.ReleasableImag
 		return nil, err
 	}
 
-	img := dcd.pool.Get()
-	ret := C.decode_cpu_packet(dcd.dcd, (*C.uint8_t)(unsafe.Pointer(&img.Pix[0])))
+	// The yuv frames keep their planes in one contiguous buffer starting at
+	// Y, matching av_image_copy_to_buffer with alignment 1.
+	var img rgb.ReleasableImage
+	var buf *byte
+	switch dcd.pixFmt {
+	case gomedia.I420:
+		frame := dcd.i420Pool.Get()
+		img, buf = frame, &frame.Y[0]
+	case gomedia.NV12:
+		frame := dcd.nv12Pool.Get()
+		img, buf = frame, &frame.Y[0]
+	default:
+		frame := dcd.pool.Get()
+		img, buf = frame, &frame.Pix[0]
+	}
+	ret := C.decode_cpu_packet(dcd.dcd, (*C.uint8_t)(unsafe.Pointer(buf)))
 	if ret != 0 {
 		img.Release()
 		if ret > 0 {
@@ -73,5 +165,6 @@ func (dcd *ffmpegCPUDecoder) Decode(pkt gomedia.VideoPacket) (rgb.ReleasableImag
 }
 
 func (dcd *ffmpegCPUDecoder) Close() {
+	dcd.ready = false
 	C.close_cpu_decoder(dcd.dcd)
 }
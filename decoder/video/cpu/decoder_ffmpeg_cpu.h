@@ -18,9 +18,12 @@ typedef struct {
     AVPacket *packet;
     struct SwsContext *scale_ctxt;
     AVFrame *rgb_frame;
+    enum AVPixelFormat out_format;
+    int crop_x, crop_y, crop_w, crop_h;
 } cpuDecoder;
 
-int init_cpu_decoder(cpuDecoder *dec, AVCodecParameters *par);
+int init_cpu_decoder(cpuDecoder *dec, AVCodecParameters *par, enum AVPixelFormat out_format);
+int configure_cpu_output(cpuDecoder *dec, int crop_x, int crop_y, int crop_w, int crop_h, int out_w, int out_h);
 int decode_cpu_packet(cpuDecoder *dec, uint8_t *buffer);
 void close_cpu_decoder(cpuDecoder *dec);
 
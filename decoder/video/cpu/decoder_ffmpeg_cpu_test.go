@@ -3,6 +3,7 @@ package cpu_test
 import (
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"image"
 	"os"
 	"testing"
@@ -11,9 +12,12 @@ import (
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 
+	"github.com/ugparu/gomedia"
 	codech264 "github.com/ugparu/gomedia/codec/h264"
 	codech265 "github.com/ugparu/gomedia/codec/h265"
+	"github.com/ugparu/gomedia/decoder"
 	"github.com/ugparu/gomedia/decoder/video/cpu"
+	"github.com/ugparu/gomedia/frame/yuv"
 	"github.com/ugparu/gomedia/mocks"
 )
 
@@ -250,6 +254,76 @@ func TestCPU_Decode_H264_ProducesImage(t *testing.T) {
 		"decoded image must have non-zero dimensions")
 }
 
+// TestCPU_Decode_H264_I420 checks that SetPixelFormat switches the output to
+// pooled yuv.I420 frames of the same size as the RGB output.
+func TestCPU_Decode_H264_I420(t *testing.T) {
+	t.Parallel()
+	par := loadH264Params(t, h264AACDataDir)
+	pkts := loadPackets(t, h264AACDataDir)
+
+	d := cpu.NewFFmpegCPUDecoder()
+	defer d.Close()
+	require.NoError(t, d.(decoder.PixelFormatSetter).SetPixelFormat(gomedia.I420)) //nolint:forcetypeassert
+	require.NoError(t, d.Init(par))
+
+	for _, p := range pkts {
+		if p.Codec != "H264" {
+			continue
+		}
+		img, err := d.Decode(makeH264Packet(t, p, par))
+		if errors.Is(err, decoder.ErrNeedMoreData) {
+			continue
+		}
+		require.NoError(t, err)
+		frame, ok := img.(*yuv.I420)
+		require.True(t, ok, "got %T", img)
+		require.Equal(t, image.Rect(0, 0, int(par.Width()), int(par.Height())), frame.Bounds()) //nolint:gosec
+		require.Equal(t, frame.Bounds(), frame.GetRGB().Bounds())
+		frame.Release()
+		return
+	}
+	t.Fatal("no frame decoded")
+}
+
+// TestCPU_Decode_H264_Output crops the left half of the picture and scales
+// it to a fixed width, then switches back to full frames.
+func TestCPU_Decode_H264_Output(t *testing.T) {
+	t.Parallel()
+	par := loadH264Params(t, h264AACDataDir)
+	pkts := loadPackets(t, h264AACDataDir)
+	w, h := int(par.Width()), int(par.Height()) //nolint:gosec
+
+	d := cpu.NewFFmpegCPUDecoder()
+	defer d.Close()
+	setter := d.(decoder.OutputSetter) //nolint:forcetypeassert
+	require.NoError(t, setter.SetOutput(gomedia.VideoOutput{Crop: image.Rect(0, 0, w/2, h), Width: 160}))
+	require.NoError(t, d.Init(par))
+
+	var decoded int
+	for _, p := range pkts {
+		if p.Codec != "H264" {
+			continue
+		}
+		img, err := d.Decode(makeH264Packet(t, p, par))
+		if errors.Is(err, decoder.ErrNeedMoreData) {
+			continue
+		}
+		require.NoError(t, err)
+		if decoded == 0 {
+			require.Equal(t, 160, img.Bounds().Dx())
+			require.Equal(t, (160*h+w/4)/(w/2), img.Bounds().Dy())
+			require.NoError(t, setter.SetOutput(gomedia.VideoOutput{}))
+		} else {
+			require.Equal(t, image.Rect(0, 0, w, h), img.Bounds())
+		}
+		img.Release()
+		if decoded++; decoded == 2 {
+			return
+		}
+	}
+	t.Fatal("not enough frames decoded")
+}
+
 // TestCPU_Decode_H264_ErrNeedMoreData feeds non-IDR packets before any IDR and
 // verifies the decoder returns ErrNeedMoreData (not a fatal error).
 func TestCPU_Decode_H264_ErrNeedMoreData(t *testing.T) {
@@ -0,0 +1,255 @@
+// Package mjpeg is a pure-Go MJPEG inner decoder for decoder.NewVideo. Each
+// packet is a complete JPEG, decoded with image/jpeg into pooled rgb.RGB
+// frames (or yuv.I420/yuv.NV12, see decoder.VideoWithPixelFormat), so it
+// builds without cgo or FFmpeg.
+package mjpeg
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+
+	"github.com/ugparu/gomedia"
+	"github.com/ugparu/gomedia/decoder"
+	"github.com/ugparu/gomedia/frame/rgb"
+	"github.com/ugparu/gomedia/frame/yuv"
+)
+
+type mjpegDecoder struct {
+	pixFmt   gomedia.PixelFormat
+	pool     *rgb.FramePool
+	i420Pool *yuv.I420Pool
+	nv12Pool *yuv.NV12Pool
+	poolSize image.Point
+	output   gomedia.VideoOutput
+	cropped  *rgb.RGB // crop converted to RGB before scaling
+	scaled   *rgb.RGB // scaled crop, for YUV output
+}
+
+func NewMJPEGDecoder() decoder.InnerVideoDecoder {
+	return &mjpegDecoder{}
+}
+
+func (dcd *mjpegDecoder) Init(codecPar gomedia.VideoCodecParameters) error {
+	if codecPar.Type() != gomedia.MJPEG {
+		return fmt.Errorf("unsupported codec type: %v", codecPar.Type())
+	}
+	// The SDP/container size is only a hint: the pool follows the size of
+	// the decoded frames.
+	if w, h := int(codecPar.Width()), int(codecPar.Height()); w > 0 && h > 0 { //nolint:gosec
+		dcd.resetPools(image.Pt(w, h))
+	}
+	return nil
+}
+
+// SetPixelFormat implements decoder.PixelFormatSetter; all formats are
+// supported.
+func (dcd *mjpegDecoder) SetPixelFormat(pf gomedia.PixelFormat) error {
+	switch pf {
+	case gomedia.RGB24, gomedia.I420, gomedia.NV12:
+		dcd.pixFmt = pf
+		return nil
+	default:
+		return fmt.Errorf("unsupported pixel format: %v", pf)
+	}
+}
+
+// SetOutput implements decoder.OutputSetter. Scaling goes through RGB with
+// rgb.Scale, so it costs an extra conversion for YUV output.
+func (dcd *mjpegDecoder) SetOutput(out gomedia.VideoOutput) error {
+	dcd.output = out
+	return nil
+}
+
+// resetPools drops the pools of the previous frame size; the pool of the
+// current pixel format is recreated lazily by Decode.
+func (dcd *mjpegDecoder) resetPools(size image.Point) {
+	dcd.pool, dcd.i420Pool, dcd.nv12Pool = nil, nil, nil
+	dcd.poolSize = size
+}
+
+// Feed is a no-op: JPEG frames are independent, so skipped frames need no
+// decoding to keep reference state.
+func (dcd *mjpegDecoder) Feed(gomedia.VideoPacket) error {
+	return nil
+}
+
+func (dcd *mjpegDecoder) Decode(pkt gomedia.VideoPacket) (rgb.ReleasableImage, error) {
+	src, err := jpeg.Decode(bytes.NewReader(pkt.Data()))
+	if err != nil {
+		return nil, err
+	}
+
+	crop, size := dcd.output.Resolve(src.Bounds())
+	if crop != src.Bounds() {
+		src = src.(interface { //nolint:forcetypeassert // every image/jpeg result has SubImage
+			SubImage(r image.Rectangle) image.Image
+		}).SubImage(crop)
+	}
+	if size != dcd.poolSize {
+		dcd.resetPools(size)
+	}
+
+	if size != crop.Size() {
+		if dcd.cropped == nil || dcd.cropped.Rect.Size() != crop.Size() {
+			dcd.cropped = rgb.NewRGB(image.Rectangle{Max: crop.Size()})
+		}
+		convertRGB(dcd.cropped, src)
+		if dcd.pixFmt == gomedia.RGB24 {
+			dst := dcd.rgbPool().Get()
+			rgb.Scale(dst, dcd.cropped)
+			return dst, nil
+		}
+		if dcd.scaled == nil || dcd.scaled.Rect.Size() != size {
+			dcd.scaled = rgb.NewRGB(image.Rectangle{Max: size})
+		}
+		rgb.Scale(dcd.scaled, dcd.cropped)
+		src = dcd.scaled
+	}
+
+	switch dcd.pixFmt {
+	case gomedia.I420:
+		if dcd.i420Pool == nil {
+			dcd.i420Pool = yuv.NewI420Pool(size.X, size.Y)
+		}
+		dst := dcd.i420Pool.Get()
+		convertI420(dst, src)
+		return dst, nil
+	case gomedia.NV12:
+		if dcd.nv12Pool == nil {
+			dcd.nv12Pool = yuv.NewNV12Pool(size.X, size.Y)
+		}
+		dst := dcd.nv12Pool.Get()
+		convertNV12(dst, src)
+		return dst, nil
+	}
+
+	dst := dcd.rgbPool().Get()
+	convertRGB(dst, src)
+	return dst, nil
+}
+
+func (dcd *mjpegDecoder) rgbPool() *rgb.FramePool {
+	if dcd.pool == nil {
+		dcd.pool = rgb.NewFramePool(dcd.poolSize.X, dcd.poolSize.Y)
+	}
+	return dcd.pool
+}
+
+// convertRGB writes src into dst, which has the size of src at the origin.
+func convertRGB(dst *rgb.RGB, src image.Image) {
+	b := src.Bounds()
+	switch img := src.(type) {
+	case *image.YCbCr:
+		convertYCbCr(dst, img)
+	case *image.Gray:
+		for y := range b.Dy() {
+			row := img.Pix[y*img.Stride : y*img.Stride+b.Dx()]
+			out := dst.Pix[y*dst.Stride:]
+			for x, v := range row {
+				out[3*x], out[3*x+1], out[3*x+2] = v, v, v
+			}
+		}
+	default:
+		// CMYK and other rare layouts take the generic path.
+		for y := range b.Dy() {
+			for x := range b.Dx() {
+				dst.Set(x, y, src.At(b.Min.X+x, b.Min.Y+y))
+			}
+		}
+	}
+}
+
+// convertYCbCr converts any chroma subsampling without going through the
+// per-pixel image.Image interface.
+func convertYCbCr(dst *rgb.RGB, img *image.YCbCr) {
+	b := img.Rect
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		out := dst.Pix[(y-b.Min.Y)*dst.Stride:]
+		for x := b.Min.X; x < b.Max.X; x++ {
+			yi := img.YOffset(x, y)
+			ci := img.COffset(x, y)
+			r, g, bl := color.YCbCrToRGB(img.Y[yi], img.Cb[ci], img.Cr[ci])
+			o := 3 * (x - b.Min.X) //nolint:mnd
+			out[o], out[o+1], out[o+2] = r, g, bl
+		}
+	}
+}
+
+// sampler returns per-pixel luma and chroma accessors for a decoded JPEG.
+// Chroma is read at the top-left pixel of each 2x2 block, which is exact for
+// 4:2:0 sources and a plain decimation for the others.
+func sampler(src image.Image) (func(x, y int) uint8, func(x, y int) (uint8, uint8)) {
+	switch img := src.(type) {
+	case *image.YCbCr:
+		return func(x, y int) uint8 { return img.Y[img.YOffset(x, y)] },
+			func(x, y int) (uint8, uint8) {
+				ci := img.COffset(x, y)
+				return img.Cb[ci], img.Cr[ci]
+			}
+	case *image.Gray:
+		return func(x, y int) uint8 { return img.GrayAt(x, y).Y },
+			func(int, int) (uint8, uint8) { return 128, 128 } //nolint:mnd // neutral chroma
+	default:
+		at := func(x, y int) color.YCbCr {
+			return color.YCbCrModel.Convert(src.At(x, y)).(color.YCbCr) //nolint:errcheck,forcetypeassert // YCbCrModel always returns YCbCr
+		}
+		return func(x, y int) uint8 { return at(x, y).Y },
+			func(x, y int) (uint8, uint8) {
+				c := at(x, y)
+				return c.Cb, c.Cr
+			}
+	}
+}
+
+// convertI420 copies the planes directly when the JPEG is already 4:2:0,
+// which is what most MJPEG cameras send.
+func convertI420(dst *yuv.I420, src image.Image) {
+	b := src.Bounds()
+	if img, ok := src.(*image.YCbCr); ok && img.SubsampleRatio == image.YCbCrSubsampleRatio420 {
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			copy(dst.Y[(y-b.Min.Y)*dst.YStride:], img.Y[img.YOffset(b.Min.X, y):img.YOffset(b.Min.X, y)+b.Dx()])
+		}
+		cw := (b.Dx() + 1) / 2 //nolint:mnd
+		for y := b.Min.Y; y < b.Max.Y; y += 2 {
+			row := (y - b.Min.Y) / 2 * dst.CStride //nolint:mnd
+			ci := img.COffset(b.Min.X, y)
+			copy(dst.Cb[row:], img.Cb[ci:ci+cw])
+			copy(dst.Cr[row:], img.Cr[ci:ci+cw])
+		}
+		return
+	}
+
+	lumaAt, chromaAt := sampler(src)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			dx, dy := x-b.Min.X, y-b.Min.Y
+			dst.Y[dst.YOffset(dx, dy)] = lumaAt(x, y)
+			if dx%2 == 0 && dy%2 == 0 {
+				ci := dst.COffset(dx, dy)
+				dst.Cb[ci], dst.Cr[ci] = chromaAt(x, y)
+			}
+		}
+	}
+}
+
+func convertNV12(dst *yuv.NV12, src image.Image) {
+	b := src.Bounds()
+	lumaAt, chromaAt := sampler(src)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			dx, dy := x-b.Min.X, y-b.Min.Y
+			dst.Y[dst.YOffset(dx, dy)] = lumaAt(x, y)
+			if dx%2 == 0 && dy%2 == 0 {
+				uv := dst.UVOffset(dx, dy)
+				dst.UV[uv], dst.UV[uv+1] = chromaAt(x, y)
+			}
+		}
+	}
+}
+
+func (dcd *mjpegDecoder) Close() {
+	dcd.resetPools(image.Point{})
+}
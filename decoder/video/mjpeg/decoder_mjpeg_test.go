@@ -0,0 +1,197 @@
+package mjpeg_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ugparu/gomedia"
+	codecmjpeg "github.com/ugparu/gomedia/codec/mjpeg"
+	"github.com/ugparu/gomedia/decoder"
+	"github.com/ugparu/gomedia/decoder/video/mjpeg"
+	"github.com/ugparu/gomedia/frame/yuv"
+)
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, img, &jpeg.Options{Quality: 100}))
+	return buf.Bytes()
+}
+
+func solidImage(w, h int, c color.Color) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+func newPacket(data []byte, par *codecmjpeg.CodecParameters) *codecmjpeg.Packet {
+	return codecmjpeg.NewPacket(true, 0, time.Now(), data, "test", par)
+}
+
+func requireNear(t *testing.T, want, got byte) {
+	t.Helper()
+	diff := int(want) - int(got)
+	require.LessOrEqual(t, diff*diff, 9, "want %d got %d", want, got) //nolint:mnd // ±3 after JPEG round-trip
+}
+
+func TestMJPEGDecoder_DecodesColour(t *testing.T) {
+	par := codecmjpeg.NewCodecParameters(32, 16, 25) //nolint:mnd
+	dec := mjpeg.NewMJPEGDecoder()
+	require.NoError(t, dec.Init(par))
+	defer dec.Close()
+
+	data := encodeJPEG(t, solidImage(32, 16, color.RGBA{R: 200, G: 100, B: 50, A: 255})) //nolint:mnd
+	img, err := dec.Decode(newPacket(data, par))
+	require.NoError(t, err)
+	defer img.Release()
+
+	require.Equal(t, image.Rect(0, 0, 32, 16), img.Bounds())
+	px := img.GetRGB().RGBAt(10, 5)
+	requireNear(t, 200, px.R)
+	requireNear(t, 100, px.G)
+	requireNear(t, 50, px.B)
+}
+
+func TestMJPEGDecoder_Grayscale(t *testing.T) {
+	par := codecmjpeg.NewCodecParameters(8, 8, 25) //nolint:mnd
+	dec := mjpeg.NewMJPEGDecoder()
+	require.NoError(t, dec.Init(par))
+
+	gray := image.NewGray(image.Rect(0, 0, 8, 8))
+	for i := range gray.Pix {
+		gray.Pix[i] = 120
+	}
+	img, err := dec.Decode(newPacket(encodeJPEG(t, gray), par))
+	require.NoError(t, err)
+	px := img.GetRGB().RGBAt(3, 3)
+	requireNear(t, 120, px.R)
+	require.Equal(t, px.R, px.G)
+	require.Equal(t, px.R, px.B)
+}
+
+func TestMJPEGDecoder_FollowsFrameSize(t *testing.T) {
+	// Parameters advertise 16x16 but the frames are 24x8.
+	par := codecmjpeg.NewCodecParameters(16, 16, 25) //nolint:mnd
+	dec := mjpeg.NewMJPEGDecoder()
+	require.NoError(t, dec.Init(par))
+
+	img, err := dec.Decode(newPacket(encodeJPEG(t, solidImage(24, 8, color.White)), par))
+	require.NoError(t, err)
+	require.Equal(t, image.Rect(0, 0, 24, 8), img.Bounds())
+	require.Len(t, img.GetRGB().Pix, 24*8*3)
+}
+
+func TestMJPEGDecoder_I420Output(t *testing.T) {
+	par := codecmjpeg.NewCodecParameters(16, 8, 25) //nolint:mnd
+	dec := mjpeg.NewMJPEGDecoder()
+	require.NoError(t, dec.(decoder.PixelFormatSetter).SetPixelFormat(gomedia.I420)) //nolint:forcetypeassert
+	require.NoError(t, dec.Init(par))
+
+	want := color.RGBA{R: 30, G: 160, B: 90, A: 255}
+	img, err := dec.Decode(newPacket(encodeJPEG(t, solidImage(16, 8, want)), par))
+	require.NoError(t, err)
+	defer img.Release()
+
+	frame, ok := img.(*yuv.I420)
+	require.True(t, ok, "got %T", img)
+	require.Equal(t, image.Rect(0, 0, 16, 8), frame.Bounds())
+	y, cb, cr := color.RGBToYCbCr(want.R, want.G, want.B)
+	requireNear(t, y, frame.Y[frame.YOffset(5, 5)])
+	requireNear(t, cb, frame.Cb[frame.COffset(5, 5)])
+	requireNear(t, cr, frame.Cr[frame.COffset(5, 5)])
+	requireNear(t, want.G, img.GetRGB().RGBAt(5, 5).G)
+}
+
+func TestMJPEGDecoder_NV12OutputFromGray(t *testing.T) {
+	par := codecmjpeg.NewCodecParameters(8, 8, 25) //nolint:mnd
+	dec := mjpeg.NewMJPEGDecoder()
+	require.NoError(t, dec.(decoder.PixelFormatSetter).SetPixelFormat(gomedia.NV12)) //nolint:forcetypeassert
+	require.NoError(t, dec.Init(par))
+
+	gray := image.NewGray(image.Rect(0, 0, 8, 8))
+	for i := range gray.Pix {
+		gray.Pix[i] = 60
+	}
+	img, err := dec.Decode(newPacket(encodeJPEG(t, gray), par))
+	require.NoError(t, err)
+
+	frame, ok := img.(*yuv.NV12)
+	require.True(t, ok, "got %T", img)
+	c := frame.YCbCrAt(3, 3)
+	requireNear(t, 60, c.Y)
+	require.Equal(t, uint8(128), c.Cb)
+	require.Equal(t, uint8(128), c.Cr)
+}
+
+func TestMJPEGDecoder_CropAndScale(t *testing.T) {
+	par := codecmjpeg.NewCodecParameters(32, 16, 25) //nolint:mnd
+	dec := mjpeg.NewMJPEGDecoder()
+	require.NoError(t, dec.Init(par))
+
+	// Left half black, right half white.
+	src := image.NewRGBA(image.Rect(0, 0, 32, 16))
+	for y := range 16 {
+		for x := 16; x < 32; x++ {
+			src.Set(x, y, color.White)
+		}
+	}
+	data := encodeJPEG(t, src)
+
+	setter := dec.(decoder.OutputSetter) //nolint:forcetypeassert
+	require.NoError(t, setter.SetOutput(gomedia.VideoOutput{Crop: image.Rect(16, 0, 32, 16)}))
+	img, err := dec.Decode(newPacket(data, par))
+	require.NoError(t, err)
+	require.Equal(t, image.Rect(0, 0, 16, 16), img.Bounds())
+	requireNear(t, 255, img.GetRGB().RGBAt(0, 0).R)
+
+	require.NoError(t, setter.SetOutput(gomedia.VideoOutput{Width: 8}))
+	img, err = dec.Decode(newPacket(data, par))
+	require.NoError(t, err)
+	require.Equal(t, image.Rect(0, 0, 8, 4), img.Bounds())
+	requireNear(t, 0, img.GetRGB().RGBAt(1, 2).R)
+	requireNear(t, 255, img.GetRGB().RGBAt(6, 2).R)
+
+	require.NoError(t, dec.(decoder.PixelFormatSetter).SetPixelFormat(gomedia.I420)) //nolint:forcetypeassert
+	img, err = dec.Decode(newPacket(data, par))
+	require.NoError(t, err)
+	require.IsType(t, &yuv.I420{}, img)
+	require.Equal(t, image.Rect(0, 0, 8, 4), img.Bounds())
+}
+
+func TestMJPEGDecoder_InvalidData(t *testing.T) {
+	par := codecmjpeg.NewCodecParameters(8, 8, 25) //nolint:mnd
+	dec := mjpeg.NewMJPEGDecoder()
+	require.NoError(t, dec.Init(par))
+
+	_, err := dec.Decode(newPacket([]byte{0xff, 0xd8, 0x00}, par))
+	require.Error(t, err)
+}
+
+func TestMJPEGDecoder_WithVideoDecoder(t *testing.T) {
+	par := codecmjpeg.NewCodecParameters(16, 16, 25)                                       //nolint:mnd
+	dec := decoder.NewVideo(4, -1, map[gomedia.CodecType]func() decoder.InnerVideoDecoder{ //nolint:mnd
+		gomedia.MJPEG: mjpeg.NewMJPEGDecoder,
+	}, decoder.VideoWithPixelFormat(gomedia.NV12))
+	dec.Decode()
+	defer dec.Close()
+
+	dec.Packets() <- newPacket(encodeJPEG(t, solidImage(16, 16, color.Black)), par)
+	select {
+	case img := <-dec.Images():
+		require.Equal(t, image.Rect(0, 0, 16, 16), img.Bounds())
+		require.IsType(t, &yuv.NV12{}, img)
+		img.Release()
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for decoded frame")
+	}
+}
@@ -5,11 +5,13 @@ package decoder
 import (
 	"errors"
 	"fmt"
+	"image"
 	"runtime"
 	"time"
 
 	"github.com/ugparu/gomedia"
 	"github.com/ugparu/gomedia/frame/rgb"
+	"github.com/ugparu/gomedia/frame/yuv"
 	"github.com/ugparu/gomedia/utils/lifecycle"
 	"github.com/ugparu/gomedia/utils/logger"
 )
@@ -23,6 +25,22 @@ type InnerVideoDecoder interface {
 	Close()
 }
 
+// PixelFormatSetter is implemented by inner decoders that can emit frames
+// other than rgb.RGB. SetPixelFormat is called before Init; an error leaves
+// the decoder on RGB24.
+type PixelFormatSetter interface {
+	SetPixelFormat(pf gomedia.PixelFormat) error
+}
+
+// OutputSetter is implemented by inner decoders that crop and scale in
+// their own conversion step. SetOutput is called before Init and again
+// whenever the output is reconfigured; it applies from the next decoded
+// frame. When it is missing or fails, the wrapper crops and scales the
+// frame itself, keeping its pixel format.
+type OutputSetter interface {
+	SetOutput(out gomedia.VideoOutput) error
+}
+
 // videoDecoder is the async wrapper around an InnerVideoDecoder. It applies
 // FPS throttling, waits for the first key frame before decoding, and swaps
 // out the inner decoder when the stream's codec parameters change.
@@ -42,6 +60,14 @@ type videoDecoder struct {
 	name          string
 	log           logger.Logger
 	outBufSize    int
+	pixFmt        gomedia.PixelFormat
+	output        gomedia.VideoOutput
+	outputChan    chan gomedia.VideoOutput
+	scaleInGo     bool           // inner decoder can not apply output itself
+	scalePool     *rgb.FramePool // frames for scaleInGo, sized scalePoolSize
+	i420Pool      *yuv.I420Pool  // as scalePool, for I420 frames
+	nv12Pool      *yuv.NV12Pool  // as scalePool, for NV12 frames
+	scalePoolSize image.Point
 }
 
 type VideoDecoderParam func(*videoDecoder)
@@ -62,6 +88,26 @@ func VideoWithOutputBufferSize(n int) VideoDecoderParam {
 	return func(dec *videoDecoder) { dec.outBufSize = n }
 }
 
+// VideoWithPixelFormat selects the layout of the frames sent on Images().
+// I420 and NV12 frames are *yuv.I420 and *yuv.NV12; they still implement
+// rgb.ReleasableImage, converting on GetRGB. Inner decoders that do not
+// implement PixelFormatSetter keep producing RGB24 and a warning is logged.
+func VideoWithPixelFormat(pf gomedia.PixelFormat) VideoDecoderParam {
+	return func(dec *videoDecoder) { dec.pixFmt = pf }
+}
+
+// VideoWithOutputSize scales decoded frames to w x h. Either may be 0 to
+// keep the aspect ratio. Change it at runtime through Output().
+func VideoWithOutputSize(w, h int) VideoDecoderParam {
+	return func(dec *videoDecoder) { dec.output.Width, dec.output.Height = w, h }
+}
+
+// VideoWithCrop limits decoded frames to rect (in decoded picture
+// coordinates) before any scaling. Change it at runtime through Output().
+func VideoWithCrop(rect image.Rectangle) VideoDecoderParam {
+	return func(dec *videoDecoder) { dec.output.Crop = rect }
+}
+
 func NewVideo(chanSize int, fps int, factory map[gomedia.CodecType]func() InnerVideoDecoder, params ...VideoDecoderParam) gomedia.VideoDecoder {
 	dec := &videoDecoder{
 		AsyncManager:      nil,
@@ -77,6 +123,7 @@ func NewVideo(chanSize int, fps int, factory map[gomedia.CodecType]func() InnerV
 		hasKey:            false,
 		log:               logger.Default,
 		outBufSize:        chanSize,
+		pixFmt:            gomedia.RGB24,
 	}
 	for _, param := range params {
 		param(dec)
@@ -84,6 +131,7 @@ func NewVideo(chanSize int, fps int, factory map[gomedia.CodecType]func() InnerV
 	dec.inpPktCh = make(chan gomedia.VideoPacket, chanSize)
 	dec.outFrmCh = make(chan rgb.ReleasableImage, dec.outBufSize)
 	dec.fpsChan = make(chan int, chanSize)
+	dec.outputChan = make(chan gomedia.VideoOutput, chanSize)
 	dec.AsyncManager = lifecycle.NewFailSafeAsyncManager(dec, dec.log)
 	runtime.SetFinalizer(dec, func(dcd *videoDecoder) { dcd.Close() })
 	return dec
@@ -132,6 +180,9 @@ func (dec *videoDecoder) processPacket(inpPkt gomedia.VideoPacket, stopCh <-chan
 	}
 
 	dec.lastFrameTime = time.Now()
+	if dec.scaleInGo {
+		img = dec.scale(img)
+	}
 
 	select {
 	case <-stopCh:
@@ -154,6 +205,8 @@ func (dec *videoDecoder) startDecoder() (err error) {
 		return errors.New("unsupported video codec")
 	}
 	dec.InnerVideoDecoder = decoderFn()
+	dec.setPixelFormat()
+	dec.setOutput()
 
 	if err = dec.InnerVideoDecoder.Init(dec.codecPar); err != nil {
 		return
@@ -164,6 +217,76 @@ func (dec *videoDecoder) startDecoder() (err error) {
 	return nil
 }
 
+// setPixelFormat applies the requested output format to a fresh inner
+// decoder, falling back to RGB24 when it cannot produce it.
+func (dec *videoDecoder) setPixelFormat() {
+	if dec.pixFmt == gomedia.RGB24 {
+		return
+	}
+	setter, ok := dec.InnerVideoDecoder.(PixelFormatSetter)
+	if !ok {
+		dec.log.Warningf(dec, "Inner decoder does not support %v output, using %v", dec.pixFmt, gomedia.RGB24)
+		return
+	}
+	if err := setter.SetPixelFormat(dec.pixFmt); err != nil {
+		dec.log.Warningf(dec, "Can not set %v output, using %v: %v", dec.pixFmt, gomedia.RGB24, err)
+	}
+}
+
+// setOutput passes the crop/size to the inner decoder, or arranges for
+// processPacket to apply it when the inner decoder can not.
+func (dec *videoDecoder) setOutput() {
+	dec.scaleInGo = false
+	setter, ok := dec.InnerVideoDecoder.(OutputSetter)
+	if !ok {
+		dec.scaleInGo = !dec.output.IsZero()
+		return
+	}
+	if err := setter.SetOutput(dec.output); err != nil {
+		dec.log.Warningf(dec, "Inner decoder can not apply output %+v, scaling RGB frames instead: %v", dec.output, err)
+		dec.scaleInGo = !dec.output.IsZero()
+	}
+}
+
+// scale crops and resizes img into a pooled frame of the same pixel format.
+// img is released.
+func (dec *videoDecoder) scale(img rgb.ReleasableImage) rgb.ReleasableImage {
+	crop, size := dec.output.Resolve(img.Bounds())
+	if crop == img.Bounds() && size == crop.Size() {
+		return img
+	}
+	if dec.scalePoolSize != size {
+		dec.scalePool, dec.i420Pool, dec.nv12Pool = nil, nil, nil
+		dec.scalePoolSize = size
+	}
+	var dst rgb.ReleasableImage
+	switch src := img.(type) {
+	case *yuv.I420:
+		if dec.i420Pool == nil {
+			dec.i420Pool = yuv.NewI420Pool(size.X, size.Y)
+		}
+		frm := dec.i420Pool.Get()
+		yuv.ScaleI420(frm, src, crop)
+		dst = frm
+	case *yuv.NV12:
+		if dec.nv12Pool == nil {
+			dec.nv12Pool = yuv.NewNV12Pool(size.X, size.Y)
+		}
+		frm := dec.nv12Pool.Get()
+		yuv.ScaleNV12(frm, src, crop)
+		dst = frm
+	default:
+		if dec.scalePool == nil {
+			dec.scalePool = rgb.NewFramePool(size.X, size.Y)
+		}
+		frm := dec.scalePool.Get()
+		rgb.Scale(frm, img.GetRGB().SubImage(crop).(*rgb.RGB)) //nolint:forcetypeassert // RGB.SubImage returns *RGB
+		dst = frm
+	}
+	img.Release()
+	return dst
+}
+
 // stopDecoder stops the inner decoder.
 func (dec *videoDecoder) stopDecoder() {
 	dec.log.Debugf(dec, "Stopping decoder")
@@ -219,6 +342,12 @@ func (dec *videoDecoder) Step(stopCh <-chan struct{}) (err error) {
 				return err
 			}
 		}
+	case out := <-dec.outputChan:
+		dec.log.Debugf(dec, "Changing output from %+v to %+v", dec.output, out)
+		dec.output = out
+		if dec.running {
+			dec.setOutput()
+		}
 	case inpPkt := <-dec.inpPktCh:
 		defer inpPkt.Release()
 		if dec.targetFPS == 0 {
@@ -271,6 +400,7 @@ drained:
 	}
 framesDrained:
 	close(dec.fpsChan)
+	close(dec.outputChan)
 }
 
 func (dec *videoDecoder) String() string {
@@ -281,6 +411,10 @@ func (dec *videoDecoder) FPS() chan<- int {
 	return dec.fpsChan
 }
 
+func (dec *videoDecoder) Output() chan<- gomedia.VideoOutput {
+	return dec.outputChan
+}
+
 func (dec *videoDecoder) Packets() chan<- gomedia.VideoPacket {
 	return dec.inpPktCh
 }
@@ -13,6 +13,7 @@ import (
 	"github.com/ugparu/gomedia"
 	decoder "github.com/ugparu/gomedia/decoder"
 	"github.com/ugparu/gomedia/frame/rgb"
+	"github.com/ugparu/gomedia/frame/yuv"
 	"github.com/ugparu/gomedia/mocks"
 )
 
@@ -194,6 +195,193 @@ func TestStep_Video_KeyFrame_ProducesImage(t *testing.T) {
 	d.Close()
 }
 
+// Pixel format
+
+// settableInner is an inner decoder that also implements PixelFormatSetter.
+type settableInner struct {
+	*mocks.MockInnerVideoDecoder
+	*mocks.MockPixelFormatSetter
+}
+
+func TestVideoWithPixelFormat_SetBeforeInit(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+
+	par := mockVideoCodecParams(ctrl, gomedia.H264)
+	inner := settableInner{mocks.NewMockInnerVideoDecoder(ctrl), mocks.NewMockPixelFormatSetter(ctrl)}
+	gomock.InOrder(
+		inner.MockPixelFormatSetter.EXPECT().SetPixelFormat(gomedia.NV12).Return(nil),
+		inner.MockInnerVideoDecoder.EXPECT().Init(par).Return(nil),
+	)
+	inner.MockInnerVideoDecoder.EXPECT().Decode(gomock.Any()).Return(rgb.NewRGB(image.Rect(0, 0, 2, 2)), nil)
+	inner.MockInnerVideoDecoder.EXPECT().Close()
+
+	d := decoder.NewVideo(1, -1, makeVideoFactory(inner), decoder.VideoWithPixelFormat(gomedia.NV12))
+	d.Decode()
+	d.Packets() <- mockKeyPkt(ctrl, par)
+
+	select {
+	case <-d.Images():
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for image")
+	}
+	d.Close()
+}
+
+func TestVideoWithPixelFormat_UnsupportedFallsBackToRGB(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+
+	par := mockVideoCodecParams(ctrl, gomedia.H264)
+	img := rgb.NewRGB(image.Rect(0, 0, 2, 2))
+	inner := mocks.NewMockInnerVideoDecoder(ctrl)
+	inner.EXPECT().Init(par).Return(nil)
+	inner.EXPECT().Decode(gomock.Any()).Return(img, nil)
+	inner.EXPECT().Close()
+
+	d := decoder.NewVideo(1, -1, makeVideoFactory(inner), decoder.VideoWithPixelFormat(gomedia.I420))
+	d.Decode()
+	d.Packets() <- mockKeyPkt(ctrl, par)
+
+	select {
+	case got := <-d.Images():
+		require.Equal(t, img, got)
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for image")
+	}
+	d.Close()
+}
+
+// Output crop and size
+
+// outputInner is an inner decoder that also implements OutputSetter.
+type outputInner struct {
+	*mocks.MockInnerVideoDecoder
+	*mocks.MockOutputSetter
+}
+
+func receiveImage(t *testing.T, d gomedia.VideoDecoder) rgb.ReleasableImage {
+	t.Helper()
+	select {
+	case img := <-d.Images():
+		return img
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for image")
+		return nil
+	}
+}
+
+func TestVideoOutput_Resolve(t *testing.T) {
+	t.Parallel()
+	frame := image.Rect(0, 0, 1920, 1080)
+	tests := []struct {
+		out  gomedia.VideoOutput
+		crop image.Rectangle
+		size image.Point
+	}{
+		{gomedia.VideoOutput{}, frame, image.Pt(1920, 1080)},
+		{gomedia.VideoOutput{Width: 640, Height: 360}, frame, image.Pt(640, 360)},
+		{gomedia.VideoOutput{Height: 360}, frame, image.Pt(640, 360)},
+		{gomedia.VideoOutput{Width: 640}, frame, image.Pt(640, 360)},
+		{gomedia.VideoOutput{Crop: image.Rect(960, 0, 1920, 540)}, image.Rect(960, 0, 1920, 540), image.Pt(960, 540)},
+		{gomedia.VideoOutput{Crop: image.Rect(1800, 0, 2000, 100), Height: 50}, image.Rect(1800, 0, 1920, 100), image.Pt(60, 50)},
+		{gomedia.VideoOutput{Crop: image.Rect(3000, 3000, 3100, 3100)}, frame, image.Pt(1920, 1080)},
+	}
+	for _, tt := range tests {
+		crop, size := tt.out.Resolve(frame)
+		require.Equal(t, tt.crop, crop, "%+v", tt.out)
+		require.Equal(t, tt.size, size, "%+v", tt.out)
+	}
+	require.True(t, gomedia.VideoOutput{}.IsZero())
+	require.False(t, gomedia.VideoOutput{Width: 1}.IsZero())
+}
+
+func TestVideoWithOutputSize_ScalesWhenInnerCannot(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+
+	par := mockVideoCodecParams(ctrl, gomedia.H264)
+	src := rgb.NewRGB(image.Rect(0, 0, 8, 4))
+	src.Set(6, 2, rgb.Color{R: 200})
+	inner := mocks.NewMockInnerVideoDecoder(ctrl)
+	inner.EXPECT().Init(par).Return(nil)
+	inner.EXPECT().Decode(gomock.Any()).Return(src, nil).Times(2)
+	inner.EXPECT().Close()
+
+	d := decoder.NewVideo(1, -1, makeVideoFactory(inner),
+		decoder.VideoWithCrop(image.Rect(4, 0, 8, 4)), decoder.VideoWithOutputSize(2, 0))
+	d.Decode()
+	defer d.Close()
+
+	d.Packets() <- mockKeyPkt(ctrl, par)
+	img := receiveImage(t, d)
+	require.Equal(t, image.Rect(0, 0, 2, 2), img.Bounds())
+	require.Equal(t, rgb.Color{R: 50}, img.GetRGB().RGBAt(1, 1))
+	img.Release()
+
+	// Back to full frames at runtime: the inner image passes through.
+	d.Output() <- gomedia.VideoOutput{}
+	d.Packets() <- mockKeyPkt(ctrl, par)
+	require.Equal(t, src, receiveImage(t, d))
+}
+
+func TestVideoWithOutputSize_ScalesYUVWhenInnerCannot(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+
+	par := mockVideoCodecParams(ctrl, gomedia.H264)
+	src := yuv.NewI420(image.Rect(0, 0, 8, 4))
+	inner := settableInner{mocks.NewMockInnerVideoDecoder(ctrl), mocks.NewMockPixelFormatSetter(ctrl)}
+	inner.MockPixelFormatSetter.EXPECT().SetPixelFormat(gomedia.I420).Return(nil)
+	inner.MockInnerVideoDecoder.EXPECT().Init(par).Return(nil)
+	inner.MockInnerVideoDecoder.EXPECT().Decode(gomock.Any()).Return(src, nil)
+	inner.MockInnerVideoDecoder.EXPECT().Close()
+
+	d := decoder.NewVideo(1, -1, makeVideoFactory(inner), decoder.VideoWithPixelFormat(gomedia.I420),
+		decoder.VideoWithCrop(image.Rect(4, 0, 8, 4)), decoder.VideoWithOutputSize(2, 0))
+	d.Decode()
+	defer d.Close()
+
+	d.Packets() <- mockKeyPkt(ctrl, par)
+	img := receiveImage(t, d)
+	require.IsType(t, &yuv.I420{}, img, "pixel format lost when scaling")
+	require.Equal(t, image.Rect(0, 0, 2, 2), img.Bounds())
+	img.Release()
+}
+
+func TestVideoWithOutputSize_InnerSetter(t *testing.T) {
+	t.Parallel()
+	ctrl := gomock.NewController(t)
+
+	par := mockVideoCodecParams(ctrl, gomedia.H264)
+	img := rgb.NewRGB(image.Rect(0, 0, 10, 10))
+	inner := outputInner{mocks.NewMockInnerVideoDecoder(ctrl), mocks.NewMockOutputSetter(ctrl)}
+	changed := make(chan struct{})
+	gomock.InOrder(
+		inner.MockOutputSetter.EXPECT().SetOutput(gomedia.VideoOutput{Width: 10, Height: 10}).Return(nil),
+		inner.MockInnerVideoDecoder.EXPECT().Init(par).Return(nil),
+		inner.MockInnerVideoDecoder.EXPECT().Decode(gomock.Any()).Return(img, nil),
+		inner.MockOutputSetter.EXPECT().SetOutput(gomedia.VideoOutput{Height: 5}).DoAndReturn(
+			func(gomedia.VideoOutput) error { close(changed); return nil }),
+	)
+	inner.MockInnerVideoDecoder.EXPECT().Close()
+
+	d := decoder.NewVideo(1, -1, makeVideoFactory(inner), decoder.VideoWithOutputSize(10, 10))
+	d.Decode()
+	defer d.Close()
+
+	d.Packets() <- mockKeyPkt(ctrl, par)
+	// The inner decoder applied the output, so the frame is not touched.
+	require.Equal(t, img, receiveImage(t, d))
+
+	d.Output() <- gomedia.VideoOutput{Height: 5}
+	select {
+	case <-changed:
+	case <-time.After(time.Second):
+		t.Fatal("SetOutput not called after Output() change")
+	}
+}
+
 // Step — non-keyframe before first keyframe is skipped
 
 func TestStep_Video_NonKeyFrame_BeforeKey_Skipped(t *testing.T) {
@@ -0,0 +1,144 @@
+// Package adpcm contains pure-Go encoders for the G.722 and G.726 ADPCM
+// codecs. Input of any raw PCM format is downmixed to mono and resampled to
+// the codec rate.
+package adpcm
+
+import (
+	"encoding/binary"
+	"time"
+
+	"github.com/ugparu/gomedia"
+	"github.com/ugparu/gomedia/codec/g722"
+	"github.com/ugparu/gomedia/codec/g726"
+	"github.com/ugparu/gomedia/codec/pcm"
+	"github.com/ugparu/gomedia/encoder"
+	framepcm "github.com/ugparu/gomedia/frame/pcm"
+	"github.com/ugparu/gomedia/utils"
+	"github.com/ugparu/gomedia/utils/buffer"
+)
+
+const frameDuration = 20 * time.Millisecond
+
+type adpcmEncoder struct {
+	sampleRate int
+	// setup creates the codec state for a stream and returns its parameters.
+	setup  func(index uint8) (gomedia.AudioCodecParameters, error)
+	encode func(dst []byte, samples []int16) []byte
+	packet func(data []byte, slot *buffer.SlotHandle, sourceID string, startTime time.Time) gomedia.AudioPacket
+
+	conv      *framepcm.Converter
+	r         *utils.Resampler
+	frameSize int // samples per output packet
+	buf       []byte
+	samples   []int16
+	code      []byte
+	ring      *buffer.GrowingRingAlloc
+}
+
+// NewG722Encoder returns a 64 kbit/s G.722 encoder.
+func NewG722Encoder() encoder.InnerAudioEncoder {
+	e := &adpcmEncoder{sampleRate: g722.SampleRate}
+	var codecPar *g722.CodecParameters
+	var enc *g722.Encoder
+	e.setup = func(index uint8) (gomedia.AudioCodecParameters, error) {
+		codecPar, enc = g722.NewCodecParameters(index), g722.NewEncoder()
+		return codecPar, nil
+	}
+	e.encode = func(dst []byte, samples []int16) []byte {
+		return enc.Encode(dst, samples)
+	}
+	e.packet = func(data []byte, slot *buffer.SlotHandle, sourceID string, startTime time.Time) gomedia.AudioPacket {
+		p := g722.NewPacket(data, 0, sourceID, startTime, codecPar, frameDuration)
+		p.Slot = slot
+		return p
+	}
+	return e
+}
+
+// NewG726Encoder returns a G.726 encoder for the given bitrate (16000, 24000,
+// 32000 or 40000 bit/s) and code word packing. An invalid bitrate is
+// reported by Init.
+func NewG726Encoder(bitrate int, packing g726.Packing) encoder.InnerAudioEncoder {
+	e := &adpcmEncoder{sampleRate: g726.SampleRate}
+	var codecPar *g726.CodecParameters
+	var enc *g726.Encoder
+	e.setup = func(index uint8) (par gomedia.AudioCodecParameters, err error) {
+		if codecPar, err = g726.NewCodecParameters(index, bitrate, packing); err != nil {
+			return nil, err
+		}
+		if enc, err = g726.NewEncoder(bitrate, packing); err != nil {
+			return nil, err
+		}
+		return codecPar, nil
+	}
+	e.encode = func(dst []byte, samples []int16) []byte {
+		return enc.Encode(dst, samples)
+	}
+	e.packet = func(data []byte, slot *buffer.SlotHandle, sourceID string, startTime time.Time) gomedia.AudioPacket {
+		p := g726.NewPacket(data, 0, sourceID, startTime, codecPar, frameDuration)
+		p.Slot = slot
+		return p
+	}
+	return e
+}
+
+func (e *adpcmEncoder) Init(params *pcm.CodecParameters) (err error) {
+	if _, err = e.setup(params.StreamIndex()); err != nil {
+		return err
+	}
+	e.conv = framepcm.NewConverter(framepcm.Format{SampleFormat: gomedia.S16, Layout: gomedia.ChMono})
+	const maxInt32 = 1<<31 - 1
+	if e.r, err = utils.NewPcmS16leResampler(1, int(min(params.SampleRate(), maxInt32)), e.sampleRate); err != nil { //nolint:gosec // clamped
+		return err
+	}
+	e.frameSize = e.sampleRate * int(frameDuration/time.Millisecond) / 1000 //nolint:mnd
+	e.buf, e.samples, e.code = nil, nil, nil
+	e.ring = buffer.NewGrowingRingAlloc(16 * 1024) //nolint:mnd
+	return nil
+}
+
+func (e *adpcmEncoder) Encode(pkt *pcm.Packet) (resp []gomedia.AudioPacket, err error) {
+	mono, err := e.conv.Convert(pkt)
+	if err != nil {
+		return nil, err
+	}
+	resampled, err := e.r.Resample(mono.Data())
+	if err != nil {
+		return nil, err
+	}
+	e.buf = append(e.buf, resampled...)
+
+	consumed := 0
+	for len(e.buf)-consumed >= e.frameSize*2 {
+		e.samples = e.samples[:0]
+		for i := range e.frameSize {
+			e.samples = append(e.samples, int16(binary.LittleEndian.Uint16(e.buf[consumed+2*i:]))) //nolint:gosec // two's complement
+		}
+		consumed += e.frameSize * 2 //nolint:mnd
+
+		e.code = e.encode(e.code[:0], e.samples)
+		var outData []byte
+		var handle *buffer.SlotHandle
+		if e.ring != nil {
+			outData, handle = e.ring.Alloc(len(e.code))
+		}
+		if outData == nil {
+			outData = make([]byte, len(e.code))
+		}
+		copy(outData, e.code)
+		resp = append(resp, e.packet(outData, handle, pkt.SourceID(), pkt.StartTime()))
+	}
+
+	remaining := len(e.buf) - consumed
+	copy(e.buf, e.buf[consumed:])
+	e.buf = e.buf[:remaining]
+	return resp, nil
+}
+
+func (e *adpcmEncoder) Close() {
+	e.buf = nil
+	e.samples = nil
+	e.code = nil
+	e.ring = nil
+	e.r = nil
+}
@@ -0,0 +1,79 @@
+//nolint:mnd // Test file uses many literal values for expected results
+package adpcm
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/ugparu/gomedia"
+	"github.com/ugparu/gomedia/codec/g722"
+	"github.com/ugparu/gomedia/codec/g726"
+	"github.com/ugparu/gomedia/codec/pcm"
+)
+
+// stereoTone returns d of a 440Hz tone as interleaved stereo S16LE.
+func stereoTone(rate int, d time.Duration) []byte {
+	n := int(time.Duration(rate) * d / time.Second)
+	out := make([]byte, 0, n*4)
+	for i := range n {
+		s := uint16(int16(8000 * math.Sin(2*math.Pi*440*float64(i)/float64(rate)))) //nolint:gosec
+		out = binary.LittleEndian.AppendUint16(out, s)
+		out = binary.LittleEndian.AppendUint16(out, s)
+	}
+	return out
+}
+
+func encodeTone(t *testing.T, enc interface {
+	Init(*pcm.CodecParameters) error
+	Encode(*pcm.Packet) ([]gomedia.AudioPacket, error)
+}) []gomedia.AudioPacket {
+	t.Helper()
+	par := pcm.NewCodecParameters(2, gomedia.PCM, 2, 48000)
+	require.NoError(t, enc.Init(par))
+
+	data := stereoTone(48000, time.Second)
+	var out []gomedia.AudioPacket
+	const chunk = 48 * 4 * 10 // 10ms
+	for off := 0; off < len(data); off += chunk {
+		pkts, err := enc.Encode(pcm.NewPacket(data[off:off+chunk], 0, "src", time.Time{}, par, 10*time.Millisecond))
+		require.NoError(t, err)
+		out = append(out, pkts...)
+	}
+	return out
+}
+
+func TestG722Encoder(t *testing.T) {
+	enc := NewG722Encoder()
+	defer enc.Close()
+	pkts := encodeTone(t, enc)
+	require.InDelta(t, 50, len(pkts), 1)
+	for _, p := range pkts {
+		require.Len(t, p.Data(), 160)
+		require.Equal(t, 20*time.Millisecond, p.Duration())
+		require.Equal(t, "src", p.SourceID())
+		par := p.CodecParameters().(*g722.CodecParameters)
+		require.Equal(t, uint8(2), par.StreamIndex())
+	}
+}
+
+func TestG726Encoder(t *testing.T) {
+	for _, bitrate := range []int{16000, 24000, 32000, 40000} {
+		enc := NewG726Encoder(bitrate, g726.RFC3551)
+		pkts := encodeTone(t, enc)
+		require.InDelta(t, 50, len(pkts), 1)
+		for _, p := range pkts {
+			require.Len(t, p.Data(), bitrate/400)
+			require.Equal(t, 20*time.Millisecond, p.Duration())
+			require.Equal(t, gomedia.G726, p.CodecParameters().Type())
+		}
+		enc.Close()
+	}
+}
+
+func TestG726Encoder_BadBitrate(t *testing.T) {
+	enc := NewG726Encoder(8000, g726.RFC3551)
+	require.ErrorIs(t, enc.Init(pcm.NewCodecParameters(0, gomedia.PCM, 1, 8000)), g726.ErrBitrate)
+}
@@ -16,7 +16,10 @@ const ALAWSampleRate = 8000
 const AlawChannels = 1
 const AlawFrameSize = ALAWSampleRate * AlawChannels * 2 / 10
 
-type alawEncoder struct {
+// lawEncoder encodes S16 PCM into 8 kHz mono G.711 A-law or μ-law.
+type lawEncoder struct {
+	codecType     gomedia.CodecType
+	encodeF       func([]byte) []byte
 	r             aresample.ResampleSampleRate
 	inpChannels   uint8
 	inpFrameSize  int
@@ -27,10 +30,20 @@ type alawEncoder struct {
 }
 
 func NewAlawEncoder() encoder.InnerAudioEncoder {
-	return new(alawEncoder)
+	return &lawEncoder{
+		codecType: gomedia.PCMAlaw,
+		encodeF:   g711.EncodeAlaw,
+	}
+}
+
+func NewUlawEncoder() encoder.InnerAudioEncoder {
+	return &lawEncoder{
+		codecType: gomedia.PCMUlaw,
+		encodeF:   g711.EncodeUlaw,
+	}
 }
 
-func (e *alawEncoder) Init(params *pcm.CodecParameters) error {
+func (e *lawEncoder) Init(params *pcm.CodecParameters) error {
 	var err error
 	e.inpChannels = params.Channels()
 
@@ -49,13 +62,13 @@ func (e *alawEncoder) Init(params *pcm.CodecParameters) error {
 	const frameDurationDivisor = 10 // 100 ms frames
 	e.frameDuration = time.Duration(ALAWSampleRate/frameDurationDivisor) * time.Second / time.Duration(ALAWSampleRate)
 
-	e.codecPar = pcm.NewCodecParameters(params.StreamIndex(), gomedia.PCMAlaw, 1, ALAWSampleRate)
+	e.codecPar = pcm.NewCodecParameters(params.StreamIndex(), e.codecType, 1, ALAWSampleRate)
 	e.ring = buffer.NewGrowingRingAlloc(16 * 1024)
 
 	return err
 }
 
-func (e *alawEncoder) Encode(pkt *pcm.Packet) (resp []gomedia.AudioPacket, err error) {
+func (e *lawEncoder) Encode(pkt *pcm.Packet) (resp []gomedia.AudioPacket, err error) {
 	if len(pkt.Data()) < 2 {
 		return nil, nil
 	}
@@ -87,7 +100,7 @@ func (e *alawEncoder) Encode(pkt *pcm.Packet) (resp []gomedia.AudioPacket, err e
 		if inBuf, err = e.r.Resample(inBuf); err != nil {
 			return
 		}
-		encoded := g711.EncodeAlaw(inBuf)
+		encoded := e.encodeF(inBuf)
 		var outData []byte
 		var handle *buffer.SlotHandle
 		if e.ring != nil {
@@ -111,7 +124,7 @@ func (e *alawEncoder) Encode(pkt *pcm.Packet) (resp []gomedia.AudioPacket, err e
 	return
 }
 
-func (e *alawEncoder) Close() {
+func (e *lawEncoder) Close() {
 	e.buf = nil
 	e.ring = nil
 	e.r = nil
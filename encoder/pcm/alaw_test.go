@@ -3,6 +3,7 @@ package pcm
 
 import (
 	"encoding/base64"
+	"encoding/binary"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -13,6 +14,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/ugparu/gomedia"
 	"github.com/ugparu/gomedia/codec/pcm"
+	"github.com/zaf/g711"
 )
 
 const pcmPacketsPath = "../../tests/data/pcm/packets.json"
@@ -76,7 +78,7 @@ func TestInit_MonoSixteenKHz(t *testing.T) {
 	err := enc.Init(par)
 	require.NoError(t, err)
 
-	inner := enc.(*alawEncoder)
+	inner := enc.(*lawEncoder)
 
 	// inpFrameSize for mono = AlawFrameSize * 1 = 1600
 	assert.Equal(t, AlawFrameSize, inner.inpFrameSize)
@@ -100,7 +102,7 @@ func TestInit_Stereo(t *testing.T) {
 	err := enc.Init(par)
 	require.NoError(t, err)
 
-	inner := enc.(*alawEncoder)
+	inner := enc.(*lawEncoder)
 
 	// inpFrameSize for stereo = AlawFrameSize * 2 = 3200
 	assert.Equal(t, AlawFrameSize*2, inner.inpFrameSize)
@@ -124,7 +126,7 @@ func TestInit_SampleRates(t *testing.T) {
 			err := enc.Init(par)
 			require.NoError(t, err)
 
-			inner := enc.(*alawEncoder)
+			inner := enc.(*lawEncoder)
 			// Output is always 8kHz A-law regardless of input rate
 			assert.Equal(t, uint64(ALAWSampleRate), inner.codecPar.SampleRate())
 		})
@@ -140,7 +142,7 @@ func TestInit_StreamIndexPreserved(t *testing.T) {
 	par := pcm.NewCodecParameters(streamIndex, gomedia.PCM, 1, 16000)
 	require.NoError(t, enc.Init(par))
 
-	inner := enc.(*alawEncoder)
+	inner := enc.(*lawEncoder)
 	assert.Equal(t, streamIndex, inner.codecPar.StreamIndex())
 }
 
@@ -152,7 +154,7 @@ func TestInit_RingAllocator(t *testing.T) {
 	par := newPCMCodecParams()
 	require.NoError(t, enc.Init(par))
 
-	inner := enc.(*alawEncoder)
+	inner := enc.(*lawEncoder)
 	require.NotNil(t, inner.ring, "ring allocator should be set after Init")
 }
 
@@ -178,7 +180,7 @@ func TestClose_ReleasesResources(t *testing.T) {
 	par := newPCMCodecParams()
 	require.NoError(t, enc.Init(par))
 
-	inner := enc.(*alawEncoder)
+	inner := enc.(*lawEncoder)
 	require.NotNil(t, inner.ring)
 	require.NotNil(t, inner.r)
 
@@ -197,7 +199,7 @@ func TestEncode_SingleFrame(t *testing.T) {
 	par := pcm.NewCodecParameters(0, gomedia.PCM, 1, ALAWSampleRate)
 	require.NoError(t, enc.Init(par))
 
-	inner := enc.(*alawEncoder)
+	inner := enc.(*lawEncoder)
 
 	// AlawFrameSize = 1600 bytes of 16-bit PCM
 	data := make([]byte, AlawFrameSize)
@@ -219,7 +221,7 @@ func TestEncode_MultipleFrames(t *testing.T) {
 	par := pcm.NewCodecParameters(0, gomedia.PCM, 1, ALAWSampleRate)
 	require.NoError(t, enc.Init(par))
 
-	inner := enc.(*alawEncoder)
+	inner := enc.(*lawEncoder)
 
 	totalPackets := 0
 	for i := range 10 {
@@ -243,7 +245,7 @@ func TestEncode_BufferingPartialFrames(t *testing.T) {
 	par := pcm.NewCodecParameters(0, gomedia.PCM, 1, ALAWSampleRate)
 	require.NoError(t, enc.Init(par))
 
-	inner := enc.(*alawEncoder)
+	inner := enc.(*lawEncoder)
 	halfBytes := AlawFrameSize / 2
 
 	// Send half a frame — should produce no output
@@ -272,7 +274,7 @@ func TestEncode_LargerThanOneFrame(t *testing.T) {
 	par := pcm.NewCodecParameters(0, gomedia.PCM, 1, ALAWSampleRate)
 	require.NoError(t, enc.Init(par))
 
-	inner := enc.(*alawEncoder)
+	inner := enc.(*lawEncoder)
 
 	// Send 2.5 frames worth of data
 	data := make([]byte, AlawFrameSize*5/2)
@@ -296,7 +298,7 @@ func TestEncode_OutputPacketMetadata(t *testing.T) {
 	par := pcm.NewCodecParameters(0, gomedia.PCM, 1, ALAWSampleRate)
 	require.NoError(t, enc.Init(par))
 
-	inner := enc.(*alawEncoder)
+	inner := enc.(*lawEncoder)
 
 	sourceID := "rtsp://example.com/stream"
 	startTime := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
@@ -315,6 +317,28 @@ func TestEncode_OutputPacketMetadata(t *testing.T) {
 	assert.Equal(t, inner.frameDuration, p.Duration())
 }
 
+func TestUlawEncoder(t *testing.T) {
+	t.Parallel()
+	enc := NewUlawEncoder()
+	defer enc.Close()
+
+	par := pcm.NewCodecParameters(3, gomedia.PCM, 1, ALAWSampleRate)
+	require.NoError(t, enc.Init(par))
+
+	data := make([]byte, AlawFrameSize)
+	for i := 0; i < len(data); i += 2 {
+		binary.LittleEndian.PutUint16(data[i:], uint16(int16(1000*(i%7-3)))) //nolint:gosec
+	}
+	result, err := enc.Encode(pcm.NewPacket(data, 0, "sip", time.Time{}, par, 100*time.Millisecond))
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+
+	p := result[0]
+	assert.Equal(t, gomedia.PCMUlaw, p.CodecParameters().Type())
+	assert.Equal(t, uint8(3), p.CodecParameters().StreamIndex())
+	assert.Equal(t, g711.EncodeUlaw(data), p.Data())
+}
+
 func TestEncode_RingAllocation(t *testing.T) {
 	t.Parallel()
 	enc := NewAlawEncoder()
@@ -323,7 +347,7 @@ func TestEncode_RingAllocation(t *testing.T) {
 	par := pcm.NewCodecParameters(0, gomedia.PCM, 1, ALAWSampleRate)
 	require.NoError(t, enc.Init(par))
 
-	inner := enc.(*alawEncoder)
+	inner := enc.(*lawEncoder)
 	require.NotNil(t, inner.ring)
 
 	data := make([]byte, AlawFrameSize)
@@ -345,7 +369,7 @@ func TestEncode_EmptyInput(t *testing.T) {
 	par := pcm.NewCodecParameters(0, gomedia.PCM, 1, ALAWSampleRate)
 	require.NoError(t, enc.Init(par))
 
-	inner := enc.(*alawEncoder)
+	inner := enc.(*lawEncoder)
 
 	// Empty data should be handled gracefully
 	pkt := pcm.NewPacket(nil, 0, "test", time.Now(), par, 0)
@@ -371,7 +395,7 @@ func TestEncode_WithRealPCMData(t *testing.T) {
 	par := newPCMCodecParams()
 	require.NoError(t, enc.Init(par))
 
-	inner := enc.(*alawEncoder)
+	inner := enc.(*lawEncoder)
 	startTime := time.Now()
 
 	var allOutput []gomedia.AudioPacket
@@ -410,7 +434,7 @@ func TestEncode_OutputSizesReasonable(t *testing.T) {
 	par := newPCMCodecParams()
 	require.NoError(t, enc.Init(par))
 
-	inner := enc.(*alawEncoder)
+	inner := enc.(*lawEncoder)
 	startTime := time.Now()
 
 	var allOutput []gomedia.AudioPacket
@@ -445,7 +469,7 @@ func TestEncode_StereoDownmix(t *testing.T) {
 	par := pcm.NewCodecParameters(0, gomedia.PCM, 2, ALAWSampleRate)
 	require.NoError(t, enc.Init(par))
 
-	inner := enc.(*alawEncoder)
+	inner := enc.(*lawEncoder)
 
 	// inpFrameSize for stereo = AlawFrameSize * 2 = 3200
 	require.Equal(t, AlawFrameSize*2, inner.inpFrameSize)
@@ -480,7 +504,7 @@ func TestEncode_StereoWithRealData(t *testing.T) {
 	par := pcm.NewCodecParameters(0, gomedia.PCM, 2, 16000)
 	require.NoError(t, enc.Init(par))
 
-	inner := enc.(*alawEncoder)
+	inner := enc.(*lawEncoder)
 	startTime := time.Now()
 
 	var allOutput []gomedia.AudioPacket
@@ -517,7 +541,7 @@ func TestEncode_CompactionPreventsBufGrowth(t *testing.T) {
 	par := pcm.NewCodecParameters(0, gomedia.PCM, 1, ALAWSampleRate)
 	require.NoError(t, enc.Init(par))
 
-	inner := enc.(*alawEncoder)
+	inner := enc.(*lawEncoder)
 
 	// Feed many frames and check the buffer doesn't grow unboundedly
 	for i := range 100 {
@@ -555,7 +579,7 @@ func TestEncode_CodecParametersValidity(t *testing.T) {
 	par := newPCMCodecParams()
 	require.NoError(t, enc.Init(par))
 
-	inner := enc.(*alawEncoder)
+	inner := enc.(*lawEncoder)
 	require.NotNil(t, inner.codecPar)
 
 	assert.Equal(t, gomedia.PCMAlaw, inner.codecPar.Type())
@@ -577,7 +601,7 @@ func TestFrameDuration(t *testing.T) {
 			par := pcm.NewCodecParameters(0, gomedia.PCM, 1, sr)
 			require.NoError(t, enc.Init(par))
 
-			inner := enc.(*alawEncoder)
+			inner := enc.(*lawEncoder)
 			assert.Equal(t, 100*time.Millisecond, inner.frameDuration)
 		})
 	}
@@ -0,0 +1,126 @@
+#include <libavutil/opt.h>
+#include "encoder_ffmpeg_cpu.h"
+
+int init_cpu_encoder(cpuEncoder *enc, int width, int height, int fps, int64_t bitrate, int gop) {
+    enc->packet = av_packet_alloc();
+    if (!enc->packet) {
+        return AVERROR(ENOMEM);
+    }
+    enc->frame = av_frame_alloc();
+    if (!enc->frame) {
+        return AVERROR(ENOMEM);
+    }
+
+    const AVCodec *codec = avcodec_find_encoder_by_name("libx264");
+    if (!codec) {
+        codec = avcodec_find_encoder(AV_CODEC_ID_H264);
+    }
+    if (!codec) {
+        return AVERROR_ENCODER_NOT_FOUND;
+    }
+
+    enc->ctxt = avcodec_alloc_context3(codec);
+    if (!enc->ctxt) {
+        return AVERROR(ENOMEM);
+    }
+
+    // 4:2:0 chroma needs even dimensions; the odd edge column/row is dropped.
+    enc->src_width = width;
+    enc->src_height = height;
+    enc->ctxt->width = width & ~1;
+    enc->ctxt->height = height & ~1;
+    enc->ctxt->pix_fmt = AV_PIX_FMT_YUV420P;
+    enc->ctxt->time_base.num = 1;
+    enc->ctxt->time_base.den = 1000000;
+    enc->ctxt->framerate.num = fps;
+    enc->ctxt->framerate.den = 1;
+    enc->ctxt->gop_size = gop;
+    enc->ctxt->max_b_frames = 0;
+    enc->ctxt->flags |= AV_CODEC_FLAG_GLOBAL_HEADER;
+    if (bitrate > 0) {
+        enc->ctxt->bit_rate = bitrate;
+        enc->ctxt->rc_max_rate = bitrate;
+        enc->ctxt->rc_buffer_size = bitrate;
+    }
+
+    if (enc->ctxt->priv_data) {
+        av_opt_set(enc->ctxt->priv_data, "preset", "veryfast", 0);
+        av_opt_set(enc->ctxt->priv_data, "tune", "zerolatency", 0);
+        // Timestamps are microseconds of a possibly jittery source clock;
+        // keep rate control and VUI timing on the nominal frame rate.
+        av_opt_set(enc->ctxt->priv_data, "x264-params", "force-cfr=1", 0);
+    }
+
+    int ret = avcodec_open2(enc->ctxt, codec, NULL);
+    if (ret < 0) {
+        return ret;
+    }
+
+    enc->frame->width = enc->ctxt->width;
+    enc->frame->height = enc->ctxt->height;
+    enc->frame->format = enc->ctxt->pix_fmt;
+    ret = av_frame_get_buffer(enc->frame, 0);
+    if (ret < 0) {
+        return ret;
+    }
+
+    enc->scale_ctxt = sws_getContext(width, height, AV_PIX_FMT_RGB24,
+                                     enc->ctxt->width, enc->ctxt->height, AV_PIX_FMT_YUV420P,
+                                     SWS_FAST_BILINEAR, NULL, NULL, NULL);
+    if (!enc->scale_ctxt) {
+        return AVERROR(ENOMEM);
+    }
+
+    return 0;
+}
+
+int send_cpu_frame(cpuEncoder *enc, const uint8_t *rgb, int stride, int64_t pts) {
+    if (!rgb) {
+        return avcodec_send_frame(enc->ctxt, NULL);
+    }
+
+    int ret = av_frame_make_writable(enc->frame);
+    if (ret < 0) {
+        return ret;
+    }
+
+    const uint8_t *src[4] = {rgb, NULL, NULL, NULL};
+    const int src_stride[4] = {stride, 0, 0, 0};
+    ret = sws_scale(enc->scale_ctxt, src, src_stride, 0, enc->src_height,
+                    enc->frame->data, enc->frame->linesize);
+    if (ret < 0) {
+        return ret;
+    }
+
+    enc->frame->pts = pts;
+    return avcodec_send_frame(enc->ctxt, enc->frame);
+}
+
+int receive_cpu_packet(cpuEncoder *enc) {
+    av_packet_unref(enc->packet);
+    int ret = avcodec_receive_packet(enc->ctxt, enc->packet);
+    if (ret == AVERROR(EAGAIN) || ret == AVERROR_EOF) {
+        return 1;
+    }
+    return ret;
+}
+
+void close_cpu_encoder(cpuEncoder *enc) {
+    if (!enc) {
+        return;
+    }
+
+    if (enc->ctxt) {
+        avcodec_free_context(&enc->ctxt);
+    }
+    if (enc->packet) {
+        av_packet_free(&enc->packet);
+    }
+    if (enc->frame) {
+        av_frame_free(&enc->frame);
+    }
+    if (enc->scale_ctxt) {
+        sws_freeContext(enc->scale_ctxt);
+        enc->scale_ctxt = NULL;
+    }
+}
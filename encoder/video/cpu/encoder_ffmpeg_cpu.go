@@ -0,0 +1,138 @@
+package cpu
+
+//#cgo pkg-config: libavcodec libavutil libswscale
+//#include "encoder_ffmpeg_cpu.h"
+import "C"
+import (
+	"errors"
+	"time"
+	"unsafe"
+
+	"github.com/ugparu/gomedia"
+	"github.com/ugparu/gomedia/codec/h264"
+	"github.com/ugparu/gomedia/decoder/video"
+	"github.com/ugparu/gomedia/encoder"
+	"github.com/ugparu/gomedia/utils/nal"
+)
+
+const (
+	naluTypeMask = 0x1f
+	naluTypeSPS  = 7
+	naluTypePPS  = 8
+)
+
+// ffmpegCPUEncoder encodes RGB frames to H.264 with libx264 (or whichever
+// H.264 encoder libavcodec provides). B-frames are disabled, so packets come
+// out in presentation order and are matched to their frames through pending.
+type ffmpegCPUEncoder struct {
+	enc      *C.cpuEncoder
+	codecPar *h264.CodecParameters
+	frameDur time.Duration
+	pending  []gomedia.VideoFrame
+}
+
+func NewFFmpegCPUEncoder() encoder.InnerVideoEncoder {
+	return &ffmpegCPUEncoder{
+		enc: new(C.cpuEncoder),
+	}
+}
+
+func (e *ffmpegCPUEncoder) Init(params gomedia.VideoEncoderParameters) error {
+	if params.Width < 2 || params.Height < 2 || params.FPS <= 0 {
+		return errors.New("invalid video encoder parameters")
+	}
+
+	e.enc = new(C.cpuEncoder)
+	if ret := C.init_cpu_encoder(e.enc, C.int(params.Width), C.int(params.Height), C.int(params.FPS),
+		C.int64_t(params.Bitrate), C.int(params.GOPSize)); ret < 0 {
+		return video.NewFFmpegError("can not init cpu encoder", int(ret))
+	}
+
+	extradata := C.GoBytes(unsafe.Pointer(e.enc.ctxt.extradata), e.enc.ctxt.extradata_size)
+	var sps, pps []byte
+	for _, nalu := range nal.SplitAnnexB(extradata, nil) {
+		switch nalu[0] & naluTypeMask {
+		case naluTypeSPS:
+			sps = nalu
+		case naluTypePPS:
+			pps = nalu
+		}
+	}
+	if sps == nil || pps == nil {
+		return errors.New("encoder did not produce SPS/PPS")
+	}
+
+	codecPar, err := h264.NewCodecDataFromSPSAndPPS(sps, pps)
+	if err != nil {
+		return err
+	}
+	codecPar.SetStreamIndex(params.StreamIndex)
+	if params.Bitrate > 0 {
+		codecPar.SetBitrate(params.Bitrate)
+	}
+	e.codecPar = &codecPar
+	e.frameDur = time.Second / time.Duration(params.FPS)
+	return nil
+}
+
+func (e *ffmpegCPUEncoder) Encode(frame gomedia.VideoFrame) ([]gomedia.VideoPacket, error) {
+	img := frame.Image.GetRGB()
+	if img == nil || len(img.Pix) == 0 {
+		return nil, errors.New("empty frame")
+	}
+
+	pix := img.Pix[img.PixOffset(img.Rect.Min.X, img.Rect.Min.Y):]
+	if ret := C.send_cpu_frame(e.enc, (*C.uint8_t)(unsafe.Pointer(&pix[0])), C.int(img.Stride),
+		C.int64_t(frame.Timestamp.Microseconds())); ret < 0 {
+		return nil, video.NewFFmpegError("can not encode frame", int(ret))
+	}
+	frame.Image = nil
+	e.pending = append(e.pending, frame)
+
+	var packets []gomedia.VideoPacket
+	for {
+		ret := C.receive_cpu_packet(e.enc)
+		if ret > 0 {
+			return packets, nil
+		}
+		if ret < 0 {
+			return packets, video.NewFFmpegError("can not receive packet", int(ret))
+		}
+		if pkt := e.packet(); pkt != nil {
+			packets = append(packets, pkt)
+		}
+	}
+}
+
+// packet converts the encoder's current AVPacket into an h264.Packet stamped
+// with the timing of the frame it was produced from.
+func (e *ffmpegCPUEncoder) packet() gomedia.VideoPacket {
+	cPkt := e.enc.packet
+	pts := time.Duration(cPkt.pts) * time.Microsecond
+
+	var frame gomedia.VideoFrame
+	for len(e.pending) > 0 {
+		frame = e.pending[0]
+		e.pending = e.pending[1:]
+		if frame.Timestamp.Microseconds() >= int64(cPkt.pts) {
+			break
+		}
+	}
+	if frame.Timestamp.Microseconds() != int64(cPkt.pts) {
+		frame.Timestamp = pts
+	}
+
+	data := nal.AnnexBToAVCC(unsafe.Slice((*byte)(cPkt.data), int(cPkt.size)))
+	if len(data) == 0 {
+		return nil
+	}
+	key := cPkt.flags&C.AV_PKT_FLAG_KEY != 0
+	pkt := h264.NewPacket(key, frame.Timestamp, frame.StartTime, data, frame.SourceID, e.codecPar)
+	pkt.SetDuration(e.frameDur)
+	return pkt
+}
+
+func (e *ffmpegCPUEncoder) Close() {
+	C.close_cpu_encoder(e.enc)
+	e.pending = nil
+}
@@ -0,0 +1,21 @@
+#ifndef _ENCODER_FFMPEG_CPU_H_
+#define _ENCODER_FFMPEG_CPU_H_
+
+#include <libavcodec/avcodec.h>
+#include <libswscale/swscale.h>
+
+typedef struct {
+    AVCodecContext *ctxt;
+    AVFrame *frame;
+    AVPacket *packet;
+    struct SwsContext *scale_ctxt;
+    int src_width;
+    int src_height;
+} cpuEncoder;
+
+int init_cpu_encoder(cpuEncoder *enc, int width, int height, int fps, int64_t bitrate, int gop);
+int send_cpu_frame(cpuEncoder *enc, const uint8_t *rgb, int stride, int64_t pts);
+int receive_cpu_packet(cpuEncoder *enc);
+void close_cpu_encoder(cpuEncoder *enc);
+
+#endif
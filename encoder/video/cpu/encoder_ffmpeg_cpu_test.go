@@ -0,0 +1,110 @@
+package cpu_test
+
+import (
+	"image"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ugparu/gomedia"
+	codech264 "github.com/ugparu/gomedia/codec/h264"
+	decodercpu "github.com/ugparu/gomedia/decoder/video/cpu"
+	"github.com/ugparu/gomedia/encoder/video/cpu"
+	"github.com/ugparu/gomedia/frame/rgb"
+)
+
+const (
+	testWidth  = 64
+	testHeight = 48
+	testFPS    = 25
+)
+
+func gradientFrame(i int) gomedia.VideoFrame {
+	img := rgb.NewRGB(image.Rect(0, 0, testWidth, testHeight))
+	for y := range testHeight {
+		for x := range testWidth {
+			img.Set(x, y, rgb.Color{R: byte(x*4 + i), G: byte(y * 5), B: byte(i * 8)})
+		}
+	}
+	return gomedia.VideoFrame{
+		Image:     img,
+		Timestamp: time.Duration(i) * time.Second / testFPS,
+		SourceID:  "test-source",
+	}
+}
+
+func encodeFrames(t *testing.T, n int) []gomedia.VideoPacket {
+	t.Helper()
+	enc := cpu.NewFFmpegCPUEncoder()
+	require.NoError(t, enc.Init(gomedia.VideoEncoderParameters{
+		Width: testWidth, Height: testHeight, FPS: testFPS, GOPSize: 10, StreamIndex: 1,
+	}))
+	defer enc.Close()
+
+	var packets []gomedia.VideoPacket
+	for i := range n {
+		pkts, err := enc.Encode(gradientFrame(i))
+		require.NoError(t, err)
+		packets = append(packets, pkts...)
+	}
+	return packets
+}
+
+func TestFFmpegCPUEncoder_ProducesH264Packets(t *testing.T) {
+	packets := encodeFrames(t, 20) //nolint:mnd
+	require.NotEmpty(t, packets)
+
+	first, ok := packets[0].(*codech264.Packet)
+	require.True(t, ok, "expected *h264.Packet, got %T", packets[0])
+	require.True(t, first.IsKeyFrame())
+
+	par := first.CodecParameters()
+	require.Equal(t, uint(testWidth), par.Width())
+	require.Equal(t, uint(testHeight), par.Height())
+	require.Equal(t, uint8(1), par.StreamIndex())
+
+	keys := 0
+	for i, pkt := range packets {
+		require.Equal(t, "test-source", pkt.SourceID())
+		require.Equal(t, time.Second/testFPS, pkt.Duration())
+		if i > 0 {
+			require.Greater(t, pkt.Timestamp(), packets[i-1].Timestamp())
+		}
+		// AVCC framing: the first length prefix covers a NALU inside the packet.
+		data := pkt.Data()
+		require.GreaterOrEqual(t, len(data), 5) //nolint:mnd
+		naluLen := int(data[0])<<24 | int(data[1])<<16 | int(data[2])<<8 | int(data[3])
+		require.LessOrEqual(t, 4+naluLen, len(data))
+		if pkt.IsKeyFrame() {
+			keys++
+		}
+	}
+	require.GreaterOrEqual(t, keys, 2, "GOP of 10 over 20 frames should give at least two key frames")
+}
+
+func TestFFmpegCPUEncoder_DecodesBack(t *testing.T) {
+	packets := encodeFrames(t, 5) //nolint:mnd
+	require.NotEmpty(t, packets)
+
+	dec := decodercpu.NewFFmpegCPUDecoder()
+	require.NoError(t, dec.Init(packets[0].CodecParameters()))
+	defer dec.Close()
+
+	decoded := 0
+	for _, pkt := range packets {
+		img, err := dec.Decode(pkt)
+		if err != nil {
+			continue
+		}
+		require.Equal(t, image.Rect(0, 0, testWidth, testHeight), img.Bounds())
+		img.Release()
+		decoded++
+	}
+	require.Positive(t, decoded)
+}
+
+func TestFFmpegCPUEncoder_InvalidParameters(t *testing.T) {
+	enc := cpu.NewFFmpegCPUEncoder()
+	require.Error(t, enc.Init(gomedia.VideoEncoderParameters{Width: 0, Height: 0, FPS: testFPS}))
+}
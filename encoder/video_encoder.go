@@ -0,0 +1,173 @@
+package encoder
+
+//go:generate mockgen -source=video_encoder.go -destination=../mocks/mock_video_encoder.go -package=mocks
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ugparu/gomedia"
+	"github.com/ugparu/gomedia/utils/lifecycle"
+	"github.com/ugparu/gomedia/utils/logger"
+)
+
+// InnerVideoEncoder is a codec backend driven by the async VideoEncoder.
+// Encode may return zero or more packets per frame; it must not retain or
+// release frame.Image.
+type InnerVideoEncoder interface {
+	Init(params gomedia.VideoEncoderParameters) error
+	Encode(frame gomedia.VideoFrame) ([]gomedia.VideoPacket, error)
+	Close()
+}
+
+// videoEncoder is the async wrapper around an InnerVideoEncoder. It restarts
+// the inner encoder whenever the frame resolution changes.
+type videoEncoder struct {
+	lifecycle.AsyncManager[*videoEncoder]
+	InnerVideoEncoder
+	newEncoderFn func() InnerVideoEncoder
+	params       gomedia.VideoEncoderParameters
+	inpFrames    chan gomedia.VideoFrame
+	outPackets   chan gomedia.Packet
+	name         string
+	log          logger.Logger
+}
+
+type VideoEncoderParam func(*videoEncoder)
+
+func VideoWithName(name string) VideoEncoderParam {
+	return func(e *videoEncoder) { e.name = name }
+}
+
+func VideoWithLogger(l logger.Logger) VideoEncoderParam {
+	return func(e *videoEncoder) { e.log = l }
+}
+
+// VideoWithBitrate sets the target bitrate in bits per second.
+func VideoWithBitrate(bitrate uint) VideoEncoderParam {
+	return func(e *videoEncoder) { e.params.Bitrate = bitrate }
+}
+
+// VideoWithGOPSize sets the key frame interval in frames.
+func VideoWithGOPSize(frames int) VideoEncoderParam {
+	return func(e *videoEncoder) { e.params.GOPSize = frames }
+}
+
+// VideoWithStreamIndex sets the stream index of the produced codec
+// parameters and packets.
+func VideoWithStreamIndex(idx uint8) VideoEncoderParam {
+	return func(e *videoEncoder) { e.params.StreamIndex = idx }
+}
+
+// NewVideoEncoder returns an async video encoder producing a stream at the
+// nominal frame rate fps. newEncoderFn creates a fresh backend, e.g.
+// cpu.NewFFmpegCPUEncoder.
+func NewVideoEncoder(chanSize int, fps int, newEncoderFn func() InnerVideoEncoder,
+	params ...VideoEncoderParam) gomedia.VideoEncoder {
+	e := &videoEncoder{
+		newEncoderFn: newEncoderFn,
+		params:       gomedia.VideoEncoderParameters{FPS: fps},
+		log:          logger.Default,
+	}
+	for _, param := range params {
+		param(e)
+	}
+	if e.params.GOPSize <= 0 {
+		e.params.GOPSize = max(fps, 1)
+	}
+	e.inpFrames = make(chan gomedia.VideoFrame, chanSize)
+	e.outPackets = make(chan gomedia.Packet, chanSize)
+	e.AsyncManager = lifecycle.NewFailSafeAsyncManager(e, e.log)
+	return e
+}
+
+func (e *videoEncoder) Encode() {
+	// FailSafeAsyncManager.Start never returns an error.
+	_ = e.Start(func(_ *videoEncoder) error { return nil })
+}
+
+func (e *videoEncoder) Step(stopCh <-chan struct{}) error {
+	select {
+	case <-stopCh:
+		return &lifecycle.BreakError{}
+	case frame := <-e.inpFrames:
+		if frame.Image == nil {
+			return errors.New("video encoder: frame without image")
+		}
+		defer frame.Image.Release()
+		packets, err := e.encodeFrame(frame)
+		if err != nil {
+			// Drop the backend so the next frame starts from a clean state.
+			e.stopEncoder()
+			return err
+		}
+		for _, pkt := range packets {
+			select {
+			case e.outPackets <- pkt:
+			case <-stopCh:
+				pkt.Release()
+				return &lifecycle.BreakError{}
+			}
+		}
+	}
+	return nil
+}
+
+func (e *videoEncoder) encodeFrame(frame gomedia.VideoFrame) ([]gomedia.VideoPacket, error) {
+	size := frame.Image.Bounds().Size()
+	if e.InnerVideoEncoder == nil || size.X != e.params.Width || size.Y != e.params.Height {
+		e.log.Infof(e, "Starting encoder for %dx%d", size.X, size.Y)
+		e.stopEncoder()
+		e.params.Width, e.params.Height = size.X, size.Y
+		e.InnerVideoEncoder = e.newEncoderFn()
+		if err := e.InnerVideoEncoder.Init(e.params); err != nil {
+			return nil, err
+		}
+	}
+	return e.InnerVideoEncoder.Encode(frame)
+}
+
+func (e *videoEncoder) stopEncoder() {
+	if e.InnerVideoEncoder == nil {
+		return
+	}
+	e.InnerVideoEncoder.Close()
+	e.InnerVideoEncoder = nil
+}
+
+// Release closes the backend and drains the input so no pooled frames leak.
+func (e *videoEncoder) Release() { //nolint:revive // required by lifecycle.AsyncInstance interface
+	e.stopEncoder()
+	for {
+		select {
+		case frame, ok := <-e.inpFrames:
+			if !ok {
+				goto drained
+			}
+			if frame.Image != nil {
+				frame.Image.Release()
+			}
+		default:
+			close(e.inpFrames)
+			goto drained
+		}
+	}
+drained:
+	close(e.outPackets)
+}
+
+func (e *videoEncoder) Close() {
+	e.AsyncManager.Close()
+}
+
+func (e *videoEncoder) String() string {
+	return fmt.Sprintf("VENCODER %s", e.name)
+}
+
+func (e *videoEncoder) Packets() <-chan gomedia.Packet {
+	return e.outPackets
+}
+
+func (e *videoEncoder) Frames() chan<- gomedia.VideoFrame {
+	return e.inpFrames
+}
@@ -0,0 +1,189 @@
+package encoder
+
+import (
+	"errors"
+	"image"
+	"testing"
+	"time"
+
+	"github.com/ugparu/gomedia"
+	"github.com/ugparu/gomedia/codec/h264"
+	"github.com/ugparu/gomedia/frame/rgb"
+	"github.com/ugparu/gomedia/mocks"
+	"go.uber.org/mock/gomock"
+)
+
+// countingImage records how many times Release is called.
+type countingImage struct {
+	*rgb.RGB
+	released int
+}
+
+func (img *countingImage) Release() { img.released++ }
+
+func newTestFrame(w, h int, ts time.Duration) (gomedia.VideoFrame, *countingImage) {
+	img := &countingImage{RGB: rgb.NewRGB(image.Rect(0, 0, w, h))}
+	return gomedia.VideoFrame{Image: img, Timestamp: ts, SourceID: "test-source"}, img
+}
+
+func newTestH264Packet(ts time.Duration) *h264.Packet {
+	return h264.NewPacket(true, ts, time.Now(), []byte{0, 0, 0, 1, 0x65}, "test-source", &h264.CodecParameters{})
+}
+
+// closeVideoEncoder stops the async loop and waits for it to finish.
+func closeVideoEncoder(enc gomedia.VideoEncoder) {
+	enc.Close()
+	<-enc.Done()
+}
+
+func receivePacket(t *testing.T, enc gomedia.VideoEncoder) gomedia.Packet {
+	t.Helper()
+	select {
+	case pkt := <-enc.Packets():
+		return pkt
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for output packet")
+	}
+	return nil
+}
+
+func TestNewVideoEncoder(t *testing.T) {
+	enc := NewVideoEncoder(10, 25, func() InnerVideoEncoder { return nil }) //nolint:mnd // buffer size, fps
+
+	if enc.Frames() == nil {
+		t.Fatal("expected non-nil input channel")
+	}
+	if enc.Packets() == nil {
+		t.Fatal("expected non-nil output channel")
+	}
+	closeVideoEncoder(enc)
+}
+
+func TestVideoEncoder_InitsFromFrameAndForwardsPackets(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockInner := mocks.NewMockInnerVideoEncoder(ctrl)
+
+	mockInner.EXPECT().Init(gomedia.VideoEncoderParameters{
+		Width: 64, Height: 48, FPS: 25, Bitrate: 500_000, GOPSize: 50, StreamIndex: 1,
+	}).Return(nil).Times(1)
+	mockInner.EXPECT().Encode(gomock.Any()).DoAndReturn(func(f gomedia.VideoFrame) ([]gomedia.VideoPacket, error) {
+		return []gomedia.VideoPacket{newTestH264Packet(f.Timestamp)}, nil
+	}).Times(2) //nolint:mnd
+	mockInner.EXPECT().Close().Times(1)
+
+	enc := NewVideoEncoder(10, 25, func() InnerVideoEncoder { return mockInner }, //nolint:mnd // buffer size, fps
+		VideoWithBitrate(500_000), VideoWithGOPSize(50), VideoWithStreamIndex(1)) //nolint:mnd
+	enc.Encode()
+
+	frame1, img1 := newTestFrame(64, 48, 0)                   //nolint:mnd
+	frame2, img2 := newTestFrame(64, 48, 40*time.Millisecond) //nolint:mnd
+	enc.Frames() <- frame1
+	enc.Frames() <- frame2
+
+	if pkt := receivePacket(t, enc); pkt.Timestamp() != 0 {
+		t.Errorf("first packet timestamp = %v, want 0", pkt.Timestamp())
+	}
+	if pkt := receivePacket(t, enc); pkt.Timestamp() != 40*time.Millisecond {
+		t.Errorf("second packet timestamp = %v, want 40ms", pkt.Timestamp())
+	}
+
+	closeVideoEncoder(enc)
+	if img1.released != 1 || img2.released != 1 {
+		t.Errorf("input frames released %d/%d times, want 1/1", img1.released, img2.released)
+	}
+}
+
+func TestVideoEncoder_DefaultGOPIsOneSecond(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockInner := mocks.NewMockInnerVideoEncoder(ctrl)
+	initDone := make(chan gomedia.VideoEncoderParameters, 1)
+
+	mockInner.EXPECT().Init(gomock.Any()).DoAndReturn(func(p gomedia.VideoEncoderParameters) error {
+		initDone <- p
+		return nil
+	})
+	mockInner.EXPECT().Encode(gomock.Any()).Return(nil, nil)
+	mockInner.EXPECT().Close()
+
+	enc := NewVideoEncoder(10, 30, func() InnerVideoEncoder { return mockInner }) //nolint:mnd
+	enc.Encode()
+	frame, _ := newTestFrame(16, 16, 0) //nolint:mnd
+	enc.Frames() <- frame
+
+	select {
+	case p := <-initDone:
+		if p.GOPSize != 30 { //nolint:mnd
+			t.Errorf("GOPSize = %d, want 30", p.GOPSize)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for Init")
+	}
+	closeVideoEncoder(enc)
+}
+
+func TestVideoEncoder_ResolutionChangeRestartsBackend(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	first := mocks.NewMockInnerVideoEncoder(ctrl)
+	second := mocks.NewMockInnerVideoEncoder(ctrl)
+
+	first.EXPECT().Init(gomock.Any()).Return(nil)
+	first.EXPECT().Encode(gomock.Any()).Return([]gomedia.VideoPacket{newTestH264Packet(0)}, nil)
+	first.EXPECT().Close()
+	second.EXPECT().Init(gomock.Any()).DoAndReturn(func(p gomedia.VideoEncoderParameters) error {
+		if p.Width != 32 || p.Height != 32 {
+			t.Errorf("second Init size = %dx%d, want 32x32", p.Width, p.Height)
+		}
+		return nil
+	})
+	second.EXPECT().Encode(gomock.Any()).Return([]gomedia.VideoPacket{newTestH264Packet(time.Second)}, nil)
+	second.EXPECT().Close()
+
+	backends := []InnerVideoEncoder{first, second}
+	enc := NewVideoEncoder(10, 25, func() InnerVideoEncoder { //nolint:mnd
+		b := backends[0]
+		backends = backends[1:]
+		return b
+	})
+	enc.Encode()
+
+	frame1, _ := newTestFrame(16, 16, 0)           //nolint:mnd
+	frame2, _ := newTestFrame(32, 32, time.Second) //nolint:mnd
+	enc.Frames() <- frame1
+	receivePacket(t, enc)
+	enc.Frames() <- frame2
+	receivePacket(t, enc)
+
+	closeVideoEncoder(enc)
+}
+
+func TestVideoEncoder_ErrorRestartsBackendOnNextFrame(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	failing := mocks.NewMockInnerVideoEncoder(ctrl)
+	healthy := mocks.NewMockInnerVideoEncoder(ctrl)
+
+	failing.EXPECT().Init(gomock.Any()).Return(nil)
+	failing.EXPECT().Encode(gomock.Any()).Return(nil, errors.New("boom"))
+	failing.EXPECT().Close()
+	healthy.EXPECT().Init(gomock.Any()).Return(nil)
+	healthy.EXPECT().Encode(gomock.Any()).Return([]gomedia.VideoPacket{newTestH264Packet(0)}, nil)
+	healthy.EXPECT().Close()
+
+	backends := []InnerVideoEncoder{failing, healthy}
+	enc := NewVideoEncoder(10, 25, func() InnerVideoEncoder { //nolint:mnd
+		b := backends[0]
+		backends = backends[1:]
+		return b
+	})
+	enc.Encode()
+
+	frame1, img1 := newTestFrame(16, 16, 0) //nolint:mnd
+	frame2, _ := newTestFrame(16, 16, 0)    //nolint:mnd
+	enc.Frames() <- frame1
+	enc.Frames() <- frame2
+	receivePacket(t, enc)
+
+	closeVideoEncoder(enc)
+	if img1.released != 1 {
+		t.Errorf("failed frame released %d times, want 1", img1.released)
+	}
+}
@@ -7,38 +7,35 @@ import (
 	"github.com/ugparu/gomedia"
 	"github.com/ugparu/gomedia/decoder"
 	"github.com/ugparu/gomedia/decoder/aac"
-	"github.com/ugparu/gomedia/encoder"
-	"github.com/ugparu/gomedia/encoder/pcm"
 	examplelogger "github.com/ugparu/gomedia/examples/logger"
 	"github.com/ugparu/gomedia/format/rtsp"
 	"github.com/ugparu/gomedia/reader"
+	"github.com/ugparu/gomedia/transcode"
 )
 
 func main() {
+	rtspURL := os.Getenv("RTSP_URL")
 	rdr := reader.NewRTSP(100, reader.WithLogger(examplelogger.New(logrus.InfoLevel)), reader.WithRTSPParams(rtsp.WithLogger(examplelogger.New(logrus.InfoLevel))))
 	rdr.Read()
 	defer rdr.Close()
-	rdr.AddURL() <- os.Getenv("RTSP_URL")
-
-	audioDecoder := decoder.NewAudioDecoder(100, map[gomedia.CodecType]func() decoder.InnerAudioDecoder{
-		gomedia.AAC: aac.NewAacDecoder,
-	})
-	audioDecoder.Decode()
+	rdr.AddURL() <- rtspURL
+
+	// G.711 and G.722/G.726 are decoded out of the box; AAC needs its decoder.
+	tr, err := transcode.NewAudio(100, gomedia.PCMAlaw, 8000, 1,
+		transcode.WithAudioDecoders(map[gomedia.CodecType]func() decoder.InnerAudioDecoder{
+			gomedia.AAC: aac.NewAacDecoder,
+		}),
+		transcode.WithPassthrough(false))
+	if err != nil {
+		panic(err)
+	}
+	tr.Write()
+	defer tr.Close()
+	tr.AddSource() <- rtspURL
 
 	go func() {
 		for pkt := range rdr.Packets() {
-			if inPkt, ok := pkt.(gomedia.AudioPacket); ok {
-				audioDecoder.Packets() <- inPkt
-			}
-		}
-	}()
-
-	alawEnc := encoder.NewAudioEncoder(100, pcm.NewAlawEncoder)
-	alawEnc.Encode()
-
-	go func() {
-		for pkt := range audioDecoder.Samples() {
-			alawEnc.Samples() <- pkt
+			tr.Packets() <- pkt
 		}
 	}()
 
@@ -49,8 +46,9 @@ func main() {
 	defer f.Close()
 
 	packets := 0
-	for pkt := range alawEnc.Packets() {
+	for pkt := range tr.Output() {
 		f.Write(pkt.Data())
+		pkt.Release()
 		packets++
 		if packets > 100 {
 			break
@@ -12,6 +12,10 @@ import (
 	"github.com/ugparu/gomedia/format/mp4"
 )
 
+// flushThreshold bounds how much packet data the muxer buffers before it is
+// written out, so merging multi-gigabyte recordings does not hold them in RAM.
+const flushThreshold = 64 << 20
+
 func main() {
 	log := examplelogger.New(logrus.InfoLevel)
 
@@ -68,6 +72,12 @@ func main() {
 				log.Errorf(log, "write packet error: %v", err)
 				break
 			}
+			if mp4wr.PendingBytes() >= flushThreshold {
+				if err = mp4wr.Flush(); err != nil {
+					log.Errorf(log, "flush error: %v", err)
+					break
+				}
+			}
 		}
 	}
 	mpDmx.Close()
@@ -113,6 +123,12 @@ func main() {
 					log.Errorf(log, "write packet error: %v", err)
 					break
 				}
+				if mp4wr.PendingBytes() >= flushThreshold {
+					if err = mp4wr.Flush(); err != nil {
+						log.Errorf(log, "flush error: %v", err)
+						break
+					}
+				}
 			}
 		}
 		mpDmx.Close()
@@ -5,7 +5,6 @@ import (
 	"io"
 	"log"
 	"os"
-	"time"
 
 	"github.com/sirupsen/logrus"
 	examplelogger "github.com/ugparu/gomedia/examples/logger"
@@ -14,7 +13,8 @@ import (
 )
 
 // This example demonstrates how to:
-//   - demux an MP4 file using the mp4.Demuxer
+//   - play an MP4 file in real time using the mp4 playback demuxer, which
+//     releases packets at the pace of their timestamps like a live camera
 //   - publish its codec parameters as an RTSP stream using the RTSP muxer
 //   - iterate over packets from the MP4 demuxer and attempt to send them via RTSP
 //
@@ -37,8 +37,9 @@ func main() {
 	log.Printf("Using input MP4: %s", inputPath)
 	log.Printf("Publishing to RTSP URL: %s", rtspURL)
 
-	// 1. Set up MP4 demuxer and read codec parameters.
-	dmx := mp4.NewDemuxer(inputPath)
+	// 1. Set up the MP4 playback demuxer and read codec parameters. Add
+	// mp4.WithLoop() to replay the file endlessly.
+	dmx := mp4.NewPlaybackDemuxer(inputPath)
 	params, err := dmx.Demux()
 	if err != nil {
 		log.Fatalf("failed to demux MP4 %q: %v", inputPath, err)
@@ -54,12 +55,8 @@ func main() {
 
 	log.Println("RTSP publish session established, starting to read MP4 packets...")
 
-	// 3. Read packets from MP4 and attempt to send them via RTSP.
-	var (
-		packetsSent int
-		lastTS      time.Duration
-		haveTS      bool
-	)
+	// 3. Read packets from MP4 as they become due and attempt to send them via RTSP.
+	var packetsSent int
 	for {
 		pkt, err := dmx.ReadPacket()
 		if err != nil {
@@ -73,18 +70,6 @@ func main() {
 			continue
 		}
 
-		// Emulate (approximate) realtime by sleeping based on packet timestamps.
-		// MP4 demuxer sets pkt.Timestamp() as a monotonically increasing media time.
-		ts := pkt.Timestamp()
-		if haveTS {
-			if delta := ts - lastTS; delta > 0 {
-				time.Sleep(delta)
-			}
-		} else {
-			haveTS = true
-		}
-		lastTS = ts
-
 		if err := mx.WritePacket(pkt); err != nil {
 			// Currently expected: rtsp.ErrRTPMuxerNotImplemented.
 			if errors.Is(err, rtsp.ErrRTPMuxerNotImplemented) {
@@ -18,6 +18,7 @@ import (
 	"github.com/ugparu/gomedia"
 	"github.com/ugparu/gomedia/decoder"
 	aacDec "github.com/ugparu/gomedia/decoder/aac"
+	"github.com/ugparu/gomedia/decoder/adpcm"
 	"github.com/ugparu/gomedia/decoder/opus"
 	"github.com/ugparu/gomedia/decoder/pcm"
 	"github.com/ugparu/gomedia/encoder"
@@ -66,6 +67,8 @@ func main() {
 	audioDecoder := decoder.NewAudioDecoder(100, map[gomedia.CodecType]func() decoder.InnerAudioDecoder{
 		gomedia.PCMAlaw: pcm.NewALAWDecoder,
 		gomedia.PCMUlaw: pcm.NewULAWDecoder,
+		gomedia.G722:    adpcm.NewG722Decoder,
+		gomedia.G726:    adpcm.NewG726Decoder,
 		gomedia.OPUS:    opus.NewOpusDecoder,
 		gomedia.AAC:     aacDec.NewAacDecoder,
 	})
@@ -2,6 +2,8 @@
 package fmp4
 
 import (
+	"crypto/aes"
+	"crypto/cipher"
 	"encoding/base64"
 	"encoding/json"
 	"os"
@@ -156,8 +158,8 @@ func parseFragment(t *testing.T, data []byte) (*mp4io.MovieFrag, []byte) {
 	require.Equal(t, mp4io.STYP, readTag(data, n), "first box must be styp")
 	n += int(readBoxSize(data, n))
 
-	// sidx boxes
-	for n+8 <= len(data) && readTag(data, n) == mp4io.SIDX {
+	// sidx / emsg boxes
+	for n+8 <= len(data) && (readTag(data, n) == mp4io.SIDX || readTag(data, n) == mp4io.EMSG) {
 		n += int(readBoxSize(data, n))
 	}
 
@@ -248,6 +250,11 @@ func TestGetInit_ParsedMoov_VideoTrack(t *testing.T) {
 	require.NotNil(t, track.Media.Info)
 	require.NotNil(t, track.Media.Info.Video, "video track must have vmhd")
 
+	// ISO 14496-12 §8.6.6: single zero-based edit spanning all fragments
+	require.NotNil(t, track.Edit)
+	require.NotNil(t, track.Edit.List)
+	require.Equal(t, []mp4io.EditListEntry{{MediaRateInteger: 1}}, track.Edit.List.Entries)
+
 	// ISO 14496-14 §5.6: mvex is required for fragmented files
 	require.NotNil(t, moov.MovieExtend)
 	require.Len(t, moov.MovieExtend.Tracks, 1)
@@ -391,6 +398,55 @@ func TestGetMP4Fragment_ParsedMoof_DecodeTime(t *testing.T) {
 	require.Equal(t, expectedTime, moof.Tracks[0].DecodeTime.Time)
 }
 
+func TestGetMP4Fragment_EventMessageBeforeMoof(t *testing.T) {
+	t.Parallel()
+	pair, videoCp, _ := loadTestCodecPair(t)
+	pair.AudioCodecParameters = nil
+
+	m := NewMuxer(logger.Default)
+	require.NoError(t, m.Mux(pair))
+	require.NoError(t, m.WritePacket(makeVideoPacket(videoCp, true, time.Second, 40*time.Millisecond, []byte{0x01})))
+	m.WriteEvent(Event{
+		SchemeIDURI:      "urn:gomedia:analytics",
+		Value:            "motion",
+		PresentationTime: 1500 * time.Millisecond,
+		Duration:         time.Second,
+		ID:               7,
+		Data:             []byte(`{"zone":1}`),
+	})
+
+	data := m.GetMP4Fragment(1).Data()
+	n := int(readBoxSize(data, 0))
+	require.Equal(t, mp4io.EMSG, readTag(data, n), "emsg must follow styp")
+
+	var emsg mp4io.EventMessage
+	_, err := emsg.Unmarshal(data[n:n+int(readBoxSize(data, n))], n)
+	require.NoError(t, err)
+	require.Equal(t, uint8(1), emsg.Version)
+	require.Equal(t, "urn:gomedia:analytics", emsg.SchemeIDURI)
+	require.Equal(t, "motion", emsg.Value)
+	require.Equal(t, uint64(1500*eventTimeScale/1000), emsg.PresentationTime)
+	require.Equal(t, uint32(eventTimeScale), emsg.EventDuration)
+	require.Equal(t, uint32(7), emsg.ID)
+	require.Equal(t, []byte(`{"zone":1}`), emsg.MessageData)
+
+	moof, _ := parseFragment(t, data)
+	require.Len(t, moof.Tracks, 1)
+
+	// Events are consumed by the fragment they were written into.
+	require.NoError(t, m.WritePacket(makeVideoPacket(videoCp, true, 2*time.Second, 40*time.Millisecond, []byte{0x01})))
+	data = m.GetMP4Fragment(2).Data()
+	require.Equal(t, mp4io.MOOF, readTag(data, int(readBoxSize(data, 0))))
+}
+
+func TestEventTicks_NoOverflowOnLongTimelines(t *testing.T) {
+	t.Parallel()
+	// pt * 90000 overflows int64 past ~28h; a week-long live stream must not wrap.
+	week := 7 * 24 * time.Hour
+	require.Equal(t, uint64(7*24*3600)*eventTimeScale, eventTicks(week))
+	require.Equal(t, uint64(7*24*3600)*eventTimeScale+eventTimeScale/2, eventTicks(week+500*time.Millisecond))
+}
+
 func TestGetMP4Fragment_DataOffset_PointsIntoMdat(t *testing.T) {
 	t.Parallel()
 	pair, videoCp, _ := loadTestCodecPair(t)
@@ -763,3 +819,116 @@ func TestFullRoundtrip_BufferExactSize(t *testing.T) {
 	}
 	require.Equal(t, len(data), n, "boxes must exactly fill the buffer with no leftover bytes")
 }
+
+func TestGetInit_CBCSProtectedSampleEntry(t *testing.T) {
+	t.Parallel()
+	pair, _, _ := loadTestCodecPair(t)
+
+	enc := &Encryption{
+		KeyID: [16]byte{1, 2, 3},
+		IV:    [16]byte{9, 9, 9},
+		PSSH:  []ProtectionSystem{{SystemID: [16]byte{0xaa}, KeyIDs: [][16]byte{{1, 2, 3}}, Data: []byte("pssh")}},
+	}
+	m := NewMuxer(logger.Default)
+	m.SetEncryption(enc)
+	require.NoError(t, m.Mux(pair))
+	_, moov := parseInitSegment(t, m.GetInit().Data())
+
+	require.Len(t, moov.Tracks, 2)
+	for i, wantTag := range []mp4io.Tag{mp4io.ENCV, mp4io.ENCA} {
+		desc := moov.Tracks[i].Media.Info.Sample.SampleDesc
+		require.Nil(t, desc.AVC1Desc)
+		require.Nil(t, desc.MP4ADesc)
+		require.Len(t, desc.Unknowns, 1)
+		entry, ok := desc.Unknowns[0].(*mp4io.ProtectedSampleEntry)
+		require.True(t, ok)
+		require.Equal(t, wantTag, entry.Tag())
+		require.Equal(t, mp4io.StringToTag("cbcs"), entry.Info.SchemeType.SchemeType)
+		tenc := entry.Info.SchemeInfo.TrackEncryption
+		require.Equal(t, enc.KeyID, tenc.DefaultKID)
+		require.Equal(t, enc.IV[:], tenc.DefaultConstantIV)
+		require.Equal(t, uint8(0), tenc.DefaultPerSampleIVSize)
+	}
+	desc := moov.Tracks[0].Media.Info.Sample.SampleDesc
+	tenc := desc.Unknowns[0].(*mp4io.ProtectedSampleEntry).Info.SchemeInfo.TrackEncryption
+	require.Equal(t, uint8(1), tenc.DefaultCryptByteBlock)
+	require.Equal(t, uint8(9), tenc.DefaultSkipByteBlock)
+	require.Equal(t, mp4io.AVC1, desc.Unknowns[0].(*mp4io.ProtectedSampleEntry).Info.OriginalFormat.DataFormat)
+
+	require.Len(t, moov.Unknowns, 1)
+	require.Equal(t, mp4io.PSSH, moov.Unknowns[0].Tag())
+	var pssh mp4io.ProtectionSystemHeader
+	raw := moov.Unknowns[0].(*mp4io.Dummy).Data
+	_, err := pssh.Unmarshal(raw, 0)
+	require.NoError(t, err)
+	require.Equal(t, uint8(1), pssh.Version)
+	require.Equal(t, enc.PSSH[0].SystemID, pssh.SystemID)
+	require.Equal(t, enc.PSSH[0].KeyIDs, pssh.KeyIDs)
+	require.Equal(t, []byte("pssh"), pssh.Data)
+}
+
+func TestGetMP4Fragment_CBCSEncryptsSamples(t *testing.T) {
+	t.Parallel()
+	pair, videoCp, _ := loadTestCodecPair(t)
+	pair.AudioCodecParameters = nil
+
+	// One SEI (never encrypted) and one 200-byte IDR slice.
+	sei := []byte{0x06, 0x05, 0x01, 0x80}
+	slice := make([]byte, 200)
+	slice[0] = 0x65
+	for i := 1; i < len(slice); i++ {
+		slice[i] = byte(i)
+	}
+	var payload []byte
+	for _, nal := range [][]byte{sei, slice} {
+		hdr := make([]byte, 4)
+		pio.PutU32BE(hdr, uint32(len(nal)))
+		payload = append(append(payload, hdr...), nal...)
+	}
+
+	enc := &Encryption{Key: [16]byte{7}, IV: [16]byte{5}}
+	m := NewMuxer(logger.Default)
+	m.SetEncryption(enc)
+	require.NoError(t, m.Mux(pair))
+	require.NoError(t, m.WritePacket(makeVideoPacket(videoCp, true, 0, 40*time.Millisecond, payload)))
+
+	data := m.GetMP4Fragment(1).Data()
+	moofStart := int(readBoxSize(data, 0))
+	moof, mdat := parseFragment(t, data)
+	require.Len(t, mdat, len(payload))
+
+	traf := moof.Tracks[0]
+	require.Len(t, traf.Unknowns, 3)
+	require.Equal(t, []mp4io.Tag{mp4io.SAIZ, mp4io.SAIO, mp4io.SENC},
+		[]mp4io.Tag{traf.Unknowns[0].Tag(), traf.Unknowns[1].Tag(), traf.Unknowns[2].Tag()})
+
+	// 4+4 SEI, 4+32 lead, 8 partial-block bytes clear; 160 protected.
+	var senc mp4io.SampleEncryption
+	_, err := senc.Unmarshal(traf.Unknowns[2].(*mp4io.Dummy).Data, 0)
+	require.NoError(t, err)
+	require.Len(t, senc.Entries, 1)
+	require.Equal(t, []mp4io.SubSample{{ClearBytes: 52, ProtectedBytes: 160}}, senc.Entries[0].SubSamples)
+
+	var saiz mp4io.SampleAuxInfoSizes
+	_, err = saiz.Unmarshal(traf.Unknowns[0].(*mp4io.Dummy).Data, 0)
+	require.NoError(t, err)
+	require.Equal(t, []uint8{8}, []uint8(saiz.SampleInfoSizes))
+
+	var saio mp4io.SampleAuxInfoOffsets
+	_, err = saio.Unmarshal(traf.Unknowns[1].(*mp4io.Dummy).Data, 0)
+	require.NoError(t, err)
+	require.Len(t, saio.Offsets, 1)
+	aux := data[moofStart+int(saio.Offsets[0]):]
+	require.Equal(t, uint16(1), pio.U16BE(aux), "saio must point at the first senc entry")
+	require.Equal(t, uint16(52), pio.U16BE(aux[2:]))
+
+	require.Equal(t, payload[:52], mdat[:52], "clear bytes must be untouched")
+	require.NotEqual(t, payload[52:68], mdat[52:68], "first pattern block must be encrypted")
+	require.Equal(t, payload[68:212], mdat[68:212], "skipped blocks must stay clear")
+
+	block, err := aes.NewCipher(enc.Key[:])
+	require.NoError(t, err)
+	plain := make([]byte, 16)
+	cipher.NewCBCDecrypter(block, enc.IV[:]).CryptBlocks(plain, mdat[52:68])
+	require.Equal(t, payload[52:68], plain)
+}
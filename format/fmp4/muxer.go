@@ -38,6 +38,47 @@ type Muxer struct {
 	strs   []*Stream
 	params gomedia.CodecParametersPair
 	log    logger.Logger
+	events []*mp4io.EventMessage // emsg boxes queued for the next fragment
+	enc    *Encryption           // 'cbcs' sample encryption, nil for clear media
+}
+
+// eventTimeScale is the emsg timescale, matching the video track so event
+// times line up exactly with frame boundaries.
+const eventTimeScale = 90000
+
+// Event is an in-band event delivered as a DASH/CMAF emsg box in front of the
+// next fragment, e.g. an analytics detection or an ID3 timed-metadata frame.
+type Event struct {
+	SchemeIDURI      string        // e.g. "https://aomedia.org/emsg/ID3" or a private URN
+	Value            string        // scheme-specific sub-type, may be empty
+	PresentationTime time.Duration // on the same timeline as packet timestamps
+	Duration         time.Duration // zero when unknown
+	ID               uint32        // events with equal scheme, value and ID are duplicates
+	Data             []byte
+}
+
+// WriteEvent queues ev for the next fragment returned by GetMP4Fragment.
+// The data is copied.
+func (m *Muxer) WriteEvent(ev Event) {
+	pt := max(ev.PresentationTime, 0)
+	m.events = append(m.events, &mp4io.EventMessage{
+		Version:          1,
+		SchemeIDURI:      ev.SchemeIDURI,
+		Value:            ev.Value,
+		TimeScale:        eventTimeScale,
+		PresentationTime: eventTicks(pt),
+		EventDuration:    uint32(min(eventTicks(max(ev.Duration, 0)), 1<<32-1)), //nolint:gosec // clamped
+		ID:               ev.ID,
+		MessageData:      append([]byte(nil), ev.Data...),
+	})
+}
+
+// eventTicks converts a non-negative d to eventTimeScale ticks, split into
+// seconds and remainder like Stream.timeToTS so days-long live timelines do
+// not overflow int64.
+func eventTicks(d time.Duration) uint64 {
+	sec, rem := d/time.Second, d%time.Second
+	return uint64(sec)*eventTimeScale + uint64(rem)*eventTimeScale/uint64(time.Second) //nolint:gosec // d >= 0
 }
 
 func NewMuxer(log logger.Logger) *Muxer {
@@ -259,8 +300,21 @@ func (m *Muxer) GetInit() buffer.Buffer {
 		})
 
 		stream.fillTrackAtom()
+		// Fragment decode times (tfdt) already carry each track's absolute
+		// start and samples are written without composition offsets, so an
+		// explicit zero-based edit keeps players from inventing their own
+		// start alignment. Duration 0 means "until the end of the fragments".
+		stream.trackAtom.Edit = &mp4io.Edit{List: &mp4io.EditList{
+			Entries: []mp4io.EditListEntry{{MediaRateInteger: 1}},
+		}}
+		if m.enc != nil {
+			m.protectSampleEntries(stream)
+		}
 		moov.Tracks = append(moov.Tracks, stream.trackAtom)
 	}
+	if m.enc != nil {
+		moov.Unknowns = append(moov.Unknowns, m.protectionSystems()...)
+	}
 
 	ftype := mp4io.NewFileType()
 	ftype.CompatibleBrands[3] = pio.U32BE([]byte("dash"))
@@ -397,8 +451,12 @@ func (m *Muxer) processDataOffsets(moof *mp4io.MovieFrag, startMOOF int, out []b
 		dataOffset := m.safeUint32Conversion(offset, "data offset")
 		moof.Tracks[i].Run.DataOffset = dataOffset
 
-		for _, pkt := range s.packets {
-			n += copy(out[n:], pkt.Data())
+		for j, pkt := range s.packets {
+			if s.encrypted != nil {
+				n += copy(out[n:], s.encrypted[j])
+			} else {
+				n += copy(out[n:], pkt.Data())
+			}
 		}
 	}
 	return n
@@ -485,10 +543,18 @@ func (m *Muxer) GetMP4Fragment(idx int) buffer.Buffer {
 
 		m.processTrackHeader(track, s)
 		m.processPackets(track, s)
+		if m.enc != nil && len(s.packets) > 0 {
+			m.encryptSamples(track, s)
+		}
 	}
 
 	styp := mp4io.NewSegmentType()
 
+	var eventsSize int
+	for _, ev := range m.events {
+		eventsSize += ev.Len()
+	}
+
 	// SIDX boxes are omitted: they are not required for LL-HLS streaming
 	// (the manifest provides all timing information) and the previous
 	// per-track SIDX had incorrect FirstOffset values with multiple tracks,
@@ -498,13 +564,19 @@ func (m *Muxer) GetMP4Fragment(idx int) buffer.Buffer {
 		totalDataSize += s.bufSize
 	}
 
-	bufSz := moof.Len() + styp.Len() + 8 + totalDataSize //nolint:mnd // 8 = mdat box header
+	bufSz := moof.Len() + styp.Len() + eventsSize + 8 + totalDataSize //nolint:mnd // 8 = mdat box header
 
 	buf := buffer.Get(bufSz)
 
 	var n int
 	n += styp.Marshal(buf.Data())
 
+	// emsg must precede the moof it applies to (ISO 23000-19 §7.4.5).
+	for _, ev := range m.events {
+		n += ev.Marshal(buf.Data()[n:])
+	}
+	m.events = m.events[:0]
+
 	startMOOF := n
 	n += moof.Len()
 
@@ -515,6 +587,9 @@ func (m *Muxer) GetMP4Fragment(idx int) buffer.Buffer {
 	n += 4
 
 	n = m.processDataOffsets(moof, startMOOF, buf.Data(), n)
+	if m.enc != nil {
+		setAuxInfoOffsets(moof)
+	}
 	moof.Marshal(buf.Data()[startMOOF:])
 
 	mdatSizeValue := n - mdatStart
@@ -22,6 +22,7 @@ type Stream struct {
 	gomedia.CodecParameters
 	log             logger.Logger
 	packets         []gomedia.Packet
+	encrypted       [][]byte // 'cbcs'-encrypted copies of packets, nil for clear media
 	bufSize         int
 	firstPacketTime time.Duration
 	trackAtom       *mp4io.Track
@@ -0,0 +1,166 @@
+package hls
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"strings"
+
+	"github.com/ugparu/gomedia/format/fmp4"
+)
+
+// EncryptionMethod selects how segments and parts are protected.
+type EncryptionMethod int
+
+const (
+	// EncryptionNone writes clear media.
+	EncryptionNone EncryptionMethod = iota
+	// EncryptionAES128 encrypts every segment and part as a whole with
+	// AES-128-CBC and PKCS#7 padding (METHOD=AES-128). Init segments stay in
+	// the clear.
+	EncryptionAES128
+	// EncryptionSampleAES encrypts the samples inside the fMP4 with Common
+	// Encryption 'cbcs' (METHOD=SAMPLE-AES). Boxes stay readable, so players
+	// and DRM systems can decrypt sample by sample.
+	EncryptionSampleAES
+)
+
+func (m EncryptionMethod) String() string {
+	switch m {
+	case EncryptionAES128:
+		return "AES-128"
+	case EncryptionSampleAES:
+		return "SAMPLE-AES"
+	default:
+		return "NONE"
+	}
+}
+
+// Key is a content key together with what the playlist tells players about
+// it.
+type Key struct {
+	ID    [16]byte // key ID written to tenc and pssh (SAMPLE-AES)
+	Value [16]byte // AES-128 key
+	// IV is advertised in #EXT-X-KEY when non-zero. For AES-128 a zero IV
+	// means the media sequence number of each segment is used, as RFC 8216
+	// specifies; for SAMPLE-AES it is the constant 'cbcs' IV.
+	IV                [16]byte
+	URI               string                  // where players fetch the key, e.g. "https://keys.example.com/k/17"
+	KeyFormat         string                  // KEYFORMAT, empty for the default "identity"
+	KeyFormatVersions string                  // KEYFORMATVERSIONS, empty for the default
+	PSSH              []fmp4.ProtectionSystem // written into SAMPLE-AES init segments
+}
+
+// KeyProvider hands out content keys. Key is called from the writing
+// goroutine once per rotation period: period 0 covers the first segments and
+// increases by one every WithKeyRotation segments.
+type KeyProvider interface {
+	Key(period uint64) (Key, error)
+}
+
+// KeyProviderFunc adapts a function to KeyProvider.
+type KeyProviderFunc func(period uint64) (Key, error)
+
+// Key implements KeyProvider.
+func (f KeyProviderFunc) Key(period uint64) (Key, error) {
+	return f(period)
+}
+
+// StaticKey returns a KeyProvider that always hands out key.
+func StaticKey(key Key) KeyProvider {
+	return KeyProviderFunc(func(uint64) (Key, error) { return key, nil })
+}
+
+// assignKey attaches the key of seg's rotation period to seg, asking the
+// provider when a new period starts. With SAMPLE-AES the key is part of the
+// init segment, so a new key also starts a new init version. If the provider
+// fails mid-stream the previous key is kept, never falling back to clear
+// media.
+func (mxr *muxer) assignKey(seg *segment) error {
+	if mxr.encryption == EncryptionNone {
+		return nil
+	}
+	var period uint64
+	if mxr.keyRotation > 0 {
+		period = seg.id / mxr.keyRotation
+	}
+	if mxr.curKey == nil || period != mxr.curKeyPeriod {
+		key, err := mxr.keys.Key(period)
+		switch {
+		case err != nil && mxr.curKey == nil:
+			return err
+		case err != nil:
+			mxr.log.Errorf(mxr, "key provider: %v; keeping key of period %d", err, mxr.curKeyPeriod)
+		default:
+			mxr.curKey, mxr.curKeyPeriod = &key, period
+		}
+	}
+
+	mxr.initMu.Lock()
+	if mxr.encryption == EncryptionSampleAES {
+		if initKey, ok := mxr.initKeys[mxr.initVersion]; ok && initKey != mxr.curKey {
+			mxr.initVersion++
+			mxr.initCache[mxr.initVersion] = mxr.codecPars
+		}
+		mxr.initKeys[mxr.initVersion] = mxr.curKey
+	}
+	seg.initVersion = mxr.initVersion
+	mxr.initMu.Unlock()
+
+	seg.encryption = mxr.encryption
+	seg.key = mxr.curKey
+	return nil
+}
+
+// keyTag renders the #EXT-X-KEY line announcing key.
+func keyTag(method EncryptionMethod, key *Key) string {
+	var b strings.Builder
+	b.WriteString("#EXT-X-KEY:METHOD=")
+	b.WriteString(method.String())
+	b.WriteString(",URI=\"")
+	b.WriteString(key.URI)
+	b.WriteByte('"')
+	if key.IV != [16]byte{} {
+		b.WriteString(",IV=0x")
+		b.WriteString(hex.EncodeToString(key.IV[:]))
+	}
+	if key.KeyFormat != "" {
+		b.WriteString(",KEYFORMAT=\"")
+		b.WriteString(key.KeyFormat)
+		b.WriteByte('"')
+	}
+	if key.KeyFormatVersions != "" {
+		b.WriteString(",KEYFORMATVERSIONS=\"")
+		b.WriteString(key.KeyFormatVersions)
+		b.WriteByte('"')
+	}
+	b.WriteByte('\n')
+	return b.String()
+}
+
+// sampleEncryption returns the fMP4 'cbcs' configuration for key.
+func sampleEncryption(key *Key) *fmp4.Encryption {
+	return &fmp4.Encryption{KeyID: key.ID, Key: key.Value, IV: key.IV, PSSH: key.PSSH}
+}
+
+// encryptAES128 returns data AES-128-CBC encrypted with PKCS#7 padding. A
+// zero key IV is replaced by the media sequence number seq.
+func encryptAES128(key *Key, seq uint64, data []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key.Value[:])
+	if err != nil {
+		return nil, err
+	}
+	iv := key.IV
+	if iv == [16]byte{} {
+		for i := range 8 {
+			iv[15-i] = byte(seq >> (8 * i))
+		}
+	}
+	pad := aes.BlockSize - len(data)%aes.BlockSize
+	out := make([]byte, len(data)+pad)
+	copy(out, data)
+	copy(out[len(data):], bytes.Repeat([]byte{byte(pad)}, pad))
+	cipher.NewCBCEncrypter(block, iv[:]).CryptBlocks(out, out)
+	return out, nil
+}
@@ -22,6 +22,7 @@ type fragment struct {
 	finished       chan struct{}
 	manifestEntry  string
 	packets        []gomedia.Packet
+	events         []fmp4.Event // in-band events emitted as emsg ahead of the fragment's moof
 	codecPars      gomedia.CodecParametersPair
 	cachedMp4      []byte // lazily generated on first HTTP request
 	mediaName      string // base filename used in manifest URIs
@@ -45,15 +46,17 @@ func newFragment(id uint8, segID uint64, targetDuration time.Duration, codecPars
 }
 
 // writePacket retains the packet's ring-buffer slot via Clone(false); the slot
-// is released when the enclosing segment is evicted.
+// is released when the enclosing segment is evicted. Video packets advance the
+// fragment duration; in an audio-only stream audio packets do, each of them
+// independently decodable.
 func (fr *fragment) writePacket(packet gomedia.Packet) error {
 	fr.log.Tracef(fr, "Writing packet %v", packet)
 
 	fr.packets = append(fr.packets, packet.Clone(false))
 
 	vPacket, casted := packet.(gomedia.VideoPacket)
-	if casted {
-		if vPacket.IsKeyFrame() {
+	if casted || fr.codecPars.VideoCodecParameters == nil {
+		if !casted || vPacket.IsKeyFrame() {
 			fr.independent = true
 		}
 		fr.duration += packet.Duration()
@@ -93,14 +96,18 @@ func (fr *fragment) close() error {
 	return nil
 }
 
-// generateMp4 encodes the retained packets into fragmented MP4 bytes.
-// Idempotent. Must be called under the owning segment's mutex while packets
-// are still live (i.e. before the segment is evicted and slots released).
-func (fr *fragment) generateMp4() {
+// generateMp4 encodes the retained packets into fragmented MP4 bytes,
+// encrypted like the owning segment seg. Idempotent. Must be called under
+// seg's mutex while packets are still live (i.e. before the segment is
+// evicted and slots released).
+func (fr *fragment) generateMp4(seg *segment) {
 	if fr.cachedMp4 != nil || len(fr.packets) == 0 {
 		return
 	}
 	mux := fmp4.NewMuxer(fr.log)
+	if seg.encryption == EncryptionSampleAES {
+		mux.SetEncryption(sampleEncryption(seg.key))
+	}
 	if err := mux.Mux(fr.codecPars); err != nil {
 		fr.log.Errorf(fr, "fragment cache: mux error: %v", err)
 		return
@@ -110,9 +117,11 @@ func (fr *fragment) generateMp4() {
 			fr.log.Errorf(fr, "fragment cache: WritePacket error: %v", err)
 		}
 	}
+	for _, ev := range fr.events {
+		mux.WriteEvent(ev)
+	}
 	if buf := mux.GetMP4Fragment(int(fr.id)); buf != nil {
-		fr.cachedMp4 = make([]byte, len(buf.Data()))
-		copy(fr.cachedMp4, buf.Data())
+		fr.cachedMp4 = seg.protect(buf.Data())
 	}
 }
 
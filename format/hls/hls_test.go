@@ -3,6 +3,8 @@ package hls
 
 import (
 	"context"
+	"crypto/aes"
+	"crypto/cipher"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
@@ -16,6 +18,8 @@ import (
 	"github.com/ugparu/gomedia"
 	"github.com/ugparu/gomedia/codec/aac"
 	"github.com/ugparu/gomedia/codec/h264"
+	"github.com/ugparu/gomedia/format/mp4"
+	"github.com/ugparu/gomedia/format/mp4/mp4io"
 	"github.com/ugparu/gomedia/mocks"
 	"github.com/ugparu/gomedia/utils/logger"
 	"go.uber.org/mock/gomock"
@@ -536,16 +540,55 @@ func TestHasPlayableSegments_NoKeyframeNeverOpens(t *testing.T) {
 	assert.False(t, mxr.HasPlayableSegments())
 }
 
-func TestGetMasterEntry_AudioOnly_ReturnsError(t *testing.T) {
+func TestGetMasterEntry_AudioOnly(t *testing.T) {
 	pair, _, _ := loadTestCodecPair(t)
 	pair.VideoCodecParameters = nil
 	mxr := newTestMuxer(t, 2*time.Second, 3)
 	require.NoError(t, mxr.Mux(pair))
 	defer mxr.Release()
 
-	_, err := mxr.GetMasterEntry()
+	entry, err := mxr.GetMasterEntry()
+	require.NoError(t, err)
+	assert.Contains(t, entry, "mp4a.")
+	assert.NotContains(t, entry, "avc1.")
+	assert.NotContains(t, entry, "RESOLUTION=")
+	assert.NotContains(t, entry, "FRAME-RATE=")
+	assert.NotContains(t, entry, "BANDWIDTH=0")
+}
+
+func TestStreamInf_AudioGroup(t *testing.T) {
+	pair, _, _ := loadTestCodecPair(t)
+
+	entry, err := StreamInf(pair, "audio")
+	require.NoError(t, err)
+	// The group's audio codec is listed even though it is not muxed in.
+	assert.Contains(t, entry, "avc1.")
+	assert.Contains(t, entry, "mp4a.")
+	assert.True(t, strings.HasSuffix(entry, `,AUDIO="audio"`), entry)
+
+	_, err = StreamInf(gomedia.CodecParametersPair{}, "")
 	require.Error(t, err)
-	assert.Contains(t, err.Error(), "no video codec")
+}
+
+func TestWritePacket_AudioOnlyClosesSegments(t *testing.T) {
+	pair, _, aCp := loadTestCodecPair(t)
+	pair.VideoCodecParameters = nil
+	mxr := newTestMuxer(t, time.Second, 5, WithFragmentDuration(200*time.Millisecond))
+	require.NoError(t, mxr.Mux(pair))
+	defer mxr.Release()
+
+	// 3s of AAC frames: without video every frame is independent and drives
+	// parts and segments.
+	frame := 1024 * time.Second / time.Duration(aCp.SampleRate())
+	for ts := time.Duration(0); ts < 3*time.Second; ts += frame {
+		pkt := aac.NewPacket(make([]byte, 64), ts, "test", time.Time{}, aCp, frame)
+		require.NoError(t, mxr.WritePacket(pkt))
+	}
+
+	m, err := mxr.GetIndexM3u8(context.Background(), -1, -1)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, strings.Count(m, "#EXTINF:"), 2, m)
+	assert.Contains(t, m, "INDEPENDENT=YES", m)
 }
 
 // UpdateCodecParameters tests
@@ -1050,3 +1093,599 @@ func TestDurationEviction_CountIsIrrelevant(t *testing.T) {
 	assert.GreaterOrEqual(t, closed, 5*time.Second)
 	assert.Greater(t, len(mxr.segIDs), 5)
 }
+
+// DVR tests
+
+func TestDVRWindow_ArchivesBehindLiveEdge(t *testing.T) {
+	mxr, _, vCp, aCp := initMuxer(t, time.Second, 3, WithDVRWindow(8*time.Second))
+	defer mxr.Release()
+	packets := loadTestPackets(t, vCp, aCp, 500)
+	for _, pkt := range packets {
+		require.NoError(t, mxr.WritePacket(pkt))
+	}
+
+	require.Positive(t, mxr.archived)
+	// The window is tight: the closed segments cover it and would not
+	// without the oldest one.
+	var closed time.Duration
+	for _, id := range mxr.segIDs[:len(mxr.segIDs)-1] {
+		seg, ok := mxr.getSegment(id)
+		require.True(t, ok)
+		closed += seg.duration
+	}
+	oldest, _ := mxr.getSegment(mxr.segIDs[0])
+	assert.GreaterOrEqual(t, closed, 8*time.Second)
+	assert.Less(t, closed-oldest.duration, 8*time.Second)
+	assert.Greater(t, mxr.mediaSequence, int64(0))
+
+	m, err := mxr.GetIndexM3u8(context.Background(), -1, -1)
+	require.NoError(t, err)
+	assert.NotContains(t, m, "#EXT-X-PLAYLIST-TYPE")
+	assert.Equal(t, strings.Count(m, "#EXTINF:"), strings.Count(m, "#EXT-X-PROGRAM-DATE-TIME:"))
+	// Archived segments are listed without their parts.
+	archivedID := mxr.segIDs[0]
+	assert.Contains(t, m, fmt.Sprintf("segment/%d/media.m4s", archivedID))
+	assert.NotContains(t, m, fmt.Sprintf("fragment/%d/", archivedID))
+	_, err = mxr.GetSegment(context.Background(), archivedID)
+	require.NoError(t, err)
+}
+
+func TestEventPlaylist_NeverEvicts(t *testing.T) {
+	mxr, _, vCp, aCp := initMuxer(t, time.Second, 3, WithEventPlaylist(true))
+	defer mxr.Release()
+	packets := loadTestPackets(t, vCp, aCp, 500)
+	for _, pkt := range packets {
+		require.NoError(t, mxr.WritePacket(pkt))
+	}
+
+	assert.Equal(t, int64(0), mxr.mediaSequence)
+	assert.Equal(t, uint64(0), mxr.segIDs[0])
+	assert.Positive(t, mxr.archived)
+
+	m, err := mxr.GetIndexM3u8(context.Background(), -1, -1)
+	require.NoError(t, err)
+	assert.Contains(t, m, "#EXT-X-PLAYLIST-TYPE:EVENT\n")
+	_, err = mxr.GetSegment(context.Background(), 0)
+	require.NoError(t, err)
+}
+
+func TestSpillDir_MovesArchivedSegmentsToDisk(t *testing.T) {
+	dir := t.TempDir()
+	mxr, _, vCp, aCp := initMuxer(t, time.Second, 3, WithEventPlaylist(true), WithSpillDir(dir))
+	packets := loadTestPackets(t, vCp, aCp, 500)
+	for _, pkt := range packets {
+		require.NoError(t, mxr.WritePacket(pkt))
+	}
+	require.Positive(t, mxr.archived)
+	require.Eventually(t, func() bool { return spilled(mxr) }, 5*time.Second, 10*time.Millisecond)
+
+	seg, ok := mxr.getSegment(0)
+	require.True(t, ok)
+	require.NotEmpty(t, seg.spillPath)
+	assert.Nil(t, seg.cachedMp4)
+	assert.Nil(t, seg.fragments[0].packets)
+
+	data, err := mxr.GetSegment(context.Background(), 0)
+	require.NoError(t, err)
+	onDisk, err := os.ReadFile(seg.spillPath)
+	require.NoError(t, err)
+	assert.Equal(t, onDisk, data)
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, files, mxr.archived)
+
+	mxr.Release()
+	files, err = os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+// spilled reports whether every segment handed to the spill worker has been
+// written to disk.
+func spilled(mxr *muxer) bool {
+	for _, id := range mxr.segIDs[:mxr.archived] {
+		seg, ok := mxr.getSegment(id)
+		if !ok {
+			return false
+		}
+		seg.mu.RLock()
+		done := seg.spillPath != "" || !seg.spillQueued
+		seg.mu.RUnlock()
+		if !done {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSpillDir_WritesOffTheWritePath(t *testing.T) {
+	dir := t.TempDir()
+	mxr := newTestMuxer(t, time.Second, 3, WithEventPlaylist(true), WithSpillDir(dir))
+	pair, vCp, _ := loadTestCodecPair(t)
+	pair.AudioCodecParameters = nil
+	require.NoError(t, mxr.Mux(pair))
+
+	for i := range 500 {
+		nal := make([]byte, 104)
+		nal[3], nal[4] = 100, 0x65
+		pkt := h264.NewPacket(i%25 == 0, time.Duration(i)*40*time.Millisecond, time.Time{}, nal, "test", vCp)
+		pkt.SetDuration(40 * time.Millisecond)
+		require.NoError(t, mxr.WritePacket(pkt))
+	}
+	require.Greater(t, mxr.archived, spillQueueSize, "more archived segments than the queue holds")
+
+	// Queued segments reach the disk in the background; the rest wait in
+	// memory for a later retire, so the oldest one is always among them.
+	require.Eventually(t, func() bool { return spilled(mxr) }, 5*time.Second, 10*time.Millisecond)
+	oldest, ok := mxr.getSegment(mxr.segIDs[0])
+	require.True(t, ok)
+	oldest.mu.RLock()
+	path := oldest.spillPath
+	oldest.mu.RUnlock()
+	require.NotEmpty(t, path)
+
+	data, err := mxr.GetSegment(context.Background(), oldest.id)
+	require.NoError(t, err)
+	onDisk, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, onDisk, data)
+
+	// Nothing spilled is still held in memory.
+	for _, id := range mxr.segIDs[:mxr.archived] {
+		seg, _ := mxr.getSegment(id)
+		seg.mu.RLock()
+		if seg.spillPath != "" {
+			assert.Nil(t, seg.fragments[0].packets, "segment %d", id)
+		}
+		seg.mu.RUnlock()
+	}
+
+	mxr.Release()
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+// VOD tests
+
+// vodTestSPS is a 1280x720 High profile SPS; vodTestPPS goes with it.
+var (
+	vodTestSPS = []byte{0x67, 0x64, 0x00, 0x1f, 0xac, 0xd9, 0x40, 0x50, 0x05, 0xbb, 0x01, 0x10, 0x00, 0x00, 0x03,
+		0x00, 0x10, 0x00, 0x00, 0x03, 0x03, 0xc0, 0xf1, 0x83, 0x19, 0x60}
+	vodTestPPS = []byte{0x68, 0xeb, 0xe3, 0xcb, 0x22, 0xc0}
+)
+
+// writeVODTestFile records frames 40ms video frames with a keyframe every
+// 25 into an MP4 file in dir.
+func writeVODTestFile(t *testing.T, dir, name string, cp *h264.CodecParameters, frames int) string {
+	t.Helper()
+	path := dir + "/" + name
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	mux := mp4.NewMuxer(f)
+	require.NoError(t, mux.Mux(gomedia.CodecParametersPair{VideoCodecParameters: cp}))
+	for i := range frames {
+		pkt := h264.NewPacket(i%25 == 0, time.Duration(i)*40*time.Millisecond, time.Now(),
+			[]byte{0x00, 0x00, 0x00, 0x02, 0x65, byte(i)}, "test", cp)
+		pkt.SetDuration(40 * time.Millisecond)
+		require.NoError(t, mux.WritePacket(pkt))
+	}
+	require.NoError(t, mux.WriteTrailer())
+	return path
+}
+
+func TestVOD_PlaylistAndSegments(t *testing.T) {
+	cp, err := h264.NewCodecDataFromSPSAndPPS(vodTestSPS, vodTestPPS)
+	require.NoError(t, err)
+	dir := t.TempDir()
+	start := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+	infos := []gomedia.FileInfo{
+		{Name: "a.mp4", Start: start},
+		{Name: "b.mp4", Start: start.Add(10 * time.Second)}, // contiguous
+		{Name: "c.mp4", Start: start.Add(60 * time.Second)}, // after a gap
+	}
+	for _, info := range infos {
+		writeVODTestFile(t, dir, info.Name, &cp, 250)
+	}
+
+	vod, err := NewVOD(VODFilesFromInfos(dir, infos), time.Time{}, time.Time{}, VODWithSegmentDuration(2*time.Second))
+	require.NoError(t, err)
+
+	m := vod.Playlist()
+	assert.Contains(t, m, "#EXT-X-PLAYLIST-TYPE:VOD\n")
+	assert.Contains(t, m, "#EXT-X-TARGETDURATION:2\n")
+	assert.True(t, strings.HasSuffix(m, "#EXT-X-ENDLIST\n"))
+	assert.Equal(t, 15, strings.Count(m, "#EXTINF:2.00000\n"))
+	assert.Equal(t, 1, strings.Count(m, "#EXT-X-DISCONTINUITY\n"), "only the gap before c.mp4")
+	assert.Equal(t, 1, strings.Count(m, "#EXT-X-MAP:"))
+	assert.Contains(t, m, "#EXT-X-PROGRAM-DATE-TIME:2026-01-02T10:01:00.000000Z\n#EXTINF:2.00000\nsegment/10/media.m4s\n")
+	assert.Equal(t, 30*time.Second, vod.Duration())
+
+	init, err := vod.GetInitByVersion(0)
+	require.NoError(t, err)
+	assert.Equal(t, "ftyp", string(init[4:8]))
+	_, err = vod.GetInitByVersion(1)
+	require.Error(t, err)
+
+	seg, err := vod.GetSegment(context.Background(), 6)
+	require.NoError(t, err)
+	assert.Contains(t, string(seg), "moof")
+	assert.Contains(t, string(seg), "mdat")
+	_, err = vod.GetSegment(context.Background(), 15)
+	require.Error(t, err)
+}
+
+func TestVOD_SegmentHoldsItsFrames(t *testing.T) {
+	cp, err := h264.NewCodecDataFromSPSAndPPS(vodTestSPS, vodTestPPS)
+	require.NoError(t, err)
+	dir := t.TempDir()
+	files := []VODFile{{Path: writeVODTestFile(t, dir, "a.mp4", &cp, 250)}}
+	vod, err := NewVOD(files, time.Time{}, time.Time{}, VODWithSegmentDuration(2*time.Second))
+	require.NoError(t, err)
+
+	// Segment 3 is frames 150..199: it starts on the keyframe at 6s and
+	// ends before the one at 8s.
+	data, err := vod.GetSegment(context.Background(), 3)
+	require.NoError(t, err)
+	pos := strings.Index(string(data), "moof") - 4
+	require.GreaterOrEqual(t, pos, 0)
+	moof := &mp4io.MovieFrag{}
+	_, err = moof.Unmarshal(data[pos:], pos)
+	require.NoError(t, err)
+	require.Len(t, moof.Tracks, 1)
+	traf := moof.Tracks[0]
+	require.Len(t, traf.Run.Entries, 50)
+	assert.Equal(t, uint64(6*90000), traf.DecodeTime.Time, "video time scale is 90 kHz")
+
+	size := traf.Header.DefaultSize
+	if traf.Run.Flags&mp4io.TRUNSampleSize != 0 {
+		size = traf.Run.Entries[0].Size
+	}
+	firstEnd := pos + int(traf.Run.DataOffset) + int(size)
+	assert.Equal(t, []byte{0x65, 150}, data[firstEnd-2:firstEnd])
+	assert.Equal(t, []byte{0x65, 199}, data[len(data)-2:])
+}
+
+func TestVOD_TimeRange(t *testing.T) {
+	cp, err := h264.NewCodecDataFromSPSAndPPS(vodTestSPS, vodTestPPS)
+	require.NoError(t, err)
+	dir := t.TempDir()
+	start := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+	files := []VODFile{
+		{Path: writeVODTestFile(t, dir, "a.mp4", &cp, 250), Start: start},
+		{Path: writeVODTestFile(t, dir, "b.mp4", &cp, 250), Start: start.Add(10 * time.Second)},
+		{Path: dir + "/missing.mp4", Start: start.Add(20 * time.Second)},
+	}
+
+	// 3s..13s starts on the keyframe at 2s and ends after the one at 12s.
+	vod, err := NewVOD(files, start.Add(3*time.Second), start.Add(13*time.Second), VODWithSegmentDuration(2*time.Second))
+	require.NoError(t, err)
+	m := vod.Playlist()
+	assert.Equal(t, 6, strings.Count(m, "#EXTINF:"))
+	assert.Contains(t, m, "#EXT-X-PROGRAM-DATE-TIME:2026-01-02T10:00:02.000000Z\n")
+	assert.Contains(t, m, "#EXT-X-PROGRAM-DATE-TIME:2026-01-02T10:00:12.000000Z\n")
+	assert.NotContains(t, m, "#EXT-X-DISCONTINUITY")
+
+	_, err = NewVOD(files, start.Add(time.Hour), time.Time{})
+	require.Error(t, err)
+}
+
+func TestVOD_CodecChangeStartsNewInit(t *testing.T) {
+	cp, err := h264.NewCodecDataFromSPSAndPPS(vodTestSPS, vodTestPPS)
+	require.NoError(t, err)
+	other, err := h264.NewCodecDataFromSPSAndPPS(vodTestSPS, []byte{0x68, 0xee, 0x3c, 0x80})
+	require.NoError(t, err)
+	dir := t.TempDir()
+	files := []VODFile{
+		{Path: writeVODTestFile(t, dir, "a.mp4", &cp, 50)},
+		{Path: writeVODTestFile(t, dir, "b.mp4", &other, 50)},
+	}
+
+	vod, err := NewVOD(files, time.Time{}, time.Time{})
+	require.NoError(t, err)
+	m := vod.Playlist()
+	assert.Contains(t, m, "#EXT-X-DISCONTINUITY\n#EXT-X-MAP:URI=\"init.mp4?v=1\"\n#EXTINF:")
+	assert.NotContains(t, m, "#EXT-X-PROGRAM-DATE-TIME")
+
+	init0, err := vod.GetInitByVersion(0)
+	require.NoError(t, err)
+	init1, err := vod.GetInitByVersion(1)
+	require.NoError(t, err)
+	assert.NotEqual(t, init0, init1)
+}
+
+// writeSyntheticFrames writes n 40ms IDR frames, each a single
+// length-prefixed 100-byte slice, starting at start.
+func writeSyntheticFrames(t *testing.T, mxr *muxer, cp *h264.CodecParameters, start time.Duration, n int) {
+	t.Helper()
+	nal := make([]byte, 104)
+	nal[3], nal[4] = 100, 0x65
+	for i := range n {
+		pkt := h264.NewPacket(true, start+time.Duration(i)*40*time.Millisecond, time.Time{}, nal, "test", cp)
+		pkt.SetDuration(40 * time.Millisecond)
+		require.NoError(t, mxr.WritePacket(pkt))
+	}
+}
+
+func testKeyProvider(calls *[]uint64) KeyProvider {
+	return KeyProviderFunc(func(period uint64) (Key, error) {
+		*calls = append(*calls, period)
+		return Key{
+			ID:    [16]byte{0xee, byte(period)},
+			Value: [16]byte{0x11, byte(period)},
+			URI:   fmt.Sprintf("https://keys.example.com/%d", period),
+		}, nil
+	})
+}
+
+func TestEncryption_RequiresKeyProvider(t *testing.T) {
+	pair, _, _ := loadTestCodecPair(t)
+	mxr := newTestMuxer(t, time.Second, 3, WithEncryption(EncryptionAES128, nil))
+	require.Error(t, mxr.Mux(pair))
+}
+
+func TestEncryptionAES128_RotatesKeys(t *testing.T) {
+	var calls []uint64
+	mxr := newTestMuxer(t, time.Second, 10,
+		WithEncryption(EncryptionAES128, testKeyProvider(&calls)), WithKeyRotation(2))
+	defer mxr.Release()
+	pair, vCp, _ := loadTestCodecPair(t)
+	pair.AudioCodecParameters = nil
+	require.NoError(t, mxr.Mux(pair))
+
+	writeSyntheticFrames(t, mxr, vCp, 0, 125)
+	require.Equal(t, []uint64{0, 1, 2}, calls, "one provider call per rotation period")
+
+	m, err := mxr.GetIndexM3u8(context.Background(), -1, -1)
+	require.NoError(t, err)
+	assert.Contains(t, m, "#EXT-X-MAP:URI=\"init.mp4?v=0\"\n#EXT-X-KEY:METHOD=AES-128,URI=\"https://keys.example.com/0\"\n")
+	assert.Less(t, strings.Index(m, "segment/1/"), strings.Index(m, "keys.example.com/1"))
+	assert.Less(t, strings.Index(m, "keys.example.com/1"), strings.Index(m, "segment/2/"))
+	assert.Equal(t, 1, strings.Count(m, "keys.example.com/1"))
+
+	// Segment 3 belongs to period 1; without an IV attribute the media
+	// sequence number is the IV.
+	enc, err := mxr.GetSegment(context.Background(), 3)
+	require.NoError(t, err)
+	require.Zero(t, len(enc)%16)
+	block, err := aes.NewCipher([]byte{0x11, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0})
+	require.NoError(t, err)
+	iv := make([]byte, 16)
+	iv[15] = 3
+	plain := make([]byte, len(enc))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plain, enc)
+	assert.Equal(t, "styp", string(plain[4:8]))
+
+	init, err := mxr.GetInit()
+	require.NoError(t, err)
+	assert.Equal(t, "ftyp", string(init[4:8]), "init segments stay clear")
+
+	// A codec change must not put the new EXT-X-MAP under the AES-128 key.
+	require.NoError(t, mxr.UpdateCodecParameters(pair))
+	writeSyntheticFrames(t, mxr, vCp, 5*time.Second, 30)
+	m, err = mxr.GetIndexM3u8(context.Background(), -1, -1)
+	require.NoError(t, err)
+	assert.Contains(t, m, "#EXT-X-KEY:METHOD=NONE\n#EXT-X-MAP:URI=\"init.mp4?v=1\"\n#EXT-X-KEY:METHOD=AES-128,URI=\"https://keys.example.com/2\"\n")
+}
+
+func TestEncryptionSampleAES_NewInitPerKey(t *testing.T) {
+	var calls []uint64
+	mxr := newTestMuxer(t, time.Second, 10,
+		WithEncryption(EncryptionSampleAES, testKeyProvider(&calls)), WithKeyRotation(2))
+	defer mxr.Release()
+	pair, vCp, _ := loadTestCodecPair(t)
+	pair.AudioCodecParameters = nil
+	require.NoError(t, mxr.Mux(pair))
+
+	writeSyntheticFrames(t, mxr, vCp, 0, 100)
+
+	m, err := mxr.GetIndexM3u8(context.Background(), -1, -1)
+	require.NoError(t, err)
+	assert.Contains(t, m, "#EXT-X-MAP:URI=\"init.mp4?v=0\"\n#EXT-X-KEY:METHOD=SAMPLE-AES,URI=\"https://keys.example.com/0\"\n")
+	assert.Contains(t, m, "#EXT-X-MAP:URI=\"init.mp4?v=1\"\n#EXT-X-KEY:METHOD=SAMPLE-AES,URI=\"https://keys.example.com/1\"\n")
+	assert.NotContains(t, m, "#EXT-X-DISCONTINUITY", "key rotation is not a discontinuity")
+
+	for version := range 2 {
+		init, initErr := mxr.GetInitByVersion(version)
+		require.NoError(t, initErr)
+		assert.Contains(t, string(init), "encv")
+		assert.Contains(t, string(init), string([]byte{0xee, byte(version), 0, 0}), "tenc carries the period's key ID")
+	}
+
+	seg, err := mxr.GetSegment(context.Background(), 0)
+	require.NoError(t, err)
+	assert.Equal(t, "styp", string(seg[4:8]), "SAMPLE-AES leaves the boxes readable")
+	assert.Contains(t, string(seg), "senc")
+}
+
+// I-frame playlist tests
+
+func TestIFramePlaylist_ByteRangesIntoSegments(t *testing.T) {
+	mxr := newTestMuxer(t, time.Second, 10, WithKeyframeSplit(true))
+	defer mxr.Release()
+	pair, vCp, _ := loadTestCodecPair(t)
+	pair.AudioCodecParameters = nil
+	require.NoError(t, mxr.Mux(pair))
+
+	writeSyntheticFrames(t, mxr, vCp, 0, 80)
+
+	m, err := mxr.GetIFrameM3u8()
+	require.NoError(t, err)
+	assert.Contains(t, m, "#EXT-X-I-FRAMES-ONLY\n")
+	assert.Contains(t, m, "#EXT-X-MAP:URI=\"init.mp4?v=0\"\n")
+	// Every frame is an IDR: one entry per frame of the closed segments.
+	var keyframes int
+	for _, seg := range mxr.iframeSnapshot().segs {
+		keyframes += len(seg.keyframes)
+	}
+	assert.Greater(t, keyframes, 3)
+	assert.Equal(t, keyframes, strings.Count(m, "#EXT-X-BYTERANGE:"))
+
+	var size, offset int
+	_, err = fmt.Sscanf(m[strings.Index(m, "#EXT-X-BYTERANGE:"):], "#EXT-X-BYTERANGE:%d@%d", &size, &offset)
+	require.NoError(t, err)
+	seg, err := mxr.GetSegment(context.Background(), 0)
+	require.NoError(t, err)
+	// The range is the moof, the mdat header and the 104-byte keyframe.
+	rng := seg[offset : offset+size]
+	assert.Equal(t, "moof", string(rng[4:8]))
+	moofSize := int(rng[0])<<24 | int(rng[1])<<16 | int(rng[2])<<8 | int(rng[3])
+	assert.Equal(t, moofSize+8+104, size)
+	assert.Equal(t, "mdat", string(rng[moofSize+4:moofSize+8]))
+}
+
+func TestIFramePlaylist_EveryKeyframeOfMultiGOPSegments(t *testing.T) {
+	mxr := newTestMuxer(t, 4*time.Second, 10)
+	defer mxr.Release()
+	pair, vCp, _ := loadTestCodecPair(t)
+	pair.AudioCodecParameters = nil
+	require.NoError(t, mxr.Mux(pair))
+
+	// 1s GOPs in 4s segments: every segment holds four keyframes.
+	for i := range 420 {
+		nal := make([]byte, 104)
+		nal[3], nal[4], nal[5] = 100, 0x65, byte(i/25)
+		pkt := h264.NewPacket(i%25 == 0, time.Duration(i)*40*time.Millisecond, time.Time{}, nal, "test", vCp)
+		pkt.SetDuration(40 * time.Millisecond)
+		require.NoError(t, mxr.WritePacket(pkt))
+	}
+
+	m, err := mxr.GetIFrameM3u8()
+	require.NoError(t, err)
+	assert.Equal(t, 16, strings.Count(m, "#EXT-X-BYTERANGE:"), m)
+	assert.Equal(t, 16, strings.Count(m, "#EXTINF:1.00000,"), m)
+	assert.Contains(t, m, "#EXT-X-TARGETDURATION:4\n")
+
+	// Each range is the moof of its GOP up to the end of its keyframe.
+	lines := strings.Split(m, "\n")
+	gop := 0
+	for i, line := range lines {
+		if !strings.HasPrefix(line, "#EXT-X-BYTERANGE:") {
+			continue
+		}
+		var size, offset int
+		_, err = fmt.Sscanf(line, "#EXT-X-BYTERANGE:%d@%d", &size, &offset)
+		require.NoError(t, err)
+		var segID uint64
+		_, err = fmt.Sscanf(lines[i+1], "segment/%d/media.m4s", &segID)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(gop/4), segID)
+		seg, segErr := mxr.GetSegment(context.Background(), segID)
+		require.NoError(t, segErr)
+		rng := seg[offset : offset+size]
+		assert.Equal(t, "moof", string(rng[4:8]))
+		assert.Equal(t, []byte{0, 0, 0, 100, 0x65, byte(gop)}, rng[len(rng)-104:len(rng)-98], "keyframe of GOP %d", gop)
+		gop++
+	}
+}
+
+func TestIFramePlaylist_EntrySpansToNextKeyframe(t *testing.T) {
+	mxr := newTestMuxer(t, time.Second, 10)
+	defer mxr.Release()
+	pair, vCp, _ := loadTestCodecPair(t)
+	pair.AudioCodecParameters = nil
+	require.NoError(t, mxr.Mux(pair))
+
+	// A 2s GOP cut into 1s segments: every other segment opens mid-GOP.
+	nal := make([]byte, 104)
+	nal[3], nal[4] = 100, 0x65
+	for i := range 105 {
+		pkt := h264.NewPacket(i%50 == 0, time.Duration(i)*40*time.Millisecond, time.Time{}, nal, "test", vCp)
+		pkt.SetDuration(40 * time.Millisecond)
+		require.NoError(t, mxr.WritePacket(pkt))
+	}
+
+	m, err := mxr.GetIFrameM3u8()
+	require.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(m, "#EXT-X-BYTERANGE:"), m)
+	assert.Equal(t, 2, strings.Count(m, "#EXTINF:2.00000,"), m)
+	assert.Contains(t, m, "#EXT-X-TARGETDURATION:2\n")
+	assert.Contains(t, m, "segment/0/media.m4s")
+	assert.NotContains(t, m, "segment/1/media.m4s")
+	assert.Contains(t, m, "segment/2/media.m4s")
+}
+
+func TestIFramePlaylist_UnavailableWithAES128(t *testing.T) {
+	var calls []uint64
+	mxr := newTestMuxer(t, time.Second, 3, WithEncryption(EncryptionAES128, testKeyProvider(&calls)))
+	defer mxr.Release()
+	pair, _, _ := loadTestCodecPair(t)
+	require.NoError(t, mxr.Mux(pair))
+
+	_, err := mxr.GetIFrameM3u8()
+	require.Error(t, err)
+}
+
+func TestIFrameStreamInf(t *testing.T) {
+	pair, _, _ := loadTestCodecPair(t)
+
+	entry, err := IFrameStreamInf(pair, "iframe.m3u8")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(entry, "#EXT-X-I-FRAME-STREAM-INF:BANDWIDTH="), entry)
+	assert.Contains(t, entry, "avc1.")
+	assert.NotContains(t, entry, "mp4a.")
+	assert.True(t, strings.HasSuffix(entry, `,URI="iframe.m3u8"`), entry)
+
+	pair.VideoCodecParameters = nil
+	_, err = IFrameStreamInf(pair, "iframe.m3u8")
+	require.Error(t, err)
+}
+
+func TestWrap_SharedTimelineFollowsVideoKeyframe(t *testing.T) {
+	pair, vCp, aCp := loadTestCodecPair(t)
+	tl := NewTimeline()
+	opts := []MuxerOption{WithMaxTimestamp(time.Second), WithFragmentDuration(200 * time.Millisecond), WithTimeline(tl)}
+
+	video := newTestMuxer(t, time.Second, 10, opts...)
+	require.NoError(t, video.Mux(gomedia.CodecParametersPair{SourceID: "test", VideoCodecParameters: vCp}))
+	defer video.Release()
+	audio := newTestMuxer(t, time.Second, 10, opts...)
+	require.NoError(t, audio.Mux(gomedia.CodecParametersPair{SourceID: "test", AudioCodecParameters: pair.AudioCodecParameters}))
+	defer audio.Release()
+
+	// 1.2s GOPs: the audio crosses maxTS 200ms before the video keyframe
+	// that may wrap, and must wait for it.
+	const frameDur = 40 * time.Millisecond
+	const gop = 1200 * time.Millisecond
+	aFrame := 1024 * time.Second / time.Duration(aCp.SampleRate())
+	var aTS time.Duration
+	for ts := time.Duration(0); ts < 4*time.Second; ts += frameDur {
+		vPkt := h264.NewPacket(ts%gop == 0, ts, time.Time{}, []byte{0, 0, 0, 2, 0x65, 0x88}, "test", vCp)
+		vPkt.SetDuration(frameDur)
+		require.NoError(t, video.WritePacket(vPkt))
+		for ; aTS < ts+frameDur; aTS += aFrame {
+			require.NoError(t, audio.WritePacket(aac.NewPacket(make([]byte, 64), aTS, "test", time.Time{}, aCp, aFrame)))
+		}
+	}
+
+	assert.Equal(t, uint64(3), video.epoch, "wraps at the keyframes at 1.2s, 2.4s and 3.6s")
+	assert.Equal(t, video.epoch, audio.epoch)
+	assert.Equal(t, 3600*time.Millisecond, video.tsOffset)
+	assert.Equal(t, video.tsOffset, audio.tsOffset)
+
+	vm, err := video.GetIndexM3u8(context.Background(), -1, -1)
+	require.NoError(t, err)
+	am, err := audio.GetIndexM3u8(context.Background(), -1, -1)
+	require.NoError(t, err)
+	assert.Equal(t, 3, strings.Count(vm, "#EXT-X-DISCONTINUITY\n"), vm)
+	assert.Equal(t, 3, strings.Count(am, "#EXT-X-DISCONTINUITY\n"), am)
+
+	// The audio of each epoch starts with the first frame at or after the
+	// keyframe its video starts with.
+	for _, mxr := range []*muxer{video, audio} {
+		mxr.segments.RLock()
+		for _, id := range mxr.segments.segIDs {
+			seg := mxr.segments.segments[id]
+			if seg.discontinuity {
+				require.NotEmpty(t, seg.fragments[0].packets)
+				ts := seg.fragments[0].packets[0].Timestamp()
+				assert.GreaterOrEqual(t, ts, time.Duration(0))
+				assert.Less(t, ts, aFrame)
+			}
+		}
+		mxr.segments.RUnlock()
+	}
+}
@@ -0,0 +1,264 @@
+package hls
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ugparu/gomedia"
+	"github.com/ugparu/gomedia/format/mp4/mp4io"
+	"github.com/ugparu/gomedia/utils/bits/pio"
+)
+
+// IFramePlaylister is implemented by the muxer returned from NewHLSMuxer.
+// GetIFrameM3u8 returns the rendition's I-frame-only playlist for trick play
+// (RFC 8216 §4.3.3.6): one #EXT-X-BYTERANGE entry per video keyframe of the
+// closed segments, pointing into that segment's existing .m4s.
+type IFramePlaylister interface {
+	GetIFrameM3u8() (string, error)
+}
+
+// iframeIndex is the state of the playlist the I-frame playlist is built
+// from, captured by the writing goroutine whenever the media playlist is
+// rebuilt so readers never touch the muxer's counters.
+type iframeIndex struct {
+	mediaSequence         int64 // I-frame entries evicted so far
+	discontinuitySequence int64
+	segs                  []*segment // closed segments, oldest first
+}
+
+// iframeSnapshot captures the closed segments for the I-frame playlist. Must
+// be called from the writing goroutine.
+func (mxr *muxer) iframeSnapshot() *iframeIndex {
+	idx := &iframeIndex{
+		mediaSequence:         mxr.iframeSequence,
+		discontinuitySequence: mxr.discontinuitySequence,
+	}
+	mxr.segments.RLock()
+	defer mxr.segments.RUnlock()
+	for _, id := range mxr.segIDs[:max(len(mxr.segIDs)-1, 0)] {
+		if seg, ok := mxr.segments.segments[id]; ok {
+			idx.segs = append(idx.segs, seg)
+		}
+	}
+	return idx
+}
+
+// GetIFrameM3u8 builds the I-frame playlist from the latest snapshot. Each
+// entry spans from its keyframe to the next one, across segment boundaries.
+// The byte ranges are found by generating (or reusing) the segment MP4s,
+// which happens lazily here rather than on the writing goroutine. AES-128
+// encrypts whole segments, so ranges into them cannot be decrypted and no
+// I-frame playlist is offered.
+func (mxr *muxer) GetIFrameM3u8() (string, error) {
+	if mxr.encryption == EncryptionAES128 {
+		return "", errors.New("hls: I-frame playlists are not available with AES-128 encryption")
+	}
+	idx, _ := mxr.iframes.Load().(*iframeIndex)
+	if idx == nil {
+		return "", errors.New("hls: I-frame playlist requested before Mux")
+	}
+	mxr.initMu.RLock()
+	video := mxr.codecPars.VideoCodecParameters
+	mxr.initMu.RUnlock()
+	if video == nil {
+		return "", errors.New("hls: I-frame playlist requires a video track")
+	}
+
+	type entry struct {
+		seg             *segment
+		byteRange       byteRange
+		start, duration time.Duration // on the playlist timeline
+		discontinuity   bool
+	}
+	var entries []*entry
+	var discontinuity bool
+	var pos time.Duration
+	for _, seg := range idx.segs {
+		discontinuity = discontinuity || seg.discontinuity
+		for i, r := range seg.iframeByteRanges() {
+			start := pos + seg.keyframes[i]
+			if len(entries) > 0 {
+				prev := entries[len(entries)-1]
+				prev.duration = start - prev.start
+			}
+			entries = append(entries, &entry{seg: seg, byteRange: r, start: start, discontinuity: discontinuity})
+			discontinuity = false
+		}
+		pos += seg.duration
+	}
+	if len(entries) > 0 {
+		last := entries[len(entries)-1]
+		last.duration = pos - last.start
+	}
+
+	targetDuration := mxr.segmentDuration
+	for _, e := range entries {
+		targetDuration = max(targetDuration, e.duration)
+	}
+	playlistType := ""
+	if mxr.eventPlaylist {
+		playlistType = "#EXT-X-PLAYLIST-TYPE:EVENT\n"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "#EXTM3U\n#EXT-X-VERSION:%d\n%s#EXT-X-TARGETDURATION:%d\n#EXT-X-I-FRAMES-ONLY\n#EXT-X-MEDIA-SEQUENCE:%d\n",
+		max(mxr.version, iframeMinVersion), playlistType, int(math.Ceil(targetDuration.Seconds())), idx.mediaSequence)
+	if idx.discontinuitySequence > 0 {
+		fmt.Fprintf(&b, "#EXT-X-DISCONTINUITY-SEQUENCE:%d\n", idx.discontinuitySequence)
+	}
+
+	curInitVersion := -1
+	var curKey *Key
+	for _, e := range entries {
+		if e.discontinuity {
+			b.WriteString("#EXT-X-DISCONTINUITY\n")
+		}
+		if e.seg.initVersion != curInitVersion {
+			curInitVersion = e.seg.initVersion
+			b.WriteString("#EXT-X-MAP:URI=\"init.mp4?v=")
+			b.WriteString(strconv.Itoa(curInitVersion))
+			b.WriteString("\"\n")
+		}
+		if e.seg.key != curKey {
+			curKey = e.seg.key
+			b.WriteString(keyTag(e.seg.encryption, curKey))
+		}
+		fmt.Fprintf(&b, "#EXTINF:%.5f,\n#EXT-X-BYTERANGE:%d@%d\nsegment/%d/%s.m4s\n",
+			e.duration.Seconds(), e.byteRange.size, e.byteRange.offset, e.seg.id, e.seg.mediaName)
+	}
+	return b.String(), nil
+}
+
+// iframeMinVersion is the lowest protocol version with EXT-X-I-FRAMES-ONLY.
+const iframeMinVersion = 4
+
+// IFrameStreamInf renders the #EXT-X-I-FRAME-STREAM-INF line advertising the
+// I-frame playlist at uri for a variant carrying codecPars. Only the video
+// track is listed in CODECS. BANDWIDTH is the variant's video bit rate, an
+// upper bound for a playlist that carries a fraction of its frames.
+func IFrameStreamInf(codecPars gomedia.CodecParametersPair, uri string) (string, error) {
+	video := codecPars.VideoCodecParameters
+	if video == nil {
+		return "", errors.New("no video codec")
+	}
+	return fmt.Sprintf("#EXT-X-I-FRAME-STREAM-INF:BANDWIDTH=%d,RESOLUTION=%dx%d,CODECS=\"%s\",URI=\"%s\"",
+		masterEntryBandwidth(video.Bitrate(), video.Width(), video.Height(), video.FPS()),
+		video.Width(), video.Height(), video.Tag(), uri), nil
+}
+
+// keyframeOffsets returns how far into the segment each of its video
+// keyframes starts. Must be called before the packets are released.
+func (element *segment) keyframeOffsets() []time.Duration {
+	element.mu.RLock()
+	defer element.mu.RUnlock()
+	var offsets []time.Duration
+	start := time.Duration(-1)
+	for _, frag := range element.fragments {
+		for _, pkt := range frag.packets {
+			vPkt, ok := pkt.(gomedia.VideoPacket)
+			if !ok {
+				continue
+			}
+			if start < 0 {
+				start = pkt.Timestamp()
+			}
+			if vPkt.IsKeyFrame() {
+				offsets = append(offsets, pkt.Timestamp()-start)
+			}
+		}
+	}
+	return offsets
+}
+
+// byteRange is a range of a segment's .m4s.
+type byteRange struct {
+	offset, size int
+}
+
+// iframeByteRanges returns the byte range of each keyframe in keyframes: the
+// moof it opens followed by the mdat up to the end of the keyframe sample.
+// Computed on first call and cached; nil for segments without keyframes or
+// whose MP4 is gone or does not match them.
+func (element *segment) iframeByteRanges() []byteRange {
+	if len(element.keyframes) == 0 || element.codecPars.VideoCodecParameters == nil {
+		return nil
+	}
+	element.mu.RLock()
+	ranges := element.iframeRanges
+	element.mu.RUnlock()
+	if ranges != nil {
+		return ranges
+	}
+
+	buf := element.getMp4Buffer()
+	if buf == nil || buf.Len() == 0 {
+		return nil
+	}
+	trackID := uint32(element.codecPars.VideoCodecParameters.StreamIndex()) + 1
+	ranges, err := keyframeRanges(buf.Data(), trackID)
+	if err != nil {
+		element.log.Errorf(element, "I-frame ranges: %v", err)
+		return nil
+	}
+	if len(ranges) != len(element.keyframes) {
+		element.log.Errorf(element, "I-frame ranges: %d keyframe fragments for %d keyframes", len(ranges), len(element.keyframes))
+		return nil
+	}
+	element.mu.Lock()
+	element.iframeRanges = ranges
+	element.mu.Unlock()
+	return ranges
+}
+
+// keyframeRanges walks the moof boxes of an fMP4 segment and returns, for
+// each one whose first sample of trackID is a sync sample, the range from
+// the moof to the end of that sample, which is all a player needs to decode
+// it. The trun data offset is relative to the moof (default-base-is-moof),
+// as fmp4.Muxer writes it.
+func keyframeRanges(data []byte, trackID uint32) ([]byteRange, error) {
+	var ranges []byteRange
+	for pos := 0; pos+8 <= len(data); {
+		boxSize := int(pio.U32BE(data[pos:]))
+		if boxSize < 8 || pos+boxSize > len(data) {
+			return nil, errors.New("malformed box")
+		}
+		if mp4io.Tag(pio.U32BE(data[pos+4:])) != mp4io.MOOF {
+			pos += boxSize
+			continue
+		}
+		moof := &mp4io.MovieFrag{}
+		if _, err := moof.Unmarshal(data[pos:pos+boxSize], pos); err != nil {
+			return nil, err
+		}
+		for _, track := range moof.Tracks {
+			if track.Header == nil || track.Header.TrackID != trackID || track.Run == nil || len(track.Run.Entries) == 0 {
+				continue
+			}
+			flags := track.Header.DefaultFlags
+			if track.Run.Flags&mp4io.TRUNFirstSampleFlags != 0 {
+				flags = track.Run.FirstSampleFlags
+			} else if track.Run.Flags&mp4io.TRUNSampleFlags != 0 {
+				flags = track.Run.Entries[0].Flags
+			}
+			if flags&mp4io.SampleIsNonSync != 0 {
+				break
+			}
+			sampleSize := track.Header.DefaultSize
+			if track.Run.Flags&mp4io.TRUNSampleSize != 0 {
+				sampleSize = track.Run.Entries[0].Size
+			}
+			size := int(track.Run.DataOffset) + int(sampleSize)
+			if pos+size > len(data) {
+				return nil, errors.New("sample past end of fragment")
+			}
+			ranges = append(ranges, byteRange{offset: pos, size: size})
+			break
+		}
+		pos += boxSize
+	}
+	return ranges, nil
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"os"
 	"strconv"
 	"strings"
 	"sync"
@@ -21,11 +22,14 @@ import (
 const (
 	fragmentDuration = time.Millisecond * 495
 	maxTS            = time.Hour // wrap-around bound for relative timestamps
+	spillQueueSize   = 8         // archived segments waiting for the spill worker
 
 	// Placeholders for the mandatory BANDWIDTH attribute when the codec
 	// parameters carry no bit rate. See masterEntryBandwidth.
 	fallbackFPS          = 25
 	fallbackBitsPerPixel = 0.1
+	// Placeholder audio bit rate per channel for BANDWIDTH, see audioBandwidth.
+	fallbackAudioBitsPerChannel = 64000
 )
 
 type segments struct {
@@ -137,6 +141,51 @@ func WithMinPlaylistDuration(d time.Duration) MuxerOption {
 	return func(m *muxer) { m.minPlaylistDuration = d }
 }
 
+// WithDVRWindow turns on time-shift: segments that leave the live edge
+// (segmentCount or WithMinPlaylistDuration) stay in the playlist as archived
+// segments, without their LL-HLS parts, until the closed segments sum to more
+// than d. Every segment carries #EXT-X-PROGRAM-DATE-TIME, so players can seek
+// back by wall time and return to live. See WithSpillDir to keep the window
+// out of memory.
+func WithDVRWindow(d time.Duration) MuxerOption {
+	return func(m *muxer) { m.dvrWindow = d }
+}
+
+// WithEventPlaylist advertises #EXT-X-PLAYLIST-TYPE:EVENT. Segments are
+// archived as with WithDVRWindow but never removed, since an EVENT playlist
+// may only grow: the whole stream stays seekable until the muxer is closed,
+// which makes WithSpillDir all but mandatory for long-running sources.
+func WithEventPlaylist(enabled bool) MuxerOption {
+	return func(m *muxer) { m.eventPlaylist = enabled }
+}
+
+// WithSpillDir moves archived segments to files in dir and releases their
+// ring-buffer slots, bounding memory to the live edge whatever the DVR window.
+// The files are written by a worker goroutine, so a slow disk never stalls
+// WritePacket; segments wait in memory while the worker is behind. Files are
+// removed when their segment is evicted or the muxer is closed. Without it
+// archived segments are kept in memory.
+func WithSpillDir(dir string) MuxerOption {
+	return func(m *muxer) { m.spillDir = dir }
+}
+
+// WithEncryption protects segments and parts with method, taking keys from
+// keys. The playlist announces each key with #EXT-X-KEY. Segments already
+// being served keep the key they were written with. See WithKeyRotation.
+func WithEncryption(method EncryptionMethod, keys KeyProvider) MuxerOption {
+	return func(m *muxer) {
+		m.encryption = method
+		m.keys = keys
+	}
+}
+
+// WithKeyRotation asks the key provider for a new key every n segments. With
+// SAMPLE-AES the key ID is part of the init segment, so every rotation also
+// advertises a new #EXT-X-MAP. Zero (the default) uses a single key.
+func WithKeyRotation(n uint64) MuxerOption {
+	return func(m *muxer) { m.keyRotation = n }
+}
+
 // muxer is an implementation of the HLS interface.
 type muxer struct {
 	lifecycle.Manager[*muxer] // Embedding lifecycle.Manager to manage lifecycle functions.
@@ -168,7 +217,26 @@ type muxer struct {
 	partHoldBack          float64                             // PART-HOLD-BACK advertised in the header; kept for header rebuilds.
 	capSplitSeen          bool                                // True once any segment was force-cut mid-GOP at the cap.
 	tsOffset              time.Duration                       // Timeline epoch: subtracted from incoming timestamps, advanced on wrap.
+	timeline              *Timeline                           // Epoch shared with other renditions (nil → wraps on its own).
+	epoch                 uint64                              // Timeline epoch tsOffset belongs to.
+	timelineVideo         bool                                // True while counted as a video muxer on timeline.
 	gateOpen              bool                                // False until the first video keyframe arrives; packets are dropped meanwhile.
+	dvrWindow             time.Duration                       // Time-shift window of archived segments (0 → no time-shift).
+	eventPlaylist         bool                                // True for #EXT-X-PLAYLIST-TYPE:EVENT: archived segments are never evicted.
+	spillDir              string                              // Directory archived segments are written to ("" → kept in memory).
+	archived              int                                 // Number of archived segments at the head of segIDs.
+	spillQueue            chan *segment                       // Archived segments for the spill worker (nil → no spilling).
+	spillDone             chan struct{}                       // Closed on Release to stop the spill worker.
+	spillWG               sync.WaitGroup                      // Tracks the spill worker.
+	spillStop             sync.Once                           // Guards closing spillDone.
+	encryption            EncryptionMethod                    // How segments and parts are protected.
+	keys                  KeyProvider                         // Source of content keys when encryption is on.
+	keyRotation           uint64                              // Segments per key (0 → one key for the whole stream).
+	curKey                *Key                                // Key of the newest segment.
+	curKeyPeriod          uint64                              // Rotation period curKey was obtained for.
+	initKeys              map[int]*Key                        // SAMPLE-AES key baked into each init version.
+	iframes               atomic.Value                        // Stores the *iframeIndex the I-frame playlist is built from.
+	iframeSequence        int64                               // I-frame playlist entries evicted with their segments.
 }
 
 // NewHLSMuxer creates a new HLS muxer with the specified segment duration and segment count.
@@ -194,6 +262,7 @@ func NewHLSMuxer(segmentDuration time.Duration, segmentCount uint8, partHoldBack
 		initVersion:      0,
 		initCache:        make(map[int]gomedia.CodecParametersPair),
 		initBytesCache:   make(map[int][]byte),
+		initKeys:         make(map[int]*Key),
 		mediaName:        "media",
 	}
 	newHLS.manifest.Store("")
@@ -226,12 +295,16 @@ func (mxr *muxer) buildHeader() string {
 	if mxr.keyframeSplit && !mxr.capSplitSeen {
 		independentTag = "#EXT-X-INDEPENDENT-SEGMENTS\n"
 	}
+	playlistType := ""
+	if mxr.eventPlaylist {
+		playlistType = "#EXT-X-PLAYLIST-TYPE:EVENT\n"
+	}
 	return fmt.Sprintf(`#EXTM3U
 #EXT-X-VERSION:%d
-#EXT-X-TARGETDURATION:%d
+%s#EXT-X-TARGETDURATION:%d
 #EXT-X-SERVER-CONTROL:CAN-BLOCK-RELOAD=YES,PART-HOLD-BACK=%.5f
 #EXT-X-PART-INF:PART-TARGET=%.5f
-%s`, mxr.version, int(math.Ceil(targetDuration.Seconds())), mxr.partHoldBack, partTarget, independentTag)
+%s`, mxr.version, playlistType, int(math.Ceil(targetDuration.Seconds())), mxr.partHoldBack, partTarget, independentTag)
 }
 
 // Mux initializes the HLS muxer with codec parameters.
@@ -240,6 +313,18 @@ func (mxr *muxer) Mux(codecPars gomedia.CodecParametersPair) (err error) {
 		if codecPars.VideoCodecParameters == nil && codecPars.AudioCodecParameters == nil {
 			return &utils.NoCodecDataError{}
 		}
+		if mxr.encryption != EncryptionNone && mxr.keys == nil {
+			return errors.New("hls: encryption requires a key provider")
+		}
+		if mxr.spillDir != "" {
+			if err = os.MkdirAll(mxr.spillDir, 0o755); err != nil { //nolint:mnd // rwxr-xr-x
+				return err
+			}
+			mxr.spillQueue = make(chan *segment, spillQueueSize)
+			mxr.spillDone = make(chan struct{})
+			mxr.spillWG.Add(1)
+			go mxr.runSpiller()
+		}
 
 		mux := fmp4.NewMuxer(mxr.log)
 		if err = mux.Mux(codecPars); err != nil {
@@ -248,11 +333,21 @@ func (mxr *muxer) Mux(codecPars gomedia.CodecParametersPair) (err error) {
 
 		mxr.codecPars = codecPars
 		mxr.initVersion = 0
+		if mxr.timeline != nil {
+			// Join the timeline at its current epoch rather than replaying
+			// the wraps that happened before this muxer existed.
+			mxr.tsOffset, mxr.epoch = mxr.timeline.current()
+			mxr.setTimelineVideo(codecPars.VideoCodecParameters != nil)
+		}
 		mxr.initCache[0] = codecPars
 		newSeg := newSegment(0, mxr.fragmentDuration, mxr.segmentDuration, codecPars, mxr.mediaName, mxr.blockingTimeout, mxr.log)
 		newSeg.initVersion = mxr.initVersion
+		if err = mxr.assignKey(newSeg); err != nil {
+			return err
+		}
 		mxr.addSegment(newSeg)
 		mxr.manifestDirty = true
+		mxr.iframes.Store(mxr.iframeSnapshot())
 
 		return nil
 	}
@@ -283,12 +378,13 @@ func (mxr *muxer) UpdateCodecParameters(codecPars gomedia.CodecParametersPair) e
 	case <-curSeg.finished:
 	default:
 		if curSeg.duration > 0 {
-			curSeg.manifestEntry = fmt.Sprintf("%s#EXT-X-PROGRAM-DATE-TIME:%s\n#EXTINF:%.5f\nsegment/%d/%s.m4s\n",
-				curSeg.cacheEntry, curSeg.time.Format("2006-01-02T15:04:05.000000Z"), curSeg.duration.Seconds(), curSeg.id, curSeg.mediaName)
+			curSeg.manifestEntry = curSeg.cacheEntry + curSeg.segmentEntry()
 		}
+		curSeg.keyframes = curSeg.keyframeOffsets()
 		close(curSeg.finished)
 	}
 
+	mxr.setTimelineVideo(codecPars.VideoCodecParameters != nil)
 	mxr.initMu.Lock()
 	mxr.codecPars = codecPars
 	mxr.initVersion++
@@ -300,6 +396,9 @@ func (mxr *muxer) UpdateCodecParameters(codecPars gomedia.CodecParametersPair) e
 	newSeg := newSegment(newSegID, mxr.fragmentDuration, mxr.segmentDuration, codecPars, mxr.mediaName, mxr.blockingTimeout, mxr.log)
 	newSeg.discontinuity = true
 	newSeg.initVersion = initVersion
+	if err := mxr.assignKey(newSeg); err != nil {
+		return err
+	}
 	mxr.addSegment(newSeg)
 
 	// The post-discontinuity segment must also open on a keyframe: the player
@@ -309,47 +408,58 @@ func (mxr *muxer) UpdateCodecParameters(codecPars gomedia.CodecParametersPair) e
 	mxr.evictOldSegments()
 
 	mxr.manifest.Store(mxr.updateIndexM3u8())
+	mxr.iframes.Store(mxr.iframeSnapshot())
 	mxr.broadcastIndex()
 	return nil
 }
 
 // evictOldSegments removes the oldest segments according to the configured
 // strategy: duration-based when minPlaylistDuration is set, count-based
-// otherwise.
+// otherwise. In DVR mode the segments leaving the live edge are archived
+// instead, and archived segments are evicted past the DVR window.
 func (mxr *muxer) evictOldSegments() {
 	if mxr.minPlaylistDuration > 0 {
 		mxr.evictByDuration()
 	} else {
 		mxr.evictByCount()
 	}
+	if mxr.dvr() {
+		mxr.evictByDVRWindow()
+	}
 	mxr.cleanupInitCache()
 }
 
-// evictByDuration drops the oldest closed segment while the remaining closed
-// segments still sum to at least minPlaylistDuration. The in-progress segment
-// is not counted — the bound is on seconds a client can actually play. Keeps
-// at least one closed segment plus the current one.
+// dvr reports whether segments leaving the live edge are archived.
+func (mxr *muxer) dvr() bool {
+	return mxr.dvrWindow > 0 || mxr.eventPlaylist
+}
+
+// evictByDuration drops the oldest closed live segment while the remaining
+// closed live segments still sum to at least minPlaylistDuration. The
+// in-progress segment is not counted — the bound is on seconds a client can
+// actually play. Keeps at least one closed segment plus the current one.
 func (mxr *muxer) evictByDuration() {
 	const minSegments = 2 // one closed + the in-progress segment
-	for len(mxr.segIDs) > minSegments {
+	for len(mxr.segIDs)-mxr.archived > minSegments {
+		live := mxr.segIDs[mxr.archived:]
 		var closedDuration time.Duration
-		for _, id := range mxr.segIDs[:len(mxr.segIDs)-1] {
+		for _, id := range live[:len(live)-1] {
 			if seg, ok := mxr.getSegment(id); ok {
 				closedDuration += seg.duration
 			}
 		}
-		oldest, ok := mxr.getSegment(mxr.segIDs[0])
+		oldest, ok := mxr.getSegment(live[0])
 		if !ok {
 			break
 		}
 		if closedDuration-oldest.duration < mxr.minPlaylistDuration {
 			break
 		}
-		mxr.evictOldest()
+		mxr.retireOldest()
 	}
 }
 
-// evictByCount removes the oldest segments when both conditions are met:
+// evictByCount removes the oldest live segments when both conditions are met:
 //  1. segment count exceeds segmentCount (minimum number of segments to keep)
 //  2. total duration of all segments exceeds segmentCount * segmentDuration
 func (mxr *muxer) evictByCount() {
@@ -359,9 +469,9 @@ func (mxr *muxer) evictByCount() {
 	if maxDuration < minDuration {
 		maxDuration = minDuration
 	}
-	for len(mxr.segIDs) > int(mxr.segmentCount) {
+	for len(mxr.segIDs)-mxr.archived > int(mxr.segmentCount) {
 		var totalDuration time.Duration
-		for _, id := range mxr.segIDs {
+		for _, id := range mxr.segIDs[mxr.archived:] {
 			if seg, ok := mxr.getSegment(id); ok {
 				totalDuration += seg.duration
 			}
@@ -369,7 +479,79 @@ func (mxr *muxer) evictByCount() {
 		if totalDuration <= maxDuration {
 			break
 		}
+		mxr.retireOldest()
+	}
+}
+
+// evictByDVRWindow drops archived segments while the remaining closed
+// segments still sum to at least dvrWindow. EVENT playlists only grow.
+func (mxr *muxer) evictByDVRWindow() {
+	if mxr.eventPlaylist {
+		return
+	}
+	for mxr.archived > 0 {
+		var closedDuration time.Duration
+		for _, id := range mxr.segIDs[:len(mxr.segIDs)-1] {
+			if seg, ok := mxr.getSegment(id); ok {
+				closedDuration += seg.duration
+			}
+		}
+		oldest, ok := mxr.getSegment(mxr.segIDs[0])
+		if !ok {
+			break
+		}
+		if closedDuration-oldest.duration < mxr.dvrWindow {
+			break
+		}
+		mxr.evictOldest()
+	}
+}
+
+// retireOldest takes the oldest live segment off the live edge: archived in
+// DVR mode, evicted otherwise.
+func (mxr *muxer) retireOldest() {
+	if !mxr.dvr() {
 		mxr.evictOldest()
+		return
+	}
+	if seg, ok := mxr.getSegment(mxr.segIDs[mxr.archived]); ok {
+		seg.archive()
+	}
+	mxr.archived++
+	mxr.queueSpills()
+}
+
+// queueSpills hands archived segments to the spill worker, oldest first,
+// until the queue is full. Segments that do not fit stay in memory and are
+// queued on a later call, so the writing goroutine never waits for the disk.
+func (mxr *muxer) queueSpills() {
+	if mxr.spillQueue == nil {
+		return
+	}
+	for _, id := range mxr.segIDs[:mxr.archived] {
+		seg, ok := mxr.getSegment(id)
+		if !ok || seg.spillQueued {
+			continue
+		}
+		select {
+		case mxr.spillQueue <- seg:
+			seg.spillQueued = true
+		default:
+			return
+		}
+	}
+}
+
+// runSpiller writes queued segments to spillDir until Release.
+func (mxr *muxer) runSpiller() {
+	defer mxr.spillWG.Done()
+	for {
+		select {
+		case <-mxr.spillDone:
+			return
+		case seg := <-mxr.spillQueue:
+			seg.spill(mxr.spillDir)
+		}
 	}
 }
 
@@ -377,11 +559,17 @@ func (mxr *muxer) evictByCount() {
 // sequence counters accordingly.
 func (mxr *muxer) evictOldest() {
 	oldestID := mxr.segIDs[0]
-	if seg, ok := mxr.getSegment(oldestID); ok && seg.discontinuity {
-		mxr.discontinuitySequence++
+	if seg, ok := mxr.getSegment(oldestID); ok {
+		if seg.discontinuity {
+			mxr.discontinuitySequence++
+		}
+		mxr.iframeSequence += int64(len(seg.keyframes))
 	}
 	mxr.removeSegment(oldestID)
 	mxr.mediaSequence++
+	if mxr.archived > 0 {
+		mxr.archived--
+	}
 }
 
 // cleanupInitCache removes init versions that are no longer referenced by any segment.
@@ -398,6 +586,7 @@ func (mxr *muxer) cleanupInitCache() {
 		if !used[v] {
 			delete(mxr.initCache, v)
 			delete(mxr.initBytesCache, v)
+			delete(mxr.initKeys, v)
 		}
 	}
 }
@@ -414,6 +603,16 @@ func (mxr *muxer) canOpenTimelineEpoch(pkt gomedia.Packet) bool {
 	return isVideo && vPkt.IsKeyFrame()
 }
 
+// drivesTimeline reports whether pkt advances fragments and segments: video
+// packets, or any packet when the stream has no video track.
+func (mxr *muxer) drivesTimeline(pkt gomedia.Packet) bool {
+	if mxr.codecPars.VideoCodecParameters == nil {
+		return true
+	}
+	_, isVideo := pkt.(gomedia.VideoPacket)
+	return isVideo
+}
+
 // startDiscontinuitySegment force-closes the current segment and opens a new
 // one flagged with #EXT-X-DISCONTINUITY, telling players the media timeline
 // restarts rather than letting them observe tfdt jumping backwards.
@@ -423,6 +622,9 @@ func (mxr *muxer) startDiscontinuitySegment() {
 	newSeg := newSegment(curSeg.id+1, mxr.fragmentDuration, mxr.segmentDuration, mxr.codecPars, mxr.mediaName, mxr.blockingTimeout, mxr.log)
 	newSeg.discontinuity = true
 	newSeg.initVersion = mxr.initVersion
+	if err := mxr.assignKey(newSeg); err != nil {
+		mxr.log.Errorf(mxr, "key provider: %v", err)
+	}
 	mxr.addSegment(newSeg)
 	mxr.evictOldSegments()
 	mxr.manifestDirty = true
@@ -453,12 +655,8 @@ func (mxr *muxer) WritePacket(inpPkt gomedia.Packet) (err error) {
 	// start a segment and is announced via #EXT-X-DISCONTINUITY, so players
 	// reinitialize instead of stalling on a backwards tfdt jump. The overshoot
 	// past maxTS while waiting for a keyframe is harmless (tfdt is uint64).
-	ts := inpPkt.Timestamp() - mxr.tsOffset
-	if ts >= mxr.maxTS && mxr.canOpenTimelineEpoch(inpPkt) {
-		mxr.tsOffset += ts
-		ts = 0
-		mxr.startDiscontinuitySegment()
-	}
+	// Renditions sharing a Timeline wrap together, see rebaseTimestamp.
+	ts := mxr.rebaseTimestamp(inpPkt)
 	if ts < 0 {
 		// An audio packet interleaved slightly behind the wrap keyframe would
 		// otherwise carry a negative timestamp into the fMP4 muxer.
@@ -477,13 +675,14 @@ func (mxr *muxer) WritePacket(inpPkt gomedia.Packet) (err error) {
 	//     before any video packet that would push the segment past the
 	//     target duration, so EXT-X-TARGETDURATION remains an honest
 	//     upper bound (RFC 8216 §4.3.3.1).
-	if vPkt, isVideo := inpPkt.(gomedia.VideoPacket); isVideo {
+	// Audio-only streams rotate on audio packets, all of which are keyframes.
+	if mxr.drivesTimeline(inpPkt) {
 		curSeg := mxr.getCurSegment()
 		var rotate bool
 		projected := curSeg.duration + curSeg.curFragment.duration + inpPkt.Duration()
 		hasContent := curSeg.duration > 0 || curSeg.curFragment.duration > 0
 		if mxr.keyframeSplit {
-			rotate = vPkt.IsKeyFrame() && curSeg.duration >= mxr.segmentDuration
+			rotate = mxr.canOpenTimelineEpoch(inpPkt) && curSeg.duration >= mxr.segmentDuration
 			if !rotate && mxr.maxSegmentDuration > 0 && hasContent && projected > mxr.maxSegmentDuration {
 				rotate = true
 				if !mxr.capSplitSeen {
@@ -500,6 +699,9 @@ func (mxr *muxer) WritePacket(inpPkt gomedia.Packet) (err error) {
 			newSegID := curSeg.id + 1
 			newSeg := newSegment(newSegID, mxr.fragmentDuration, mxr.segmentDuration, mxr.codecPars, mxr.mediaName, mxr.blockingTimeout, mxr.log)
 			newSeg.initVersion = mxr.initVersion
+			if err = mxr.assignKey(newSeg); err != nil {
+				return err
+			}
 			mxr.addSegment(newSeg)
 			mxr.evictOldSegments()
 			mxr.manifestDirty = true
@@ -511,11 +713,12 @@ func (mxr *muxer) WritePacket(inpPkt gomedia.Packet) (err error) {
 		return writeErr
 	}
 
-	// Audio packets cannot close fragments or segments — only video packets
-	// advance fragment duration. The manifest is unchanged after an audio write,
-	// so skip the expensive rebuild and indexChan signal to avoid wasting CPU
-	// and falsely unblocking LL-HLS long-poll clients.
-	if _, isVideo := inpPkt.(gomedia.VideoPacket); !isVideo {
+	// Audio packets cannot close fragments or segments when there is video —
+	// only video packets advance fragment duration. The manifest is unchanged
+	// after such an audio write, so skip the expensive rebuild and indexChan
+	// signal to avoid wasting CPU and falsely unblocking LL-HLS long-poll
+	// clients.
+	if !mxr.drivesTimeline(inpPkt) {
 		return nil
 	}
 
@@ -531,10 +734,35 @@ func (mxr *muxer) WritePacket(inpPkt gomedia.Packet) (err error) {
 
 	// Update the HLS manifest and broadcast the change to all waiting readers.
 	mxr.manifest.Store(mxr.updateIndexM3u8())
+	mxr.iframes.Store(mxr.iframeSnapshot())
 	mxr.broadcastIndex()
 	return nil
 }
 
+// EventWriter is implemented by the muxer returned from NewHLSMuxer. Events
+// are carried as emsg boxes in the fMP4 part and segment that are open when
+// WriteEvent is called, so downstream players receive them in-band.
+type EventWriter interface {
+	WriteEvent(ev fmp4.Event) error
+}
+
+// WriteEvent attaches ev to the fragment currently being filled. Like
+// WritePacket it must be called from the writing goroutine. The event's
+// PresentationTime is rebased with the same timeline epoch as packets.
+func (mxr *muxer) WriteEvent(ev fmp4.Event) error {
+	mxr.segments.RLock()
+	open := len(mxr.segIDs) > 0
+	mxr.segments.RUnlock()
+	if !open {
+		return errors.New("hls: WriteEvent called before Mux")
+	}
+	seg := mxr.getCurSegment()
+	ev.PresentationTime = max(ev.PresentationTime-mxr.tsOffset, 0)
+	ev.Data = append([]byte(nil), ev.Data...)
+	seg.curFragment.events = append(seg.curFragment.events, ev)
+	return nil
+}
+
 // broadcastIndex wakes every blocked GetIndexM3u8 caller by closing indexCh,
 // then swaps in a fresh channel for the next wait cycle.
 func (mxr *muxer) broadcastIndex() {
@@ -570,6 +798,7 @@ func (mxr *muxer) updateIndexM3u8() string {
 	}
 
 	curInitVersion := -1
+	var curKey *Key
 	for _, id := range mxr.segIDs {
 		seg, ok := mxr.getSegment(id)
 		if !ok {
@@ -588,12 +817,23 @@ func (mxr *muxer) updateIndexM3u8() string {
 
 		// Emit #EXT-X-MAP when init version changes (including the first segment).
 		if seg.initVersion != curInitVersion {
+			// An EXT-X-MAP under an AES-128 key would have to be encrypted
+			// too; init segments are served clear, so lift the key first.
+			if curKey != nil && seg.encryption == EncryptionAES128 {
+				b.WriteString("#EXT-X-KEY:METHOD=NONE\n")
+				curKey = nil
+			}
 			curInitVersion = seg.initVersion
 			b.WriteString("#EXT-X-MAP:URI=\"init.mp4?v=")
 			b.WriteString(strconv.Itoa(curInitVersion))
 			b.WriteString("\"\n")
 		}
 
+		if seg.key != curKey {
+			curKey = seg.key
+			b.WriteString(keyTag(seg.encryption, curKey))
+		}
+
 		b.WriteString(seg.manifestEntry)
 	}
 
@@ -691,11 +931,15 @@ func (mxr *muxer) GetInitByVersion(version int) ([]byte, error) {
 		return cached, nil
 	}
 	codecPars, ok := mxr.initCache[version]
+	key := mxr.initKeys[version]
 	mxr.initMu.RUnlock()
 	if !ok {
 		return nil, fmt.Errorf("init version %d not found", version)
 	}
 	mux := fmp4.NewMuxer(mxr.log)
+	if key != nil {
+		mux.SetEncryption(sampleEncryption(key))
+	}
 	if err := mux.Mux(codecPars); err != nil {
 		return nil, err
 	}
@@ -754,6 +998,11 @@ func (mxr *muxer) GetFragment(ctx context.Context, segindex uint64, index uint8)
 
 // Release drops every segment and frees their retained ring-buffer slots.
 func (mxr *muxer) Release() { //nolint:revive // Method name required by interface
+	mxr.setTimelineVideo(false)
+	if mxr.spillDone != nil {
+		mxr.spillStop.Do(func() { close(mxr.spillDone) })
+		mxr.spillWG.Wait()
+	}
 	mxr.segments.Lock()
 	segs := make([]*segment, 0, len(mxr.segments.segments))
 	for _, seg := range mxr.segments.segments {
@@ -768,38 +1017,65 @@ func (mxr *muxer) Release() { //nolint:revive // Method name required by interfa
 	}
 }
 
-// GetMasterEntry builds this stream's #EXT-X-STREAM-INF line for the master playlist.
+// GetMasterEntry builds this stream's #EXT-X-STREAM-INF line for the master
+// playlist. A stream without video yields an audio-only variant.
 func (mxr *muxer) GetMasterEntry() (string, error) {
 	mxr.initMu.RLock()
 	codecPars := mxr.codecPars
 	mxr.initMu.RUnlock()
-	if codecPars.VideoCodecParameters == nil {
-		return "", errors.New("no video codec")
-	}
-	w, h := codecPars.VideoCodecParameters.Width(), codecPars.VideoCodecParameters.Height()
-	codecsStr := codecPars.VideoCodecParameters.Tag()
-	if codecPars.AudioCodecParameters != nil {
-		codecsStr += fmt.Sprintf(",%s", codecPars.AudioCodecParameters.Tag())
-	}
-
-	fps := codecPars.VideoCodecParameters.FPS()
-	masterEntry := fmt.Sprintf(
-		"#EXT-X-STREAM-INF:BANDWIDTH=%d,RESOLUTION=%dx%d,CODECS=\"%s\"",
-		masterEntryBandwidth(codecPars.VideoCodecParameters.Bitrate(), w, h, fps),
-		w,
-		h,
-		codecsStr,
-	)
+	return StreamInf(codecPars, "")
+}
+
+// StreamInf renders the #EXT-X-STREAM-INF line of a variant carrying
+// codecPars. When audioGroup is set the variant references that
+// #EXT-X-MEDIA:TYPE=AUDIO group and codecPars.AudioCodecParameters describes
+// the group's audio: RFC 8216 §4.3.4.2 wants its codec in CODECS and its bit
+// rate in BANDWIDTH even though it is not muxed into the variant. Without
+// video the variant is audio-only.
+func StreamInf(codecPars gomedia.CodecParametersPair, audioGroup string) (string, error) {
+	video, audio := codecPars.VideoCodecParameters, codecPars.AudioCodecParameters
+	if video == nil && audio == nil {
+		return "", errors.New("no codec")
+	}
+
+	var bandwidth uint
+	var codecs []string
+	if video != nil {
+		bandwidth += masterEntryBandwidth(video.Bitrate(), video.Width(), video.Height(), video.FPS())
+		codecs = append(codecs, video.Tag())
+	}
+	if audio != nil {
+		bandwidth += audioBandwidth(audio)
+		codecs = append(codecs, audio.Tag())
+	}
+
+	masterEntry := fmt.Sprintf("#EXT-X-STREAM-INF:BANDWIDTH=%d", bandwidth)
+	if video != nil {
+		masterEntry += fmt.Sprintf(",RESOLUTION=%dx%d", video.Width(), video.Height())
+	}
+	masterEntry += fmt.Sprintf(",CODECS=\"%s\"", strings.Join(codecs, ","))
 	// FRAME-RATE is optional (RFC 8216 §4.3.4.2) and must be positive; parsers
 	// that sort variants by frame rate treat an advertised 0.000 as the slowest
 	// stream. Not every codec parser fills FPS (H.265 SPS timing info is not
 	// parsed), so omit the attribute rather than lie about it.
-	if fps > 0 {
-		masterEntry += fmt.Sprintf(",FRAME-RATE=%.3f", float64(fps))
+	if video != nil && video.FPS() > 0 {
+		masterEntry += fmt.Sprintf(",FRAME-RATE=%.3f", float64(video.FPS()))
+	}
+	if audioGroup != "" {
+		masterEntry += fmt.Sprintf(",AUDIO=\"%s\"", audioGroup)
 	}
 	return masterEntry, nil
 }
 
+// audioBandwidth returns the bit rate of an audio track, falling back to a
+// per-channel placeholder when the codec parameters carry none.
+func audioBandwidth(audio gomedia.AudioCodecParameters) uint {
+	if audio.Bitrate() > 0 {
+		return audio.Bitrate()
+	}
+	return uint(max(audio.Channels(), 1)) * fallbackAudioBitsPerChannel
+}
+
 // masterEntryBandwidth returns the BANDWIDTH value to advertise. The attribute
 // is mandatory in EXT-X-STREAM-INF and must be a positive peak bit rate, but
 // codec parameters do not always carry one (H.265 leaves Bitrate at 0). A zero
@@ -3,11 +3,13 @@ package hls
 import (
 	"context"
 	"fmt"
+	"os"
 	"sync"
 	"time"
 
 	"github.com/ugparu/gomedia"
 	"github.com/ugparu/gomedia/format/fmp4"
+	"github.com/ugparu/gomedia/utils/bits/pio"
 	"github.com/ugparu/gomedia/utils/buffer"
 	"github.com/ugparu/gomedia/utils/logger"
 )
@@ -40,8 +42,14 @@ type segment struct {
 	cachedMp4          []byte       // lazily generated full-segment MP4
 	mu                 sync.RWMutex // guards fragments, lazy MP4 generation, and release
 	released           bool
-	discontinuity      bool // true if this segment starts after a codec change
+	spillPath          string          // file holding the MP4 of an archived segment, "" while in memory
+	spillQueued        bool            // handed to the spill worker; touched by the writing goroutine only
+	discontinuity      bool            // true if this segment starts after a codec change
+	keyframes          []time.Duration // offsets of the video keyframes from the segment start, set on close
+	iframeRanges       []byteRange     // byte ranges of those keyframes, see iframeByteRanges
 	initVersion        int
+	encryption         EncryptionMethod
+	key                *Key // content key, nil for clear media
 	mediaName          string
 	blockingTimeout    time.Duration
 	log                logger.Logger
@@ -127,11 +135,66 @@ func (element *segment) closeSeg() {
 		}
 	}
 
-	element.manifestEntry = fmt.Sprintf("%s#EXT-X-PROGRAM-DATE-TIME:%s\n#EXTINF:%.5f\nsegment/%d/%s.m4s\n",
-		element.cacheEntry, element.time.Format("2006-01-02T15:04:05.000000Z"), element.duration.Seconds(), element.id, element.mediaName)
+	element.manifestEntry = element.cacheEntry + element.segmentEntry()
+	element.keyframes = element.keyframeOffsets()
 	_ = element.close()
 }
 
+// segmentEntry is the manifest entry of a closed segment without its parts.
+func (element *segment) segmentEntry() string {
+	return fmt.Sprintf("#EXT-X-PROGRAM-DATE-TIME:%s\n#EXTINF:%.5f\nsegment/%d/%s.m4s\n",
+		element.time.Format("2006-01-02T15:04:05.000000Z"), element.duration.Seconds(), element.id, element.mediaName)
+}
+
+// archive drops the parts of a segment that left the live edge from its
+// manifest entry.
+func (element *segment) archive() {
+	if element.duration > 0 {
+		element.manifestEntry = element.segmentEntry()
+	}
+}
+
+// spill writes the MP4 of an archived segment to a file in dir and frees the
+// retained packets. On a write error the segment stays in memory. Runs on
+// the muxer's spill worker, see muxer.runSpiller.
+func (element *segment) spill(dir string) {
+	element.mu.RLock()
+	released := element.released
+	element.mu.RUnlock()
+	if released {
+		return
+	}
+	buf := element.getMp4Buffer()
+	if buf == nil || buf.Len() == 0 {
+		return
+	}
+
+	f, err := os.CreateTemp(dir, fmt.Sprintf("%s-%d-*.m4s", element.mediaName, element.id))
+	if err != nil {
+		element.log.Errorf(element, "segment spill: %v", err)
+		return
+	}
+	_, err = f.Write(buf.Data())
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		element.log.Errorf(element, "segment spill: %v", err)
+		_ = os.Remove(f.Name())
+		return
+	}
+
+	element.mu.Lock()
+	defer element.mu.Unlock()
+	if element.released {
+		_ = os.Remove(f.Name())
+		return
+	}
+	element.spillPath = f.Name()
+	element.cachedMp4 = nil
+	element.releasePackets()
+}
+
 // close signals completion. Packets are kept alive for lazy MP4 generation and
 // only freed when release() is called (segment eviction).
 func (element *segment) close() (err error) {
@@ -150,17 +213,29 @@ func (element *segment) release() {
 		return
 	}
 	element.released = true
+	element.releasePackets()
+
+	if element.spillPath != "" {
+		_ = os.Remove(element.spillPath)
+		element.spillPath = ""
+	}
+}
 
+// releasePackets frees the packets and part MP4s of every fragment. Must be
+// called under mu.
+func (element *segment) releasePackets() {
 	for _, frag := range element.fragments {
 		for _, pkt := range frag.packets {
 			pkt.Release()
 		}
 		frag.packets = nil
+		frag.cachedMp4 = nil
 	}
 }
 
-// getMp4Buffer returns the full-segment MP4, generating it on first call.
-// Returns nil once release() has freed the underlying packets.
+// getMp4Buffer returns the full-segment MP4, generating it on first call, or
+// reads it back from the file of a spilled segment. Returns nil once release()
+// has freed the underlying packets.
 func (element *segment) getMp4Buffer() buffer.Buffer {
 	element.mu.Lock()
 	defer element.mu.Unlock()
@@ -171,23 +246,56 @@ func (element *segment) getMp4Buffer() buffer.Buffer {
 	if element.released {
 		return nil
 	}
+	if element.spillPath != "" {
+		data, err := os.ReadFile(element.spillPath)
+		if err != nil {
+			element.log.Errorf(element, "segment spill: %v", err)
+			return nil
+		}
+		return &staticBuffer{data}
+	}
 
 	mux := fmp4.NewMuxer(element.log)
+	if element.encryption == EncryptionSampleAES {
+		mux.SetEncryption(sampleEncryption(element.key))
+	}
 	if muxErr := mux.Mux(element.codecPars); muxErr != nil {
 		element.log.Errorf(element, "segment cache: mux error: %v", muxErr)
 		return nil
 	}
+	// Every video keyframe opens a moof/mdat pair of its own, so the
+	// I-frame playlist can address each one with a byte range. Only the
+	// first pair keeps its styp.
+	var out []byte
+	pending := false
+	flush := func() {
+		data := mux.GetMP4Fragment(int(element.id)).Data()
+		if out == nil {
+			out = data
+		} else {
+			out = append(out, data[pio.U32BE(data):]...)
+		}
+		pending = false
+	}
 	for _, frag := range element.fragments {
 		for _, pkt := range frag.packets {
+			if vPkt, ok := pkt.(gomedia.VideoPacket); ok && vPkt.IsKeyFrame() && pending {
+				flush()
+			}
 			if wErr := mux.WritePacket(pkt); wErr != nil {
 				element.log.Errorf(element, "segment cache: WritePacket error: %v", wErr)
 			}
+			pending = true
+		}
+		for _, ev := range frag.events {
+			mux.WriteEvent(ev)
+			pending = true
 		}
 	}
-	if buf := mux.GetMP4Fragment(int(element.id)); buf != nil {
-		element.cachedMp4 = make([]byte, len(buf.Data()))
-		copy(element.cachedMp4, buf.Data())
+	if pending || out == nil {
+		flush()
 	}
+	element.cachedMp4 = element.protect(out)
 
 	return &staticBuffer{element.cachedMp4}
 }
@@ -223,10 +331,27 @@ func (element *segment) getFragment(ctx context.Context, id uint8) buffer.Buffer
 		return nil
 	}
 
-	frag.generateMp4()
+	frag.generateMp4(element)
 	return frag.getMp4Buffer()
 }
 
+// protect returns a copy of the generated MP4 bytes, AES-128 encrypted as a
+// whole when the segment uses that method. Segments and their parts share
+// the segment's media sequence number as IV.
+func (element *segment) protect(data []byte) []byte {
+	if element.encryption != EncryptionAES128 {
+		out := make([]byte, len(data))
+		copy(out, data)
+		return out
+	}
+	out, err := encryptAES128(element.key, element.id, data)
+	if err != nil {
+		element.log.Errorf(element, "segment encryption: %v", err)
+		return nil
+	}
+	return out
+}
+
 // waitFragment blocks until fragment id closes, the segment finishes, or ctx is cancelled.
 func (element *segment) waitFragment(ctx context.Context, id uint8) {
 	for {
@@ -0,0 +1,98 @@
+package hls
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ugparu/gomedia"
+)
+
+// Timeline is a timestamp epoch shared by the muxers of renditions that are
+// played together, such as video variants and their separate audio
+// rendition. Independent wraps would give the renditions different tfdt
+// values and discontinuity sequences for the same content, so one decision is
+// made for all: a muxer with video wraps at its first keyframe past the max
+// timestamp, and every other muxer on the timeline follows at its first
+// packet of the new epoch that may open a segment. Muxers without video wrap
+// on their own only while no muxer with video is on the timeline.
+type Timeline struct {
+	mu     sync.Mutex
+	offset time.Duration // start of the current epoch in source timestamps
+	epoch  uint64        // number of wraps so far
+	video  int           // muxers with video on the timeline
+}
+
+// NewTimeline returns a timeline starting at timestamp zero.
+func NewTimeline() *Timeline {
+	return &Timeline{}
+}
+
+// WithTimeline makes the muxer share tl with the other muxers created with it.
+func WithTimeline(tl *Timeline) MuxerOption {
+	return func(m *muxer) { m.timeline = tl }
+}
+
+// current returns the start and number of the current epoch.
+func (tl *Timeline) current() (time.Duration, uint64) {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	return tl.offset, tl.epoch
+}
+
+// wrap starts a new epoch at offset and returns its number.
+func (tl *Timeline) wrap(offset time.Duration) uint64 {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	tl.offset = offset
+	tl.epoch++
+	return tl.epoch
+}
+
+func (tl *Timeline) hasVideo() bool {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	return tl.video > 0
+}
+
+// setTimelineVideo records on the timeline whether this muxer carries video.
+func (mxr *muxer) setTimelineVideo(video bool) {
+	if mxr.timeline == nil || video == mxr.timelineVideo {
+		return
+	}
+	mxr.timelineVideo = video
+	mxr.timeline.mu.Lock()
+	defer mxr.timeline.mu.Unlock()
+	if video {
+		mxr.timeline.video++
+	} else {
+		mxr.timeline.video--
+	}
+}
+
+// decidesWrap reports whether the muxer may start a new epoch by itself.
+func (mxr *muxer) decidesWrap() bool {
+	return mxr.timeline == nil || mxr.timelineVideo || !mxr.timeline.hasVideo()
+}
+
+// rebaseTimestamp maps a source timestamp onto the muxer's current epoch,
+// starting a new one (announced with a discontinuity segment) when the
+// timeline wrapped elsewhere or the timestamp reached the max timestamp.
+func (mxr *muxer) rebaseTimestamp(pkt gomedia.Packet) time.Duration {
+	if mxr.timeline != nil && mxr.canOpenTimelineEpoch(pkt) {
+		if offset, epoch := mxr.timeline.current(); epoch != mxr.epoch && pkt.Timestamp() >= offset {
+			mxr.tsOffset, mxr.epoch = offset, epoch
+			mxr.startDiscontinuitySegment()
+		}
+	}
+
+	ts := pkt.Timestamp() - mxr.tsOffset
+	if ts >= mxr.maxTS && mxr.canOpenTimelineEpoch(pkt) && mxr.decidesWrap() {
+		mxr.tsOffset += ts
+		ts = 0
+		if mxr.timeline != nil {
+			mxr.epoch = mxr.timeline.wrap(mxr.tsOffset)
+		}
+		mxr.startDiscontinuitySegment()
+	}
+	return ts
+}
@@ -0,0 +1,379 @@
+package hls
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ugparu/gomedia"
+	"github.com/ugparu/gomedia/format/fmp4"
+	"github.com/ugparu/gomedia/format/mp4"
+	"github.com/ugparu/gomedia/utils/logger"
+)
+
+const (
+	vodSegmentDuration = time.Second * 6
+	vodMaxGap          = time.Second
+)
+
+// VODFile is one recorded MP4 file of a VOD playlist.
+type VODFile struct {
+	Path string
+	// Start is the wall time of the first sample. A zero Start means the file
+	// directly follows the previous one; when no file has a Start the
+	// playlist carries no #EXT-X-PROGRAM-DATE-TIME and cannot be cut to a
+	// time range.
+	Start time.Time
+}
+
+// VODFilesFromInfos returns the files recorded by a segmenter writing to
+// dir, in the order of infos.
+func VODFilesFromInfos(dir string, infos []gomedia.FileInfo) []VODFile {
+	files := make([]VODFile, 0, len(infos))
+	for _, info := range infos {
+		files = append(files, VODFile{Path: filepath.Join(dir, info.Name), Start: info.Start})
+	}
+	return files
+}
+
+// VODOption is a functional option for configuring a VOD.
+type VODOption func(*VOD)
+
+// VODWithLogger sets the logger of the VOD.
+func VODWithLogger(l logger.Logger) VODOption {
+	return func(v *VOD) { v.log = l }
+}
+
+// VODWithSegmentDuration overrides the default target segment duration (6s).
+// Segments are cut on the first video keyframe past it.
+func VODWithSegmentDuration(d time.Duration) VODOption {
+	return func(v *VOD) { v.segmentDuration = d }
+}
+
+// VODWithMaxGap overrides the wall-time gap between two files (default 1s)
+// past which they are separated by #EXT-X-DISCONTINUITY.
+func VODWithMaxGap(d time.Duration) VODOption {
+	return func(v *VOD) { v.maxGap = d }
+}
+
+// VODWithMediaName overrides the default media filename base ("media") used in segment URIs.
+func VODWithMediaName(name string) VODOption {
+	return func(v *VOD) { v.mediaName = name }
+}
+
+// VODWithVersion overrides the default HLS protocol version (7).
+func VODWithVersion(version int) VODOption {
+	return func(v *VOD) { v.version = version }
+}
+
+// vodSegment is a [start, end) range of one file's timeline, played at base
+// on the playlist timeline.
+type vodSegment struct {
+	file          int
+	start, end    time.Duration
+	base          time.Duration
+	wall          time.Time // zero when the file has no known start
+	initVersion   int
+	discontinuity bool
+}
+
+// VOD plays a time range of recorded MP4 files back over HLS. The playlist is
+// built once by NewVOD; segments are re-muxed to fMP4 from the files on every
+// GetSegment, so nothing but the index is kept in memory. The URIs match
+// those of the live muxer: init.mp4?v=<version> and segment/<index>/<media>.m4s.
+type VOD struct {
+	log             logger.Logger
+	segmentDuration time.Duration
+	maxGap          time.Duration
+	mediaName       string
+	version         int
+
+	files    []string
+	inits    [][]byte
+	segments []vodSegment
+	playlist string
+}
+
+// NewVOD indexes files, in playback order, and builds a
+// #EXT-X-PLAYLIST-TYPE:VOD playlist of the segments overlapping [from, to).
+// A zero from or to leaves that side open. Playback starts at the keyframe at
+// or before from. Keyframes are found in the sample tables of each file,
+// without reading its samples; a file is skipped unread when the next one
+// starts before from, and files past to are not opened. Codec changes and
+// wall-time gaps between files start a new #EXT-X-DISCONTINUITY, with a new
+// init segment for codec changes.
+func NewVOD(files []VODFile, from, to time.Time, opts ...VODOption) (*VOD, error) {
+	v := &VOD{
+		log:             logger.Default,
+		segmentDuration: vodSegmentDuration,
+		maxGap:          vodMaxGap,
+		mediaName:       "media",
+		version:         7, //nolint:mnd // same default as the live muxer
+	}
+	for _, o := range opts {
+		o(v)
+	}
+
+	var (
+		base     time.Duration
+		wall     time.Time // wall time the previous file ended at, zero when unknown
+		lastInit = -1      // init version of the previous segment
+	)
+	for i, f := range files {
+		if i+1 < len(files) && !from.IsZero() && !files[i+1].Start.IsZero() && !files[i+1].Start.After(from) {
+			wall = time.Time{}
+			continue // ends before the range
+		}
+		start := f.Start
+		if start.IsZero() {
+			start = wall
+		}
+		if !start.IsZero() && !to.IsZero() && !start.Before(to) {
+			break
+		}
+
+		idx, err := indexVODFile(f.Path, v.segmentDuration)
+		if err != nil {
+			return nil, fmt.Errorf("hls: vod: %s: %w", f.Path, err)
+		}
+		gap := !wall.IsZero() && (start.Sub(wall) > v.maxGap || wall.Sub(start) > v.maxGap)
+		wall = time.Time{}
+		if !start.IsZero() {
+			wall = start.Add(idx.end)
+		}
+
+		fromOff, toOff := time.Duration(math.MinInt64), time.Duration(math.MaxInt64)
+		if !start.IsZero() && !from.IsZero() {
+			fromOff = from.Sub(start)
+		}
+		if !start.IsZero() && !to.IsZero() {
+			toOff = to.Sub(start)
+		}
+
+		initVersion := v.initVersion(idx.init)
+		fileIdx := len(v.files)
+		v.files = append(v.files, f.Path)
+		firstOfFile := true
+		for j, cut := range idx.cuts {
+			end := idx.end
+			if j+1 < len(idx.cuts) {
+				end = idx.cuts[j+1]
+			}
+			if end <= fromOff || cut >= toOff {
+				continue
+			}
+			seg := vodSegment{
+				file:          fileIdx,
+				start:         cut,
+				end:           end,
+				base:          base,
+				initVersion:   initVersion,
+				discontinuity: len(v.segments) > 0 && (initVersion != lastInit || firstOfFile && gap),
+			}
+			if !start.IsZero() {
+				seg.wall = start.Add(cut)
+			}
+			v.segments = append(v.segments, seg)
+			base += end - cut
+			lastInit, firstOfFile = initVersion, false
+		}
+	}
+	if len(v.segments) == 0 {
+		return nil, errors.New("hls: vod: no media in the requested range")
+	}
+
+	v.playlist = v.buildPlaylist()
+	return v, nil
+}
+
+// initVersion returns the version of the init segment init, adding it if new.
+func (v *VOD) initVersion(init []byte) int {
+	for i, known := range v.inits {
+		if bytes.Equal(known, init) {
+			return i
+		}
+	}
+	v.inits = append(v.inits, init)
+	return len(v.inits) - 1
+}
+
+// vodIndex is the layout of one file: its fMP4 init segment, the timestamps
+// segments may start at and the end of its timeline.
+type vodIndex struct {
+	init []byte
+	cuts []time.Duration
+	end  time.Duration
+}
+
+// indexVODFile reads the sample tables of the file at path, not its
+// samples. Segments start at the first video keyframe (any sample in
+// audio-only files) at least segDur after the start of the previous one.
+func indexVODFile(path string, segDur time.Duration) (idx vodIndex, err error) {
+	dmx, _ := mp4.NewDemuxer(path).(*mp4.Demuxer)
+	defer dmx.Close()
+	params, err := dmx.Demux()
+	if err != nil {
+		return idx, err
+	}
+	mux := fmp4.NewMuxer(logger.Default)
+	if err = mux.Mux(params); err != nil {
+		return idx, err
+	}
+	idx.init = mux.GetInit().Data()
+
+	keyframes, err := dmx.Keyframes()
+	if err != nil {
+		return idx, err
+	}
+	for _, ts := range keyframes {
+		if len(idx.cuts) == 0 || ts-idx.cuts[len(idx.cuts)-1] >= segDur {
+			idx.cuts = append(idx.cuts, ts)
+		}
+	}
+	if len(idx.cuts) == 0 {
+		return idx, errors.New("no keyframe")
+	}
+	if idx.end, err = dmx.Duration(); err != nil {
+		return idx, err
+	}
+	return idx, nil
+}
+
+// buildPlaylist renders the complete playlist. Every segment starts on a
+// keyframe, so #EXT-X-INDEPENDENT-SEGMENTS always holds.
+func (v *VOD) buildPlaylist() string {
+	var target time.Duration
+	for _, seg := range v.segments {
+		target = max(target, seg.end-seg.start)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "#EXTM3U\n#EXT-X-VERSION:%d\n#EXT-X-PLAYLIST-TYPE:VOD\n#EXT-X-TARGETDURATION:%d\n",
+		v.version, int(math.Ceil(target.Seconds())))
+	b.WriteString("#EXT-X-MEDIA-SEQUENCE:0\n#EXT-X-INDEPENDENT-SEGMENTS\n")
+
+	curInitVersion := -1
+	for i, seg := range v.segments {
+		if seg.discontinuity {
+			b.WriteString("#EXT-X-DISCONTINUITY\n")
+		}
+		if seg.initVersion != curInitVersion {
+			curInitVersion = seg.initVersion
+			b.WriteString("#EXT-X-MAP:URI=\"init.mp4?v=")
+			b.WriteString(strconv.Itoa(curInitVersion))
+			b.WriteString("\"\n")
+		}
+		if !seg.wall.IsZero() {
+			fmt.Fprintf(&b, "#EXT-X-PROGRAM-DATE-TIME:%s\n", seg.wall.UTC().Format("2006-01-02T15:04:05.000000Z"))
+		}
+		fmt.Fprintf(&b, "#EXTINF:%.5f\nsegment/%d/%s.m4s\n", (seg.end - seg.start).Seconds(), i, v.mediaName)
+	}
+	b.WriteString("#EXT-X-ENDLIST\n")
+	return b.String()
+}
+
+// Playlist returns the media playlist.
+func (v *VOD) Playlist() string {
+	return v.playlist
+}
+
+// Duration returns the total duration of the playlist.
+func (v *VOD) Duration() time.Duration {
+	last := v.segments[len(v.segments)-1]
+	return last.base + last.end - last.start
+}
+
+// GetInitByVersion returns the init segment referenced by
+// #EXT-X-MAP:URI="init.mp4?v=<version>".
+func (v *VOD) GetInitByVersion(version int) ([]byte, error) {
+	if version < 0 || version >= len(v.inits) {
+		return nil, fmt.Errorf("init version %d not found", version)
+	}
+	return v.inits[version], nil
+}
+
+// GetSegment re-muxes segment index from its file, reading from its first
+// sample on. Its timestamps are moved onto the playlist timeline, which runs
+// without gaps across files.
+func (v *VOD) GetSegment(ctx context.Context, index uint64) ([]byte, error) {
+	if index >= uint64(len(v.segments)) {
+		return nil, errors.New("segment not found")
+	}
+	seg := v.segments[index]
+
+	dmx, _ := mp4.NewDemuxer(v.files[seg.file]).(*mp4.Demuxer)
+	defer dmx.Close()
+	params, err := dmx.Demux()
+	if err != nil {
+		return nil, err
+	}
+	if err = dmx.SeekTo(seg.start); err != nil {
+		return nil, err
+	}
+	mux := fmp4.NewMuxer(v.log)
+	if err = mux.Mux(params); err != nil {
+		return nil, err
+	}
+
+	var (
+		pkts      []gomedia.Packet
+		lastVideo gomedia.Packet
+	)
+	defer func() {
+		for _, pkt := range pkts {
+			pkt.Release()
+		}
+	}()
+	for {
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+		pkt, readErr := dmx.ReadPacket()
+		if errors.Is(readErr, io.EOF) {
+			break
+		}
+		if readErr != nil {
+			return nil, readErr
+		}
+		ts := pkt.Timestamp()
+		if ts >= seg.end {
+			pkt.Release()
+			break
+		}
+		pkts = append(pkts, pkt)
+		pkt.SetTimestamp(seg.base + ts - seg.start)
+		// Video samples read from MP4 carry no duration; it is the distance
+		// to the next frame, or to the end of the segment for the last one.
+		if _, isVideo := pkt.(gomedia.VideoPacket); isVideo {
+			if lastVideo != nil {
+				lastVideo.SetDuration(pkt.Timestamp() - lastVideo.Timestamp())
+			}
+			lastVideo = pkt
+		}
+	}
+	if lastVideo != nil {
+		lastVideo.SetDuration(seg.base + seg.end - seg.start - lastVideo.Timestamp())
+	}
+	for _, pkt := range pkts {
+		if err = mux.WritePacket(pkt); err != nil {
+			return nil, err
+		}
+	}
+
+	buf := mux.GetMP4Fragment(int(index))
+	if buf == nil {
+		return nil, errors.New("empty segment")
+	}
+	return buf.Data(), nil
+}
+
+func (v *VOD) String() string {
+	return fmt.Sprintf("HLS_VOD segs=%d", len(v.segments))
+}
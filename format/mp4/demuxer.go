@@ -14,7 +14,8 @@ import (
 )
 
 type Demuxer struct {
-	r              *os.File
+	r              io.ReadSeeker
+	file           *os.File // set when the demuxer opened url itself and owns the handle
 	streams        []*Stream
 	movieAtom      *mp4io.Movie
 	url            string
@@ -22,16 +23,31 @@ type Demuxer struct {
 	audioCodecData gomedia.AudioCodecParameters
 }
 
+// NewDemuxer returns a demuxer for the MP4 file at url (a local path). The
+// file is opened by Demux and closed by Close.
 func NewDemuxer(url string) gomedia.Demuxer {
 	dmx := new(Demuxer)
 	dmx.url = url
 	return dmx
 }
 
+// NewDemuxerFromReader returns a demuxer reading from r, e.g. an in-memory
+// buffer, an object-storage reader or an httpreader.Reader. sourceID is
+// reported as the SourceID of the codec parameters and packets. The caller
+// keeps ownership of r: Close does not close it.
+func NewDemuxerFromReader(r io.ReadSeeker, sourceID string) gomedia.Demuxer {
+	dmx := new(Demuxer)
+	dmx.r = r
+	dmx.url = sourceID
+	return dmx
+}
+
 func (dmx *Demuxer) Demux() (params gomedia.CodecParametersPair, err error) {
-	dmx.r, err = os.Open(dmx.url)
-	if err != nil {
-		return
+	if dmx.r == nil {
+		if dmx.file, err = os.Open(dmx.url); err != nil {
+			return
+		}
+		dmx.r = dmx.file
 	}
 	if err = dmx.probe(); err != nil {
 		return
@@ -45,12 +61,15 @@ func (dmx *Demuxer) Demux() (params gomedia.CodecParametersPair, err error) {
 }
 
 func (dmx *Demuxer) Close() {
-	if dmx.r != nil {
-		dmx.r.Close()
+	if dmx.file != nil {
+		dmx.file.Close()
 	}
 }
 
 func (dmx *Demuxer) ReadPacket() (pkt gomedia.Packet, err error) {
+	if dmx.r == nil {
+		return nil, errors.New("mp4: ReadPacket called before Demux")
+	}
 	if err = dmx.probe(); err != nil {
 		return
 	}
@@ -74,15 +93,23 @@ func (dmx *Demuxer) ReadPacket() (pkt gomedia.Packet, err error) {
 	// Interleave streams by DTS so downstream consumers see packets in monotonic order.
 	var chosen *Stream
 	for _, stream := range validStreams {
-		if chosen == nil || stream.tsToTime(stream.dts) < chosen.tsToTime(chosen.dts) {
+		if chosen == nil || stream.tsToTime(stream.dts)+stream.editShift < chosen.tsToTime(chosen.dts)+chosen.editShift {
 			chosen = stream
 		}
 	}
 
-	tm := chosen.tsToTime(chosen.dts)
+	tm := chosen.tsToTime(chosen.dts) + chosen.editShift
 	return chosen.readPacket(tm, dmx.url)
 }
 
+// rewind restarts reading from the first sample of every stream, keeping
+// the codec parameters.
+func (dmx *Demuxer) rewind() {
+	for _, stream := range dmx.streams {
+		stream.rewind()
+	}
+}
+
 func (dmx *Demuxer) VideoParameters() gomedia.VideoCodecParameters {
 	return dmx.videoCodecData
 }
@@ -92,6 +119,17 @@ func (dmx *Demuxer) AudioParameters() gomedia.AudioCodecParameters {
 }
 
 func (dmx *Demuxer) readat(pos int64, b []byte) (err error) {
+	if ra, ok := dmx.r.(io.ReaderAt); ok {
+		var n int
+		n, err = ra.ReadAt(b, pos)
+		if n == len(b) {
+			return nil
+		}
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return
+	}
 	if _, err = dmx.r.Seek(pos, 0); err != nil {
 		return
 	}
@@ -144,6 +182,9 @@ func (dmx *Demuxer) probe() (err error) {
 		stream.demuxer = dmx
 		stream.sample = atrack.Media.Info.Sample
 		stream.timeScale = int64(atrack.Media.Header.TimeScale)
+		if moov.Header != nil {
+			stream.applyEditList(int64(moov.Header.TimeScale))
+		}
 
 		if avc1 := atrack.GetAVC1Conf(); avc1 != nil {
 			var res h264.CodecParameters
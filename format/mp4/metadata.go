@@ -0,0 +1,225 @@
+package mp4
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ugparu/gomedia/format/mp4/mp4io"
+)
+
+// Well-known file-level metadata keys. The QuickTime reverse-DNS keys are
+// understood by common players and tools (ffprobe, exiftool, Apple/Android
+// galleries); the gomedia ones are private to archives written by this package.
+const (
+	MetadataKeyTitle        = "com.apple.quicktime.title"
+	MetadataKeyCreationDate = "com.apple.quicktime.creationdate"
+	// MetadataKeyLocation holds an ISO 6709 string, e.g. "+55.7558+037.6173/".
+	MetadataKeyLocation   = "com.apple.quicktime.location.ISO6709"
+	MetadataKeyCameraName = "com.apple.quicktime.camera.identifier"
+	// MetadataKeyRecordMode holds the gomedia.RecordMode the file was recorded in.
+	MetadataKeyRecordMode = "org.ugparu.gomedia.record-mode"
+)
+
+// Common MIME types for WithTimedMetadata.
+const (
+	MetadataMIMEJSON = "application/json"
+	MetadataMIMEKLV  = "application/x-klv"
+)
+
+const (
+	metadataTimeScale = 1000 // ms resolution is plenty for event samples
+	minMetadataSample = time.Millisecond
+)
+
+type metadataEntry struct {
+	key   string
+	value string
+}
+
+// WithMetadata stores a file-level key/value pair in moov/udta/meta using
+// the QuickTime keys/ilst layout. Repeating a key replaces its value.
+func WithMetadata(key, value string) MuxerOption {
+	return func(m *Muxer) { m.SetMetadata(key, value) }
+}
+
+// WithTimedMetadata adds a timed-metadata track (mett sample entry) whose
+// samples carry payloads of the given MIME type, e.g. MetadataMIMEJSON.
+// Samples are written with WriteMetadataSample.
+func WithTimedMetadata(mime string) MuxerOption {
+	return func(m *Muxer) { m.metaMIME = mime }
+}
+
+// SetMetadata sets a file-level metadata value. It may be called at any time
+// before WriteTrailer and survives Mux, so values configured once apply to
+// every file written by a reused muxer.
+func (mux *Muxer) SetMetadata(key, value string) {
+	for i := range mux.metadata {
+		if mux.metadata[i].key == key {
+			mux.metadata[i].value = value
+			return
+		}
+	}
+	mux.metadata = append(mux.metadata, metadataEntry{key: key, value: value})
+}
+
+// WriteMetadataSample appends a timed-metadata sample covering
+// [ts, ts+dur) on the same timeline as the media packets. Gaps between
+// samples are filled with empty samples; a sample that starts before the
+// previous one ended is moved to its end. The data is copied.
+func (mux *Muxer) WriteMetadataSample(ts, dur time.Duration, data []byte) error {
+	if mux.metaTrack == nil {
+		return errors.New("mp4: timed metadata track is not enabled (use WithTimedMetadata)")
+	}
+	mt := mux.metaTrack
+	if !mt.hasFirstTS {
+		mt.firstTS, mt.hasFirstTS = ts, true
+	}
+	end := mt.firstTS + time.Duration(mt.duration)*time.Second/metadataTimeScale
+	if gap := ts - end; gap >= minMetadataSample {
+		mux.addMetadataSample(gap, nil)
+	}
+	mux.addMetadataSample(max(dur, minMetadataSample), append([]byte(nil), data...))
+	return nil
+}
+
+func (mux *Muxer) addMetadataSample(dur time.Duration, data []byte) {
+	mt := mux.metaTrack
+	ticks := uint32(timeToTS(dur, metadataTimeScale)) //nolint:gosec // dur >= 1ms
+	stts := mt.sample.TimeToSample
+	if n := len(stts.Entries); n == 0 || stts.Entries[n-1].Duration != ticks {
+		stts.Entries = append(stts.Entries, mp4io.TimeToSampleEntry{Duration: ticks})
+	}
+	stts.Entries[len(stts.Entries)-1].Count++
+	mt.duration += int64(ticks)
+
+	mt.sample.ChunkOffset.Entries = append(mt.sample.ChunkOffset.Entries, uint64(mux.writePosition)) //nolint:gosec
+	mt.sample.SampleSize.Entries = append(mt.sample.SampleSize.Entries, uint32(len(data)))           //nolint:gosec
+	if len(data) > 0 {
+		mux.pending = append(mux.pending, pendingWrite{data: data, totalSize: len(data)})
+		mux.pendingSize += len(data)
+		mux.writePosition += int64(len(data))
+	}
+}
+
+// timedMetadataTrack accumulates the sample table of the mett track.
+// Samples are written to mdat immediately (no hold-back) since their
+// durations are supplied by the caller.
+type timedMetadataTrack struct {
+	trackAtom  *mp4io.Track
+	sample     *mp4io.SampleTable
+	duration   int64 // in metadataTimeScale
+	firstTS    time.Duration
+	hasFirstTS bool
+}
+
+func newTimedMetadataTrack(mime string, trackID int32) *timedMetadataTrack {
+	now := time.Now()
+	mt := &timedMetadataTrack{
+		sample: &mp4io.SampleTable{
+			SampleDesc: &mp4io.SampleDesc{
+				METTDesc: &mp4io.METTDesc{DataRefIdx: 1, MimeFormat: mime},
+			},
+			TimeToSample: new(mp4io.TimeToSample),
+			SampleToChunk: &mp4io.SampleToChunk{
+				Entries: []mp4io.SampleToChunkEntry{{FirstChunk: 1, SampleDescId: 1, SamplesPerChunk: 1}},
+			},
+			ChunkOffset: new(mp4io.ChunkOffset),
+			SampleSize:  new(mp4io.SampleSize),
+		},
+	}
+	const trackFlags = 0x0003
+	mt.trackAtom = &mp4io.Track{
+		Header: &mp4io.TrackHeader{
+			Flags:      trackFlags,
+			CreateTime: now,
+			ModifyTime: now,
+			TrackId:    trackID,
+			Matrix:     [9]int32{0x00010000, 0, 0, 0, 0x00010000, 0, 0, 0, 0x00010000},
+		},
+		Media: &mp4io.Media{
+			Header: &mp4io.MediaHeader{
+				CreateTime: now,
+				ModifyTime: now,
+				TimeScale:  metadataTimeScale,
+				Language:   21956, //nolint:mnd // "und"
+			},
+			Handler: &mp4io.HandlerRefer{
+				SubType: [4]byte{'m', 'e', 't', 'a'},
+				Name:    []byte("MetadataHandler"),
+			},
+			Info: &mp4io.MediaInfo{
+				Null: &mp4io.NullMediaInfo{},
+				Data: &mp4io.DataInfo{
+					Refer: &mp4io.DataRefer{
+						Url: &mp4io.DataReferUrl{Flags: 0x000001}, //nolint:mnd // self-contained
+					},
+				},
+				Sample: mt.sample,
+			},
+		},
+	}
+	return mt
+}
+
+func (mt *timedMetadataTrack) sampleCount() int {
+	return len(mt.sample.SampleSize.Entries)
+}
+
+func (mt *timedMetadataTrack) buildTrack(earliest time.Duration, movieTimeScale int64) (*mp4io.Track, time.Duration) {
+	edit, dur := buildEdit(mt.firstTS-earliest, 0, mt.duration, metadataTimeScale, movieTimeScale)
+	mt.trackAtom.Edit = edit
+	mt.trackAtom.Header.Duration = timeToTS(dur, movieTimeScale)
+	mt.trackAtom.Header.Version = headerVersion(mt.trackAtom.Header.Duration)
+	mt.trackAtom.Media.Header.Duration = mt.duration
+	mt.trackAtom.Media.Header.Version = headerVersion(mt.duration)
+	return mt.trackAtom, dur
+}
+
+// buildUserData renders the file-level metadata as udta/meta with an mdta
+// handler, or returns nil when no metadata was set.
+func (mux *Muxer) buildUserData() *mp4io.UserData {
+	if len(mux.metadata) == 0 {
+		return nil
+	}
+	keys := &mp4io.MetadataKeys{}
+	items := &mp4io.MetadataItemList{}
+	for i, entry := range mux.metadata {
+		keys.Entries = append(keys.Entries, mp4io.MetadataKey{
+			Namespace: mp4io.MetadataKeyNamespaceMdta,
+			Value:     entry.key,
+		})
+		items.Items = append(items.Items, mp4io.MetadataItem{
+			KeyIndex: uint32(i + 1), //nolint:gosec
+			DataType: mp4io.MetadataTypeUTF8,
+			Value:    []byte(entry.value),
+		})
+	}
+	return &mp4io.UserData{Meta: &mp4io.Meta{
+		Handler: &mp4io.HandlerRefer{SubType: [4]byte{'m', 'd', 't', 'a'}},
+		Keys:    keys,
+		Items:   items,
+	}}
+}
+
+// Metadata returns the file-level key/value metadata stored in
+// moov/udta/meta. Values of non-text items are returned as raw bytes.
+func (dmx *Demuxer) Metadata() map[string]string {
+	if dmx.r == nil {
+		return nil
+	}
+	if err := dmx.probe(); err != nil || dmx.movieAtom.UserData == nil {
+		return nil
+	}
+	meta := dmx.movieAtom.UserData.Meta
+	if meta == nil || meta.Keys == nil || meta.Items == nil {
+		return nil
+	}
+	res := make(map[string]string, len(meta.Items.Items))
+	for _, item := range meta.Items.Items {
+		if item.KeyIndex == 0 || int(item.KeyIndex) > len(meta.Keys.Entries) {
+			continue
+		}
+		res[meta.Keys.Entries[item.KeyIndex-1].Value] = string(item.Value)
+	}
+	return res
+}
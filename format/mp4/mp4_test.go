@@ -2,9 +2,12 @@
 package mp4
 
 import (
+	"bytes"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"io"
+	"math"
 	"os"
 	"testing"
 	"time"
@@ -1198,3 +1201,808 @@ func demuxAndGetMoov(t *testing.T, path string) *mp4io.Movie {
 	t.Fatal("moov atom not found")
 	return nil
 }
+
+// Recover — truncated progressive files
+
+// writeTruncatedMP4 muxes synthetic interleaved H.264/AAC packets, flushes
+// them to disk and never writes the trailer, mimicking a recorder that died
+// mid-segment. It returns the file path and the number of flushed samples
+// per stream (the Muxer holds back the last packet of each stream).
+func writeTruncatedMP4(t *testing.T, frames int) (path string, video, audio int) {
+	t.Helper()
+	pair, videoCp, audioCp := loadTestCodecPair(t)
+
+	f, err := os.CreateTemp("", "gomedia_test_*.mp4")
+	require.NoError(t, err)
+	path = f.Name()
+	t.Cleanup(func() { os.Remove(path) })
+
+	mux := NewMuxer(f)
+	require.NoError(t, mux.Mux(pair))
+
+	idr := []byte{0, 0, 0, 6, 0x65, 0x88, 0x84, 0x00, 0x33, 0xff}
+	nonIDR := []byte{0, 0, 0, 5, 0x41, 0x9a, 0x02, 0x03, 0x04}
+	aacFrame := []byte{0x01, 0x40, 0x20, 0x55, 0x07}
+
+	base := time.Now()
+	const frameDur = 40 * time.Millisecond
+	for i := range frames {
+		ts := time.Duration(i) * frameDur
+		data := nonIDR
+		if i%5 == 0 {
+			data = idr
+		}
+		vPkt := h264.NewPacket(i%5 == 0, ts, base, append([]byte(nil), data...), "test", videoCp)
+		require.NoError(t, mux.WritePacket(vPkt))
+		for j := range 2 {
+			aTS := ts + time.Duration(j)*frameDur/2
+			require.NoError(t, mux.WritePacket(aac.NewPacket(append([]byte(nil), aacFrame...), aTS, "test", base, audioCp, frameDur/2)))
+		}
+	}
+	require.NoError(t, mux.Flush())
+	mux.ReleasePending()
+	require.NoError(t, f.Close())
+	return path, frames - 1, 2*frames - 1
+}
+
+func countPacketsPerStream(t *testing.T, path string) map[uint8]int {
+	t.Helper()
+	dmx := NewDemuxer(path)
+	defer dmx.Close()
+	_, err := dmx.Demux()
+	require.NoError(t, err)
+
+	counts := map[uint8]int{}
+	for {
+		pkt, readErr := dmx.ReadPacket()
+		if readErr == io.EOF {
+			break
+		}
+		require.NoError(t, readErr)
+		if pkt != nil {
+			counts[pkt.StreamIndex()]++
+		}
+	}
+	return counts
+}
+
+func TestRecover_RebuildsSampleTables(t *testing.T) {
+	t.Parallel()
+	src, wantVideo, wantAudio := writeTruncatedMP4(t, 10)
+	pair, _, _ := loadTestCodecPair(t)
+
+	dst := src + ".recovered.mp4"
+	t.Cleanup(func() { os.Remove(dst) })
+
+	stats, err := RecoverFile(src, dst, pair)
+	require.NoError(t, err)
+	assert.Equal(t, wantVideo, stats.VideoSamples)
+	assert.Equal(t, 2, stats.Keyframes)
+	assert.Equal(t, wantAudio, stats.AudioSamples)
+	assert.Zero(t, stats.SkippedBytes)
+	assert.False(t, stats.Truncated)
+
+	moov := demuxAndGetMoov(t, dst)
+	require.Len(t, moov.Tracks, 2)
+	assert.Len(t, moov.Tracks[0].Media.Info.Sample.SyncSample.Entries, 2)
+
+	counts := countPacketsPerStream(t, dst)
+	assert.Equal(t, wantVideo, counts[0])
+	assert.Equal(t, wantAudio, counts[1])
+}
+
+func TestRecover_DropsCutOffTailSample(t *testing.T) {
+	t.Parallel()
+	src, wantVideo, wantAudio := writeTruncatedMP4(t, 10)
+	pair, _, _ := loadTestCodecPair(t)
+
+	fi, err := os.Stat(src)
+	require.NoError(t, err)
+	require.NoError(t, os.Truncate(src, fi.Size()-2))
+
+	dst := src + ".recovered.mp4"
+	t.Cleanup(func() { os.Remove(dst) })
+
+	stats, err := RecoverFile(src, dst, pair)
+	require.NoError(t, err)
+	assert.True(t, stats.Truncated)
+	assert.Equal(t, wantVideo, stats.VideoSamples)
+	assert.Equal(t, wantAudio-1, stats.AudioSamples)
+}
+
+func TestRecover_RejectsCompleteFile(t *testing.T) {
+	t.Parallel()
+	src, _, _ := writeTruncatedMP4(t, 3)
+	pair, _, _ := loadTestCodecPair(t)
+
+	dst := src + ".recovered.mp4"
+	t.Cleanup(func() { os.Remove(dst) })
+	_, err := RecoverFile(src, dst, pair)
+	require.NoError(t, err)
+
+	_, err = RecoverFile(dst, dst+".again", pair)
+	t.Cleanup(func() { os.Remove(dst + ".again") })
+	require.Error(t, err)
+	assert.NoFileExists(t, dst+".again", "partial output is removed")
+}
+
+func TestRecover_SmallWindowMatchesWholePayload(t *testing.T) {
+	t.Parallel()
+	src, wantVideo, wantAudio := writeTruncatedMP4(t, 40)
+	fi, err := os.Stat(src)
+	require.NoError(t, err)
+	require.NoError(t, os.Truncate(src, fi.Size()-2))
+	pair, _, _ := loadTestCodecPair(t)
+	start := WithRecoverStartTime(time.Unix(0, 0))
+
+	whole := src + ".whole.mp4"
+	t.Cleanup(func() { os.Remove(whole) })
+	wantStats, err := RecoverFile(src, whole, pair, start)
+	require.NoError(t, err)
+
+	// A 64-byte window slides many times over the payload.
+	windowed := src + ".windowed.mp4"
+	t.Cleanup(func() { os.Remove(windowed) })
+	stats, err := RecoverFile(src, windowed, pair, start, func(c *recoverConfig) { c.window = 64 })
+	require.NoError(t, err)
+	assert.Equal(t, wantStats, stats)
+	assert.Equal(t, wantVideo, stats.VideoSamples)
+	assert.Equal(t, wantAudio-1, stats.AudioSamples)
+	assert.True(t, stats.Truncated)
+
+	wantData, err := os.ReadFile(whole)
+	require.NoError(t, err)
+	data, err := os.ReadFile(windowed)
+	require.NoError(t, err)
+	assert.Equal(t, wantData, data)
+}
+
+// Large files — co64, 64-bit box sizes and header versions
+
+func TestSampleTable_ChunkOffset_Co64RoundTrip(t *testing.T) {
+	t.Parallel()
+	const beyond4GiB = uint64(5) << 30
+	stbl := mp4io.SampleTable{
+		ChunkOffset: &mp4io.ChunkOffset{Entries: []uint64{56, 1 << 31, beyond4GiB}},
+	}
+	assert.Equal(t, mp4io.CO64, stbl.ChunkOffset.Tag())
+
+	b := make([]byte, stbl.Len())
+	stbl.Marshal(b)
+	assert.Equal(t, mp4io.CO64, readTag(b, 8))
+
+	var parsed mp4io.SampleTable
+	_, err := parsed.Unmarshal(b, 0)
+	require.NoError(t, err)
+	require.NotNil(t, parsed.ChunkOffset)
+	assert.Equal(t, []uint64{56, 1 << 31, beyond4GiB}, parsed.ChunkOffset.Entries)
+}
+
+func TestSampleTable_ChunkOffset_StcoWhenSmall(t *testing.T) {
+	t.Parallel()
+	co := mp4io.ChunkOffset{Entries: []uint64{56, math.MaxUint32}}
+	assert.Equal(t, mp4io.STCO, co.Tag())
+	assert.Equal(t, 16+4*2, co.Len())
+}
+
+func TestReadFileAtoms_LargeSizeAndToEOF(t *testing.T) {
+	t.Parallel()
+	var buf []byte
+	// free box with a 64-bit largesize header.
+	free := make([]byte, 24)
+	pio.PutU32BE(free[0:], 1)
+	pio.PutU32BE(free[4:], uint32(mp4io.StringToTag("free")))
+	pio.PutU64BE(free[8:], 24)
+	buf = append(buf, free...)
+	// mdat with size 0: extends to end of file.
+	mdat := make([]byte, 20)
+	pio.PutU32BE(mdat[4:], uint32(mp4io.MDAT))
+	buf = append(buf, mdat...)
+
+	f, err := os.CreateTemp("", "gomedia_test_*.mp4")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.Remove(f.Name()) })
+	defer f.Close()
+	_, err = f.Write(buf)
+	require.NoError(t, err)
+	_, err = f.Seek(0, io.SeekStart)
+	require.NoError(t, err)
+
+	atoms, err := mp4io.ReadFileAtoms(f)
+	require.NoError(t, err)
+	require.Len(t, atoms, 2)
+	_, size := atoms[0].Pos()
+	assert.Equal(t, 24, size)
+	offset, size := atoms[1].Pos()
+	assert.Equal(t, 24, offset)
+	assert.Equal(t, 20, size)
+}
+
+func TestMuxer_LongDuration_UsesVersion1Headers(t *testing.T) {
+	t.Parallel()
+	pair, videoCp, _ := loadTestCodecPair(t)
+	pair.AudioCodecParameters = nil
+
+	mux := NewMuxer(nil)
+	require.NoError(t, mux.Mux(pair))
+	require.NoError(t, mux.WritePacket(h264.NewPacket(true, 0, time.Now(), []byte{0x01}, "test", videoCp)))
+	stream := mux.streams[0]
+	stream.duration = int64(14 * time.Hour / time.Second * 90000) // > 2^31 ticks at 90 kHz
+
+	b, err := mux.buildMoov()
+	require.NoError(t, err)
+	mux.ReleasePending()
+
+	var moov mp4io.Movie
+	_, err = moov.Unmarshal(b, 0)
+	require.NoError(t, err)
+	require.Len(t, moov.Tracks, 1)
+	// mvhd/tkhd use the 1 kHz movie timescale and still fit in 32 bits;
+	// the 90 kHz media timescale does not.
+	assert.Equal(t, uint8(0), moov.Header.Version)
+	assert.Equal(t, uint8(1), moov.Tracks[0].Media.Header.Version)
+	assert.Equal(t, stream.duration, moov.Tracks[0].Media.Header.Duration)
+}
+
+func TestEditList_MarshalRoundTrip(t *testing.T) {
+	t.Parallel()
+	for _, entries := range [][]mp4io.EditListEntry{
+		{{SegmentDuration: 200, MediaTime: mp4io.EmptyEdit, MediaRateInteger: 1}, {SegmentDuration: 4000, MediaTime: 1800, MediaRateInteger: 1}},
+		{{SegmentDuration: math.MaxUint32 + 1, MediaTime: 0, MediaRateInteger: 1}},
+	} {
+		edit := &mp4io.Edit{List: &mp4io.EditList{Entries: entries}}
+		b := make([]byte, edit.Len())
+		require.Equal(t, len(b), edit.Marshal(b))
+
+		var got mp4io.Edit
+		_, err := got.Unmarshal(b, 0)
+		require.NoError(t, err)
+		require.NotNil(t, got.List)
+		assert.Equal(t, entries, got.List.Entries)
+	}
+}
+
+func TestMuxer_EditList_DelaysLateTrack(t *testing.T) {
+	t.Parallel()
+	pair, videoCp, audioCp := loadTestCodecPair(t)
+
+	f, err := os.CreateTemp("", "gomedia_test_*.mp4")
+	require.NoError(t, err)
+	path := f.Name()
+	t.Cleanup(func() { os.Remove(path) })
+
+	mux := NewMuxer(f)
+	require.NoError(t, mux.Mux(pair))
+
+	base := time.Now()
+	const frameDur = 40 * time.Millisecond
+	const videoDelay = 200 * time.Millisecond
+	aacFrame := []byte{0x01, 0x40, 0x20, 0x55, 0x07}
+	for i := range 20 {
+		ts := time.Duration(i) * frameDur
+		require.NoError(t, mux.WritePacket(aac.NewPacket(append([]byte(nil), aacFrame...), ts, "test", base, audioCp, frameDur)))
+		if ts >= videoDelay {
+			data := []byte{0, 0, 0, 6, 0x65, 0x88, 0x84, 0x00, 0x33, 0xff}
+			require.NoError(t, mux.WritePacket(h264.NewPacket(true, ts, base, data, "test", videoCp)))
+		}
+	}
+	require.NoError(t, mux.WriteTrailer())
+	require.NoError(t, f.Close())
+
+	atoms, err := func() ([]mp4io.Atom, error) {
+		rf, openErr := os.Open(path)
+		require.NoError(t, openErr)
+		defer rf.Close()
+		return mp4io.ReadFileAtoms(rf)
+	}()
+	require.NoError(t, err)
+	var moov *mp4io.Movie
+	for _, atom := range atoms {
+		if m, ok := atom.(*mp4io.Movie); ok {
+			moov = m
+		}
+	}
+	require.NotNil(t, moov)
+	for _, track := range moov.Tracks {
+		if track.Media.Handler.SubType == [4]byte{'v', 'i', 'd', 'e'} {
+			require.NotNil(t, track.Edit)
+			require.Len(t, track.Edit.List.Entries, 2)
+			assert.Equal(t, int64(mp4io.EmptyEdit), track.Edit.List.Entries[0].MediaTime)
+			assert.Equal(t, uint64(videoDelay/time.Millisecond), track.Edit.List.Entries[0].SegmentDuration)
+		} else {
+			assert.Nil(t, track.Edit, "audio starts the timeline and needs no edit")
+		}
+	}
+
+	dmx := NewDemuxer(path)
+	defer dmx.Close()
+	_, err = dmx.Demux()
+	require.NoError(t, err)
+	for {
+		pkt, readErr := dmx.ReadPacket()
+		if errors.Is(readErr, io.EOF) {
+			t.Fatal("no video packet demuxed")
+		}
+		require.NoError(t, readErr)
+		if _, ok := pkt.(gomedia.VideoPacket); ok {
+			assert.Equal(t, videoDelay, pkt.Timestamp())
+			pkt.Release()
+			break
+		}
+		pkt.Release()
+	}
+}
+
+func TestMuxer_Metadata_RoundTrip(t *testing.T) {
+	t.Parallel()
+	pair, videoCp, _ := loadTestCodecPair(t)
+	pair.AudioCodecParameters = nil
+
+	f, err := os.CreateTemp("", "gomedia_test_*.mp4")
+	require.NoError(t, err)
+	path := f.Name()
+	t.Cleanup(func() { os.Remove(path) })
+
+	mux := NewMuxer(f,
+		WithMetadata(MetadataKeyCameraName, "gate-1"),
+		WithMetadata(MetadataKeyLocation, "+55.7558+037.6173/"),
+	)
+	require.NoError(t, mux.Mux(pair))
+	mux.SetMetadata(MetadataKeyRecordMode, "event")
+	mux.SetMetadata(MetadataKeyCameraName, "gate-2")
+
+	base := time.Now()
+	for i := range 5 {
+		data := []byte{0, 0, 0, 6, 0x65, 0x88, 0x84, 0x00, 0x33, 0xff}
+		require.NoError(t, mux.WritePacket(h264.NewPacket(true, time.Duration(i)*40*time.Millisecond, base, data, "test", videoCp)))
+	}
+	require.NoError(t, mux.WriteTrailer())
+	require.NoError(t, f.Close())
+
+	dmx := NewDemuxer(path)
+	defer dmx.Close()
+	_, err = dmx.Demux()
+	require.NoError(t, err)
+	mp4Dmx, ok := dmx.(*Demuxer)
+	require.True(t, ok)
+	assert.Equal(t, map[string]string{
+		MetadataKeyCameraName: "gate-2",
+		MetadataKeyLocation:   "+55.7558+037.6173/",
+		MetadataKeyRecordMode: "event",
+	}, mp4Dmx.Metadata())
+}
+
+func TestMuxer_TimedMetadataTrack(t *testing.T) {
+	t.Parallel()
+	pair, videoCp, _ := loadTestCodecPair(t)
+	pair.AudioCodecParameters = nil
+
+	f, err := os.CreateTemp("", "gomedia_test_*.mp4")
+	require.NoError(t, err)
+	path := f.Name()
+	t.Cleanup(func() { os.Remove(path) })
+
+	mux := NewMuxer(f, WithTimedMetadata(MetadataMIMEJSON))
+	require.NoError(t, mux.Mux(pair))
+
+	base := time.Now()
+	for i := range 25 {
+		ts := time.Duration(i) * 40 * time.Millisecond
+		data := []byte{0, 0, 0, 6, 0x65, 0x88, 0x84, 0x00, 0x33, 0xff}
+		require.NoError(t, mux.WritePacket(h264.NewPacket(true, ts, base, data, "test", videoCp)))
+		switch i {
+		case 5:
+			require.NoError(t, mux.WriteMetadataSample(ts, 100*time.Millisecond, []byte(`{"event":"motion"}`)))
+		case 15:
+			require.NoError(t, mux.WriteMetadataSample(ts, 0, []byte(`{"event":"clear"}`)))
+		}
+	}
+	require.NoError(t, mux.WriteTrailer())
+	require.NoError(t, f.Close())
+
+	rf, err := os.Open(path)
+	require.NoError(t, err)
+	defer rf.Close()
+	atoms, err := mp4io.ReadFileAtoms(rf)
+	require.NoError(t, err)
+	var moov *mp4io.Movie
+	for _, atom := range atoms {
+		if m, isMoov := atom.(*mp4io.Movie); isMoov {
+			moov = m
+		}
+	}
+	require.NotNil(t, moov)
+	require.Len(t, moov.Tracks, 2)
+	assert.Equal(t, int32(3), moov.Header.NextTrackID)
+
+	track := moov.Tracks[1]
+	assert.Equal(t, [4]byte{'m', 'e', 't', 'a'}, track.Media.Handler.SubType)
+	require.NotNil(t, track.Media.Info.Null)
+	stbl := track.Media.Info.Sample
+	require.NotNil(t, stbl.SampleDesc.METTDesc)
+	assert.Equal(t, MetadataMIMEJSON, stbl.SampleDesc.METTDesc.MimeFormat)
+
+	// motion (100ms), empty gap (300ms), clear (1ms minimum)
+	assert.Equal(t, []uint32{18, 0, 17}, stbl.SampleSize.Entries)
+	assert.Equal(t, []mp4io.TimeToSampleEntry{{Count: 1, Duration: 100}, {Count: 1, Duration: 300}, {Count: 1, Duration: 1}},
+		stbl.TimeToSample.Entries)
+
+	// The track starts 200ms into the movie.
+	require.NotNil(t, track.Edit)
+	assert.Equal(t, int64(mp4io.EmptyEdit), track.Edit.List.Entries[0].MediaTime)
+	assert.Equal(t, uint64(200), track.Edit.List.Entries[0].SegmentDuration)
+
+	// Sample bytes land where the chunk offsets say.
+	buf := make([]byte, 18)
+	_, err = rf.ReadAt(buf, int64(stbl.ChunkOffset.Entries[0]))
+	require.NoError(t, err)
+	assert.Equal(t, `{"event":"motion"}`, string(buf))
+
+	// The demuxer ignores the metadata track.
+	dmx := NewDemuxer(path)
+	defer dmx.Close()
+	params, err := dmx.Demux()
+	require.NoError(t, err)
+	assert.NotNil(t, params.VideoCodecParameters)
+	assert.Nil(t, params.AudioCodecParameters)
+}
+
+func TestMuxer_WriteMetadataSample_RequiresTrack(t *testing.T) {
+	t.Parallel()
+	mux := NewMuxer(nil)
+	require.Error(t, mux.WriteMetadataSample(0, time.Second, []byte("x")))
+}
+
+func TestDemuxerFromReader_MatchesFileDemuxer(t *testing.T) {
+	t.Parallel()
+	path, _, _ := writeTruncatedMP4(t, 10)
+	src, err := os.Open(path)
+	require.NoError(t, err)
+	recovered := path + ".recovered.mp4"
+	t.Cleanup(func() { os.Remove(recovered) })
+	pair, _, _ := loadTestCodecPair(t)
+	dst, err := os.Create(recovered)
+	require.NoError(t, err)
+	_, err = Recover(src, dst, pair)
+	require.NoError(t, err)
+	require.NoError(t, src.Close())
+	require.NoError(t, dst.Close())
+
+	data, err := os.ReadFile(recovered)
+	require.NoError(t, err)
+
+	dmx := NewDemuxerFromReader(bytes.NewReader(data), "memory://clip")
+	params, err := dmx.Demux()
+	require.NoError(t, err)
+	assert.Equal(t, "memory://clip", params.SourceID)
+
+	var fromReader []gomedia.Packet
+	for {
+		pkt, readErr := dmx.ReadPacket()
+		if errors.Is(readErr, io.EOF) {
+			break
+		}
+		require.NoError(t, readErr)
+		assert.Equal(t, "memory://clip", pkt.SourceID())
+		fromReader = append(fromReader, pkt)
+	}
+	dmx.Close()
+	require.NotEmpty(t, fromReader)
+
+	fileDmx := NewDemuxer(recovered)
+	defer fileDmx.Close()
+	_, err = fileDmx.Demux()
+	require.NoError(t, err)
+	for i, want := range fromReader {
+		got, readErr := fileDmx.ReadPacket()
+		require.NoError(t, readErr, "packet %d", i)
+		assert.Equal(t, want.Timestamp(), got.Timestamp())
+		assert.Equal(t, want.Data(), got.Data())
+		got.Release()
+		want.Release()
+	}
+	_, err = fileDmx.ReadPacket()
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+// createPlaybackMP4 writes a video-only file of n 40ms frames with a keyframe
+// every 5 frames.
+func createPlaybackMP4(t *testing.T, n int) string {
+	t.Helper()
+	pair, videoCp, _ := loadTestCodecPair(t)
+	pair.AudioCodecParameters = nil
+	packets := make([]gomedia.Packet, 0, n)
+	for i := range n {
+		pkt := h264.NewPacket(i%5 == 0, time.Duration(i)*40*time.Millisecond, time.Now(),
+			[]byte{0, 0, 0, 2, 0x65, byte(i)}, "test", videoCp)
+		pkt.SetDuration(40 * time.Millisecond)
+		packets = append(packets, pkt)
+	}
+	return createTempMP4(t, pair, packets)
+}
+
+func TestPlayback_PacesByTimestamp(t *testing.T) {
+	t.Parallel()
+	path := createPlaybackMP4(t, 10)
+
+	dmx := NewPlaybackDemuxer(path, WithPlaybackRate(4))
+	defer dmx.Close()
+	_, err := dmx.Demux()
+	require.NoError(t, err)
+
+	start := time.Now()
+	var first time.Time
+	for i := range 10 {
+		pkt, readErr := dmx.ReadPacket()
+		require.NoError(t, readErr)
+		want := time.Duration(i) * 40 * time.Millisecond
+		assert.Equal(t, want, pkt.Timestamp())
+		if i == 0 {
+			first = pkt.StartTime()
+		}
+		assert.Equal(t, want/4, pkt.StartTime().Sub(first))
+		assert.GreaterOrEqual(t, time.Since(start), want/4-5*time.Millisecond)
+		pkt.Release()
+	}
+	_, err = dmx.ReadPacket()
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestPlayback_LoopKeepsTimestampsMonotonic(t *testing.T) {
+	t.Parallel()
+	path := createPlaybackMP4(t, 10)
+
+	dmx := NewPlaybackDemuxer(path, WithLoop(), WithPlaybackRate(100))
+	defer dmx.Close()
+	params, err := dmx.Demux()
+	require.NoError(t, err)
+
+	for i := range 25 {
+		pkt, readErr := dmx.ReadPacket()
+		require.NoError(t, readErr)
+		vPkt, ok := pkt.(gomedia.VideoPacket)
+		require.True(t, ok)
+		assert.Equal(t, time.Duration(i)*40*time.Millisecond, pkt.Timestamp(), "packet %d", i)
+		assert.Equal(t, i%5 == 0, vPkt.IsKeyFrame(), "packet %d", i)
+		assert.Equal(t, []byte{0, 0, 0, 2, 0x65, byte(i % 10)}, pkt.Data())
+		assert.Same(t, params.VideoCodecParameters, vPkt.CodecParameters())
+		pkt.Release()
+	}
+}
+
+func TestPlayback_StartOffset(t *testing.T) {
+	t.Parallel()
+	path := createPlaybackMP4(t, 10)
+
+	dmx := NewPlaybackDemuxer(path, WithStartOffset(100*time.Millisecond), WithPlaybackRate(100))
+	defer dmx.Close()
+	_, err := dmx.Demux()
+	require.NoError(t, err)
+
+	// Playback starts at the keyframe at 200ms.
+	for i := range 5 {
+		pkt, readErr := dmx.ReadPacket()
+		require.NoError(t, readErr)
+		assert.Equal(t, time.Duration(i)*40*time.Millisecond, pkt.Timestamp())
+		assert.Equal(t, []byte{0, 0, 0, 2, 0x65, byte(i + 5)}, pkt.Data())
+		pkt.Release()
+	}
+	_, err = dmx.ReadPacket()
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+// readAtRecorder records the offsets of the sample reads of a demuxer.
+type readAtRecorder struct {
+	*os.File
+	offsets []int64
+}
+
+func (r *readAtRecorder) ReadAt(b []byte, off int64) (int, error) {
+	r.offsets = append(r.offsets, off)
+	return r.File.ReadAt(b, off)
+}
+
+func TestPlayback_StartOffsetSeeksPastSkippedSamples(t *testing.T) {
+	t.Parallel()
+	path := createPlaybackMP4(t, 10)
+	skipped := demuxAndGetMoov(t, path).Tracks[0].Media.Info.Sample.ChunkOffset.Entries[:5]
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	r := &readAtRecorder{File: f}
+	dmx := NewPlaybackDemuxerFromReader(r, "test", WithStartOffset(100*time.Millisecond), WithPlaybackRate(100))
+	defer dmx.Close()
+	_, err = dmx.Demux()
+	require.NoError(t, err)
+
+	pkt, err := dmx.ReadPacket()
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0, 0, 0, 2, 0x65, 5}, pkt.Data())
+	pkt.Release()
+	for _, off := range skipped {
+		assert.NotContains(t, r.offsets, int64(off)) //nolint:gosec
+	}
+}
+
+func TestPlayback_CloseInterruptsWait(t *testing.T) {
+	t.Parallel()
+	path := createPlaybackMP4(t, 10)
+
+	dmx := NewPlaybackDemuxer(path, WithPlaybackRate(0.001))
+	_, err := dmx.Demux()
+	require.NoError(t, err)
+	pkt, err := dmx.ReadPacket()
+	require.NoError(t, err)
+	pkt.Release()
+
+	time.AfterFunc(20*time.Millisecond, dmx.Close)
+	start := time.Now()
+	_, err = dmx.ReadPacket()
+	assert.ErrorIs(t, err, io.EOF)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestDemuxer_EditList_MediaTimeKeepsDecodeTimes(t *testing.T) {
+	t.Parallel()
+	const frames = 10
+	const frameDur = 40 * time.Millisecond
+	path := createPlaybackMP4(t, frames)
+
+	// Rewrite the trailing moov as a B-frame encoder would: every sample
+	// presented two frames after its decode time, and an edit whose
+	// media_time skips that delay, behind a 100 ms empty edit.
+	moov := demuxAndGetMoov(t, path)
+	require.Len(t, moov.Tracks, 1)
+	track := moov.Tracks[0]
+	mediaScale := int64(track.Media.Header.TimeScale)
+	delay := uint32(2 * frameDur * time.Duration(mediaScale) / time.Second) //nolint:gosec
+	track.Media.Info.Sample.CompositionOffset = &mp4io.CompositionOffset{
+		Entries: []mp4io.CompositionOffsetEntry{{Count: frames, Offset: delay}},
+	}
+	movieScale := uint64(moov.Header.TimeScale) //nolint:gosec
+	track.Edit = &mp4io.Edit{List: &mp4io.EditList{Entries: []mp4io.EditListEntry{
+		{SegmentDuration: movieScale / 10, MediaTime: mp4io.EmptyEdit, MediaRateInteger: 1},
+		{SegmentDuration: movieScale * frames * 40 / 1000, MediaTime: int64(delay), MediaRateInteger: 1},
+	}}}
+	rewriteMoov(t, path, moov)
+
+	dmx := NewDemuxer(path)
+	defer dmx.Close()
+	_, err := dmx.Demux()
+	require.NoError(t, err)
+	for i := range frames {
+		pkt, readErr := dmx.ReadPacket()
+		require.NoError(t, readErr)
+		assert.Equal(t, 100*time.Millisecond+time.Duration(i)*frameDur, pkt.Timestamp(), "packet %d", i)
+		pkt.Release()
+	}
+}
+
+func TestDemuxer_EditList_SubtractsMediaTime(t *testing.T) {
+	t.Parallel()
+	const frames = 10
+	const frameDur = 40 * time.Millisecond
+	path := createPlaybackMP4(t, frames)
+
+	// A 100 ms empty edit, then a media edit trimming the first two frames.
+	moov := demuxAndGetMoov(t, path)
+	require.Len(t, moov.Tracks, 1)
+	track := moov.Tracks[0]
+	mediaScale := int64(track.Media.Header.TimeScale)
+	movieScale := uint64(moov.Header.TimeScale) //nolint:gosec
+	track.Edit = &mp4io.Edit{List: &mp4io.EditList{Entries: []mp4io.EditListEntry{
+		{SegmentDuration: movieScale / 10, MediaTime: mp4io.EmptyEdit, MediaRateInteger: 1},
+		{SegmentDuration: movieScale * (frames - 2) * 40 / 1000, MediaTime: int64(2 * frameDur * time.Duration(mediaScale) / time.Second), MediaRateInteger: 1},
+	}}}
+	rewriteMoov(t, path, moov)
+
+	dmx := NewDemuxer(path)
+	defer dmx.Close()
+	_, err := dmx.Demux()
+	require.NoError(t, err)
+	for i := range frames {
+		pkt, readErr := dmx.ReadPacket()
+		require.NoError(t, readErr)
+		assert.Equal(t, 100*time.Millisecond+time.Duration(i-2)*frameDur, pkt.Timestamp(), "packet %d", i)
+		pkt.Release()
+	}
+}
+
+// rewriteMoov replaces the trailing moov of the file at path.
+func rewriteMoov(t *testing.T, path string, moov *mp4io.Movie) {
+	t.Helper()
+	b := make([]byte, moov.Len())
+	moov.Marshal(b)
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	require.NoError(t, err)
+	require.NoError(t, f.Truncate(int64(moov.Offset)))
+	_, err = f.WriteAt(b, int64(moov.Offset))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+}
+
+func TestDemuxer_KeyframesAndDuration(t *testing.T) {
+	t.Parallel()
+	dmx := NewDemuxer(createPlaybackMP4(t, 12)).(*Demuxer)
+	defer dmx.Close()
+	_, err := dmx.Demux()
+	require.NoError(t, err)
+
+	keys, err := dmx.Keyframes()
+	require.NoError(t, err)
+	assert.Equal(t, []time.Duration{0, 200 * time.Millisecond, 400 * time.Millisecond}, keys)
+	end, err := dmx.Duration()
+	require.NoError(t, err)
+	assert.Equal(t, 480*time.Millisecond, end)
+}
+
+func TestDemuxer_SeekTo_MatchesSequentialRead(t *testing.T) {
+	t.Parallel()
+	const frames = 20
+	path := createPlaybackMP4(t, frames)
+
+	// Regroup the one-sample chunks the Muxer writes into runs of 3 and
+	// then 2 samples, so seeking has to resolve stsc runs and offsets
+	// inside a chunk.
+	moov := demuxAndGetMoov(t, path)
+	sample := moov.Tracks[0].Media.Info.Sample
+	offsets := sample.ChunkOffset.Entries
+	require.Len(t, offsets, frames)
+	var chunks []uint64
+	for i := 0; i < 9; i += 3 {
+		chunks = append(chunks, offsets[i])
+	}
+	for i := 9; i < frames; i += 2 {
+		chunks = append(chunks, offsets[i])
+	}
+	sample.ChunkOffset.Entries = chunks
+	sample.SampleToChunk.Entries = []mp4io.SampleToChunkEntry{
+		{FirstChunk: 1, SamplesPerChunk: 3, SampleDescId: 1},
+		{FirstChunk: 4, SamplesPerChunk: 2, SampleDescId: 1},
+	}
+	b := make([]byte, moov.Len())
+	moov.Marshal(b)
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	require.NoError(t, err)
+	require.NoError(t, f.Truncate(int64(moov.Offset)))
+	_, err = f.WriteAt(b, int64(moov.Offset))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	type read struct {
+		ts   time.Duration
+		key  bool
+		data string
+	}
+	readAll := func(dmx *Demuxer) (out []read) {
+		for {
+			pkt, readErr := dmx.ReadPacket()
+			if errors.Is(readErr, io.EOF) {
+				return out
+			}
+			require.NoError(t, readErr)
+			vPkt, _ := pkt.(gomedia.VideoPacket)
+			out = append(out, read{pkt.Timestamp(), vPkt.IsKeyFrame(), string(pkt.Data())})
+			pkt.Release()
+		}
+	}
+
+	dmx := NewDemuxer(path).(*Demuxer)
+	defer dmx.Close()
+	_, err = dmx.Demux()
+	require.NoError(t, err)
+	all := readAll(dmx)
+	require.Len(t, all, frames)
+
+	for _, tm := range []time.Duration{0, 40 * time.Millisecond, 130 * time.Millisecond, 400 * time.Millisecond, 760 * time.Millisecond, time.Second} {
+		require.NoError(t, dmx.SeekTo(tm))
+		var want []read
+		for _, r := range all {
+			if r.ts >= tm {
+				want = append(want, r)
+			}
+		}
+		assert.Equal(t, want, readAll(dmx), "seek to %v", tm)
+	}
+}
@@ -0,0 +1,85 @@
+package mp4io
+
+import "github.com/ugparu/gomedia/utils/bits/pio"
+
+const EDTS = Tag(0x65647473)
+
+func (self Edit) Tag() Tag {
+	return EDTS
+}
+
+// Edit is the edts container (ISO 14496-12 §8.6.5). It maps the media
+// timeline of a track onto the movie presentation timeline through its
+// edit list.
+type Edit struct {
+	List     *EditList
+	Unknowns []Atom
+	AtomPos
+}
+
+func (self Edit) Marshal(b []byte) (n int) {
+	pio.PutU32BE(b[4:], uint32(EDTS))
+	n += self.marshal(b[8:]) + 8
+	pio.PutU32BE(b[0:], uint32(n))
+	return
+}
+func (self Edit) marshal(b []byte) (n int) {
+	if self.List != nil {
+		n += self.List.Marshal(b[n:])
+	}
+	for _, atom := range self.Unknowns {
+		n += atom.Marshal(b[n:])
+	}
+	return
+}
+func (self Edit) Len() (n int) {
+	n += 8
+	if self.List != nil {
+		n += self.List.Len()
+	}
+	for _, atom := range self.Unknowns {
+		n += atom.Len()
+	}
+	return
+}
+func (self *Edit) Unmarshal(b []byte, offset int) (n int, err error) {
+	(&self.AtomPos).setPos(offset, len(b))
+	n += 8
+	for n+8 <= len(b) {
+		tag := Tag(pio.U32BE(b[n+4:]))
+		size := int(pio.U32BE(b[n:]))
+		if size < 8 || len(b) < n+size {
+			err = parseErr("TagSizeInvalid", n+offset, err)
+			return
+		}
+		switch tag {
+		case ELST:
+			{
+				atom := &EditList{}
+				if _, err = atom.Unmarshal(b[n:n+size], offset+n); err != nil {
+					err = parseErr("elst", n+offset, err)
+					return
+				}
+				self.List = atom
+			}
+		default:
+			{
+				atom := &Dummy{Tag_: tag, Data: b[n : n+size]}
+				if _, err = atom.Unmarshal(b[n:n+size], offset+n); err != nil {
+					err = parseErr("", n+offset, err)
+					return
+				}
+				self.Unknowns = append(self.Unknowns, atom)
+			}
+		}
+		n += size
+	}
+	return
+}
+func (self Edit) Children() (r []Atom) {
+	if self.List != nil {
+		r = append(r, self.List)
+	}
+	r = append(r, self.Unknowns...)
+	return
+}
@@ -0,0 +1,154 @@
+package mp4io
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/ugparu/gomedia/utils/bits/pio"
+)
+
+func (self EditList) String() string {
+	return fmt.Sprintf("entries=%d", len(self.Entries))
+}
+
+const ELST = Tag(0x656c7374)
+
+func (self EditList) Tag() Tag {
+	return ELST
+}
+
+// EmptyEdit is the media_time value of an empty edit: the segment plays
+// nothing for SegmentDuration, delaying the start of the track.
+const EmptyEdit = -1
+
+// EditListEntry maps SegmentDuration (movie timescale) of presentation time
+// onto the media timeline starting at MediaTime (media timescale).
+type EditListEntry struct {
+	SegmentDuration   uint64
+	MediaTime         int64
+	MediaRateInteger  int16
+	MediaRateFraction int16
+}
+
+// EditList is the elst box (ISO 14496-12 §8.6.6). Version 1 (64-bit fields)
+// is selected on Marshal whenever an entry does not fit the 32-bit layout.
+type EditList struct {
+	Version uint8
+	Flags   uint32
+	Entries []EditListEntry
+	AtomPos
+}
+
+func (self EditList) needs64() bool {
+	if self.Version == 1 {
+		return true
+	}
+	for _, entry := range self.Entries {
+		if entry.SegmentDuration > math.MaxUint32 || entry.MediaTime > math.MaxInt32 || entry.MediaTime < math.MinInt32 {
+			return true
+		}
+	}
+	return false
+}
+
+func (self EditList) Marshal(b []byte) (n int) {
+	pio.PutU32BE(b[4:], uint32(ELST))
+	n += self.marshal(b[8:]) + 8
+	pio.PutU32BE(b[0:], uint32(n))
+	return
+}
+func (self EditList) marshal(b []byte) (n int) {
+	use64 := self.needs64()
+	version := uint8(0)
+	if use64 {
+		version = 1
+	}
+	pio.PutU8(b[n:], version)
+	n += 1
+	pio.PutU24BE(b[n:], self.Flags)
+	n += 3
+	pio.PutU32BE(b[n:], uint32(len(self.Entries)))
+	n += 4
+	for _, entry := range self.Entries {
+		if use64 {
+			pio.PutU64BE(b[n:], entry.SegmentDuration)
+			n += 8
+			pio.PutI64BE(b[n:], entry.MediaTime)
+			n += 8
+		} else {
+			pio.PutU32BE(b[n:], uint32(entry.SegmentDuration)) //nolint:gosec
+			n += 4
+			pio.PutI32BE(b[n:], int32(entry.MediaTime)) //nolint:gosec
+			n += 4
+		}
+		pio.PutI16BE(b[n:], entry.MediaRateInteger)
+		n += 2
+		pio.PutI16BE(b[n:], entry.MediaRateFraction)
+		n += 2
+	}
+	return
+}
+func (self EditList) Len() (n int) {
+	n += 8
+	n += 1
+	n += 3
+	n += 4
+	if self.needs64() {
+		n += 20 * len(self.Entries)
+	} else {
+		n += 12 * len(self.Entries)
+	}
+	return
+}
+func (self *EditList) Unmarshal(b []byte, offset int) (n int, err error) {
+	(&self.AtomPos).setPos(offset, len(b))
+	n += 8
+	if len(b) < n+1 {
+		err = parseErr("Version", n+offset, err)
+		return
+	}
+	self.Version = pio.U8(b[n:])
+	n += 1
+	if len(b) < n+3 {
+		err = parseErr("Flags", n+offset, err)
+		return
+	}
+	self.Flags = pio.U24BE(b[n:])
+	n += 3
+	if len(b) < n+4 {
+		err = parseErr("EntryCount", n+offset, err)
+		return
+	}
+	var _len_Entries uint32 = pio.U32BE(b[n:])
+	n += 4
+	entrySize := 12
+	if self.Version == 1 {
+		entrySize = 20
+	}
+	if uint64(len(b)-n) < uint64(_len_Entries)*uint64(entrySize) {
+		err = parseErr("EditListEntry", n+offset, err)
+		return
+	}
+	self.Entries = make([]EditListEntry, _len_Entries)
+	for i := range self.Entries {
+		if self.Version == 1 {
+			self.Entries[i].SegmentDuration = pio.U64BE(b[n:])
+			n += 8
+			self.Entries[i].MediaTime = pio.I64BE(b[n:])
+			n += 8
+		} else {
+			self.Entries[i].SegmentDuration = uint64(pio.U32BE(b[n:]))
+			n += 4
+			self.Entries[i].MediaTime = int64(pio.I32BE(b[n:]))
+			n += 4
+		}
+		self.Entries[i].MediaRateInteger = pio.I16BE(b[n:])
+		n += 2
+		self.Entries[i].MediaRateFraction = pio.I16BE(b[n:])
+		n += 2
+	}
+	return
+}
+func (self EditList) Children() (r []Atom) {
+	return
+}
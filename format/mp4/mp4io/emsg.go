@@ -0,0 +1,148 @@
+package mp4io
+
+import (
+	"bytes"
+
+	"github.com/ugparu/gomedia/utils/bits/pio"
+)
+
+const EMSG = Tag(0x656d7367)
+
+func (self EventMessage) Tag() Tag {
+	return EMSG
+}
+
+// EventMessage is the DASH/CMAF emsg box (ISO 23009-1 §5.10.3.3) carrying an
+// in-band event ahead of a fragment. Version 0 places the event relative to
+// the segment start (PresentationTimeDelta), version 1 on the absolute media
+// timeline (PresentationTime).
+type EventMessage struct {
+	Version               uint8
+	Flags                 uint32
+	SchemeIDURI           string
+	Value                 string
+	TimeScale             uint32
+	PresentationTimeDelta uint32
+	PresentationTime      uint64
+	EventDuration         uint32
+	ID                    uint32
+	MessageData           []byte
+	AtomPos
+}
+
+func (self EventMessage) Marshal(b []byte) (n int) {
+	pio.PutU32BE(b[4:], uint32(EMSG))
+	n += self.marshal(b[8:]) + 8
+	pio.PutU32BE(b[0:], uint32(n))
+	return
+}
+func (self EventMessage) marshal(b []byte) (n int) {
+	pio.PutU8(b[n:], self.Version)
+	n += 1
+	pio.PutU24BE(b[n:], self.Flags)
+	n += 3
+	putString := func(s string) {
+		n += copy(b[n:], s)
+		b[n] = 0
+		n += 1
+	}
+	if self.Version == 0 {
+		putString(self.SchemeIDURI)
+		putString(self.Value)
+		pio.PutU32BE(b[n:], self.TimeScale)
+		n += 4
+		pio.PutU32BE(b[n:], self.PresentationTimeDelta)
+		n += 4
+	} else {
+		pio.PutU32BE(b[n:], self.TimeScale)
+		n += 4
+		pio.PutU64BE(b[n:], self.PresentationTime)
+		n += 8
+	}
+	pio.PutU32BE(b[n:], self.EventDuration)
+	n += 4
+	pio.PutU32BE(b[n:], self.ID)
+	n += 4
+	if self.Version != 0 {
+		putString(self.SchemeIDURI)
+		putString(self.Value)
+	}
+	n += copy(b[n:], self.MessageData)
+	return
+}
+func (self EventMessage) Len() (n int) {
+	n += 8
+	n += 1
+	n += 3
+	n += len(self.SchemeIDURI) + 1
+	n += len(self.Value) + 1
+	n += 4
+	if self.Version == 0 {
+		n += 4
+	} else {
+		n += 8
+	}
+	n += 4
+	n += 4
+	n += len(self.MessageData)
+	return
+}
+func (self *EventMessage) Unmarshal(b []byte, offset int) (n int, err error) {
+	(&self.AtomPos).setPos(offset, len(b))
+	n += 8
+	if len(b) < n+4 {
+		err = parseErr("Version", n+offset, err)
+		return
+	}
+	self.Version = pio.U8(b[n:])
+	n += 1
+	self.Flags = pio.U24BE(b[n:])
+	n += 3
+	getString := func(s *string) bool {
+		end := bytes.IndexByte(b[n:], 0)
+		if end < 0 {
+			err = parseErr("String", n+offset, err)
+			return false
+		}
+		*s = string(b[n : n+end])
+		n += end + 1
+		return true
+	}
+	if self.Version == 0 {
+		if !getString(&self.SchemeIDURI) || !getString(&self.Value) {
+			return
+		}
+		if len(b) < n+16 {
+			err = parseErr("EventFields", n+offset, err)
+			return
+		}
+		self.TimeScale = pio.U32BE(b[n:])
+		n += 4
+		self.PresentationTimeDelta = pio.U32BE(b[n:])
+		n += 4
+	} else {
+		if len(b) < n+20 {
+			err = parseErr("EventFields", n+offset, err)
+			return
+		}
+		self.TimeScale = pio.U32BE(b[n:])
+		n += 4
+		self.PresentationTime = pio.U64BE(b[n:])
+		n += 8
+	}
+	self.EventDuration = pio.U32BE(b[n:])
+	n += 4
+	self.ID = pio.U32BE(b[n:])
+	n += 4
+	if self.Version != 0 {
+		if !getString(&self.SchemeIDURI) || !getString(&self.Value) {
+			return
+		}
+	}
+	self.MessageData = b[n:]
+	n += len(b[n:])
+	return
+}
+func (self EventMessage) Children() (r []Atom) {
+	return
+}
@@ -0,0 +1,86 @@
+package mp4io
+
+import "github.com/ugparu/gomedia/utils/bits/pio"
+
+const (
+	ENCV = Tag(0x656e6376)
+	ENCA = Tag(0x656e6361)
+)
+
+// Sizes of the fixed sample entry fields preceding the child boxes.
+const (
+	visualSampleEntryLen = 78
+	audioSampleEntryLen  = 28
+)
+
+func (self ProtectedSampleEntry) Tag() Tag {
+	return self.Tag_
+}
+
+// ProtectedSampleEntry is an encv or enca sample entry (ISO/IEC 14496-12
+// §8.12): the original entry (avc1, hev1, mp4a, ...) renamed, with a sinf
+// box appended after its own children. Entry is marshalled as-is and then
+// patched with the protected tag and the combined size.
+type ProtectedSampleEntry struct {
+	Tag_  Tag
+	Entry Atom
+	Info  *ProtectionSchemeInfo
+	AtomPos
+}
+
+func (self ProtectedSampleEntry) Marshal(b []byte) (n int) {
+	n += self.Entry.Marshal(b)
+	if self.Info != nil {
+		n += self.Info.Marshal(b[n:])
+	}
+	pio.PutU32BE(b[0:], uint32(n))
+	pio.PutU32BE(b[4:], uint32(self.Tag_))
+	return
+}
+func (self ProtectedSampleEntry) Len() (n int) {
+	n += self.Entry.Len()
+	if self.Info != nil {
+		n += self.Info.Len()
+	}
+	return
+}
+
+// Unmarshal extracts the sinf box; the rest of the entry is kept verbatim
+// in Entry.
+func (self *ProtectedSampleEntry) Unmarshal(b []byte, offset int) (n int, err error) {
+	(&self.AtomPos).setPos(offset, len(b))
+	self.Tag_ = Tag(pio.U32BE(b[4:]))
+	n = 8 + audioSampleEntryLen
+	if self.Tag_ == ENCV {
+		n = 8 + visualSampleEntryLen
+	}
+	rest := make([]byte, 0, len(b))
+	rest = append(rest, b[:min(n, len(b))]...)
+	for n+8 <= len(b) {
+		tag := Tag(pio.U32BE(b[n+4:]))
+		size := int(pio.U32BE(b[n:]))
+		if size < 8 || len(b) < n+size {
+			err = parseErr("TagSizeInvalid", n+offset, err)
+			return
+		}
+		if tag == SINF {
+			atom := &ProtectionSchemeInfo{}
+			if _, err = atom.Unmarshal(b[n:n+size], offset+n); err != nil {
+				err = parseErr("sinf", n+offset, err)
+				return
+			}
+			self.Info = atom
+		} else {
+			rest = append(rest, b[n:n+size]...)
+		}
+		n += size
+	}
+	self.Entry = &Dummy{Tag_: self.Tag_, Data: rest}
+	return
+}
+func (self ProtectedSampleEntry) Children() (r []Atom) {
+	if self.Info != nil {
+		r = append(r, self.Info)
+	}
+	return
+}
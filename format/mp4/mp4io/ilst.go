@@ -0,0 +1,105 @@
+package mp4io
+
+import (
+	"fmt"
+
+	"github.com/ugparu/gomedia/utils/bits/pio"
+)
+
+func (self MetadataItemList) String() string {
+	return fmt.Sprintf("items=%d", len(self.Items))
+}
+
+const (
+	ILST = Tag(0x696c7374)
+	DATA = Tag(0x64617461)
+)
+
+func (self MetadataItemList) Tag() Tag {
+	return ILST
+}
+
+// Well-known value types of a metadata data box (QuickTime File Format,
+// "Well-Known Types").
+const (
+	MetadataTypeBinary = 0
+	MetadataTypeUTF8   = 1
+)
+
+// MetadataItem is one ilst entry: the value for key number KeyIndex
+// (1-based into MetadataKeys) wrapped in a single data box.
+type MetadataItem struct {
+	KeyIndex uint32
+	DataType uint32
+	Locale   uint32
+	Value    []byte
+}
+
+// MetadataItemList is the ilst box holding metadata values.
+type MetadataItemList struct {
+	Items []MetadataItem
+	AtomPos
+}
+
+func (self MetadataItemList) Marshal(b []byte) (n int) {
+	pio.PutU32BE(b[4:], uint32(ILST))
+	n += self.marshal(b[8:]) + 8
+	pio.PutU32BE(b[0:], uint32(n))
+	return
+}
+func (self MetadataItemList) marshal(b []byte) (n int) {
+	for _, item := range self.Items {
+		pio.PutU32BE(b[n:], uint32(24+len(item.Value)))
+		pio.PutU32BE(b[n+4:], item.KeyIndex)
+		n += 8
+		pio.PutU32BE(b[n:], uint32(16+len(item.Value)))
+		pio.PutU32BE(b[n+4:], uint32(DATA))
+		n += 8
+		pio.PutU32BE(b[n:], item.DataType&0xffffff)
+		n += 4
+		pio.PutU32BE(b[n:], item.Locale)
+		n += 4
+		n += copy(b[n:], item.Value)
+	}
+	return
+}
+func (self MetadataItemList) Len() (n int) {
+	n += 8
+	for _, item := range self.Items {
+		n += 24 + len(item.Value)
+	}
+	return
+}
+func (self *MetadataItemList) Unmarshal(b []byte, offset int) (n int, err error) {
+	(&self.AtomPos).setPos(offset, len(b))
+	n += 8
+	for n+8 <= len(b) {
+		size := int(pio.U32BE(b[n:]))
+		if size < 8 || len(b) < n+size {
+			err = parseErr("TagSizeInvalid", n+offset, err)
+			return
+		}
+		item := MetadataItem{KeyIndex: pio.U32BE(b[n+4:])}
+		// Items may carry name/mean boxes besides data; keep the first data.
+		for pos := n + 8; pos+8 <= n+size; {
+			childSize := int(pio.U32BE(b[pos:]))
+			if childSize < 8 || pos+childSize > n+size {
+				err = parseErr("TagSizeInvalid", pos+offset, err)
+				return
+			}
+			if Tag(pio.U32BE(b[pos+4:])) == DATA && childSize >= 16 {
+				item.DataType = pio.U32BE(b[pos+8:]) & 0xffffff
+				item.Locale = pio.U32BE(b[pos+12:])
+				item.Value = b[pos+16 : pos+childSize]
+				break
+			}
+			pos += childSize
+		}
+		self.Items = append(self.Items, item)
+		n += size
+	}
+	return
+}
+func (self MetadataItemList) Children() (r []Atom) {
+	return
+}
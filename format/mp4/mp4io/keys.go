@@ -0,0 +1,101 @@
+package mp4io
+
+import (
+	"fmt"
+
+	"github.com/ugparu/gomedia/utils/bits/pio"
+)
+
+func (self MetadataKeys) String() string {
+	return fmt.Sprintf("keys=%d", len(self.Entries))
+}
+
+const KEYS = Tag(0x6b657973)
+
+func (self MetadataKeys) Tag() Tag {
+	return KEYS
+}
+
+// MetadataKeyNamespaceMdta is the reverse-DNS key namespace ("mdta").
+const MetadataKeyNamespaceMdta = 0x6d647461
+
+type MetadataKey struct {
+	Namespace uint32
+	Value     string
+}
+
+// MetadataKeys is the QuickTime keys box: the ordered key table referenced
+// by 1-based index from ilst items.
+type MetadataKeys struct {
+	Version uint8
+	Flags   uint32
+	Entries []MetadataKey
+	AtomPos
+}
+
+func (self MetadataKeys) Marshal(b []byte) (n int) {
+	pio.PutU32BE(b[4:], uint32(KEYS))
+	n += self.marshal(b[8:]) + 8
+	pio.PutU32BE(b[0:], uint32(n))
+	return
+}
+func (self MetadataKeys) marshal(b []byte) (n int) {
+	pio.PutU8(b[n:], self.Version)
+	n += 1
+	pio.PutU24BE(b[n:], self.Flags)
+	n += 3
+	pio.PutU32BE(b[n:], uint32(len(self.Entries)))
+	n += 4
+	for _, entry := range self.Entries {
+		pio.PutU32BE(b[n:], uint32(8+len(entry.Value)))
+		n += 4
+		pio.PutU32BE(b[n:], entry.Namespace)
+		n += 4
+		n += copy(b[n:], entry.Value)
+	}
+	return
+}
+func (self MetadataKeys) Len() (n int) {
+	n += 8
+	n += 1
+	n += 3
+	n += 4
+	for _, entry := range self.Entries {
+		n += 8 + len(entry.Value)
+	}
+	return
+}
+func (self *MetadataKeys) Unmarshal(b []byte, offset int) (n int, err error) {
+	(&self.AtomPos).setPos(offset, len(b))
+	n += 8
+	if len(b) < n+8 {
+		err = parseErr("EntryCount", n+offset, err)
+		return
+	}
+	self.Version = pio.U8(b[n:])
+	n += 1
+	self.Flags = pio.U24BE(b[n:])
+	n += 3
+	var _len_Entries uint32 = pio.U32BE(b[n:])
+	n += 4
+	for range _len_Entries {
+		if len(b) < n+8 {
+			err = parseErr("MetadataKey", n+offset, err)
+			return
+		}
+		size := int(pio.U32BE(b[n:]))
+		if size < 8 || len(b) < n+size {
+			err = parseErr("MetadataKey", n+offset, err)
+			return
+		}
+		self.Entries = append(self.Entries, MetadataKey{
+			Namespace: pio.U32BE(b[n+4:]),
+			Value:     string(b[n+8 : n+size]),
+		})
+		n += size
+	}
+	return
+}
+func (self MetadataKeys) Children() (r []Atom) {
+	return
+}
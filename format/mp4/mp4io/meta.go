@@ -0,0 +1,143 @@
+package mp4io
+
+import "github.com/ugparu/gomedia/utils/bits/pio"
+
+const META = Tag(0x6d657461)
+
+func (self Meta) Tag() Tag {
+	return META
+}
+
+// Meta is the meta box (ISO 14496-12 §8.11.1). With an "mdta" handler it
+// carries QuickTime-style key/value metadata: Keys names each entry and
+// Items holds the values indexed by key position.
+//
+// ISO writers emit meta as a full box while QuickTime omits version/flags;
+// Unmarshal accepts both, Marshal always writes the ISO form.
+type Meta struct {
+	Version  uint8
+	Flags    uint32
+	Handler  *HandlerRefer
+	Keys     *MetadataKeys
+	Items    *MetadataItemList
+	Unknowns []Atom
+	AtomPos
+}
+
+func (self Meta) Marshal(b []byte) (n int) {
+	pio.PutU32BE(b[4:], uint32(META))
+	n += self.marshal(b[8:]) + 8
+	pio.PutU32BE(b[0:], uint32(n))
+	return
+}
+func (self Meta) marshal(b []byte) (n int) {
+	pio.PutU8(b[n:], self.Version)
+	n += 1
+	pio.PutU24BE(b[n:], self.Flags)
+	n += 3
+	if self.Handler != nil {
+		n += self.Handler.Marshal(b[n:])
+	}
+	if self.Keys != nil {
+		n += self.Keys.Marshal(b[n:])
+	}
+	if self.Items != nil {
+		n += self.Items.Marshal(b[n:])
+	}
+	for _, atom := range self.Unknowns {
+		n += atom.Marshal(b[n:])
+	}
+	return
+}
+func (self Meta) Len() (n int) {
+	n += 8
+	n += 1
+	n += 3
+	if self.Handler != nil {
+		n += self.Handler.Len()
+	}
+	if self.Keys != nil {
+		n += self.Keys.Len()
+	}
+	if self.Items != nil {
+		n += self.Items.Len()
+	}
+	for _, atom := range self.Unknowns {
+		n += atom.Len()
+	}
+	return
+}
+func (self *Meta) Unmarshal(b []byte, offset int) (n int, err error) {
+	(&self.AtomPos).setPos(offset, len(b))
+	n += 8
+	if len(b) < n+4 {
+		err = parseErr("Version", n+offset, err)
+		return
+	}
+	// A QuickTime meta starts directly with a child box, whose size field
+	// is never zero; the ISO form starts with version 0 and zero flags.
+	if pio.U32BE(b[n:]) == 0 {
+		n += 4
+	}
+	for n+8 <= len(b) {
+		tag := Tag(pio.U32BE(b[n+4:]))
+		size := int(pio.U32BE(b[n:]))
+		if size < 8 || len(b) < n+size {
+			err = parseErr("TagSizeInvalid", n+offset, err)
+			return
+		}
+		switch tag {
+		case HDLR:
+			{
+				atom := &HandlerRefer{}
+				if _, err = atom.Unmarshal(b[n:n+size], offset+n); err != nil {
+					err = parseErr("hdlr", n+offset, err)
+					return
+				}
+				self.Handler = atom
+			}
+		case KEYS:
+			{
+				atom := &MetadataKeys{}
+				if _, err = atom.Unmarshal(b[n:n+size], offset+n); err != nil {
+					err = parseErr("keys", n+offset, err)
+					return
+				}
+				self.Keys = atom
+			}
+		case ILST:
+			{
+				atom := &MetadataItemList{}
+				if _, err = atom.Unmarshal(b[n:n+size], offset+n); err != nil {
+					err = parseErr("ilst", n+offset, err)
+					return
+				}
+				self.Items = atom
+			}
+		default:
+			{
+				atom := &Dummy{Tag_: tag, Data: b[n : n+size]}
+				if _, err = atom.Unmarshal(b[n:n+size], offset+n); err != nil {
+					err = parseErr("", n+offset, err)
+					return
+				}
+				self.Unknowns = append(self.Unknowns, atom)
+			}
+		}
+		n += size
+	}
+	return
+}
+func (self Meta) Children() (r []Atom) {
+	if self.Handler != nil {
+		r = append(r, self.Handler)
+	}
+	if self.Keys != nil {
+		r = append(r, self.Keys)
+	}
+	if self.Items != nil {
+		r = append(r, self.Items)
+	}
+	r = append(r, self.Unknowns...)
+	return
+}
@@ -0,0 +1,97 @@
+package mp4io
+
+import (
+	"bytes"
+
+	"github.com/ugparu/gomedia/utils/bits/pio"
+)
+
+const METT = Tag(0x6d657474)
+
+func (self METTDesc) Tag() Tag {
+	return METT
+}
+
+// METTDesc is the TextMetaDataSampleEntry (ISO 14496-12 §12.3.3.2) of a
+// timed-metadata track. MimeFormat names the sample payload, e.g.
+// "application/json" or "application/x-klv".
+type METTDesc struct {
+	DataRefIdx      int16
+	ContentEncoding string
+	MimeFormat      string
+	Unknowns        []Atom
+	AtomPos
+}
+
+func (self METTDesc) Marshal(b []byte) (n int) {
+	pio.PutU32BE(b[4:], uint32(METT))
+	n += self.marshal(b[8:]) + 8
+	pio.PutU32BE(b[0:], uint32(n))
+	return
+}
+func (self METTDesc) marshal(b []byte) (n int) {
+	n += 6
+	pio.PutI16BE(b[n:], self.DataRefIdx)
+	n += 2
+	n += copy(b[n:], self.ContentEncoding)
+	b[n] = 0
+	n += 1
+	n += copy(b[n:], self.MimeFormat)
+	b[n] = 0
+	n += 1
+	for _, atom := range self.Unknowns {
+		n += atom.Marshal(b[n:])
+	}
+	return
+}
+func (self METTDesc) Len() (n int) {
+	n += 8
+	n += 6
+	n += 2
+	n += len(self.ContentEncoding) + 1
+	n += len(self.MimeFormat) + 1
+	for _, atom := range self.Unknowns {
+		n += atom.Len()
+	}
+	return
+}
+func (self *METTDesc) Unmarshal(b []byte, offset int) (n int, err error) {
+	(&self.AtomPos).setPos(offset, len(b))
+	n += 8
+	n += 6
+	if len(b) < n+2 {
+		err = parseErr("DataRefIdx", n+offset, err)
+		return
+	}
+	self.DataRefIdx = pio.I16BE(b[n:])
+	n += 2
+	for _, field := range []*string{&self.ContentEncoding, &self.MimeFormat} {
+		end := bytes.IndexByte(b[n:], 0)
+		if end < 0 {
+			err = parseErr("String", n+offset, err)
+			return
+		}
+		*field = string(b[n : n+end])
+		n += end + 1
+	}
+	for n+8 <= len(b) {
+		tag := Tag(pio.U32BE(b[n+4:]))
+		size := int(pio.U32BE(b[n:]))
+		if size < 8 || len(b) < n+size {
+			err = parseErr("TagSizeInvalid", n+offset, err)
+			return
+		}
+		atom := &Dummy{Tag_: tag, Data: b[n : n+size]}
+		if _, err = atom.Unmarshal(b[n:n+size], offset+n); err != nil {
+			err = parseErr("", n+offset, err)
+			return
+		}
+		self.Unknowns = append(self.Unknowns, atom)
+		n += size
+	}
+	return
+}
+func (self METTDesc) Children() (r []Atom) {
+	r = append(r, self.Unknowns...)
+	return
+}
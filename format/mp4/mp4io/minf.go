@@ -11,6 +11,7 @@ func (self MediaInfo) Tag() Tag {
 type MediaInfo struct {
 	Sound    *SoundMediaInfo
 	Video    *VideoMediaInfo
+	Null     *NullMediaInfo
 	Data     *DataInfo
 	Sample   *SampleTable
 	Unknowns []Atom
@@ -30,6 +31,9 @@ func (self MediaInfo) marshal(b []byte) (n int) {
 	if self.Video != nil {
 		n += self.Video.Marshal(b[n:])
 	}
+	if self.Null != nil {
+		n += self.Null.Marshal(b[n:])
+	}
 	if self.Data != nil {
 		n += self.Data.Marshal(b[n:])
 	}
@@ -49,6 +53,9 @@ func (self MediaInfo) Len() (n int) {
 	if self.Video != nil {
 		n += self.Video.Len()
 	}
+	if self.Null != nil {
+		n += self.Null.Len()
+	}
 	if self.Data != nil {
 		n += self.Data.Len()
 	}
@@ -89,6 +96,15 @@ func (self *MediaInfo) Unmarshal(b []byte, offset int) (n int, err error) {
 				}
 				self.Video = atom
 			}
+		case NMHD:
+			{
+				atom := &NullMediaInfo{}
+				if _, err = atom.Unmarshal(b[n:n+size], offset+n); err != nil {
+					err = parseErr("nmhd", n+offset, err)
+					return
+				}
+				self.Null = atom
+			}
 		case DINF:
 			{
 				atom := &DataInfo{}
@@ -128,6 +144,9 @@ func (self MediaInfo) Children() (r []Atom) {
 	if self.Video != nil {
 		r = append(r, self.Video)
 	}
+	if self.Null != nil {
+		r = append(r, self.Null)
+	}
 	if self.Data != nil {
 		r = append(r, self.Data)
 	}
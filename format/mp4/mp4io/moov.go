@@ -12,6 +12,7 @@ type Movie struct {
 	Header      *MovieHeader
 	MovieExtend *MovieExtend
 	Tracks      []*Track
+	UserData    *UserData
 	Unknowns    []Atom
 	AtomPos
 }
@@ -34,6 +35,9 @@ func (m Movie) marshal(b []byte) (n int) {
 	if m.MovieExtend != nil {
 		n += m.MovieExtend.Marshal(b[n:])
 	}
+	if m.UserData != nil {
+		n += m.UserData.Marshal(b[n:])
+	}
 	for _, atom := range m.Unknowns {
 		n += atom.Marshal(b[n:])
 	}
@@ -51,6 +55,9 @@ func (m Movie) Len() (n int) {
 	for _, atom := range m.Tracks {
 		n += atom.Len()
 	}
+	if m.UserData != nil {
+		n += m.UserData.Len()
+	}
 	for _, atom := range m.Unknowns {
 		n += atom.Len()
 	}
@@ -95,6 +102,15 @@ func (m *Movie) Unmarshal(b []byte, offset int) (n int, err error) {
 				}
 				m.Tracks = append(m.Tracks, atom)
 			}
+		case UDTA:
+			{
+				atom := &UserData{}
+				if _, err = atom.Unmarshal(b[n:n+size], offset+n); err != nil {
+					err = parseErr("udta", n+offset, err)
+					return
+				}
+				m.UserData = atom
+			}
 		default:
 			{
 				atom := &Dummy{Tag_: tag, Data: b[n : n+size]}
@@ -120,6 +136,9 @@ func (m Movie) Children() (r []Atom) {
 	for _, atom := range m.Tracks {
 		r = append(r, atom)
 	}
+	if m.UserData != nil {
+		r = append(r, m.UserData)
+	}
 	r = append(r, m.Unknowns...)
 	return
 }
@@ -170,14 +170,33 @@ func ReadFileAtoms(r io.ReadSeeker) (atoms []Atom, err error) {
 		}
 		size := int(pio.U32BE(taghdr[0:]))
 		tag := Tag(pio.U32BE(taghdr[4:]))
-		isExtendedSize := tag == MDAT && size == 1
+		isExtendedSize := size == 1
 
+		// ISO 14496-12 §4.2: size 1 means a 64-bit largesize follows the
+		// tag, size 0 means the box extends to the end of the file.
 		if isExtendedSize {
 			sBuf := make([]byte, 8)
 			if _, err = io.ReadFull(r, sBuf); err != nil {
 				return
 			}
 			size = int(pio.I64BE(sBuf))
+		} else if size == 0 {
+			var end int64
+			if end, err = r.Seek(0, io.SeekEnd); err != nil {
+				return
+			}
+			size = int(end - offset)
+			if _, err = r.Seek(offset+8, io.SeekStart); err != nil {
+				return
+			}
+		}
+		hdrLen := 8
+		if isExtendedSize {
+			hdrLen += 8
+		}
+		if size < hdrLen {
+			err = parseErr("TagSizeInvalid", int(offset), nil)
+			return
 		}
 
 		var atom Atom
@@ -189,11 +208,14 @@ func ReadFileAtoms(r io.ReadSeeker) (atoms []Atom, err error) {
 		}
 
 		if atom != nil {
-			b := make([]byte, int(size))
+			// Atom parsers expect a compact 8-byte header, so a largesize
+			// header is rewritten into one before unmarshalling.
+			b := make([]byte, size-hdrLen+8)
 			if _, err = io.ReadFull(r, b[8:]); err != nil {
 				return
 			}
 			copy(b, taghdr)
+			pio.PutU32BE(b, uint32(len(b)))
 			if _, err = atom.Unmarshal(b, int(offset)); err != nil {
 				return
 			}
@@ -202,10 +224,7 @@ func ReadFileAtoms(r io.ReadSeeker) (atoms []Atom, err error) {
 			dummy := &Dummy{Tag_: tag}
 			dummy.setPos(int(offset), int(size))
 			atoms = append(atoms, dummy)
-			seek := int64(size) - 8
-			if isExtendedSize {
-				seek -= 8
-			}
+			seek := int64(size - hdrLen)
 			if _, err = r.Seek(seek, 1); err != nil {
 				return
 			}
@@ -0,0 +1,53 @@
+package mp4io
+
+import "github.com/ugparu/gomedia/utils/bits/pio"
+
+const NMHD = Tag(0x6e6d6864)
+
+func (self NullMediaInfo) Tag() Tag {
+	return NMHD
+}
+
+// NullMediaInfo is the nmhd box (ISO 14496-12 §8.4.5.5) used by tracks
+// without a dedicated media header, such as timed metadata.
+type NullMediaInfo struct {
+	Version uint8
+	Flags   uint32
+	AtomPos
+}
+
+func (self NullMediaInfo) Marshal(b []byte) (n int) {
+	pio.PutU32BE(b[4:], uint32(NMHD))
+	n += self.marshal(b[8:]) + 8
+	pio.PutU32BE(b[0:], uint32(n))
+	return
+}
+func (self NullMediaInfo) marshal(b []byte) (n int) {
+	pio.PutU8(b[n:], self.Version)
+	n += 1
+	pio.PutU24BE(b[n:], self.Flags)
+	n += 3
+	return
+}
+func (self NullMediaInfo) Len() (n int) {
+	n += 8
+	n += 1
+	n += 3
+	return
+}
+func (self *NullMediaInfo) Unmarshal(b []byte, offset int) (n int, err error) {
+	(&self.AtomPos).setPos(offset, len(b))
+	n += 8
+	if len(b) < n+4 {
+		err = parseErr("Flags", n+offset, err)
+		return
+	}
+	self.Version = pio.U8(b[n:])
+	n += 1
+	self.Flags = pio.U24BE(b[n:])
+	n += 3
+	return
+}
+func (self NullMediaInfo) Children() (r []Atom) {
+	return
+}
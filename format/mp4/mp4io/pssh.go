@@ -0,0 +1,106 @@
+package mp4io
+
+import "github.com/ugparu/gomedia/utils/bits/pio"
+
+const PSSH = Tag(0x70737368)
+
+func (self ProtectionSystemHeader) Tag() Tag {
+	return PSSH
+}
+
+// ProtectionSystemHeader is the pssh box (ISO/IEC 23001-7 §8.1) carrying the
+// DRM-system specific data a CDM needs to obtain the content keys. Version 1
+// additionally lists the key IDs the data applies to.
+type ProtectionSystemHeader struct {
+	Version  uint8
+	Flags    uint32
+	SystemID [16]byte
+	KeyIDs   [][16]byte
+	Data     []byte
+	AtomPos
+}
+
+func (self ProtectionSystemHeader) Marshal(b []byte) (n int) {
+	pio.PutU32BE(b[4:], uint32(PSSH))
+	n += self.marshal(b[8:]) + 8
+	pio.PutU32BE(b[0:], uint32(n))
+	return
+}
+func (self ProtectionSystemHeader) marshal(b []byte) (n int) {
+	pio.PutU8(b[n:], self.Version)
+	n += 1
+	pio.PutU24BE(b[n:], self.Flags)
+	n += 3
+	n += copy(b[n:], self.SystemID[:])
+	if self.Version > 0 {
+		pio.PutU32BE(b[n:], uint32(len(self.KeyIDs)))
+		n += 4
+		for _, kid := range self.KeyIDs {
+			n += copy(b[n:], kid[:])
+		}
+	}
+	pio.PutU32BE(b[n:], uint32(len(self.Data)))
+	n += 4
+	n += copy(b[n:], self.Data)
+	return
+}
+func (self ProtectionSystemHeader) Len() (n int) {
+	n += 8
+	n += 1
+	n += 3
+	n += 16
+	if self.Version > 0 {
+		n += 4
+		n += 16 * len(self.KeyIDs)
+	}
+	n += 4
+	n += len(self.Data)
+	return
+}
+func (self *ProtectionSystemHeader) Unmarshal(b []byte, offset int) (n int, err error) {
+	(&self.AtomPos).setPos(offset, len(b))
+	n += 8
+	if len(b) < n+20 {
+		err = parseErr("SystemID", n+offset, err)
+		return
+	}
+	self.Version = pio.U8(b[n:])
+	n += 1
+	self.Flags = pio.U24BE(b[n:])
+	n += 3
+	copy(self.SystemID[:], b[n:])
+	n += 16
+	if self.Version > 0 {
+		if len(b) < n+4 {
+			err = parseErr("KIDCount", n+offset, err)
+			return
+		}
+		count := int(pio.U32BE(b[n:]))
+		n += 4
+		if len(b) < n+16*count {
+			err = parseErr("KID", n+offset, err)
+			return
+		}
+		self.KeyIDs = make([][16]byte, count)
+		for i := range self.KeyIDs {
+			copy(self.KeyIDs[i][:], b[n:])
+			n += 16
+		}
+	}
+	if len(b) < n+4 {
+		err = parseErr("DataSize", n+offset, err)
+		return
+	}
+	size := int(pio.U32BE(b[n:]))
+	n += 4
+	if len(b) < n+size {
+		err = parseErr("Data", n+offset, err)
+		return
+	}
+	self.Data = b[n : n+size]
+	n += size
+	return
+}
+func (self ProtectionSystemHeader) Children() (r []Atom) {
+	return
+}
@@ -0,0 +1,116 @@
+package mp4io
+
+import "github.com/ugparu/gomedia/utils/bits/pio"
+
+const SAIO = Tag(0x7361696f)
+
+func (self SampleAuxInfoOffsets) Tag() Tag {
+	return SAIO
+}
+
+// SampleAuxInfoOffsets is the saio box (ISO/IEC 14496-12 §8.7.9) locating
+// sample auxiliary information. In a traf the offsets are relative to the
+// same base as trun data offsets. Version 1 stores 64-bit offsets.
+type SampleAuxInfoOffsets struct {
+	Version              uint8
+	Flags                uint32
+	AuxInfoType          uint32
+	AuxInfoTypeParameter uint32
+	Offsets              []uint64
+	AtomPos
+}
+
+func (self SampleAuxInfoOffsets) Marshal(b []byte) (n int) {
+	pio.PutU32BE(b[4:], uint32(SAIO))
+	n += self.marshal(b[8:]) + 8
+	pio.PutU32BE(b[0:], uint32(n))
+	return
+}
+func (self SampleAuxInfoOffsets) marshal(b []byte) (n int) {
+	pio.PutU8(b[n:], self.Version)
+	n += 1
+	pio.PutU24BE(b[n:], self.Flags)
+	n += 3
+	if self.Flags&SAIZHasAuxInfoType != 0 {
+		pio.PutU32BE(b[n:], self.AuxInfoType)
+		n += 4
+		pio.PutU32BE(b[n:], self.AuxInfoTypeParameter)
+		n += 4
+	}
+	pio.PutU32BE(b[n:], uint32(len(self.Offsets)))
+	n += 4
+	for _, off := range self.Offsets {
+		if self.Version == 0 {
+			pio.PutU32BE(b[n:], uint32(off))
+			n += 4
+		} else {
+			pio.PutU64BE(b[n:], off)
+			n += 8
+		}
+	}
+	return
+}
+func (self SampleAuxInfoOffsets) Len() (n int) {
+	n += 8
+	n += 1
+	n += 3
+	if self.Flags&SAIZHasAuxInfoType != 0 {
+		n += 8
+	}
+	n += 4
+	if self.Version == 0 {
+		n += 4 * len(self.Offsets)
+	} else {
+		n += 8 * len(self.Offsets)
+	}
+	return
+}
+func (self *SampleAuxInfoOffsets) Unmarshal(b []byte, offset int) (n int, err error) {
+	(&self.AtomPos).setPos(offset, len(b))
+	n += 8
+	if len(b) < n+4 {
+		err = parseErr("Version", n+offset, err)
+		return
+	}
+	self.Version = pio.U8(b[n:])
+	n += 1
+	self.Flags = pio.U24BE(b[n:])
+	n += 3
+	if self.Flags&SAIZHasAuxInfoType != 0 {
+		if len(b) < n+8 {
+			err = parseErr("AuxInfoType", n+offset, err)
+			return
+		}
+		self.AuxInfoType = pio.U32BE(b[n:])
+		n += 4
+		self.AuxInfoTypeParameter = pio.U32BE(b[n:])
+		n += 4
+	}
+	if len(b) < n+4 {
+		err = parseErr("EntryCount", n+offset, err)
+		return
+	}
+	count := int(pio.U32BE(b[n:]))
+	n += 4
+	size := 4
+	if self.Version > 0 {
+		size = 8
+	}
+	if len(b) < n+size*count {
+		err = parseErr("Offset", n+offset, err)
+		return
+	}
+	self.Offsets = make([]uint64, count)
+	for i := range self.Offsets {
+		if self.Version == 0 {
+			self.Offsets[i] = uint64(pio.U32BE(b[n:]))
+		} else {
+			self.Offsets[i] = pio.U64BE(b[n:])
+		}
+		n += size
+	}
+	return
+}
+func (self SampleAuxInfoOffsets) Children() (r []Atom) {
+	return
+}
@@ -0,0 +1,111 @@
+package mp4io
+
+import "github.com/ugparu/gomedia/utils/bits/pio"
+
+const SAIZ = Tag(0x7361697a)
+
+// SAIZHasAuxInfoType marks saiz/saio boxes that carry aux_info_type and
+// aux_info_type_parameter.
+const SAIZHasAuxInfoType = 0x01
+
+func (self SampleAuxInfoSizes) Tag() Tag {
+	return SAIZ
+}
+
+// SampleAuxInfoSizes is the saiz box (ISO/IEC 14496-12 §8.7.8) giving the
+// size of each sample's auxiliary information, such as its senc entry. When
+// all sizes are equal DefaultSampleInfoSize is set and SampleInfoSizes is
+// empty.
+type SampleAuxInfoSizes struct {
+	Version               uint8
+	Flags                 uint32
+	AuxInfoType           uint32
+	AuxInfoTypeParameter  uint32
+	DefaultSampleInfoSize uint8
+	SampleCount           uint32
+	SampleInfoSizes       []uint8
+	AtomPos
+}
+
+func (self SampleAuxInfoSizes) Marshal(b []byte) (n int) {
+	pio.PutU32BE(b[4:], uint32(SAIZ))
+	n += self.marshal(b[8:]) + 8
+	pio.PutU32BE(b[0:], uint32(n))
+	return
+}
+func (self SampleAuxInfoSizes) marshal(b []byte) (n int) {
+	pio.PutU8(b[n:], self.Version)
+	n += 1
+	pio.PutU24BE(b[n:], self.Flags)
+	n += 3
+	if self.Flags&SAIZHasAuxInfoType != 0 {
+		pio.PutU32BE(b[n:], self.AuxInfoType)
+		n += 4
+		pio.PutU32BE(b[n:], self.AuxInfoTypeParameter)
+		n += 4
+	}
+	pio.PutU8(b[n:], self.DefaultSampleInfoSize)
+	n += 1
+	pio.PutU32BE(b[n:], self.SampleCount)
+	n += 4
+	if self.DefaultSampleInfoSize == 0 {
+		n += copy(b[n:], self.SampleInfoSizes)
+	}
+	return
+}
+func (self SampleAuxInfoSizes) Len() (n int) {
+	n += 8
+	n += 1
+	n += 3
+	if self.Flags&SAIZHasAuxInfoType != 0 {
+		n += 8
+	}
+	n += 1
+	n += 4
+	if self.DefaultSampleInfoSize == 0 {
+		n += len(self.SampleInfoSizes)
+	}
+	return
+}
+func (self *SampleAuxInfoSizes) Unmarshal(b []byte, offset int) (n int, err error) {
+	(&self.AtomPos).setPos(offset, len(b))
+	n += 8
+	if len(b) < n+4 {
+		err = parseErr("Version", n+offset, err)
+		return
+	}
+	self.Version = pio.U8(b[n:])
+	n += 1
+	self.Flags = pio.U24BE(b[n:])
+	n += 3
+	if self.Flags&SAIZHasAuxInfoType != 0 {
+		if len(b) < n+8 {
+			err = parseErr("AuxInfoType", n+offset, err)
+			return
+		}
+		self.AuxInfoType = pio.U32BE(b[n:])
+		n += 4
+		self.AuxInfoTypeParameter = pio.U32BE(b[n:])
+		n += 4
+	}
+	if len(b) < n+5 {
+		err = parseErr("SampleCount", n+offset, err)
+		return
+	}
+	self.DefaultSampleInfoSize = pio.U8(b[n:])
+	n += 1
+	self.SampleCount = pio.U32BE(b[n:])
+	n += 4
+	if self.DefaultSampleInfoSize == 0 {
+		if len(b) < n+int(self.SampleCount) {
+			err = parseErr("SampleInfoSize", n+offset, err)
+			return
+		}
+		self.SampleInfoSizes = b[n : n+int(self.SampleCount)]
+		n += int(self.SampleCount)
+	}
+	return
+}
+func (self SampleAuxInfoSizes) Children() (r []Atom) {
+	return
+}
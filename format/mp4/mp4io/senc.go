@@ -0,0 +1,132 @@
+package mp4io
+
+import "github.com/ugparu/gomedia/utils/bits/pio"
+
+const SENC = Tag(0x73656e63)
+
+// SENCUseSubSamples marks senc entries as carrying a subsample map.
+const SENCUseSubSamples = 0x02
+
+func (self SampleEncryption) Tag() Tag {
+	return SENC
+}
+
+// SampleEncryption is the senc box (ISO/IEC 23001-7 §7.2) with the per-sample
+// IVs and subsample maps of a track fragment. The IV size is not stored in the
+// box itself but in the track's tenc, so IVSize must be set before Unmarshal.
+type SampleEncryption struct {
+	Version uint8
+	Flags   uint32
+	IVSize  uint8
+	Entries []SampleEncryptionEntry
+	AtomPos
+}
+
+// SampleEncryptionEntry describes the encryption of one sample.
+type SampleEncryptionEntry struct {
+	IV         []byte
+	SubSamples []SubSample
+}
+
+// SubSample is a run of clear bytes followed by a run of protected bytes.
+type SubSample struct {
+	ClearBytes     uint16
+	ProtectedBytes uint32
+}
+
+// Len returns the size of the entry as sample auxiliary information (saiz).
+func (self SampleEncryptionEntry) Len(flags uint32) (n int) {
+	n += len(self.IV)
+	if flags&SENCUseSubSamples != 0 {
+		n += 2
+		n += 6 * len(self.SubSamples)
+	}
+	return
+}
+
+func (self SampleEncryption) Marshal(b []byte) (n int) {
+	pio.PutU32BE(b[4:], uint32(SENC))
+	n += self.marshal(b[8:]) + 8
+	pio.PutU32BE(b[0:], uint32(n))
+	return
+}
+func (self SampleEncryption) marshal(b []byte) (n int) {
+	pio.PutU8(b[n:], self.Version)
+	n += 1
+	pio.PutU24BE(b[n:], self.Flags)
+	n += 3
+	pio.PutU32BE(b[n:], uint32(len(self.Entries)))
+	n += 4
+	for _, entry := range self.Entries {
+		n += copy(b[n:], entry.IV)
+		if self.Flags&SENCUseSubSamples != 0 {
+			pio.PutU16BE(b[n:], uint16(len(entry.SubSamples)))
+			n += 2
+			for _, sub := range entry.SubSamples {
+				pio.PutU16BE(b[n:], sub.ClearBytes)
+				n += 2
+				pio.PutU32BE(b[n:], sub.ProtectedBytes)
+				n += 4
+			}
+		}
+	}
+	return
+}
+func (self SampleEncryption) Len() (n int) {
+	n += 8
+	n += 1
+	n += 3
+	n += 4
+	for _, entry := range self.Entries {
+		n += entry.Len(self.Flags)
+	}
+	return
+}
+func (self *SampleEncryption) Unmarshal(b []byte, offset int) (n int, err error) {
+	(&self.AtomPos).setPos(offset, len(b))
+	n += 8
+	if len(b) < n+8 {
+		err = parseErr("SampleCount", n+offset, err)
+		return
+	}
+	self.Version = pio.U8(b[n:])
+	n += 1
+	self.Flags = pio.U24BE(b[n:])
+	n += 3
+	count := int(pio.U32BE(b[n:]))
+	n += 4
+	self.Entries = make([]SampleEncryptionEntry, 0, min(count, len(b)))
+	for i := 0; i < count; i++ {
+		var entry SampleEncryptionEntry
+		if len(b) < n+int(self.IVSize) {
+			err = parseErr("IV", n+offset, err)
+			return
+		}
+		entry.IV = b[n : n+int(self.IVSize)]
+		n += int(self.IVSize)
+		if self.Flags&SENCUseSubSamples != 0 {
+			if len(b) < n+2 {
+				err = parseErr("SubSampleCount", n+offset, err)
+				return
+			}
+			subCount := int(pio.U16BE(b[n:]))
+			n += 2
+			if len(b) < n+6*subCount {
+				err = parseErr("SubSample", n+offset, err)
+				return
+			}
+			entry.SubSamples = make([]SubSample, subCount)
+			for j := range entry.SubSamples {
+				entry.SubSamples[j].ClearBytes = pio.U16BE(b[n:])
+				n += 2
+				entry.SubSamples[j].ProtectedBytes = pio.U32BE(b[n:])
+				n += 4
+			}
+		}
+		self.Entries = append(self.Entries, entry)
+	}
+	return
+}
+func (self SampleEncryption) Children() (r []Atom) {
+	return
+}
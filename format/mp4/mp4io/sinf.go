@@ -0,0 +1,329 @@
+package mp4io
+
+import "github.com/ugparu/gomedia/utils/bits/pio"
+
+const (
+	SINF = Tag(0x73696e66)
+	FRMA = Tag(0x66726d61)
+	SCHM = Tag(0x7363686d)
+	SCHI = Tag(0x73636869)
+)
+
+func (self ProtectionSchemeInfo) Tag() Tag {
+	return SINF
+}
+
+// ProtectionSchemeInfo is the sinf box (ISO/IEC 14496-12 §8.12.1) attached to
+// an encv/enca sample entry. It names the original sample entry format, the
+// protection scheme (e.g. 'cbcs') and the scheme's track parameters.
+type ProtectionSchemeInfo struct {
+	OriginalFormat *OriginalFormat
+	SchemeType     *SchemeType
+	SchemeInfo     *SchemeInfo
+	Unknowns       []Atom
+	AtomPos
+}
+
+func (self ProtectionSchemeInfo) Marshal(b []byte) (n int) {
+	pio.PutU32BE(b[4:], uint32(SINF))
+	n += self.marshal(b[8:]) + 8
+	pio.PutU32BE(b[0:], uint32(n))
+	return
+}
+func (self ProtectionSchemeInfo) marshal(b []byte) (n int) {
+	if self.OriginalFormat != nil {
+		n += self.OriginalFormat.Marshal(b[n:])
+	}
+	if self.SchemeType != nil {
+		n += self.SchemeType.Marshal(b[n:])
+	}
+	if self.SchemeInfo != nil {
+		n += self.SchemeInfo.Marshal(b[n:])
+	}
+	for _, atom := range self.Unknowns {
+		n += atom.Marshal(b[n:])
+	}
+	return
+}
+func (self ProtectionSchemeInfo) Len() (n int) {
+	n += 8
+	if self.OriginalFormat != nil {
+		n += self.OriginalFormat.Len()
+	}
+	if self.SchemeType != nil {
+		n += self.SchemeType.Len()
+	}
+	if self.SchemeInfo != nil {
+		n += self.SchemeInfo.Len()
+	}
+	for _, atom := range self.Unknowns {
+		n += atom.Len()
+	}
+	return
+}
+func (self *ProtectionSchemeInfo) Unmarshal(b []byte, offset int) (n int, err error) {
+	(&self.AtomPos).setPos(offset, len(b))
+	n += 8
+	for n+8 < len(b) {
+		tag := Tag(pio.U32BE(b[n+4:]))
+		size := int(pio.U32BE(b[n:]))
+		if len(b) < n+size {
+			err = parseErr("TagSizeInvalid", n+offset, err)
+			return
+		}
+		switch tag {
+		case FRMA:
+			{
+				atom := &OriginalFormat{}
+				if _, err = atom.Unmarshal(b[n:n+size], offset+n); err != nil {
+					err = parseErr("frma", n+offset, err)
+					return
+				}
+				self.OriginalFormat = atom
+			}
+		case SCHM:
+			{
+				atom := &SchemeType{}
+				if _, err = atom.Unmarshal(b[n:n+size], offset+n); err != nil {
+					err = parseErr("schm", n+offset, err)
+					return
+				}
+				self.SchemeType = atom
+			}
+		case SCHI:
+			{
+				atom := &SchemeInfo{}
+				if _, err = atom.Unmarshal(b[n:n+size], offset+n); err != nil {
+					err = parseErr("schi", n+offset, err)
+					return
+				}
+				self.SchemeInfo = atom
+			}
+		default:
+			{
+				atom := &Dummy{Tag_: tag, Data: b[n : n+size]}
+				if _, err = atom.Unmarshal(b[n:n+size], offset+n); err != nil {
+					err = parseErr("", n+offset, err)
+					return
+				}
+				self.Unknowns = append(self.Unknowns, atom)
+			}
+		}
+		n += size
+	}
+	return
+}
+func (self ProtectionSchemeInfo) Children() (r []Atom) {
+	if self.OriginalFormat != nil {
+		r = append(r, self.OriginalFormat)
+	}
+	if self.SchemeType != nil {
+		r = append(r, self.SchemeType)
+	}
+	if self.SchemeInfo != nil {
+		r = append(r, self.SchemeInfo)
+	}
+	r = append(r, self.Unknowns...)
+	return
+}
+
+func (self OriginalFormat) Tag() Tag {
+	return FRMA
+}
+
+// OriginalFormat is the frma box: the four-character code of the sample entry
+// before it was renamed to encv/enca.
+type OriginalFormat struct {
+	DataFormat Tag
+	AtomPos
+}
+
+func (self OriginalFormat) Marshal(b []byte) (n int) {
+	pio.PutU32BE(b[4:], uint32(FRMA))
+	n += self.marshal(b[8:]) + 8
+	pio.PutU32BE(b[0:], uint32(n))
+	return
+}
+func (self OriginalFormat) marshal(b []byte) (n int) {
+	pio.PutU32BE(b[n:], uint32(self.DataFormat))
+	n += 4
+	return
+}
+func (self OriginalFormat) Len() (n int) {
+	n += 8
+	n += 4
+	return
+}
+func (self *OriginalFormat) Unmarshal(b []byte, offset int) (n int, err error) {
+	(&self.AtomPos).setPos(offset, len(b))
+	n += 8
+	if len(b) < n+4 {
+		err = parseErr("DataFormat", n+offset, err)
+		return
+	}
+	self.DataFormat = Tag(pio.U32BE(b[n:]))
+	n += 4
+	return
+}
+func (self OriginalFormat) Children() (r []Atom) {
+	return
+}
+
+func (self SchemeType) Tag() Tag {
+	return SCHM
+}
+
+// SchemeType is the schm box naming the protection scheme, e.g. 'cenc' or
+// 'cbcs' with version 0x00010000.
+type SchemeType struct {
+	Version       uint8
+	Flags         uint32
+	SchemeType    Tag
+	SchemeVersion uint32
+	SchemeURI     string
+	AtomPos
+}
+
+func (self SchemeType) Marshal(b []byte) (n int) {
+	pio.PutU32BE(b[4:], uint32(SCHM))
+	n += self.marshal(b[8:]) + 8
+	pio.PutU32BE(b[0:], uint32(n))
+	return
+}
+func (self SchemeType) marshal(b []byte) (n int) {
+	pio.PutU8(b[n:], self.Version)
+	n += 1
+	pio.PutU24BE(b[n:], self.Flags)
+	n += 3
+	pio.PutU32BE(b[n:], uint32(self.SchemeType))
+	n += 4
+	pio.PutU32BE(b[n:], self.SchemeVersion)
+	n += 4
+	if self.Flags&0x01 != 0 {
+		n += copy(b[n:], self.SchemeURI)
+		b[n] = 0
+		n += 1
+	}
+	return
+}
+func (self SchemeType) Len() (n int) {
+	n += 8
+	n += 1
+	n += 3
+	n += 4
+	n += 4
+	if self.Flags&0x01 != 0 {
+		n += len(self.SchemeURI) + 1
+	}
+	return
+}
+func (self *SchemeType) Unmarshal(b []byte, offset int) (n int, err error) {
+	(&self.AtomPos).setPos(offset, len(b))
+	n += 8
+	if len(b) < n+12 {
+		err = parseErr("SchemeVersion", n+offset, err)
+		return
+	}
+	self.Version = pio.U8(b[n:])
+	n += 1
+	self.Flags = pio.U24BE(b[n:])
+	n += 3
+	self.SchemeType = Tag(pio.U32BE(b[n:]))
+	n += 4
+	self.SchemeVersion = pio.U32BE(b[n:])
+	n += 4
+	if self.Flags&0x01 != 0 {
+		uri := b[n:]
+		for i, c := range uri {
+			if c == 0 {
+				uri = uri[:i]
+				break
+			}
+		}
+		self.SchemeURI = string(uri)
+		n = len(b)
+	}
+	return
+}
+func (self SchemeType) Children() (r []Atom) {
+	return
+}
+
+func (self SchemeInfo) Tag() Tag {
+	return SCHI
+}
+
+// SchemeInfo is the schi box holding the scheme-specific boxes; for the
+// common encryption schemes that is the tenc box.
+type SchemeInfo struct {
+	TrackEncryption *TrackEncryption
+	Unknowns        []Atom
+	AtomPos
+}
+
+func (self SchemeInfo) Marshal(b []byte) (n int) {
+	pio.PutU32BE(b[4:], uint32(SCHI))
+	n += self.marshal(b[8:]) + 8
+	pio.PutU32BE(b[0:], uint32(n))
+	return
+}
+func (self SchemeInfo) marshal(b []byte) (n int) {
+	if self.TrackEncryption != nil {
+		n += self.TrackEncryption.Marshal(b[n:])
+	}
+	for _, atom := range self.Unknowns {
+		n += atom.Marshal(b[n:])
+	}
+	return
+}
+func (self SchemeInfo) Len() (n int) {
+	n += 8
+	if self.TrackEncryption != nil {
+		n += self.TrackEncryption.Len()
+	}
+	for _, atom := range self.Unknowns {
+		n += atom.Len()
+	}
+	return
+}
+func (self *SchemeInfo) Unmarshal(b []byte, offset int) (n int, err error) {
+	(&self.AtomPos).setPos(offset, len(b))
+	n += 8
+	for n+8 < len(b) {
+		tag := Tag(pio.U32BE(b[n+4:]))
+		size := int(pio.U32BE(b[n:]))
+		if len(b) < n+size {
+			err = parseErr("TagSizeInvalid", n+offset, err)
+			return
+		}
+		switch tag {
+		case TENC:
+			{
+				atom := &TrackEncryption{}
+				if _, err = atom.Unmarshal(b[n:n+size], offset+n); err != nil {
+					err = parseErr("tenc", n+offset, err)
+					return
+				}
+				self.TrackEncryption = atom
+			}
+		default:
+			{
+				atom := &Dummy{Tag_: tag, Data: b[n : n+size]}
+				if _, err = atom.Unmarshal(b[n:n+size], offset+n); err != nil {
+					err = parseErr("", n+offset, err)
+					return
+				}
+				self.Unknowns = append(self.Unknowns, atom)
+			}
+		}
+		n += size
+	}
+	return
+}
+func (self SchemeInfo) Children() (r []Atom) {
+	if self.TrackEncryption != nil {
+		r = append(r, self.TrackEncryption)
+	}
+	r = append(r, self.Unknowns...)
+	return
+}
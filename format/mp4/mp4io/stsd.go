@@ -14,6 +14,7 @@ type SampleDesc struct {
 	HV1Desc  *HV1Desc
 	MJPGDesc *MJPGDesc
 	MP4ADesc *MP4ADesc
+	METTDesc *METTDesc
 	Unknowns []Atom
 	AtomPos
 }
@@ -41,6 +42,9 @@ func (self SampleDesc) marshal(b []byte) (n int) {
 	if self.MP4ADesc != nil {
 		_childrenNR++
 	}
+	if self.METTDesc != nil {
+		_childrenNR++
+	}
 	_childrenNR += len(self.Unknowns)
 	pio.PutI32BE(b[n:], int32(_childrenNR))
 	n += 4
@@ -56,6 +60,9 @@ func (self SampleDesc) marshal(b []byte) (n int) {
 	if self.MP4ADesc != nil {
 		n += self.MP4ADesc.Marshal(b[n:])
 	}
+	if self.METTDesc != nil {
+		n += self.METTDesc.Marshal(b[n:])
+	}
 	for _, atom := range self.Unknowns {
 		n += atom.Marshal(b[n:])
 	}
@@ -78,6 +85,9 @@ func (self SampleDesc) Len() (n int) {
 	if self.MP4ADesc != nil {
 		n += self.MP4ADesc.Len()
 	}
+	if self.METTDesc != nil {
+		n += self.METTDesc.Len()
+	}
 	for _, atom := range self.Unknowns {
 		n += atom.Len()
 	}
@@ -138,6 +148,24 @@ func (self *SampleDesc) Unmarshal(b []byte, offset int) (n int, err error) {
 				}
 				self.MP4ADesc = atom
 			}
+		case METT:
+			{
+				atom := &METTDesc{}
+				if _, err = atom.Unmarshal(b[n:n+size], offset+n); err != nil {
+					err = parseErr("mett", n+offset, err)
+					return
+				}
+				self.METTDesc = atom
+			}
+		case ENCV, ENCA:
+			{
+				atom := &ProtectedSampleEntry{}
+				if _, err = atom.Unmarshal(b[n:n+size], offset+n); err != nil {
+					err = parseErr("encv", n+offset, err)
+					return
+				}
+				self.Unknowns = append(self.Unknowns, atom)
+			}
 		default:
 			{
 				atom := &Dummy{Tag_: tag, Data: b[n : n+size]}
@@ -165,6 +193,9 @@ func (self SampleDesc) Children() (r []Atom) {
 	if self.MP4ADesc != nil {
 		r = append(r, self.MP4ADesc)
 	}
+	if self.METTDesc != nil {
+		r = append(r, self.METTDesc)
+	}
 	r = append(r, self.Unknowns...)
 	return
 }
@@ -0,0 +1,117 @@
+package mp4io
+
+import "github.com/ugparu/gomedia/utils/bits/pio"
+
+const TENC = Tag(0x74656e63)
+
+func (self TrackEncryption) Tag() Tag {
+	return TENC
+}
+
+// TrackEncryption is the tenc box (ISO/IEC 23001-7 §8.2) holding the default
+// encryption parameters of a protected track. The crypt/skip pattern is only
+// stored by version 1; a constant IV is present when the track is protected
+// without per-sample IVs, as 'cbcs' does.
+type TrackEncryption struct {
+	Version                uint8
+	Flags                  uint32
+	DefaultCryptByteBlock  uint8
+	DefaultSkipByteBlock   uint8
+	DefaultIsProtected     uint8
+	DefaultPerSampleIVSize uint8
+	DefaultKID             [16]byte
+	DefaultConstantIV      []byte
+	AtomPos
+}
+
+func (self TrackEncryption) Marshal(b []byte) (n int) {
+	pio.PutU32BE(b[4:], uint32(TENC))
+	n += self.marshal(b[8:]) + 8
+	pio.PutU32BE(b[0:], uint32(n))
+	return
+}
+func (self TrackEncryption) marshal(b []byte) (n int) {
+	pio.PutU8(b[n:], self.Version)
+	n += 1
+	pio.PutU24BE(b[n:], self.Flags)
+	n += 3
+	b[n] = 0
+	n += 1
+	if self.Version > 0 {
+		pio.PutU8(b[n:], self.DefaultCryptByteBlock<<4|self.DefaultSkipByteBlock&0x0f)
+	} else {
+		b[n] = 0
+	}
+	n += 1
+	pio.PutU8(b[n:], self.DefaultIsProtected)
+	n += 1
+	pio.PutU8(b[n:], self.DefaultPerSampleIVSize)
+	n += 1
+	n += copy(b[n:], self.DefaultKID[:])
+	if self.hasConstantIV() {
+		pio.PutU8(b[n:], uint8(len(self.DefaultConstantIV)))
+		n += 1
+		n += copy(b[n:], self.DefaultConstantIV)
+	}
+	return
+}
+func (self TrackEncryption) hasConstantIV() bool {
+	return self.DefaultIsProtected == 1 && self.DefaultPerSampleIVSize == 0
+}
+func (self TrackEncryption) Len() (n int) {
+	n += 8
+	n += 1
+	n += 3
+	n += 2
+	n += 1
+	n += 1
+	n += 16
+	if self.hasConstantIV() {
+		n += 1
+		n += len(self.DefaultConstantIV)
+	}
+	return
+}
+func (self *TrackEncryption) Unmarshal(b []byte, offset int) (n int, err error) {
+	(&self.AtomPos).setPos(offset, len(b))
+	n += 8
+	if len(b) < n+24 {
+		err = parseErr("DefaultKID", n+offset, err)
+		return
+	}
+	self.Version = pio.U8(b[n:])
+	n += 1
+	self.Flags = pio.U24BE(b[n:])
+	n += 3
+	n += 1
+	if self.Version > 0 {
+		pattern := pio.U8(b[n:])
+		self.DefaultCryptByteBlock = pattern >> 4
+		self.DefaultSkipByteBlock = pattern & 0x0f
+	}
+	n += 1
+	self.DefaultIsProtected = pio.U8(b[n:])
+	n += 1
+	self.DefaultPerSampleIVSize = pio.U8(b[n:])
+	n += 1
+	copy(self.DefaultKID[:], b[n:])
+	n += 16
+	if self.hasConstantIV() {
+		if len(b) < n+1 {
+			err = parseErr("DefaultConstantIVSize", n+offset, err)
+			return
+		}
+		size := int(pio.U8(b[n:]))
+		n += 1
+		if len(b) < n+size {
+			err = parseErr("DefaultConstantIV", n+offset, err)
+			return
+		}
+		self.DefaultConstantIV = b[n : n+size]
+		n += size
+	}
+	return
+}
+func (self TrackEncryption) Children() (r []Atom) {
+	return
+}
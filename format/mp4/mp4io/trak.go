@@ -10,6 +10,7 @@ func (self Track) Tag() Tag {
 
 type Track struct {
 	Header   *TrackHeader
+	Edit     *Edit
 	Media    *Media
 	Unknowns []Atom
 	AtomPos
@@ -25,6 +26,9 @@ func (self Track) marshal(b []byte) (n int) {
 	if self.Header != nil {
 		n += self.Header.Marshal(b[n:])
 	}
+	if self.Edit != nil {
+		n += self.Edit.Marshal(b[n:])
+	}
 	if self.Media != nil {
 		n += self.Media.Marshal(b[n:])
 	}
@@ -38,6 +42,9 @@ func (self Track) Len() (n int) {
 	if self.Header != nil {
 		n += self.Header.Len()
 	}
+	if self.Edit != nil {
+		n += self.Edit.Len()
+	}
 	if self.Media != nil {
 		n += self.Media.Len()
 	}
@@ -66,6 +73,15 @@ func (self *Track) Unmarshal(b []byte, offset int) (n int, err error) {
 				}
 				self.Header = atom
 			}
+		case EDTS:
+			{
+				atom := &Edit{}
+				if _, err = atom.Unmarshal(b[n:n+size], offset+n); err != nil {
+					err = parseErr("edts", n+offset, err)
+					return
+				}
+				self.Edit = atom
+			}
 		case MDIA:
 			{
 				atom := &Media{}
@@ -117,6 +133,9 @@ func (self Track) Children() (r []Atom) {
 	if self.Header != nil {
 		r = append(r, self.Header)
 	}
+	if self.Edit != nil {
+		r = append(r, self.Edit)
+	}
 	if self.Media != nil {
 		r = append(r, self.Media)
 	}
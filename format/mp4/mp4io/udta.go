@@ -0,0 +1,84 @@
+package mp4io
+
+import "github.com/ugparu/gomedia/utils/bits/pio"
+
+const UDTA = Tag(0x75647461)
+
+func (self UserData) Tag() Tag {
+	return UDTA
+}
+
+// UserData is the udta container (ISO 14496-12 §8.10.1) holding file-level
+// metadata.
+type UserData struct {
+	Meta     *Meta
+	Unknowns []Atom
+	AtomPos
+}
+
+func (self UserData) Marshal(b []byte) (n int) {
+	pio.PutU32BE(b[4:], uint32(UDTA))
+	n += self.marshal(b[8:]) + 8
+	pio.PutU32BE(b[0:], uint32(n))
+	return
+}
+func (self UserData) marshal(b []byte) (n int) {
+	if self.Meta != nil {
+		n += self.Meta.Marshal(b[n:])
+	}
+	for _, atom := range self.Unknowns {
+		n += atom.Marshal(b[n:])
+	}
+	return
+}
+func (self UserData) Len() (n int) {
+	n += 8
+	if self.Meta != nil {
+		n += self.Meta.Len()
+	}
+	for _, atom := range self.Unknowns {
+		n += atom.Len()
+	}
+	return
+}
+func (self *UserData) Unmarshal(b []byte, offset int) (n int, err error) {
+	(&self.AtomPos).setPos(offset, len(b))
+	n += 8
+	for n+8 <= len(b) {
+		tag := Tag(pio.U32BE(b[n+4:]))
+		size := int(pio.U32BE(b[n:]))
+		if size < 8 || len(b) < n+size {
+			err = parseErr("TagSizeInvalid", n+offset, err)
+			return
+		}
+		switch tag {
+		case META:
+			{
+				atom := &Meta{}
+				if _, err = atom.Unmarshal(b[n:n+size], offset+n); err != nil {
+					err = parseErr("meta", n+offset, err)
+					return
+				}
+				self.Meta = atom
+			}
+		default:
+			{
+				atom := &Dummy{Tag_: tag, Data: b[n : n+size]}
+				if _, err = atom.Unmarshal(b[n:n+size], offset+n); err != nil {
+					err = parseErr("", n+offset, err)
+					return
+				}
+				self.Unknowns = append(self.Unknowns, atom)
+			}
+		}
+		n += size
+	}
+	return
+}
+func (self UserData) Children() (r []Atom) {
+	if self.Meta != nil {
+		r = append(r, self.Meta)
+	}
+	r = append(r, self.Unknowns...)
+	return
+}
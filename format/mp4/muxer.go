@@ -5,6 +5,7 @@ package mp4
 import (
 	"fmt"
 	"io"
+	"math"
 	"time"
 
 	"github.com/ugparu/gomedia"
@@ -21,12 +22,28 @@ const (
 // The muxer holds a reference to the packet to keep the ring allocator slot alive.
 type pendingWrite struct {
 	pkt       gomedia.Packet
+	data      []byte // muxer-owned sample (timed metadata) written when pkt is nil
 	extras    [maxExtras][]byte
 	numExtras int
 	extraSize int
 	totalSize int // extraSize + pkt.Len()
 }
 
+func (pw *pendingWrite) payload() []byte {
+	if pw.pkt != nil {
+		return pw.pkt.Data()
+	}
+	return pw.data
+}
+
+func (pw *pendingWrite) release() {
+	if pw.pkt != nil {
+		pw.pkt.Release()
+		pw.pkt = nil
+	}
+	pw.data = nil
+}
+
 // MuxerOption is a functional option for configuring a Muxer.
 type MuxerOption func(*Muxer)
 
@@ -40,6 +57,8 @@ func WithBatchedDump() MuxerOption {
 
 // Muxer assembles one or more media streams into a single MP4 file.
 // Packets are buffered by WritePacket; I/O is deferred until Flush or WriteTrailer.
+// Files past 4 GiB are handled transparently: mdat always carries a 64-bit
+// largesize header and chunk offsets are written as co64 once they overflow stco.
 type Muxer struct {
 	writer        io.WriteSeeker
 	writePosition int64
@@ -48,6 +67,10 @@ type Muxer struct {
 	pending       []pendingWrite // accumulated writes awaiting Flush
 	pendingSize   int            // total mdat bytes across all pending entries
 	flushed       bool           // true after first Flush() call
+
+	metadata  []metadataEntry     // file-level udta/meta key/value pairs
+	metaMIME  string              // MIME type of the timed-metadata track, empty if disabled
+	metaTrack *timedMetadataTrack // timed-metadata track, created by Mux
 }
 
 func NewMuxer(writer io.WriteSeeker, opts ...MuxerOption) *Muxer {
@@ -208,6 +231,7 @@ func (mux *Muxer) Mux(streams gomedia.CodecParametersPair) (err error) {
 	mux.streams = []*Stream{}
 	mux.flushed = false
 	mux.writePosition = headerSize
+	mux.metaTrack = nil
 
 	if streams.VideoCodecParameters != nil {
 		if err = mux.newStream(streams.VideoCodecParameters); err != nil {
@@ -226,6 +250,10 @@ func (mux *Muxer) Mux(streams gomedia.CodecParametersPair) (err error) {
 			stream.sample.CompositionOffset = new(mp4io.CompositionOffset)
 		}
 	}
+
+	if mux.metaMIME != "" {
+		mux.metaTrack = newTimedMetadataTrack(mux.metaMIME, int32(len(mux.streams)+1)) //nolint:gosec
+	}
 	return
 }
 
@@ -261,10 +289,7 @@ func (mux *Muxer) ReleasePending() { mux.releasePending() }
 
 func (mux *Muxer) releasePending() {
 	for i := range mux.pending {
-		if mux.pending[i].pkt != nil {
-			mux.pending[i].pkt.Release()
-			mux.pending[i].pkt = nil
-		}
+		mux.pending[i].release()
 	}
 	mux.pending = mux.pending[:0]
 	mux.pendingSize = 0
@@ -342,11 +367,10 @@ func (mux *Muxer) writePendingDirect() error {
 				return err
 			}
 		}
-		if _, err := mux.writer.Write(pw.pkt.Data()); err != nil {
+		if _, err := mux.writer.Write(pw.payload()); err != nil {
 			return err
 		}
-		pw.pkt.Release()
-		pw.pkt = nil
+		pw.release()
 	}
 	mux.pending = mux.pending[:0]
 	mux.pendingSize = 0
@@ -364,10 +388,8 @@ func (mux *Muxer) copyPendingInto(dst []byte, off int) int {
 			copy(dst[off:], pw.extras[j])
 			off += len(pw.extras[j])
 		}
-		copy(dst[off:], pw.pkt.Data())
-		off += pw.pkt.Len()
-		pw.pkt.Release()
-		pw.pkt = nil
+		off += copy(dst[off:], pw.payload())
+		pw.release()
 	}
 	mux.pending = mux.pending[:0]
 	mux.pendingSize = 0
@@ -379,6 +401,21 @@ func (mux *Muxer) buildMoov() ([]byte, error) {
 	moov := new(mp4io.Movie)
 	moov.Header = mp4io.NewMovieHeader()
 	moov.Header.NextTrackID = int32(len(mux.streams) + 1) //nolint:gosec
+	if mux.metaTrack != nil {
+		moov.Header.NextTrackID++
+	}
+
+	// Tracks rarely start together (audio usually arrives before the first
+	// video keyframe); edit lists shift each one onto the common timeline.
+	earliest, haveStart := time.Duration(0), false
+	for _, stream := range mux.streams {
+		if stream.hasFirstTS && (!haveStart || stream.firstTS < earliest) {
+			earliest, haveStart = stream.firstTS, true
+		}
+	}
+	if mt := mux.metaTrack; mt != nil && mt.hasFirstTS && (!haveStart || mt.firstTS < earliest) {
+		earliest = mt.firstTS
+	}
 
 	maxDur := time.Duration(0)
 	for _, stream := range mux.streams {
@@ -386,19 +423,39 @@ func (mux *Muxer) buildMoov() ([]byte, error) {
 			return nil, err
 		}
 		dur := stream.tsToTime(stream.duration)
+		stream.trackAtom.Edit, dur = stream.editList(earliest, int64(moov.Header.TimeScale))
 		stream.trackAtom.Header.Duration = timeToTS(dur, int64(moov.Header.TimeScale))
 		if dur > maxDur {
 			maxDur = dur
 		}
+		stream.trackAtom.Header.Version = headerVersion(stream.trackAtom.Header.Duration)
+		stream.trackAtom.Media.Header.Version = headerVersion(stream.trackAtom.Media.Header.Duration)
 		moov.Tracks = append(moov.Tracks, stream.trackAtom)
 	}
+	if mux.metaTrack != nil && mux.metaTrack.sampleCount() > 0 {
+		track, dur := mux.metaTrack.buildTrack(earliest, int64(moov.Header.TimeScale))
+		maxDur = max(maxDur, dur)
+		moov.Tracks = append(moov.Tracks, track)
+	}
 	moov.Header.Duration = timeToTS(maxDur, int64(moov.Header.TimeScale))
+	moov.UserData = mux.buildUserData()
+	moov.Header.Version = headerVersion(moov.Header.Duration)
 
 	b := make([]byte, moov.Len())
 	moov.Marshal(b)
 	return b, nil
 }
 
+// headerVersion picks the mvhd/tkhd/mdhd box version for a duration: version 1
+// carries 64-bit times (ISO 14496-12 §8.2.2), needed once a long recording at
+// a 90 kHz timescale no longer fits the signed 32-bit field our demuxer reads.
+func headerVersion(duration int64) uint8 {
+	if duration > math.MaxInt32 {
+		return 1
+	}
+	return 0
+}
+
 // WriteTrailer completes the MP4 file by writing the trailer and necessary metadata.
 // All accumulated packets are released (even on error).
 // If Flush was never called, the entire MP4 is written without seeks.
@@ -0,0 +1,245 @@
+package mp4
+
+import (
+	"errors"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ugparu/gomedia"
+)
+
+// PlaybackOption is a functional option for configuring a playback demuxer.
+type PlaybackOption func(*playback)
+
+// WithPlaybackRate sets the playback speed relative to real time: 2 plays
+// twice as fast, 0.5 at half speed. Non-positive rates are ignored. Packet
+// timestamps keep media time; StartTime follows the wall clock.
+func WithPlaybackRate(rate float64) PlaybackOption {
+	return func(p *playback) {
+		if rate > 0 {
+			p.rate = rate
+		}
+	}
+}
+
+// WithLoop restarts the file from the beginning at EOF. Timestamps keep
+// increasing across loops.
+func WithLoop() PlaybackOption {
+	return func(p *playback) { p.loop = true }
+}
+
+// WithStartOffset skips the first d of media time. Playback starts at the
+// first video keyframe at or after d, so the first packet is decodable. The
+// keyframe is looked up in the sample tables and the samples before it are
+// not read. Loops restart from the beginning of the file.
+func WithStartOffset(d time.Duration) PlaybackOption {
+	return func(p *playback) { p.offset = d }
+}
+
+// streamEnd tracks where the last packet of a stream ends.
+type streamEnd struct {
+	last time.Duration // timestamp of the last packet
+	step time.Duration // its duration, or the gap to the packet before it
+}
+
+// playback releases the packets of an MP4 file as a live source would: each
+// packet is returned once the wall clock reaches its timestamp, timestamps
+// start at zero and StartTime is the wall time of the packet.
+type playback struct {
+	dmx    *Demuxer
+	rate   float64
+	loop   bool
+	offset time.Duration
+
+	mu        sync.Mutex // serialises reads of dmx with Close
+	done      chan struct{}
+	closeOnce sync.Once
+
+	started  bool                // the current pass released a packet
+	passBase time.Duration       // file timestamp of the first packet of the pass
+	shift    time.Duration       // output timestamp of passBase
+	ends     map[uint8]streamEnd // per stream index, in file time
+	epoch    time.Time           // wall time of output timestamp zero
+}
+
+// NewPlaybackDemuxer returns a demuxer that plays the MP4 file at url (a
+// local path) in real time. See WithPlaybackRate, WithLoop and
+// WithStartOffset. Close may be called from another goroutine to interrupt
+// a ReadPacket waiting for its packet, which then returns io.EOF.
+func NewPlaybackDemuxer(url string, opts ...PlaybackOption) gomedia.Demuxer {
+	return newPlayback(&Demuxer{url: url}, opts)
+}
+
+// NewPlaybackDemuxerFromReader is NewPlaybackDemuxer for an MP4 read from
+// r, as with NewDemuxerFromReader. The caller keeps ownership of r.
+func NewPlaybackDemuxerFromReader(r io.ReadSeeker, sourceID string, opts ...PlaybackOption) gomedia.Demuxer {
+	return newPlayback(&Demuxer{r: r, url: sourceID}, opts)
+}
+
+func newPlayback(dmx *Demuxer, opts []PlaybackOption) *playback {
+	p := &playback{
+		dmx:  dmx,
+		rate: 1,
+		done: make(chan struct{}),
+		ends: map[uint8]streamEnd{},
+	}
+	for _, o := range opts {
+		o(p)
+	}
+	return p
+}
+
+func (p *playback) Demux() (gomedia.CodecParametersPair, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	params, err := p.dmx.Demux()
+	if err != nil || p.offset <= 0 {
+		return params, err
+	}
+	return params, p.seekOffset()
+}
+
+// seekOffset moves the demuxer to the first keyframe at or after the start
+// offset, found in the sample tables, so the samples before it are never
+// read. Past the last keyframe the file is played from its end, i.e. not at
+// all.
+func (p *playback) seekOffset() error {
+	keyframes, err := p.dmx.Keyframes()
+	if err != nil {
+		return err
+	}
+	i := sort.Search(len(keyframes), func(i int) bool { return keyframes[i] >= p.offset })
+	if i < len(keyframes) {
+		return p.dmx.SeekTo(keyframes[i])
+	}
+	end, err := p.dmx.Duration()
+	if err != nil {
+		return err
+	}
+	return p.dmx.SeekTo(end + 1)
+}
+
+// ReadPacket returns the next packet once it is due. A consumer that falls
+// behind gets the overdue packets at once.
+func (p *playback) ReadPacket() (gomedia.Packet, error) {
+	pkt, err := p.next()
+	if err != nil || pkt == nil {
+		return pkt, err
+	}
+
+	ts := p.shift + pkt.Timestamp() - p.passBase
+	if p.epoch.IsZero() {
+		p.epoch = time.Now()
+	}
+	at := p.epoch.Add(time.Duration(float64(ts) / p.rate))
+	if !p.wait(at) {
+		pkt.Release()
+		return nil, io.EOF
+	}
+	pkt.SetTimestamp(ts)
+	pkt.SetStartTime(at)
+	return pkt, nil
+}
+
+func (p *playback) Close() {
+	p.closeOnce.Do(func() { close(p.done) })
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.dmx.Close()
+}
+
+// next reads the next packet of the file, skipping up to the start of the
+// pass and rewinding at EOF when looping.
+func (p *playback) next() (gomedia.Packet, error) {
+	for {
+		select {
+		case <-p.done:
+			return nil, io.EOF
+		default:
+		}
+
+		p.mu.Lock()
+		pkt, err := p.dmx.ReadPacket()
+		p.mu.Unlock()
+		if errors.Is(err, io.EOF) && p.loop && p.started {
+			p.rewind()
+			continue
+		}
+		if err != nil || pkt == nil {
+			return pkt, err
+		}
+
+		if !p.started {
+			if p.skip(pkt) {
+				pkt.Release()
+				continue
+			}
+			p.started = true
+			p.passBase = pkt.Timestamp()
+		}
+		p.track(pkt)
+		return pkt, nil
+	}
+}
+
+// skip reports whether pkt precedes the first packet of the pass: the start
+// offset and, when the file has video, the first keyframe. Demux already
+// sought there, so this only guards against files whose sample tables and
+// packets disagree.
+func (p *playback) skip(pkt gomedia.Packet) bool {
+	if pkt.Timestamp() < p.offset {
+		return true
+	}
+	if p.dmx.videoCodecData == nil {
+		return false
+	}
+	vPkt, ok := pkt.(gomedia.VideoPacket)
+	return !ok || !vPkt.IsKeyFrame()
+}
+
+func (p *playback) track(pkt gomedia.Packet) {
+	ts := pkt.Timestamp()
+	e, ok := p.ends[pkt.StreamIndex()]
+	if ok && ts > e.last {
+		e.step = ts - e.last
+	}
+	if d := pkt.Duration(); d > 0 {
+		e.step = d
+	}
+	e.last = ts
+	p.ends[pkt.StreamIndex()] = e
+}
+
+// rewind starts the next pass right where the current one ends.
+func (p *playback) rewind() {
+	var end time.Duration
+	for _, e := range p.ends {
+		end = max(end, e.last+e.step)
+	}
+	p.shift += end - p.passBase
+	clear(p.ends)
+	p.started = false
+	p.offset = 0
+
+	p.mu.Lock()
+	p.dmx.rewind()
+	p.mu.Unlock()
+}
+
+// wait blocks until at and reports false when Close interrupted it.
+func (p *playback) wait(at time.Time) bool {
+	d := time.Until(at)
+	if d <= 0 {
+		return true
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-p.done:
+		return false
+	}
+}
@@ -0,0 +1,623 @@
+package mp4
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+	"os"
+	"time"
+
+	"github.com/ugparu/gomedia"
+	"github.com/ugparu/gomedia/codec/aac"
+	"github.com/ugparu/gomedia/codec/h264"
+	"github.com/ugparu/gomedia/codec/h265"
+	"github.com/ugparu/gomedia/format/mp4/mp4io"
+	"github.com/ugparu/gomedia/utils/bits/pio"
+)
+
+const (
+	avccLengthSize     = 4    //nolint:mnd // Muxer always writes 4-byte AVCC length prefixes
+	minAACFrameSize    = 4    //nolint:mnd // a silent mono AAC-LC raw_data_block is 4 bytes
+	defaultRecoverFPS  = 25   //nolint:mnd // fallback when the SPS carries no VUI timing
+	aacSamplesPerFrame = 1024 //nolint:mnd // AAC-LC frame length per ISO 14496-3 §4.5.1.1
+	// recoverWindow bounds the mdat bytes held in memory while scanning.
+	// Half of it is kept ahead of the scan position, which is the largest
+	// sample that can be recovered.
+	recoverWindow = 32 << 20 //nolint:mnd // 32 MiB
+)
+
+// RecoverOption is a functional option for configuring Recover.
+type RecoverOption func(*recoverConfig)
+
+type recoverConfig struct {
+	window    int
+	fps       uint
+	startTime time.Time
+	muxerOpts []MuxerOption
+}
+
+// WithRecoverFPS overrides the frame rate used to rebuild video sample
+// durations. By default the FPS of the video codec parameters is used,
+// falling back to 25 when the SPS carries no timing information.
+func WithRecoverFPS(fps uint) RecoverOption {
+	return func(c *recoverConfig) { c.fps = fps }
+}
+
+// WithRecoverStartTime sets the wall-clock time stamped on recovered packets
+// (Packet.StartTime). Defaults to the time Recover is called.
+func WithRecoverStartTime(t time.Time) RecoverOption {
+	return func(c *recoverConfig) { c.startTime = t }
+}
+
+// WithRecoverMuxerOptions passes options through to the Muxer that writes the
+// recovered file.
+func WithRecoverMuxerOptions(opts ...MuxerOption) RecoverOption {
+	return func(c *recoverConfig) { c.muxerOpts = append(c.muxerOpts, opts...) }
+}
+
+// RecoverStats summarises what Recover salvaged from a truncated file.
+type RecoverStats struct {
+	VideoSamples int           // video access units written
+	Keyframes    int           // of which keyframes
+	AudioSamples int           // AAC frames written
+	SkippedBytes int64         // mdat bytes that could not be attributed to any sample
+	Truncated    bool          // the trailing sample was cut short and dropped
+	Duration     time.Duration // duration of the longest recovered track
+}
+
+// ParametersFromFile demuxes a healthy MP4 (typically a sibling segment
+// recorded from the same camera) and returns its codec parameters for use
+// with Recover.
+func ParametersFromFile(path string) (gomedia.CodecParametersPair, error) {
+	dmx := NewDemuxer(path)
+	defer dmx.Close()
+	return dmx.Demux()
+}
+
+// RecoverFile is a convenience wrapper around Recover that reads src and
+// writes the rebuilt file to dst. On error the partial dst is removed.
+func RecoverFile(src, dst string, params gomedia.CodecParametersPair, opts ...RecoverOption) (RecoverStats, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return RecoverStats{}, err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return RecoverStats{}, err
+	}
+	stats, err := Recover(in, out, params, opts...)
+	if cErr := out.Close(); err == nil {
+		err = cErr
+	}
+	if err != nil {
+		_ = os.Remove(dst)
+	}
+	return stats, err
+}
+
+// Recover rebuilds a playable MP4 from a progressive file whose moov atom was
+// never written (e.g. the recording process died before WriteTrailer). The
+// mdat payload is scanned for sample boundaries: video access units are found
+// by walking AVCC length prefixes and validating NAL headers, and the bytes
+// between them are split into raw AAC frames using the raw_data_block element
+// framing (leading SCE/CPE element, trailing ID_END + byte alignment). Sample
+// timing is not stored in mdat, so it is synthesised from the frame rate and
+// the AAC frame length. params must describe the codecs the file was recorded
+// with — see ParametersFromFile. The mdat is read through a bounded window,
+// so memory use does not grow with the file.
+func Recover(r io.ReadSeeker, w io.WriteSeeker, params gomedia.CodecParametersPair, opts ...RecoverOption) (stats RecoverStats, err error) {
+	cfg := recoverConfig{window: recoverWindow, startTime: time.Now()}
+	for _, o := range opts {
+		o(&cfg)
+	}
+
+	sc, pair, err := newRecoverScanner(params, cfg)
+	if err != nil {
+		return
+	}
+
+	size, err := seekMdatPayload(r)
+	if err != nil {
+		return
+	}
+	sc.src, sc.remaining = r, size
+	sc.buf = make([]byte, min(int64(cfg.window), size))
+
+	mux := NewMuxer(w, cfg.muxerOpts...)
+	if err = mux.Mux(pair); err != nil {
+		return
+	}
+	sc.emit = func(pkt gomedia.Packet) error { return mux.WritePacket(pkt) }
+
+	if err = sc.scan(); err != nil {
+		mux.ReleasePending()
+		return
+	}
+	stats = sc.stats
+	if stats.VideoSamples == 0 && stats.AudioSamples == 0 {
+		mux.ReleasePending()
+		err = errors.New("mp4: no recoverable samples found in mdat")
+		return
+	}
+
+	err = mux.WriteTrailer()
+	return
+}
+
+// seekMdatPayload walks the top-level boxes of r, leaves r at the payload of
+// the first mdat and returns the payload size. A zero or overlong size (the placeholder left by Flush, or a
+// file cut mid-write) is clamped to EOF. Files that already carry a moov are
+// rejected so a healthy recording is never rewritten with synthetic timing.
+func seekMdatPayload(r io.ReadSeeker) (int64, error) {
+	fileSize, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		hdr                [16]byte //nolint:mnd // size + tag + largesize
+		mdatStart, mdatEnd int64    = -1, -1
+	)
+	for off := int64(0); off+mp4io.HeaderSize <= fileSize; {
+		if _, err = r.Seek(off, io.SeekStart); err != nil {
+			return 0, err
+		}
+		if _, err = io.ReadFull(r, hdr[:mp4io.HeaderSize]); err != nil {
+			return 0, err
+		}
+		size := int64(pio.U32BE(hdr[0:]))
+		tag := mp4io.Tag(pio.U32BE(hdr[4:]))
+		hdrLen := int64(mp4io.HeaderSize)
+
+		if size == 1 {
+			if _, err = io.ReadFull(r, hdr[mp4io.HeaderSize:]); err != nil {
+				return 0, err
+			}
+			size = int64(pio.U64BE(hdr[mp4io.HeaderSize:])) //nolint:gosec
+			hdrLen += 8                                     //nolint:mnd // largesize field
+		}
+
+		if tag == mp4io.MOOV {
+			return 0, errors.New("mp4: file has a 'moov' atom, nothing to recover")
+		}
+
+		end := off + size
+		if size < hdrLen || end > fileSize {
+			end = fileSize
+		}
+		if tag == mp4io.MDAT && mdatStart < 0 {
+			mdatStart, mdatEnd = off+hdrLen, end
+		}
+		off = end
+	}
+
+	if mdatStart < 0 {
+		return 0, errors.New("mp4: 'mdat' atom not found")
+	}
+	if _, err = r.Seek(mdatStart, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return mdatEnd - mdatStart, nil
+}
+
+// recoverScanner splits an mdat payload into samples and feeds them to emit.
+// The payload is scanned through data, a window into buf that fill slides
+// forward; positions are relative to the window, and its end is the end of
+// the payload only once eof is set.
+type recoverScanner struct {
+	src       io.Reader
+	remaining int64 // payload bytes not read from src yet
+	buf       []byte
+	data      []byte
+	eof       bool
+	emit      func(gomedia.Packet) error
+
+	videoType gomedia.CodecType // zero when the file has no video track
+	h264Par   *h264.CodecParameters
+	h265Par   *h265.CodecParameters
+	frameDur  time.Duration
+
+	aacPar      *aac.CodecParameters
+	aacElement  byte // expected syntactic element id of the first element
+	aacFrameDur time.Duration
+
+	startTime time.Time
+	sourceID  string
+	stats     RecoverStats
+}
+
+// newRecoverScanner copies the caller's parameters (stream indexes are
+// reassigned to match the Muxer's track order) and derives sample timing.
+func newRecoverScanner(params gomedia.CodecParametersPair,
+	cfg recoverConfig) (*recoverScanner, gomedia.CodecParametersPair, error) {
+	sc := &recoverScanner{startTime: cfg.startTime, sourceID: params.SourceID}
+	pair := gomedia.CodecParametersPair{SourceID: params.SourceID}
+
+	var idx uint8
+	if params.VideoCodecParameters != nil {
+		fps := cfg.fps
+		if fps == 0 {
+			fps = params.VideoCodecParameters.FPS()
+		}
+		if fps == 0 {
+			fps = defaultRecoverFPS
+		}
+		sc.frameDur = time.Second / time.Duration(fps)
+
+		switch par := params.VideoCodecParameters.(type) {
+		case *h264.CodecParameters:
+			cp := *par
+			cp.SetStreamIndex(idx)
+			sc.h264Par = &cp
+			pair.VideoCodecParameters = &cp
+		case *h265.CodecParameters:
+			cp := *par
+			cp.SetStreamIndex(idx)
+			sc.h265Par = &cp
+			pair.VideoCodecParameters = &cp
+		default:
+			return nil, pair, fmt.Errorf("mp4: recovery of video codec %v is not supported", par.Type())
+		}
+		sc.videoType = params.VideoCodecParameters.Type()
+		idx++
+	}
+
+	if params.AudioCodecParameters != nil {
+		par, ok := params.AudioCodecParameters.(*aac.CodecParameters)
+		if !ok {
+			return nil, pair, fmt.Errorf("mp4: recovery of audio codec %v is not supported", params.AudioCodecParameters.Type())
+		}
+		if par.SampleRate() == 0 {
+			return nil, pair, errors.New("mp4: AAC parameters carry no sample rate")
+		}
+		cp := *par
+		cp.SetStreamIndex(idx)
+		sc.aacPar = &cp
+		pair.AudioCodecParameters = &cp
+		sc.aacFrameDur = aacSamplesPerFrame * time.Second / time.Duration(par.SampleRate()) //nolint:gosec
+		const idSCE, idCPE = 0, 1                                                           // ISO 14496-3 Table 4.85
+		sc.aacElement = idSCE
+		if par.Config.ChannelConfig == 2 { //nolint:mnd // stereo is coded as a single CPE
+			sc.aacElement = idCPE
+		}
+	}
+
+	if sc.videoType == 0 && sc.aacPar == nil {
+		return nil, pair, errors.New("mp4: no codec parameters supplied for recovery")
+	}
+	return sc, pair, nil
+}
+
+// fill slides the window so that it holds half a window of payload from p,
+// or all of the rest, and returns p's new position.
+func (sc *recoverScanner) fill(p int) (int, error) {
+	if sc.eof || len(sc.data)-p >= len(sc.buf)/2 {
+		return p, nil
+	}
+	n := copy(sc.buf, sc.data[p:])
+	m := int(min(int64(len(sc.buf)-n), sc.remaining))
+	if _, err := io.ReadFull(sc.src, sc.buf[n:n+m]); err != nil {
+		return p, err
+	}
+	sc.remaining -= int64(m)
+	sc.eof = sc.remaining == 0
+	sc.data = sc.buf[:n+m]
+	return 0, nil
+}
+
+func (sc *recoverScanner) scan() error {
+	for p := 0; ; {
+		var err error
+		if p, err = sc.fill(p); err != nil {
+			return err
+		}
+		if p >= len(sc.data) {
+			return nil
+		}
+
+		if sc.videoType != 0 {
+			end, key, res := sc.videoSample(p)
+			switch res {
+			case sampleOK:
+				if err := sc.writeVideo(sc.data[p:end], key); err != nil {
+					return err
+				}
+				p = end
+				continue
+			case sampleTruncated:
+				// A cut-off access unit can only sit at the tail. If these bytes
+				// also read as AAC frames followed by more video, they are audio.
+				// Before the tail, it is a sample larger than the window.
+				if sc.eof && (sc.aacPar == nil || !sc.isAACFrameStart(p) || sc.audioRunEnd(p) == len(sc.data)) {
+					sc.stats.Truncated = true
+					sc.stats.SkippedBytes += int64(len(sc.data) - p)
+					return nil
+				}
+			}
+		}
+
+		if sc.aacPar != nil && sc.isAACFrameStart(p) {
+			if p, err = sc.writeAudioRun(p, sc.audioRunEnd(p)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// Neither a NAL unit chain nor an AAC frame: resynchronise.
+		sc.stats.SkippedBytes++
+		p++
+	}
+}
+
+type sampleResult int
+
+const (
+	sampleInvalid sampleResult = iota
+	sampleOK
+	sampleTruncated
+)
+
+// videoSample reports whether a complete access unit starts at p and returns
+// its end offset. An access unit ends at the first NAL unit that starts a new
+// picture, at the first bytes that do not form a valid NAL unit (audio), or at
+// the end of the payload.
+func (sc *recoverScanner) videoSample(p int) (end int, key bool, res sampleResult) {
+	q := p
+	sawVCL := false
+	for q < len(sc.data) {
+		size, ok := sc.naluAt(q)
+		if !ok {
+			break
+		}
+		hdr := sc.data[q+avccLengthSize : min(q+avccLengthSize+size, len(sc.data))]
+		if q == p && !sc.startsAccessUnit(hdr) {
+			return 0, false, sampleInvalid
+		}
+		if q+avccLengthSize+size > len(sc.data) {
+			return 0, false, sampleTruncated
+		}
+		if sawVCL && sc.startsAccessUnit(hdr) {
+			break
+		}
+		vcl, isKey := sc.classify(hdr)
+		sawVCL = sawVCL || vcl
+		key = key || isKey
+		q += avccLengthSize + size
+	}
+
+	if !sawVCL {
+		return 0, false, sampleInvalid
+	}
+	if q < len(sc.data) && sc.aacPar != nil && !sc.isAACFrameStart(q) {
+		if _, ok := sc.naluAt(q); !ok {
+			return 0, false, sampleInvalid
+		}
+	}
+	return q, key, sampleOK
+}
+
+// naluAt validates the AVCC length prefix and NAL header at p.
+func (sc *recoverScanner) naluAt(p int) (size int, ok bool) {
+	if p+avccLengthSize+2 > len(sc.data) {
+		return 0, false
+	}
+	size = int(pio.U32BE(sc.data[p:]))
+	if size < 2 || size > len(sc.data) {
+		return 0, false
+	}
+	b0, b1 := sc.data[p+avccLengthSize], sc.data[p+avccLengthSize+1]
+	if b0&0x80 != 0 { //nolint:mnd // forbidden_zero_bit
+		return 0, false
+	}
+
+	switch sc.videoType {
+	case gomedia.H264:
+		refIdc := b0 >> 5 & 0x03 //nolint:mnd // nal_ref_idc
+		switch b0 & 0x1f {       //nolint:mnd // nal_unit_type
+		case 1, 2, 3, 4:
+			return size, true
+		case h264.NaluCodedIDR, h264.NaluSPS, h264.NaluPPS:
+			return size, refIdc != 0
+		case 6, 9, 10, 11, 12: //nolint:mnd // SEI, AUD, end of seq/stream, filler: nal_ref_idc must be 0
+			return size, refIdc == 0
+		}
+	case gomedia.H265:
+		layerID := (b0&0x01)<<5 | b1>>3 //nolint:mnd // nuh_layer_id
+		tid := b1 & 0x07                //nolint:mnd // nuh_temporal_id_plus1
+		if layerID != 0 || tid == 0 {
+			return 0, false
+		}
+		typ := (b0 >> 1) & h265NALTypeMask
+		switch {
+		case typ <= h265.NalUnitCodedSliceRaslR,
+			typ >= h265.NalUnitCodedSliceBlaWLp && typ <= h265.NalUnitCodedSliceCra,
+			typ >= h265.NalUnitVps && typ <= h265.NalUnitSuffixSei:
+			return size, true
+		}
+	}
+	return 0, false
+}
+
+// startsAccessUnit reports whether nalu can only appear at the start of a new
+// access unit: parameter sets, AUD, prefix SEI or the first slice of a picture.
+func (sc *recoverScanner) startsAccessUnit(nalu []byte) bool {
+	switch sc.videoType {
+	case gomedia.H264:
+		switch nalu[0] & 0x1f { //nolint:mnd // nal_unit_type
+		case 6, h264.NaluSPS, h264.NaluPPS, 9: //nolint:mnd // SEI, SPS, PPS, AUD
+			return true
+		case 1, h264.NaluCodedIDR:
+			return nalu[1]&0x80 != 0 //nolint:mnd // first_mb_in_slice == 0 codes as a single '1' bit
+		}
+	case gomedia.H265:
+		switch typ := (nalu[0] >> 1) & h265NALTypeMask; typ {
+		case h265.NalUnitVps, h265.NalUnitSps, h265.NalUnitPps,
+			h265.NalUnitAccessUnitDelimiter, h265.NalUnitPrefixSei:
+			return true
+		default:
+			return isH265FirstSliceInPicture(nalu)
+		}
+	}
+	return false
+}
+
+func (sc *recoverScanner) classify(nalu []byte) (vcl, key bool) {
+	switch sc.videoType {
+	case gomedia.H264:
+		typ := nalu[0] & 0x1f //nolint:mnd // nal_unit_type
+		return typ >= 1 && typ <= h264.NaluCodedIDR, typ == h264.NaluCodedIDR
+	case gomedia.H265:
+		typ := (nalu[0] >> 1) & h265NALTypeMask
+		return isH265Slice(nalu), h265.IsKey(typ)
+	}
+	return false, false
+}
+
+// isParameterSet reports whether nalu is an SPS/PPS/VPS. The Muxer re-inserts
+// the configured parameter sets in front of every keyframe, so recovered
+// in-band copies are dropped to avoid duplicating them.
+func (sc *recoverScanner) isParameterSet(nalu []byte) bool {
+	switch sc.videoType {
+	case gomedia.H264:
+		typ := nalu[0] & 0x1f //nolint:mnd // nal_unit_type
+		return typ == h264.NaluSPS || typ == h264.NaluPPS
+	case gomedia.H265:
+		typ := (nalu[0] >> 1) & h265NALTypeMask
+		return typ == h265.NalUnitVps || typ == h265.NalUnitSps || typ == h265.NalUnitPps
+	}
+	return false
+}
+
+func (sc *recoverScanner) writeVideo(au []byte, key bool) error {
+	data := make([]byte, 0, len(au))
+	for q := 0; q < len(au); {
+		size := int(pio.U32BE(au[q:]))
+		nalu := au[q : q+avccLengthSize+size]
+		if !sc.isParameterSet(nalu[avccLengthSize:]) {
+			data = append(data, nalu...)
+		}
+		q += avccLengthSize + size
+	}
+	if len(data) == 0 {
+		return nil
+	}
+
+	ts := time.Duration(sc.stats.VideoSamples) * sc.frameDur
+	var pkt gomedia.Packet
+	switch sc.videoType {
+	case gomedia.H264:
+		pkt = h264.NewPacket(key, ts, sc.startTime.Add(ts), data, sc.sourceID, sc.h264Par)
+	case gomedia.H265:
+		pkt = h265.NewPacket(key, ts, sc.startTime.Add(ts), data, sc.sourceID, sc.h265Par)
+	}
+	pkt.SetDuration(sc.frameDur)
+
+	sc.stats.VideoSamples++
+	if key {
+		sc.stats.Keyframes++
+	}
+	sc.stats.Duration = max(sc.stats.Duration, ts+sc.frameDur)
+	return sc.emit(pkt)
+}
+
+// isAACFrameStart checks that the first syntactic element at p matches the
+// channel configuration with element_instance_tag 0.
+func (sc *recoverScanner) isAACFrameStart(p int) bool {
+	if p >= len(sc.data) {
+		return false
+	}
+	return sc.data[p]>>1 == sc.aacElement<<4 //nolint:mnd // id_syn_ele(3) + element_instance_tag(4)
+}
+
+// isAACFrameEnd checks that the bytes before p end with ID_END (0b111)
+// followed by 0-7 zero bits of byte alignment.
+func (sc *recoverScanner) isAACFrameEnd(p int) bool {
+	if p < 2 { //nolint:mnd // ID_END may straddle the last two bytes
+		return false
+	}
+	v := uint16(sc.data[p-2])<<8 | uint16(sc.data[p-1])
+	if v == 0 {
+		return false
+	}
+	tz := bits.TrailingZeros16(v)
+	return tz < 8 && (v>>tz)&0x07 == 0x07 //nolint:mnd // at most 7 padding bits, then ID_END
+}
+
+// audioRunEnd returns the end of the run of AAC frames starting at p: the
+// first frame boundary followed by a complete video access unit, or the end
+// of the window.
+func (sc *recoverScanner) audioRunEnd(p int) int {
+	if sc.videoType == 0 {
+		return len(sc.data)
+	}
+	for q := p + minAACFrameSize; q < len(sc.data); q++ {
+		if !sc.isAACFrameEnd(q) {
+			continue
+		}
+		if _, _, res := sc.videoSample(q); res != sampleInvalid {
+			return q
+		}
+	}
+	return len(sc.data)
+}
+
+// writeAudioRun splits [start, end) into AAC frames at positions where one
+// frame's ID_END is immediately followed by the next frame's first element,
+// and returns where scanning continues.
+func (sc *recoverScanner) writeAudioRun(start, end int) (int, error) {
+	next := end
+	if end == len(sc.data) && (!sc.eof || !sc.isAACFrameEnd(end)) {
+		// Trim back to the last boundary we can vouch for.
+		trimmed := start
+		for q := start + minAACFrameSize; q < end; q++ {
+			if sc.isAACFrameEnd(q) && sc.isAACFrameStart(q) {
+				trimmed = q
+			}
+		}
+		switch {
+		case sc.eof:
+			// The file was cut inside the last frame.
+			sc.stats.Truncated = true
+			sc.stats.SkippedBytes += int64(end - trimmed)
+		case trimmed == start:
+			// No frame boundary within the window: resynchronise.
+			sc.stats.SkippedBytes++
+			next = start + 1
+		default:
+			// The run goes on past the window: continue after the last
+			// frame it holds.
+			next = trimmed
+		}
+		end = trimmed
+	}
+
+	frameStart := start
+	for q := start + minAACFrameSize; q <= end; q++ {
+		if q != end && (q-frameStart < minAACFrameSize || !sc.isAACFrameEnd(q) || !sc.isAACFrameStart(q)) {
+			continue
+		}
+		if q-frameStart < minAACFrameSize {
+			sc.stats.SkippedBytes += int64(q - frameStart)
+			break
+		}
+		if err := sc.writeAudio(sc.data[frameStart:q]); err != nil {
+			return next, err
+		}
+		frameStart = q
+	}
+	return next, nil
+}
+
+func (sc *recoverScanner) writeAudio(frame []byte) error {
+	ts := time.Duration(sc.stats.AudioSamples) * sc.aacFrameDur
+	data := make([]byte, len(frame))
+	copy(data, frame)
+	pkt := aac.NewPacket(data, ts, sc.sourceID, sc.startTime.Add(ts), sc.aacPar, sc.aacFrameDur)
+
+	sc.stats.AudioSamples++
+	sc.stats.Duration = max(sc.stats.Duration, ts+sc.aacFrameDur)
+	return sc.emit(pkt)
+}
@@ -0,0 +1,195 @@
+package mp4
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/ugparu/gomedia"
+)
+
+// Keyframes returns the timestamps playback can start at, read from the
+// sample tables (stss and stts) without touching sample data: the sync
+// samples of the video track, or every sample of a file without video.
+func (dmx *Demuxer) Keyframes() ([]time.Duration, error) {
+	if dmx.r == nil {
+		return nil, errors.New("mp4: Keyframes called before Demux")
+	}
+	var times []time.Duration
+	for _, s := range dmx.streams {
+		_, isVideo := s.CodecParameters.(gomedia.VideoCodecParameters)
+		if isVideo == (dmx.videoCodecData != nil) {
+			times = append(times, s.syncTimes()...)
+		}
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+	return times, nil
+}
+
+// Duration returns where the longest track ends, from the sample tables.
+// The last sample of a track is taken to last as long as the one before it:
+// a recorder does not know how long its final frame is shown, and the
+// Muxer writes it with a nominal duration.
+func (dmx *Demuxer) Duration() (time.Duration, error) {
+	if dmx.r == nil {
+		return 0, errors.New("mp4: Duration called before Demux")
+	}
+	var end time.Duration
+	for _, s := range dmx.streams {
+		var dts, last, prev int64
+		for _, e := range s.sample.TimeToSample.Entries {
+			if e.Count == 0 {
+				continue
+			}
+			dts += int64(e.Count) * int64(e.Duration)
+			if e.Count > 1 {
+				prev = int64(e.Duration)
+			} else {
+				prev = last
+			}
+			last = int64(e.Duration)
+		}
+		if dts > last {
+			dts += prev - last
+		}
+		end = max(end, s.tsToTime(dts)+s.editShift)
+	}
+	return end, nil
+}
+
+// SeekTo moves every stream to its first sample at or after tm, so that
+// ReadPacket continues from there. The samples are located through the
+// sample tables; nothing before them is read. Seeking to a timestamp from
+// Keyframes makes the first video packet a keyframe.
+func (dmx *Demuxer) SeekTo(tm time.Duration) error {
+	if dmx.r == nil {
+		return errors.New("mp4: SeekTo called before Demux")
+	}
+	for _, s := range dmx.streams {
+		s.seekSample(s.sampleAt(tm))
+	}
+	return nil
+}
+
+// syncTimes returns the timestamps of the stream's sync samples. Video
+// without stss has none, as ReadPacket then marks no packet as a keyframe;
+// other streams without stss are all sync samples.
+func (s *Stream) syncTimes() []time.Duration {
+	var syncs []uint32
+	if s.sample.SyncSample != nil {
+		syncs = s.sample.SyncSample.Entries
+	} else if _, isVideo := s.CodecParameters.(gomedia.VideoCodecParameters); isVideo {
+		return nil
+	}
+
+	var times []time.Duration
+	var dts int64
+	first := uint32(1) // number of the first sample of the stts entry
+	for _, e := range s.sample.TimeToSample.Entries {
+		if s.sample.SyncSample == nil {
+			for i := range int64(e.Count) {
+				times = append(times, s.tsToTime(dts+i*int64(e.Duration))+s.editShift)
+			}
+		}
+		for len(syncs) > 0 && syncs[0] < first+e.Count {
+			times = append(times, s.tsToTime(dts+int64(syncs[0]-first)*int64(e.Duration))+s.editShift)
+			syncs = syncs[1:]
+		}
+		dts += int64(e.Count) * int64(e.Duration)
+		first += e.Count
+	}
+	return times
+}
+
+// sampleAt returns the index of the first sample whose timestamp is at or
+// after tm, or the sample count when there is none.
+func (s *Stream) sampleAt(tm time.Duration) int {
+	at := func(dts int64) bool { return s.tsToTime(dts)+s.editShift >= tm }
+	var dts int64
+	var n int
+	for _, e := range s.sample.TimeToSample.Entries {
+		count, dur := int64(e.Count), int64(e.Duration)
+		if at(dts) {
+			return n
+		}
+		if dur > 0 && at(dts+(count-1)*dur) {
+			// Estimate from the time scale, then settle on the exact sample.
+			i := min(max(timeToTS(tm-s.editShift, s.timeScale)-dts, 0)/dur, count-1)
+			for i > 0 && at(dts+(i-1)*dur) {
+				i--
+			}
+			for !at(dts + i*dur) {
+				i++
+			}
+			return n + int(i)
+		}
+		dts += count * dur
+		n += int(count)
+	}
+	return n
+}
+
+// seekSample moves the read position to sample n (zero-based) by walking the
+// run-length tables instead of the samples before it.
+func (s *Stream) seekSample(n int) {
+	s.rewind()
+
+	rest := n
+	for _, e := range s.sample.TimeToSample.Entries {
+		if rest < int(e.Count) {
+			s.dts += int64(rest) * int64(e.Duration)
+			s.sampleIndexInSttsEntry = rest
+			break
+		}
+		s.dts += int64(e.Count) * int64(e.Duration)
+		rest -= int(e.Count)
+		s.sttsEntryIndex++
+	}
+
+	if ctts := s.sample.CompositionOffset; ctts != nil {
+		rest = n
+		for _, e := range ctts.Entries {
+			if rest < int(e.Count) {
+				s.sampleIndexInCttsEntry = rest
+				break
+			}
+			rest -= int(e.Count)
+			s.cttsEntryIndex++
+		}
+	}
+
+	// Chunk runs: entry k covers chunks FirstChunk(k) .. FirstChunk(k+1)-1.
+	rest = n
+	stsc := s.sample.SampleToChunk.Entries
+	for k, e := range stsc {
+		s.chunkGroupIndex = k
+		perChunk := int(e.SamplesPerChunk)
+		chunks := math.MaxInt / max(perChunk, 1)
+		if k+1 < len(stsc) {
+			chunks = int(stsc[k+1].FirstChunk - e.FirstChunk)
+		}
+		if perChunk > 0 && rest < chunks*perChunk {
+			s.chunkIndex = int(e.FirstChunk) - 1 + rest/perChunk
+			s.sampleIndexInChunk = rest % perChunk
+			break
+		}
+		rest -= chunks * perChunk
+		s.chunkIndex = int(e.FirstChunk) - 1 + chunks
+	}
+	for i := n - s.sampleIndexInChunk; i < n; i++ {
+		if s.sample.SampleSize.SampleSize != 0 {
+			s.sampleOffsetInChunk += int64(s.sample.SampleSize.SampleSize)
+		} else if i < len(s.sample.SampleSize.Entries) {
+			s.sampleOffsetInChunk += int64(s.sample.SampleSize.Entries[i])
+		}
+	}
+
+	if s.sample.SyncSample != nil {
+		// The last sync sample at or before n, as incSampleIndex tracks it.
+		entries := s.sample.SyncSample.Entries
+		i := sort.Search(len(entries), func(i int) bool { return int(entries[i])-1 > n })
+		s.syncSampleIndex = max(i-1, 0)
+	}
+	s.sampleIndex = n
+}
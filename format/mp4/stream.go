@@ -68,6 +68,13 @@ type Stream struct {
 	lastPacketDataSize   int64
 
 	naluBuf [][]byte // Reusable buffer for SplitNALUs to avoid per-packet allocation.
+
+	firstTS    time.Duration // Timestamp of the first packet written to the stream.
+	hasFirstTS bool          // True once firstTS is set.
+
+	// Edit list mapping used by the demuxer: packet time = dts + editShift,
+	// the total of the leading empty edits less the media_time skipped.
+	editShift time.Duration
 }
 
 // timeToTS converts a duration to a timestamp based on the stream's time scale.
@@ -224,6 +231,80 @@ func (s *Stream) fillTrackAtom() (err error) {
 	return
 }
 
+// editList builds the track's edts: an empty edit covering the gap between
+// the earliest track start and this track's first sample, then a media edit
+// starting at the first sample's composition offset so a B-frame ctts delay
+// does not show up as a blank lead-in. It returns nil when the mapping would
+// be the identity, together with the resulting presentation duration.
+func (s *Stream) editList(earliest time.Duration, movieTimeScale int64) (*mp4io.Edit, time.Duration) {
+	var delay time.Duration
+	if s.hasFirstTS {
+		delay = s.firstTS - earliest
+	}
+	var mediaTime int64
+	if ctts := s.sample.CompositionOffset; ctts != nil && len(ctts.Entries) > 0 {
+		mediaTime = int64(ctts.Entries[0].Offset)
+	}
+	return buildEdit(delay, mediaTime, s.duration, s.timeScale, movieTimeScale)
+}
+
+// buildEdit assembles an edit list delaying a track by delay (movie time) and
+// starting playback at mediaTime (media timescale). mediaDur is the track's
+// total sample duration in the media timescale.
+func buildEdit(delay time.Duration, mediaTime, mediaDur, timeScale, movieTimeScale int64) (*mp4io.Edit, time.Duration) {
+	toTime := func(ts int64) time.Duration { return time.Duration(ts) * time.Second / time.Duration(timeScale) }
+	if delay <= 0 && mediaTime == 0 {
+		return nil, toTime(mediaDur)
+	}
+
+	list := &mp4io.EditList{}
+	if delay > 0 {
+		list.Entries = append(list.Entries, mp4io.EditListEntry{
+			SegmentDuration:  uint64(timeToTS(delay, movieTimeScale)), //nolint:gosec // delay > 0
+			MediaTime:        mp4io.EmptyEdit,
+			MediaRateInteger: 1,
+		})
+	} else {
+		delay = 0
+	}
+	playDur := max(toTime(mediaDur-mediaTime), 0)
+	list.Entries = append(list.Entries, mp4io.EditListEntry{
+		SegmentDuration:  uint64(timeToTS(playDur, movieTimeScale)), //nolint:gosec // clamped above
+		MediaTime:        mediaTime,
+		MediaRateInteger: 1,
+	})
+	return &mp4io.Edit{List: list}, delay + playDur
+}
+
+// applyEditList derives editShift from the track's edit list so demuxed
+// timestamps land on the movie timeline: the leading empty edits delay the
+// track and the media_time of the first media edit, a presentation time in
+// the track timescale, is subtracted. Packets carry decode times, so they
+// are moved by the same amount as the presentation times, and the first
+// sample's composition offset is added back: a media_time that only skips a
+// B-frame ctts delay leaves the track where the empty edits put it. Samples
+// before media_time are not dropped and keep earlier times. Edits at other
+// rates are ignored.
+func (s *Stream) applyEditList(movieTimeScale int64) {
+	if s.trackAtom.Edit == nil || s.trackAtom.Edit.List == nil || movieTimeScale <= 0 || s.timeScale <= 0 {
+		return
+	}
+	var shift time.Duration
+	for _, entry := range s.trackAtom.Edit.List.Entries {
+		if entry.MediaTime != mp4io.EmptyEdit {
+			if entry.MediaRateInteger == 1 {
+				var delay int64
+				if s.sample != nil && s.sample.CompositionOffset != nil && len(s.sample.CompositionOffset.Entries) > 0 {
+					delay = int64(s.sample.CompositionOffset.Entries[0].Offset)
+				}
+				s.editShift = shift - s.tsToTime(entry.MediaTime-delay)
+			}
+			return
+		}
+		shift += time.Duration(entry.SegmentDuration) * time.Second / time.Duration(movieTimeScale) //nolint:gosec
+	}
+}
+
 func (s *Stream) isSampleValid() bool {
 	if s.chunkIndex >= len(s.sample.ChunkOffset.Entries) {
 		return false
@@ -302,6 +383,22 @@ func (s *Stream) incSampleIndex() (duration int64) {
 	return
 }
 
+// rewind moves the read position back to the first sample.
+func (s *Stream) rewind() {
+	s.sampleIndex = 0
+	s.sampleOffsetInChunk = 0
+	s.syncSampleIndex = 0
+	s.dts = 0
+	s.sttsEntryIndex, s.sampleIndexInSttsEntry = 0, 0
+	s.cttsEntryIndex, s.sampleIndexInCttsEntry = 0, 0
+	s.chunkGroupIndex, s.chunkIndex, s.sampleIndexInChunk = 0, 0, 0
+	if s.h265SlicedPacket != nil {
+		s.h265SlicedPacket.Release()
+		s.h265SlicedPacket = nil
+	}
+	s.h265BufferHasKey = false
+}
+
 // readPacket returns the next access unit for this stream. For H.265 the packet
 // spans all slice NALUs that share a picture (first_slice_segment_in_pic_flag
 // marks the boundary), so one MP4 sample can span multiple returned slices or
@@ -451,6 +548,9 @@ func (s *Stream) writePacket(nPkt gomedia.Packet) (err error) {
 		s.lastPacket = nPkt
 	}()
 
+	if !s.hasFirstTS {
+		s.firstTS, s.hasFirstTS = nPkt.Timestamp(), true
+	}
 	if s.lastPacket == nil {
 		return
 	}
@@ -0,0 +1,55 @@
+package rtp
+
+import (
+	"io"
+	"time"
+
+	"github.com/ugparu/gomedia"
+	"github.com/ugparu/gomedia/codec/g722"
+	"github.com/ugparu/gomedia/utils/buffer"
+	"github.com/ugparu/gomedia/utils/sdp"
+)
+
+type g722Demuxer struct {
+	baseDemuxer
+	*g722.CodecParameters
+}
+
+// NewG722Demuxer depacketizes RFC 3551 G.722: each payload is a run of code
+// bytes and the RTP clock ticks once per byte.
+func NewG722Demuxer(rdr io.Reader, sdp sdp.Media, index uint8, options ...DemuxerOption) gomedia.Demuxer {
+	if sdp.TimeScale == 0 {
+		sdp.TimeScale = g722.ClockRate
+	}
+	return &g722Demuxer{
+		baseDemuxer:     *newBaseDemuxer(rdr, sdp, index, options...),
+		CodecParameters: g722.NewCodecParameters(index),
+	}
+}
+
+func (d *g722Demuxer) Demux() (codecs gomedia.CodecParametersPair, err error) {
+	codecs.AudioCodecParameters = d.CodecParameters
+	return
+}
+
+func (d *g722Demuxer) ReadPacket() (pkt gomedia.Packet, err error) {
+	if _, err = d.baseDemuxer.ReadPacket(); err != nil {
+		return
+	}
+
+	needed := d.end - d.offset
+	var buf []byte
+	var handle *buffer.SlotHandle
+	if d.ring != nil {
+		buf, handle = d.ring.Alloc(needed)
+	}
+	if buf == nil {
+		buf = make([]byte, needed)
+	}
+	copy(buf, d.payload.Data()[d.offset:d.end])
+	p := g722.NewPacket(buf, (time.Duration(d.timestamp)*time.Second)/time.Duration(d.sdp.TimeScale),
+		"", time.Now(), d.CodecParameters, g722.Duration(len(buf)))
+	p.Slot = handle
+	pkt = p
+	return
+}
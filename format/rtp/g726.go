@@ -0,0 +1,75 @@
+package rtp
+
+import (
+	"io"
+	"time"
+
+	"github.com/ugparu/gomedia"
+	"github.com/ugparu/gomedia/codec/g726"
+	"github.com/ugparu/gomedia/utils/buffer"
+	"github.com/ugparu/gomedia/utils/sdp"
+)
+
+const defaultG726Bitrate = 32000
+
+type g726Demuxer struct {
+	baseDemuxer
+	*g726.CodecParameters
+	err error
+}
+
+// NewG726Demuxer depacketizes RFC 3551 G.726. The bitrate and code word
+// packing come from the SDP encoding name; G726-32 is assumed when it has
+// none.
+func NewG726Demuxer(rdr io.Reader, sdp sdp.Media, index uint8, options ...DemuxerOption) gomedia.Demuxer {
+	if sdp.TimeScale == 0 {
+		sdp.TimeScale = g726.SampleRate
+	}
+	bitrate := sdp.Bitrate
+	if bitrate == 0 {
+		bitrate = defaultG726Bitrate
+	}
+	packing := g726.RFC3551
+	if sdp.AAL2 {
+		packing = g726.AAL2
+	}
+	par, err := g726.NewCodecParameters(index, bitrate, packing)
+	return &g726Demuxer{
+		baseDemuxer:     *newBaseDemuxer(rdr, sdp, index, options...),
+		CodecParameters: par,
+		err:             err,
+	}
+}
+
+func (d *g726Demuxer) Demux() (codecs gomedia.CodecParametersPair, err error) {
+	if d.err != nil {
+		return codecs, d.err
+	}
+	codecs.AudioCodecParameters = d.CodecParameters
+	return
+}
+
+func (d *g726Demuxer) ReadPacket() (pkt gomedia.Packet, err error) {
+	if d.err != nil {
+		return nil, d.err
+	}
+	if _, err = d.baseDemuxer.ReadPacket(); err != nil {
+		return
+	}
+
+	needed := d.end - d.offset
+	var buf []byte
+	var handle *buffer.SlotHandle
+	if d.ring != nil {
+		buf, handle = d.ring.Alloc(needed)
+	}
+	if buf == nil {
+		buf = make([]byte, needed)
+	}
+	copy(buf, d.payload.Data()[d.offset:d.end])
+	p := g726.NewPacket(buf, (time.Duration(d.timestamp)*time.Second)/time.Duration(d.sdp.TimeScale),
+		"", time.Now(), d.CodecParameters, d.Duration(len(buf)))
+	p.Slot = handle
+	pkt = p
+	return
+}
@@ -13,13 +13,26 @@ import (
 type alawDemuxer struct {
 	baseDemuxer
 	*pcm.CodecParameters
+	// frameSize is the size of one sample of every channel.
+	frameSize int
 }
 
+// NewPCMDemuxer depacketizes G.711 (ct PCMAlaw or PCMUlaw) and RFC 3551 L16
+// (ct PCM). L16 samples travel in network byte order and are swapped to the
+// little-endian S16 of pcm.Packet.
 func NewPCMDemuxer(rdr io.Reader, sdp sdp.Media, index uint8, ct gomedia.CodecType, options ...DemuxerOption) gomedia.Demuxer {
+	if sdp.ChannelCount == 0 {
+		sdp.ChannelCount = 1
+	}
+	frameSize := 1
+	if ct == gomedia.PCM {
+		frameSize = 2 * sdp.ChannelCount //nolint:mnd
+	}
 	return &alawDemuxer{
 		baseDemuxer: *newBaseDemuxer(rdr, sdp, index, options...),
 		CodecParameters: pcm.NewCodecParameters(index, ct,
 			uint8(sdp.ChannelCount), uint64(sdp.TimeScale)), //nolint:gosec
+		frameSize: frameSize,
 	}
 }
 
@@ -43,10 +56,21 @@ func (d *alawDemuxer) ReadPacket() (pkt gomedia.Packet, err error) {
 		buf = make([]byte, needed)
 	}
 	copy(buf, d.payload.Data()[d.offset:d.end])
+	if d.CodecType == gomedia.PCM {
+		swapS16(buf)
+	}
 	p := pcm.NewPacket(buf, (time.Duration(d.timestamp)*time.Second)/time.Duration(d.sdp.TimeScale),
 		"", time.Now(), d.CodecParameters,
-		(time.Duration(len(buf))*time.Second)/time.Duration(d.sdp.TimeScale)) //nolint:mnd
+		(time.Duration(len(buf)/d.frameSize)*time.Second)/time.Duration(d.sdp.TimeScale)) //nolint:mnd
 	p.Slot = handle
 	pkt = p
 	return
 }
+
+// swapS16 converts 16-bit samples between big and little endian in place. A
+// trailing odd byte is left as is.
+func swapS16(b []byte) {
+	for i := 0; i+1 < len(b); i += 2 {
+		b[i], b[i+1] = b[i+1], b[i]
+	}
+}
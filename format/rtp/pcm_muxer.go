@@ -0,0 +1,78 @@
+package rtp
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ugparu/gomedia"
+	"github.com/ugparu/gomedia/codec/pcm"
+	"github.com/ugparu/gomedia/utils/buffer"
+	"github.com/ugparu/gomedia/utils/logger"
+	"github.com/ugparu/gomedia/utils/sdp"
+)
+
+// pcmMuxer performs RTP packetization of G.711 and L16 audio (RFC 3551).
+type pcmMuxer struct {
+	*baseMuxer
+	codec     *pcm.CodecParameters
+	frameSize int // bytes per sample of every channel
+	mtu       int
+	swapBuf   buffer.Buffer
+}
+
+// NewPCMMuxer constructs an RTP muxer for PCMA, PCMU or L16 audio. L16
+// samples are converted from the little-endian S16 of pcm.Packet to network
+// byte order. Packets larger than mtu are split on sample boundaries.
+func NewPCMMuxer(w io.Writer, media sdp.Media, channel uint8, codec *pcm.CodecParameters, mtu int, log logger.Logger) *pcmMuxer {
+	if media.TimeScale == 0 {
+		media.TimeScale = int(codec.SampleRate()) //nolint:gosec
+	}
+	if mtu <= 0 {
+		mtu = DefaultMTU
+	}
+	frameSize := int(codec.Channels())
+	if codec.Type() == gomedia.PCM {
+		frameSize *= 2
+	}
+	frameSize = max(frameSize, 1)
+	return &pcmMuxer{
+		baseMuxer: newBaseMuxer(w, media, channel, codec.StreamIndex(), log),
+		codec:     codec,
+		frameSize: frameSize,
+		mtu:       mtu - mtu%frameSize,
+		swapBuf:   buffer.Get(DefaultMTU),
+	}
+}
+
+// WritePacket writes a single PCM packet as one or more RTP packets.
+func (m *pcmMuxer) WritePacket(pkt gomedia.AudioPacket) error {
+	pp, ok := pkt.(*pcm.Packet)
+	if !ok {
+		return fmt.Errorf("rtp: expected *pcm.Packet, got %T", pkt)
+	}
+	if m.codec.Type() == gomedia.PCM && m.codec.SampleFormat() != gomedia.S16 {
+		return fmt.Errorf("rtp: L16 needs S16 samples, got %v", m.codec.SampleFormat())
+	}
+
+	data := pp.Data()
+	for off := 0; off < len(data); {
+		n := min(len(data)-off, m.mtu)
+		payload := data[off : off+n]
+		if m.codec.Type() == gomedia.PCM {
+			m.swapBuf.Resize(n)
+			payload = m.swapBuf.Data()
+			copy(payload, data[off:off+n])
+			swapS16(payload)
+		}
+		// Audio has no frame boundaries to signal; the marker bit is only
+		// set after silence suppression, which is not used here.
+		// Round up so writeRTP's truncating conversion lands on the sample.
+		ts := pp.Timestamp() + (time.Duration(off/m.frameSize)*time.Second+time.Duration(m.clockRate)-1)/time.Duration(m.clockRate)
+		if err := m.writeRTP(payload, ts, false); err != nil {
+			return err
+		}
+		off += n
+	}
+	return nil
+}
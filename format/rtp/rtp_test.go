@@ -13,6 +13,8 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/ugparu/gomedia"
 	"github.com/ugparu/gomedia/codec/aac"
+	"github.com/ugparu/gomedia/codec/g722"
+	"github.com/ugparu/gomedia/codec/g726"
 	"github.com/ugparu/gomedia/codec/h264"
 	"github.com/ugparu/gomedia/codec/h265"
 	"github.com/ugparu/gomedia/codec/opus"
@@ -1047,6 +1049,140 @@ func TestPCMDemuxer_MuLaw(t *testing.T) {
 	require.Equal(t, gomedia.PCMUlaw, cp.Type())
 }
 
+func TestPCMDemuxer_L16(t *testing.T) {
+	media := sdp.Media{Type: gomedia.PCM, TimeScale: 16000, PayloadType: 96, ChannelCount: 2}
+
+	// 10ms of stereo L16 in network byte order.
+	be := make([]byte, 160*4)
+	for i := 0; i < len(be); i += 2 {
+		binary.BigEndian.PutUint16(be[i:], uint16(i))
+	}
+	frame := buildRTSPInterleavedRTP(0, 96, 1, 16000, 0x12345678, true, be)
+	dmx := NewPCMDemuxer(bytes.NewReader(frame), media, 0, gomedia.PCM)
+
+	pkt, err := dmx.ReadPacket()
+	require.NoError(t, err)
+	require.Len(t, pkt.Data(), len(be))
+	for i := 0; i < len(be); i += 2 {
+		require.Equal(t, uint16(i), binary.LittleEndian.Uint16(pkt.Data()[i:]))
+	}
+	require.Equal(t, time.Second, pkt.Timestamp())
+	require.Equal(t, 10*time.Millisecond, pkt.Duration())
+	require.Equal(t, uint8(2), pkt.(*pcm.Packet).CodecParameters().Channels())
+}
+
+// ===========================================================================
+// PCM muxer tests
+// ===========================================================================
+
+// splitInterleaved returns the RTP packets of RTSP interleaved output.
+func splitInterleaved(t *testing.T, out []byte) [][]byte {
+	t.Helper()
+	var pkts [][]byte
+	for len(out) > 0 {
+		require.Equal(t, byte(0x24), out[0])
+		n := int(binary.BigEndian.Uint16(out[2:4]))
+		pkts = append(pkts, out[4:4+n])
+		out = out[4+n:]
+	}
+	return pkts
+}
+
+func TestPCMMuxer_L16(t *testing.T) {
+	par := pcm.NewCodecParameters(1, gomedia.PCM, 2, 44100)
+	var buf bytes.Buffer
+	muxer := NewPCMMuxer(&buf, sdp.Media{PayloadType: 10}, 2, par, 1000, nil)
+
+	// 600 stereo samples, split into 250 + 250 + 100 at a 1000 byte MTU.
+	le := make([]byte, 600*4)
+	for i := 0; i < len(le); i += 2 {
+		binary.LittleEndian.PutUint16(le[i:], uint16(i))
+	}
+	require.NoError(t, muxer.WritePacket(pcm.NewPacket(le, 0, "", time.Time{}, par, 0)))
+
+	pkts := splitInterleaved(t, buf.Bytes())
+	require.Len(t, pkts, 3)
+	var payload []byte
+	for i, p := range pkts {
+		require.Equal(t, byte(10), p[1]&0x7f)
+		require.Equal(t, uint32(250*i), binary.BigEndian.Uint32(p[4:8])-binary.BigEndian.Uint32(pkts[0][4:8]))
+		payload = append(payload, p[12:]...)
+	}
+	require.Len(t, payload, len(le))
+	for i := 0; i < len(payload); i += 2 {
+		require.Equal(t, uint16(i), binary.BigEndian.Uint16(payload[i:]))
+	}
+}
+
+func TestPCMMuxer_PCMU(t *testing.T) {
+	par := pcm.NewCodecParameters(0, gomedia.PCMUlaw, 1, 8000)
+	var buf bytes.Buffer
+	muxer := NewPCMMuxer(&buf, sdp.Media{PayloadType: 0, TimeScale: 8000}, 0, par, 0, nil)
+
+	data := bytes.Repeat([]byte{0xff}, 160)
+	require.NoError(t, muxer.WritePacket(pcm.NewPacket(data, time.Second, "", time.Time{}, par, 20*time.Millisecond)))
+
+	pkts := splitInterleaved(t, buf.Bytes())
+	require.Len(t, pkts, 1)
+	require.Equal(t, uint32(8000), binary.BigEndian.Uint32(pkts[0][4:8]))
+	require.Equal(t, data, pkts[0][12:])
+}
+
+func TestPCMMuxer_RejectsOtherPackets(t *testing.T) {
+	par := pcm.NewCodecParameters(0, gomedia.PCMAlaw, 1, 8000)
+	muxer := NewPCMMuxer(io.Discard, sdp.Media{}, 0, par, 0, nil)
+	require.Error(t, muxer.WritePacket(g722.NewPacket(nil, 0, "", time.Time{}, g722.NewCodecParameters(0), 0)))
+}
+
+// ===========================================================================
+// G.722 / G.726 demuxer tests
+// ===========================================================================
+
+func TestG722Demuxer_ReadPacket(t *testing.T) {
+	media := sdp.Media{Type: gomedia.G722, TimeScale: 8000, PayloadType: 9, ChannelCount: 1}
+
+	data := bytes.Repeat([]byte{0xfa}, 160) // 20ms: 320 samples at 16kHz
+	frame := buildRTSPInterleavedRTP(0, 9, 1, 16000, 0x12345678, true, data)
+	dmx := NewG722Demuxer(bytes.NewReader(frame), media, 0)
+
+	codecs, err := dmx.Demux()
+	require.NoError(t, err)
+	cp := codecs.AudioCodecParameters.(*g722.CodecParameters)
+	require.Equal(t, uint64(16000), cp.SampleRate())
+
+	pkt, err := dmx.ReadPacket()
+	require.NoError(t, err)
+	require.Equal(t, data, pkt.Data())
+	require.Equal(t, 2*time.Second, pkt.Timestamp()) // the RTP clock runs at 8kHz
+	require.Equal(t, 20*time.Millisecond, pkt.Duration())
+}
+
+func TestG726Demuxer_ReadPacket(t *testing.T) {
+	media := sdp.Media{Type: gomedia.G726, TimeScale: 8000, PayloadType: 96, ChannelCount: 1, Bitrate: 24000, AAL2: true}
+
+	data := bytes.Repeat([]byte{0x11}, 60) // 20ms at 3 bits per sample
+	frame := buildRTSPInterleavedRTP(0, 96, 1, 8000, 0x12345678, true, data)
+	dmx := NewG726Demuxer(bytes.NewReader(frame), media, 0)
+
+	codecs, err := dmx.Demux()
+	require.NoError(t, err)
+	cp := codecs.AudioCodecParameters.(*g726.CodecParameters)
+	require.Equal(t, 3, cp.BitsPerSample())
+	require.Equal(t, g726.AAL2, cp.Packing())
+
+	pkt, err := dmx.ReadPacket()
+	require.NoError(t, err)
+	require.Equal(t, data, pkt.Data())
+	require.Equal(t, time.Second, pkt.Timestamp())
+	require.Equal(t, 20*time.Millisecond, pkt.Duration())
+}
+
+func TestG726Demuxer_BadBitrate(t *testing.T) {
+	media := sdp.Media{Type: gomedia.G726, TimeScale: 8000, PayloadType: 96, Bitrate: 20000}
+	_, err := NewG726Demuxer(bytes.NewReader(nil), media, 0).Demux()
+	require.ErrorIs(t, err, g726.ErrBitrate)
+}
+
 // ===========================================================================
 // H.264 muxer tests
 // ===========================================================================
@@ -1,5 +1,6 @@
 // Package rtsp implements an RTSP 1.0 client (RFC 2326) with TCP-interleaved
-// RTP transport, basic and digest authentication, and reconnect-aware reads.
+// or UDP RTP transport, basic and digest authentication, and reconnect-aware
+// reads.
 //
 // File-level nolint directives suppress gosec/mnd across the legacy parts of
 // this file; per-line directives are preferred for new code.
@@ -20,6 +21,7 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/ugparu/gomedia/utils/buffer"
@@ -43,8 +45,11 @@ type client struct {
 	name     string
 	url      string
 	headers  map[string]string
+	creds    *url.Userinfo       // replaces the user info of the URL when set
 	methods  map[rtspMethod]bool // populated from OPTIONS → Public.
 	log      logger.Logger
+
+	interrupted atomic.Bool // set by interrupt: Read fails at once
 }
 
 func newClient() *client {
@@ -62,6 +67,7 @@ func newClient() *client {
 		name:     "",
 		url:      "",
 		headers:  map[string]string{"User-Agent": "gomedia"},
+		creds:    nil,
 		log:      logger.Default,
 		methods: map[rtspMethod]bool{
 			describe:     false,
@@ -94,6 +100,10 @@ func (c *client) establishConnection(rawURL string) (err error) {
 		password, _ = l.User.Password()
 		l.User = nil
 	}
+	if c.creds != nil {
+		username = c.creds.Username()
+		password, _ = c.creds.Password()
+	}
 
 	if l.Port() == "" {
 		l.Host = fmt.Sprintf("%s:%s", l.Host, "554") // default RTSP port
@@ -386,6 +396,31 @@ func (c *client) setup(chTMP int, uri string, mode string) (streamIdx int, err e
 	return -1, errors.New("no interleaved")
 }
 
+// setupUDP performs SETUP for one media stream over RTP/AVP on the local
+// UDP ports rtpPort / rtpPort+1.
+func (c *client) setupUDP(rtpPort int, uri string, mode string) (err error) {
+	c.log.Debug(c, "Processing setup request")
+
+	headers := map[string]string{"Transport": fmt.Sprintf("RTP/AVP;unicast;client_port=%d-%d;mode=%s", rtpPort, rtpPort+1, mode)}
+
+	c.log.Debugf(c, "Setting up stream with URI: %s", uri)
+	c.log.Debugf(c, "Headers: %+v", headers)
+
+	resp, err := c.request(setup, headers, uri, nil, false)
+	if err != nil {
+		return err
+	}
+
+	val, ok := resp["Transport"]
+	if !ok {
+		return errors.New("no transport header")
+	}
+	if strings.Contains(val, "interleaved") || strings.Contains(val, "RTP/AVP/TCP") {
+		return errors.New("server refused UDP transport")
+	}
+	return nil
+}
+
 func (c *client) play() (err error) {
 	c.log.Debug(c, "Processing play request")
 
@@ -451,6 +486,9 @@ func (c *client) Read(buf []byte) (err error) {
 		if err = c.conn.SetReadDeadline(time.Now().Add(readWriteTimeout)); err != nil {
 			return err
 		}
+		if c.interrupted.Load() {
+			return errInterrupted
+		}
 
 		if _, err = io.ReadFull(c.connRW, buf); err != nil {
 			return err
@@ -461,6 +499,17 @@ func (c *client) Read(buf []byte) (err error) {
 	return
 }
 
+var errInterrupted = errors.New("read interrupted")
+
+// interrupt makes a Read blocked on another goroutine, and every later one,
+// fail at once. Requests still work, so TEARDOWN can be sent afterwards.
+func (c *client) interrupt() {
+	c.interrupted.Store(true)
+	if c.conn != nil {
+		_ = c.conn.SetReadDeadline(time.Unix(1, 0))
+	}
+}
+
 // Close tries a best-effort TEARDOWN and then closes the TCP/TLS connection.
 // Errors are logged but not returned — Close is called from defer paths.
 func (c *client) Close() {
@@ -7,10 +7,12 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/url"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/ugparu/gomedia"
@@ -36,6 +38,9 @@ type innerRTSPDemuxer struct {
 	noVideo, noAudio  bool
 	readBuffer        buffer.Buffer
 	rtpRingBufferSize int
+	transport         Transport
+	udp               *udpReceiver // RTP ports of TransportUDP, nil before SETUP
+	readMu            sync.Mutex   // held by ReadPacket, so Close from another goroutine waits for it
 	log               logger.Logger
 }
 
@@ -64,6 +69,22 @@ func WithRingBuffer(size int) DemuxerOption {
 	}
 }
 
+// WithCredentials authenticates with username and password instead of the
+// user info of the URL.
+func WithCredentials(username, password string) DemuxerOption {
+	return func(d *innerRTSPDemuxer) {
+		d.client.creds = url.UserPassword(username, password)
+	}
+}
+
+// WithHeader sends a header with every request, e.g. "User-Agent" to
+// replace the default one.
+func WithHeader(key, value string) DemuxerOption {
+	return func(d *innerRTSPDemuxer) {
+		d.client.headers[key] = value
+	}
+}
+
 func New(url string, opts ...DemuxerOption) gomedia.Demuxer {
 	d := &innerRTSPDemuxer{
 		url:               url,
@@ -142,7 +163,7 @@ func (dmx *innerRTSPDemuxer) findStreams() (params gomedia.CodecParametersPair,
 		}
 
 		var idx int
-		if idx, err = dmx.client.setup(dmx.chTMP, dmx.controlTrack(i2.Control), "play"); err != nil {
+		if idx, err = dmx.setupStream(dmx.chTMP, dmx.controlTrack(i2.Control)); err != nil {
 			return
 		}
 
@@ -209,6 +230,20 @@ func (dmx *innerRTSPDemuxer) findStreams() (params gomedia.CodecParametersPair,
 					return
 				}
 				params.AudioCodecParameters = opusPair.AudioCodecParameters
+			case gomedia.G722:
+				var g722Pair gomedia.CodecParametersPair
+				dmx.audioDemuxer = rtp.NewG722Demuxer(dmx.buffer, i2, uint8(index), opts...) //nolint:gosec
+				if g722Pair, err = dmx.audioDemuxer.Demux(); err != nil {
+					return
+				}
+				params.AudioCodecParameters = g722Pair.AudioCodecParameters
+			case gomedia.G726:
+				var g726Pair gomedia.CodecParametersPair
+				dmx.audioDemuxer = rtp.NewG726Demuxer(dmx.buffer, i2, uint8(index), opts...) //nolint:gosec
+				if g726Pair, err = dmx.audioDemuxer.Demux(); err != nil {
+					return
+				}
+				params.AudioCodecParameters = g726Pair.AudioCodecParameters
 			default:
 				dmx.log.Debugf(dmx, "SDP audio codec type %v not supported", i2.Type)
 			}
@@ -221,11 +256,37 @@ func (dmx *innerRTSPDemuxer) findStreams() (params gomedia.CodecParametersPair,
 	return
 }
 
+// setupStream sets up one stream on channel ch and returns the channel its
+// RTP arrives on. With TransportUDP the channel is ours to choose: datagrams
+// of the stream's RTP port are tagged with it.
+func (dmx *innerRTSPDemuxer) setupStream(ch int, uri string) (int, error) {
+	if dmx.transport != TransportUDP {
+		return dmx.client.setup(ch, uri, "play")
+	}
+	if dmx.udp == nil {
+		var server net.IP
+		if addr, ok := dmx.client.conn.RemoteAddr().(*net.TCPAddr); ok {
+			server = addr.IP
+		}
+		dmx.udp = newUDPReceiver(server)
+	}
+	port, err := dmx.udp.listen(uint8(ch)) //nolint:gosec // two channels per stream
+	if err != nil {
+		return -1, err
+	}
+	if err = dmx.client.setupUDP(port, uri, "play"); err != nil {
+		return -1, err
+	}
+	return ch, nil
+}
+
 func (dmx *innerRTSPDemuxer) controlTrack(track string) string {
 	return controlTrack(dmx.client.control, track)
 }
 
 func (dmx *innerRTSPDemuxer) ReadPacket() (packet gomedia.Packet, err error) {
+	dmx.readMu.Lock()
+	defer dmx.readMu.Unlock()
 	defer func() {
 		if packet != nil {
 			packet.SetSourceID(dmx.url)
@@ -243,6 +304,17 @@ func (dmx *innerRTSPDemuxer) ReadPacket() (packet gomedia.Packet, err error) {
 		return
 	}
 
+	if dmx.udp != nil {
+		if err = dmx.readUDP(); err != nil {
+			return
+		}
+		if len(dmx.packets) > 0 {
+			packet = dmx.packets[0]
+			dmx.packets = dmx.packets[1:]
+		}
+		return
+	}
+
 	var desync bool
 	var header [headerSize]byte
 	for {
@@ -276,20 +348,7 @@ func (dmx *innerRTSPDemuxer) ReadPacket() (packet gomedia.Packet, err error) {
 	case rtspPacket:
 		err = dmx.processRTSPPacket(header)
 	case rtpPacket:
-		var targetDmx gomedia.Demuxer
-
-		switch int8(header[1]) {
-		case dmx.videoIdx + 1:
-			fallthrough
-		case dmx.videoIdx:
-			targetDmx = dmx.videoDemuxer
-		case dmx.audioIdx + 1:
-			fallthrough
-		case dmx.audioIdx:
-			targetDmx = dmx.audioDemuxer
-		default:
-			dmx.log.Warningf(dmx, "Unknown stream index %d. Possible desync", header[1])
-		}
+		targetDmx := dmx.channelDemuxer(header[1])
 
 		length := int32(binary.BigEndian.Uint16(header[2:]))
 		if length < 12 {
@@ -302,29 +361,9 @@ func (dmx *innerRTSPDemuxer) ReadPacket() (packet gomedia.Packet, err error) {
 			return
 		}
 
-		if targetDmx == nil {
-			return
-		}
-
-		if _, err = dmx.buffer.Write(header[:]); err != nil {
-			return
-		}
-		if _, err = dmx.buffer.Write(dmx.readBuffer.Data()[:length]); err != nil {
+		if err = dmx.demuxRTP(targetDmx, header, dmx.readBuffer.Data()[:length]); err != nil {
 			return
 		}
-
-		var pkt gomedia.Packet
-		for {
-			if pkt, err = targetDmx.ReadPacket(); err != nil {
-				if errors.Is(err, io.EOF) {
-					err = nil
-					break
-				}
-				return
-			}
-			dmx.lastPktRcv = time.Now()
-			dmx.packets = append(dmx.packets, pkt)
-		}
 	}
 
 	select {
@@ -343,6 +382,76 @@ func (dmx *innerRTSPDemuxer) ReadPacket() (packet gomedia.Packet, err error) {
 	return
 }
 
+// channelDemuxer returns the RTP demuxer of an interleaved channel, nil for
+// unknown channels.
+func (dmx *innerRTSPDemuxer) channelDemuxer(ch byte) gomedia.Demuxer {
+	switch int8(ch) { //nolint:gosec // channel indices are small
+	case dmx.videoIdx, dmx.videoIdx + 1:
+		return dmx.videoDemuxer
+	case dmx.audioIdx, dmx.audioIdx + 1:
+		return dmx.audioDemuxer
+	default:
+		dmx.log.Warningf(dmx, "Unknown stream index %d. Possible desync", ch)
+		return nil
+	}
+}
+
+// demuxRTP hands one RTP packet, framed with its interleaved header, to
+// targetDmx and queues the media packets it completes.
+func (dmx *innerRTSPDemuxer) demuxRTP(targetDmx gomedia.Demuxer, header [headerSize]byte, data []byte) (err error) {
+	if targetDmx == nil {
+		return nil
+	}
+
+	if _, err = dmx.buffer.Write(header[:]); err != nil {
+		return
+	}
+	if _, err = dmx.buffer.Write(data); err != nil {
+		return
+	}
+
+	var pkt gomedia.Packet
+	for {
+		if pkt, err = targetDmx.ReadPacket(); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return
+		}
+		dmx.lastPktRcv = time.Now()
+		dmx.packets = append(dmx.packets, pkt)
+	}
+}
+
+// readUDP waits for the next RTP datagram and demuxes it as if it had been
+// interleaved on its stream's channel. RTSP keep-alives are sent meanwhile.
+func (dmx *innerRTSPDemuxer) readUDP() error {
+	timeout := time.NewTimer(minPacketInterval - time.Since(dmx.lastPktRcv))
+	defer timeout.Stop()
+	for {
+		select {
+		case dg := <-dmx.udp.datagrams:
+			if len(dg.data) < 12 || len(dg.data) > maxUDPDatagram {
+				dmx.log.Warningf(dmx, "RTSP client incorrect packet size %v", len(dg.data))
+				return nil
+			}
+			header := [headerSize]byte{rtpPacket, dg.channel}
+			binary.BigEndian.PutUint16(header[2:], uint16(len(dg.data))) //nolint:gosec // bounded above
+			return dmx.demuxRTP(dmx.channelDemuxer(dg.channel), header, dg.data)
+		case <-dmx.ticker.C:
+			// Nothing else reads the control connection, so wait for the
+			// reply instead of leaving it queued there.
+			if err := dmx.client.options(); err != nil {
+				return err
+			}
+		case <-timeout.C:
+			return errors.New("packet timeout expired")
+		case <-dmx.udp.done:
+			return errUDPClosed
+		}
+	}
+}
+
 func (dmx *innerRTSPDemuxer) processRTSPPacket(header [headerSize]byte) (err error) {
 	if string(header[:]) != "RTSP" {
 		dmx.log.Warningf(dmx, "rtsp packet reading desync: first symbols are %s. Trying to recover", string(header[:]))
@@ -387,7 +496,16 @@ func (dmx *innerRTSPDemuxer) processRTSPPacket(header [headerSize]byte) (err err
 	return nil
 }
 
+// Close may be called while ReadPacket blocks on another goroutine: the read
+// is interrupted first and the demuxer torn down once it returned.
 func (dmx *innerRTSPDemuxer) Close() {
+	dmx.client.interrupt()
+	if dmx.udp != nil {
+		dmx.udp.close()
+	}
+	dmx.readMu.Lock()
+	defer dmx.readMu.Unlock()
+
 	dmx.ticker.Stop()
 	for _, pkt := range dmx.packets {
 		pkt.Release()
@@ -10,6 +10,8 @@ import (
 
 	"github.com/ugparu/gomedia"
 	"github.com/ugparu/gomedia/codec/aac"
+	"github.com/ugparu/gomedia/codec/g722"
+	"github.com/ugparu/gomedia/codec/g726"
 	"github.com/ugparu/gomedia/codec/h264"
 	"github.com/ugparu/gomedia/codec/h265"
 	"github.com/ugparu/gomedia/codec/mjpeg"
@@ -251,6 +253,16 @@ func audioCodecToSDPMedia(params gomedia.AudioCodecParameters, trackID int) (sdp
 		m.Type = gomedia.OPUS
 		m.TimeScale = 48000
 		m.ChannelCount = int(p.Channels())
+	case *g722.CodecParameters:
+		m.Type = gomedia.G722
+		m.TimeScale = g722.ClockRate
+		m.ChannelCount = 1
+		m.PayloadType = 9
+	case *g726.CodecParameters:
+		m.Type = gomedia.G726
+		m.ChannelCount = 1
+		m.Bitrate = int(p.Bitrate()) //nolint:gosec // at most 40000
+		m.AAL2 = p.Packing() == g726.AAL2
 	case *pcm.CodecParameters:
 		m.Type = p.CodecType
 		m.TimeScale = int(p.SampleRate())
@@ -16,6 +16,8 @@ import (
 
 	"github.com/ugparu/gomedia"
 	"github.com/ugparu/gomedia/codec/aac"
+	"github.com/ugparu/gomedia/codec/g722"
+	"github.com/ugparu/gomedia/codec/g726"
 	"github.com/ugparu/gomedia/codec/h264"
 	"github.com/ugparu/gomedia/codec/h265"
 	"github.com/ugparu/gomedia/codec/mjpeg"
@@ -1086,6 +1088,35 @@ func TestDemuxerClose_StopsTicker(t *testing.T) {
 	d.Close()
 }
 
+func TestDemuxerClose_InterruptsBlockedRead(t *testing.T) {
+	// The camera keeps the session open but sends nothing.
+	cli, srv := net.Pipe()
+	defer srv.Close()
+	go func() { _, _ = io.Copy(io.Discard, srv) }()
+
+	d := New("rtsp://test").(*innerRTSPDemuxer)
+	d.client.conn = cli
+	d.client.connRW = bufio.NewReadWriter(bufio.NewReader(cli), bufio.NewWriter(cli))
+	d.lastPktRcv = time.Now()
+
+	readErr := make(chan error, 1)
+	go func() {
+		_, err := d.ReadPacket()
+		readErr <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	go d.Close()
+	select {
+	case err := <-readErr:
+		if err == nil {
+			t.Fatal("expected ReadPacket to fail once closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("ReadPacket still blocked after Close")
+	}
+}
+
 // =========================================================================
 // Muxer — codecParamsToSDPMedias
 // =========================================================================
@@ -1375,6 +1406,27 @@ func TestAudioCodecToSDPMedia_AACMissingConfig(t *testing.T) {
 	}
 }
 
+func TestAudioCodecToSDPMedia_G722G726(t *testing.T) {
+	m, err := audioCodecToSDPMedia(g722.NewCodecParameters(1), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Type != gomedia.G722 || m.PayloadType != 9 || m.TimeScale != 8000 {
+		t.Fatalf("unexpected G.722 media: %+v", m)
+	}
+
+	par, err := g726.NewCodecParameters(1, 16000, g726.AAL2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m, err = audioCodecToSDPMedia(par, 1); err != nil {
+		t.Fatal(err)
+	}
+	if m.Type != gomedia.G726 || m.Bitrate != 16000 || !m.AAL2 || m.TimeScale != 8000 {
+		t.Fatalf("unexpected G.726 media: %+v", m)
+	}
+}
+
 // =========================================================================
 // Muxer — NewMuxer
 // =========================================================================
@@ -2128,3 +2180,48 @@ func TestRequest_BodyWrittenCorrectly(t *testing.T) {
 		t.Fatal("body not written to connection")
 	}
 }
+
+func TestDemuxer_CredentialsAndHeaders(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	sent := make(chan string, 1)
+	go func() {
+		conn, acceptErr := ln.Accept()
+		if acceptErr != nil {
+			sent <- ""
+			return
+		}
+		defer conn.Close()
+		_, _ = conn.Write([]byte("RTSP/1.0 401 Unauthorized\r\nCSeq: 0\r\nWWW-Authenticate: Basic realm=\"cam\"\r\n\r\n" +
+			"RTSP/1.0 200 OK\r\nCSeq: 0\r\n\r\n"))
+		_ = conn.SetReadDeadline(time.Now().Add(300 * time.Millisecond))
+		var buf bytes.Buffer
+		_, _ = io.Copy(&buf, conn)
+		sent <- buf.String()
+	}()
+
+	dmx := New("rtsp://url-user:url-pass@"+ln.Addr().String()+"/stream",
+		WithCredentials("admin", "secret"), WithHeader("User-Agent", "cam-tool"), WithHeader("X-Site", "north"))
+	if _, err = dmx.Demux(); err == nil {
+		t.Fatal("expected DESCRIBE to fail on the closed connection")
+	}
+	dmx.Close()
+
+	req := <-sent
+	if !strings.Contains(req, "Authorization: Basic "+base64.StdEncoding.EncodeToString([]byte("admin:secret"))) {
+		t.Fatalf("credentials not used: %q", req)
+	}
+	if strings.Contains(req, "url-user") {
+		t.Fatalf("URL credentials leaked: %q", req)
+	}
+	if !strings.Contains(req, "User-Agent: cam-tool\r\n") || strings.Contains(req, "User-Agent: gomedia") {
+		t.Fatalf("User-Agent not replaced: %q", req)
+	}
+	if !strings.Contains(req, "X-Site: north\r\n") {
+		t.Fatalf("custom header missing: %q", req)
+	}
+}
@@ -0,0 +1,117 @@
+package rtsp
+
+import (
+	"errors"
+	"net"
+	"sync"
+)
+
+// Transport selects how the camera delivers RTP.
+type Transport int
+
+const (
+	// TransportTCP interleaves RTP with RTSP on the control connection
+	// (RTP/AVP/TCP). It passes NAT and firewalls and is the default.
+	TransportTCP Transport = iota
+	// TransportUDP receives RTP on a pair of local UDP ports per stream
+	// (RTP/AVP). Lost datagrams do not stall the other streams, but the
+	// camera must be able to reach the client directly.
+	TransportUDP
+)
+
+// WithTransport selects the RTP transport, TransportTCP by default.
+func WithTransport(t Transport) DemuxerOption {
+	return func(d *innerRTSPDemuxer) { d.transport = t }
+}
+
+const (
+	maxUDPDatagram = 1<<16 - 1
+	// udpPortAttempts bounds the search for a free even/odd port pair.
+	udpPortAttempts = 16
+	udpQueueSize    = 256
+)
+
+var errUDPClosed = errors.New("udp receiver closed")
+
+// udpDatagram is an RTP packet received on the RTP port of channel.
+type udpDatagram struct {
+	channel uint8
+	data    []byte
+}
+
+// udpReceiver reads the RTP ports of all streams into one queue. RTCP ports
+// are bound so the camera has somewhere to send reports, and drained.
+// Datagrams from hosts other than the camera are dropped.
+type udpReceiver struct {
+	server    net.IP // nil → accept any sender
+	datagrams chan udpDatagram
+	done      chan struct{}
+	conns     []*net.UDPConn
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
+func newUDPReceiver(server net.IP) *udpReceiver {
+	return &udpReceiver{
+		server:    server,
+		datagrams: make(chan udpDatagram, udpQueueSize),
+		done:      make(chan struct{}),
+	}
+}
+
+// listen binds an RTP port (even, per RFC 3550 §11) and the RTCP port
+// above it, starts reading them as channel and returns the RTP port.
+func (r *udpReceiver) listen(channel uint8) (int, error) {
+	for range udpPortAttempts {
+		rtpConn, err := net.ListenUDP("udp", &net.UDPAddr{})
+		if err != nil {
+			return 0, err
+		}
+		port := rtpConn.LocalAddr().(*net.UDPAddr).Port //nolint:forcetypeassert // ListenUDP
+		if port%2 != 0 {
+			_ = rtpConn.Close()
+			continue
+		}
+		rtcpConn, err := net.ListenUDP("udp", &net.UDPAddr{Port: port + 1})
+		if err != nil {
+			_ = rtpConn.Close()
+			continue
+		}
+		r.conns = append(r.conns, rtpConn, rtcpConn)
+		r.wg.Add(2) //nolint:mnd // RTP + RTCP readers
+		go r.read(rtpConn, channel, true)
+		go r.read(rtcpConn, channel+1, false)
+		return port, nil
+	}
+	return 0, errors.New("no free UDP port pair")
+}
+
+// read forwards the datagrams of conn until it is closed. RTCP is dropped.
+func (r *udpReceiver) read(conn *net.UDPConn, channel uint8, forward bool) {
+	defer r.wg.Done()
+	buf := make([]byte, maxUDPDatagram)
+	for {
+		n, addr, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		if !forward || (r.server != nil && !addr.IP.Equal(r.server)) {
+			continue
+		}
+		select {
+		case r.datagrams <- udpDatagram{channel: channel, data: append([]byte(nil), buf[:n]...)}:
+		case <-r.done:
+			return
+		}
+	}
+}
+
+func (r *udpReceiver) close() {
+	r.closeOnce.Do(func() {
+		close(r.done)
+		for _, conn := range r.conns {
+			_ = conn.Close()
+		}
+		r.wg.Wait()
+	})
+}
@@ -0,0 +1,142 @@
+package rtsp
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ugparu/gomedia"
+)
+
+func TestSetupUDP_RequestsClientPorts(t *testing.T) {
+	resp := "RTSP/1.0 200 OK\r\nCSeq: 0\r\nSession: SESS1\r\nTransport: RTP/AVP;unicast;client_port=5000-5001;server_port=6000-6001\r\n\r\n"
+	c, fc := setupClient(resp)
+	if err := c.setupUDP(5000, "rtsp://example.com/stream/trackID=0", "play"); err != nil {
+		t.Fatal(err)
+	}
+	if req := fc.writeBuf.String(); !strings.Contains(req, "Transport: RTP/AVP;unicast;client_port=5000-5001;mode=play") {
+		t.Fatalf("transport header mismatch: %q", req)
+	}
+	if c.session != "SESS1" {
+		t.Fatalf("expected session SESS1, got %q", c.session)
+	}
+}
+
+func TestSetupUDP_RejectsInterleavedAnswer(t *testing.T) {
+	resp := "RTSP/1.0 200 OK\r\nCSeq: 0\r\nTransport: RTP/AVP/TCP;unicast;interleaved=0-1\r\n\r\n"
+	c, _ := setupClient(resp)
+	if err := c.setupUDP(5000, c.control, "play"); err == nil {
+		t.Fatal("expected error when the server answers with TCP transport")
+	}
+}
+
+func TestUDPTransport_ReceivesRTP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	sdpBody := "v=0\r\n" +
+		"m=video 0 RTP/AVP 96\r\n" +
+		"a=rtpmap:96 H264/90000\r\n" +
+		"a=fmtp:96 packetization-mode=1;sprop-parameter-sets=Z01AKJWQB4AiflwEQAAA+gAAMNQ4AAAFuNgAAehILvLgoA==,aOuPIA==\r\n" +
+		"a=control:trackID=0\r\n"
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		rdr := bufio.NewReader(conn)
+		var clientPort int
+		for cseq := 0; cseq < 4; cseq++ {
+			var method string
+			for {
+				line, err := rdr.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if method == "" {
+					method, _, _ = strings.Cut(line, " ")
+				}
+				if v, ok := strings.CutPrefix(strings.TrimSpace(line), "Transport: "); ok {
+					if _, after, ok := strings.Cut(v, "client_port="); ok {
+						_, _ = fmt.Sscanf(after, "%d", &clientPort)
+					}
+				}
+				if strings.TrimSpace(line) == "" {
+					break
+				}
+			}
+
+			switch method {
+			case "DESCRIBE":
+				fmt.Fprintf(conn, "RTSP/1.0 200 OK\r\nCSeq: %d\r\nContent-Type: application/sdp\r\nContent-Length: %d\r\n\r\n%s",
+					cseq, len(sdpBody), sdpBody)
+			case "SETUP":
+				fmt.Fprintf(conn, "RTSP/1.0 200 OK\r\nCSeq: %d\r\nSession: TESTSESS\r\nTransport: RTP/AVP;unicast;client_port=%d-%d;server_port=7000-7001\r\n\r\n",
+					cseq, clientPort, clientPort+1)
+			default:
+				fmt.Fprintf(conn, "RTSP/1.0 200 OK\r\nCSeq: %d\r\n\r\n", cseq)
+			}
+		}
+
+		udp, err := net.Dial("udp", fmt.Sprintf("127.0.0.1:%d", clientPort))
+		if err != nil {
+			return
+		}
+		defer udp.Close()
+		for i := 0; ; i++ {
+			rtp := make([]byte, 12, 32)
+			rtp[0] = 0x80
+			rtp[1] = 0x80 | 96 // marker: one NAL per frame
+			binary.BigEndian.PutUint16(rtp[2:], uint16(i))
+			binary.BigEndian.PutUint32(rtp[4:], uint32(i*3600))
+			binary.BigEndian.PutUint32(rtp[8:], 0x12345678)
+			rtp = append(rtp, 0x65, 0x88, 0x84, 0x00, 0x33, 0xff) // IDR slice
+			if _, err = udp.Write(rtp); err != nil {
+				return
+			}
+			select {
+			case <-done:
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}()
+
+	dmx := New(fmt.Sprintf("rtsp://127.0.0.1:%d/stream", port), WithTransport(TransportUDP)).(*innerRTSPDemuxer)
+	defer dmx.Close()
+
+	if _, err = dmx.Demux(); err != nil {
+		t.Fatalf("Demux() failed: %v", err)
+	}
+	if dmx.udp == nil {
+		t.Fatal("expected UDP receiver after SETUP")
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		pkt, err := dmx.ReadPacket()
+		if err != nil {
+			t.Fatalf("ReadPacket() failed: %v", err)
+		}
+		if vPkt, ok := pkt.(gomedia.VideoPacket); ok {
+			if !vPkt.IsKeyFrame() {
+				t.Fatal("expected keyframe")
+			}
+			return
+		}
+	}
+	t.Fatal("no video packet received over UDP")
+}
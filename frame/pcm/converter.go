@@ -0,0 +1,84 @@
+package pcm
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ugparu/gomedia"
+	codecpcm "github.com/ugparu/gomedia/codec/pcm"
+)
+
+var (
+	ErrNotPCM     = errors.New("not raw PCM audio")
+	ErrSampleRate = errors.New("sample rate mismatch, resample with utils.Resampler first")
+)
+
+// Converter converts raw PCM packets to one sample format and channel
+// layout and applies a gain. Zero fields of the target format keep the
+// corresponding property of the input. It does not resample.
+type Converter struct {
+	to     Format
+	gain   float32
+	inPar  gomedia.AudioCodecParameters
+	outPar *codecpcm.CodecParameters
+}
+
+// NewConverter returns a converter to the given format with unity gain.
+func NewConverter(to Format) *Converter {
+	return &Converter{to: to, gain: 1}
+}
+
+// SetGain sets the linear gain applied to every sample; see DB.
+func (c *Converter) SetGain(g float32) {
+	c.gain = g
+}
+
+// Convert returns a heap-backed copy of pkt in the target format. pkt stays
+// owned by the caller.
+func (c *Converter) Convert(pkt gomedia.AudioPacket) (*codecpcm.Packet, error) {
+	planes, from, err := planesOf(pkt)
+	if err != nil {
+		return nil, err
+	}
+	to := c.target(from)
+	if to.SampleRate != from.SampleRate {
+		return nil, fmt.Errorf("%w: %d -> %d", ErrSampleRate, from.SampleRate, to.SampleRate)
+	}
+	planes = Remix(planes, from.Layout, to.Layout)
+	Gain(planes, c.gain)
+	data, err := Encode(planes, to.SampleFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	if par := pkt.CodecParameters(); par != c.inPar {
+		c.inPar = par
+		c.outPar = codecpcm.NewRawCodecParameters(par.StreamIndex(), to.SampleFormat, uint8(to.Channels()), to.SampleRate) //nolint:gosec
+	}
+	return codecpcm.NewPacket(data, pkt.Timestamp(), pkt.SourceID(), pkt.StartTime(), c.outPar, pkt.Duration()), nil
+}
+
+func (c *Converter) target(from Format) Format {
+	to := c.to
+	if to.SampleFormat == 0 {
+		to.SampleFormat = from.SampleFormat
+	}
+	if to.Layout == 0 {
+		to.Layout = from.Layout
+	}
+	if to.SampleRate == 0 {
+		to.SampleRate = from.SampleRate
+	}
+	return to
+}
+
+// planesOf decodes a raw PCM packet.
+func planesOf(pkt gomedia.AudioPacket) ([][]float32, Format, error) {
+	par := pkt.CodecParameters()
+	if par.Type() != gomedia.PCM {
+		return nil, Format{}, fmt.Errorf("%w: %v", ErrNotPCM, par.Type())
+	}
+	from := FormatOf(par)
+	planes, err := Decode(pkt.Data(), from.SampleFormat, from.Channels())
+	return planes, from, err
+}
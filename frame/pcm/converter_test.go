@@ -0,0 +1,57 @@
+package pcm
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ugparu/gomedia"
+	"github.com/ugparu/gomedia/codec/aac"
+	codecpcm "github.com/ugparu/gomedia/codec/pcm"
+)
+
+func TestConverter_Convert(t *testing.T) {
+	par := codecpcm.NewCodecParameters(1, gomedia.PCM, 2, 8000)
+	start := time.Now()
+	pkt := codecpcm.NewPacket(s16(1000, 3000, -2000, 0), time.Second, "cam", start, par, time.Millisecond)
+
+	c := NewConverter(Format{SampleFormat: gomedia.S16, Layout: gomedia.ChMono})
+	c.SetGain(2)
+	out, err := c.Convert(pkt)
+	require.NoError(t, err)
+	require.Equal(t, s16(4000, -2000), out.Data())
+	require.Equal(t, time.Second, out.Timestamp())
+	require.Equal(t, start, out.StartTime())
+	require.Equal(t, "cam", out.SourceID())
+
+	outPar := out.CodecParameters()
+	require.Equal(t, gomedia.PCM, outPar.Type())
+	require.Equal(t, uint8(1), outPar.Channels())
+	require.Equal(t, uint64(8000), outPar.SampleRate())
+	require.Equal(t, uint8(1), outPar.StreamIndex())
+
+	again, err := c.Convert(pkt)
+	require.NoError(t, err)
+	require.Same(t, out.CodecParameters(), again.CodecParameters(), "parameters rebuilt for the same input")
+}
+
+func TestConverter_Errors(t *testing.T) {
+	pkt := codecpcm.NewPacket(s16(0), 0, "", time.Time{}, codecpcm.NewCodecParameters(0, gomedia.PCM, 1, 8000), 0)
+	_, err := NewConverter(Format{SampleRate: 16000}).Convert(pkt)
+	require.ErrorIs(t, err, ErrSampleRate)
+
+	alaw := codecpcm.NewPacket([]byte{0}, 0, "", time.Time{}, codecpcm.NewCodecParameters(0, gomedia.PCMAlaw, 1, 8000), 0)
+	_, err = NewConverter(Format{}).Convert(alaw)
+	require.ErrorIs(t, err, ErrNotPCM)
+}
+
+func TestFormatOf(t *testing.T) {
+	f := FormatOf(codecpcm.NewRawCodecParameters(0, gomedia.FLTP, 6, 48000))
+	require.Equal(t, Format{SampleFormat: gomedia.FLTP, Layout: DefaultLayout(6), SampleRate: 48000}, f)
+
+	var par aac.CodecParameters
+	par.Config.ChannelLayout = gomedia.Ch21
+	par.Config.SampleRate = 44100
+	require.Equal(t, gomedia.Ch21, FormatOf(&par).Layout)
+}
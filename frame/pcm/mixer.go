@@ -0,0 +1,261 @@
+package pcm
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ugparu/gomedia"
+	codecpcm "github.com/ugparu/gomedia/codec/pcm"
+)
+
+const (
+	defaultFrameDuration = 20 * time.Millisecond
+	defaultLatency       = 200 * time.Millisecond
+	defaultMixSourceID   = "mix"
+)
+
+// MixerOption configures a Mixer.
+type MixerOption func(*Mixer)
+
+// WithFrameDuration sets the duration of the output packets (20ms by
+// default).
+func WithFrameDuration(d time.Duration) MixerOption {
+	return func(m *Mixer) {
+		m.frameDur = d
+	}
+}
+
+// WithLatency sets how far the most advanced source may run ahead before
+// frames are emitted without waiting for the others (200ms by default).
+func WithLatency(d time.Duration) MixerOption {
+	return func(m *Mixer) {
+		m.latency = d
+	}
+}
+
+// WithSourceID sets the source ID of the output packets ("mix" by default).
+func WithSourceID(id string) MixerOption {
+	return func(m *Mixer) {
+		m.sourceID = id
+	}
+}
+
+// Mixer sums raw PCM from any number of sources, aligned by time. Packets
+// are placed by StartTime, or by Timestamp when StartTime is zero, so all
+// sources of one mixer must carry the same kind of clock. Gaps in a source
+// are filled with silence and overlapping or late samples are dropped.
+//
+// A frame is emitted once every source covers it, or once the most
+// advanced source is ahead of it by the latency, in which case
+// the lagging sources contribute silence. Sources must already have the
+// mixer's sample rate.
+type Mixer struct {
+	format   Format
+	frameDur time.Duration
+	latency  time.Duration
+	sourceID string
+
+	frame     int64 // samples per output frame
+	tolerance int64 // timing jitter absorbed without padding or trimming
+	lag       int64 // latency in samples
+
+	sources map[string]*mixSource
+	epoch   time.Time // StartTime of position zero
+	started bool
+	next    int64 // position of the next output frame
+	outPar  *codecpcm.CodecParameters
+}
+
+type mixSource struct {
+	gain   float32
+	fed    bool        // got a packet; the first one is placed exactly
+	start  int64       // position of planes[ch][0]
+	planes [][]float32 // in the output layout
+}
+
+func (s *mixSource) end() int64 {
+	return s.start + int64(len(s.planes[0]))
+}
+
+// NewMixer returns a mixer producing packets in format. All fields of
+// format are required.
+func NewMixer(format Format, opts ...MixerOption) (*Mixer, error) {
+	if format.SampleFormat.BytesPerSample() == 0 {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedFormat, format.SampleFormat)
+	}
+	if format.Layout.Count() == 0 || format.SampleRate == 0 {
+		return nil, errors.New("mixer format needs a channel layout and a sample rate")
+	}
+	m := &Mixer{
+		format:   format,
+		frameDur: defaultFrameDuration,
+		latency:  defaultLatency,
+		sourceID: defaultMixSourceID,
+		sources:  map[string]*mixSource{},
+	}
+	for _, o := range opts {
+		o(m)
+	}
+	m.frame = max(1, m.samples(m.frameDur))
+	m.tolerance = m.frame / 2 //nolint:mnd
+	m.lag = m.samples(m.latency)
+	m.outPar = codecpcm.NewRawCodecParameters(0, format.SampleFormat, uint8(format.Channels()), format.SampleRate) //nolint:gosec
+	return m, nil
+}
+
+// AddSource makes the mixer wait for sourceID before it sends its first
+// packet. Sources are otherwise added by their first packet.
+func (m *Mixer) AddSource(sourceID string) {
+	m.source(sourceID)
+}
+
+// SetGain sets the linear gain of a source, adding it if it has not sent
+// any packet yet.
+func (m *Mixer) SetGain(sourceID string, g float32) {
+	m.source(sourceID).gain = g
+}
+
+// RemoveSource drops a source and its pending samples.
+func (m *Mixer) RemoveSource(sourceID string) {
+	delete(m.sources, sourceID)
+}
+
+// Push adds pkt to the mix and returns the frames that became ready. pkt
+// stays owned by the caller.
+func (m *Mixer) Push(pkt gomedia.AudioPacket) ([]*codecpcm.Packet, error) {
+	planes, from, err := planesOf(pkt)
+	if err != nil {
+		return nil, err
+	}
+	if from.SampleRate != m.format.SampleRate {
+		return nil, fmt.Errorf("%w: %d -> %d", ErrSampleRate, from.SampleRate, m.format.SampleRate)
+	}
+	planes = Remix(planes, from.Layout, m.format.Layout)
+
+	pos := m.position(pkt)
+	if !m.started {
+		m.started = true
+		m.next = pos
+	}
+	src := m.source(pkt.SourceID())
+	Gain(planes, src.gain)
+	src.add(pos, planes, m.next, m.tolerance)
+	return m.mix(false), nil
+}
+
+// Flush returns every pending frame, padding the sources with silence.
+func (m *Mixer) Flush() []*codecpcm.Packet {
+	return m.mix(true)
+}
+
+func (m *Mixer) source(id string) *mixSource {
+	src, ok := m.sources[id]
+	if !ok {
+		src = &mixSource{gain: 1, start: m.next, planes: make([][]float32, m.format.Channels())}
+		m.sources[id] = src
+	}
+	return src
+}
+
+// position returns the sample position of pkt on the mixer timeline.
+func (m *Mixer) position(pkt gomedia.Packet) int64 {
+	if st := pkt.StartTime(); !st.IsZero() {
+		if m.epoch.IsZero() {
+			m.epoch = st
+		}
+		return m.samples(st.Sub(m.epoch))
+	}
+	return m.samples(pkt.Timestamp())
+}
+
+func (m *Mixer) samples(d time.Duration) int64 {
+	return int64((d*time.Duration(m.format.SampleRate) + time.Second/2) / time.Second) //nolint:gosec
+}
+
+func (m *Mixer) duration(samples int64) time.Duration {
+	return time.Duration(samples) * time.Second / time.Duration(m.format.SampleRate) //nolint:gosec
+}
+
+// add appends planes starting at pos. Differences from the expected position
+// within tolerance are treated as jitter; larger gaps are filled with
+// silence and larger overlaps, including samples before the already mixed
+// position next, are dropped.
+func (s *mixSource) add(pos int64, planes [][]float32, next, tolerance int64) {
+	if len(s.planes[0]) == 0 {
+		s.start = max(s.start, next)
+		if !s.fed {
+			s.start = max(s.start, pos)
+		}
+	}
+	s.fed = true
+	var skip int64
+	switch gap := pos - s.end(); {
+	case gap > tolerance:
+		s.pad(gap)
+	case -gap > tolerance:
+		skip = min(-gap, int64(len(planes[0])))
+	}
+	for ch := range s.planes {
+		s.planes[ch] = append(s.planes[ch], planes[ch][skip:]...)
+	}
+}
+
+func (s *mixSource) pad(n int64) {
+	for ch := range s.planes {
+		s.planes[ch] = append(s.planes[ch], make([]float32, n)...)
+	}
+}
+
+// mix emits the frames that are ready, or all pending ones when flushing.
+func (m *Mixer) mix(flush bool) []*codecpcm.Packet {
+	var out []*codecpcm.Packet
+	for len(m.sources) > 0 {
+		frameEnd := m.next + m.frame
+		covered, furthest := true, int64(0)
+		for _, src := range m.sources {
+			covered = covered && src.end() >= frameEnd
+			furthest = max(furthest, src.end())
+		}
+		switch {
+		case covered, furthest >= frameEnd+m.lag:
+		case flush && furthest > m.next:
+		default:
+			return out
+		}
+
+		mixed := make([][]float32, m.format.Channels())
+		for ch := range mixed {
+			mixed[ch] = make([]float32, m.frame)
+		}
+		for _, src := range m.sources {
+			src.take(m.next, frameEnd, mixed)
+		}
+		data, _ := Encode(mixed, m.format.SampleFormat) // format checked in NewMixer
+		pkt := codecpcm.NewPacket(data, m.duration(m.next), m.sourceID, time.Time{}, m.outPar, m.duration(m.frame))
+		if !m.epoch.IsZero() {
+			pkt.SetStartTime(m.epoch.Add(m.duration(m.next)))
+		}
+		out = append(out, pkt)
+		m.next = frameEnd
+	}
+	return out
+}
+
+// take adds the samples of [from, to) into dst and drops them.
+func (s *mixSource) take(from, to int64, dst [][]float32) {
+	lo, hi := max(from, s.start), min(to, s.end())
+	if lo < hi {
+		for ch, plane := range s.planes {
+			d := dst[ch][lo-from:]
+			for i, v := range plane[lo-s.start : hi-s.start] {
+				d[i] += v
+			}
+		}
+	}
+	drop := min(max(to-s.start, 0), int64(len(s.planes[0])))
+	for ch := range s.planes {
+		s.planes[ch] = s.planes[ch][drop:]
+	}
+	s.start = max(s.start+drop, to)
+}
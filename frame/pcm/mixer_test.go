@@ -0,0 +1,113 @@
+package pcm
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ugparu/gomedia"
+	codecpcm "github.com/ugparu/gomedia/codec/pcm"
+)
+
+// mixRate gives 10 samples per 10ms frame.
+const mixRate = 1000
+
+var mixEpoch = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func constPacket(source string, at time.Duration, n int, v int16, par *codecpcm.CodecParameters) *codecpcm.Packet {
+	samples := make([]int16, n*int(par.Channels()))
+	for i := range samples {
+		samples[i] = v
+	}
+	return codecpcm.NewPacket(s16(samples...), at, source, mixEpoch.Add(at), par,
+		time.Duration(n)*time.Second/mixRate)
+}
+
+func newTestMixer(t *testing.T, opts ...MixerOption) *Mixer {
+	t.Helper()
+	opts = append([]MixerOption{WithFrameDuration(10 * time.Millisecond), WithLatency(30 * time.Millisecond)}, opts...)
+	m, err := NewMixer(Format{SampleFormat: gomedia.S16, Layout: gomedia.ChMono, SampleRate: mixRate}, opts...)
+	require.NoError(t, err)
+	return m
+}
+
+func pushAll(t *testing.T, m *Mixer, pkts ...gomedia.AudioPacket) []*codecpcm.Packet {
+	t.Helper()
+	var out []*codecpcm.Packet
+	for _, p := range pkts {
+		o, err := m.Push(p)
+		require.NoError(t, err)
+		out = append(out, o...)
+	}
+	return out
+}
+
+func TestMixer_SumsAlignedSources(t *testing.T) {
+	mono := codecpcm.NewCodecParameters(0, gomedia.PCM, 1, mixRate)
+	stereo := codecpcm.NewCodecParameters(0, gomedia.PCM, 2, mixRate)
+	m := newTestMixer(t, WithSourceID("talkback"))
+	m.SetGain("b", 0.5)
+
+	out := pushAll(t, m, constPacket("a", 0, 20, 1000, mono))
+	require.Empty(t, out, "emitted before the other source")
+	out = pushAll(t, m, constPacket("b", 0, 20, 4000, stereo))
+	require.Len(t, out, 2)
+	for i, p := range out {
+		require.Equal(t, s16(3000, 3000, 3000, 3000, 3000, 3000, 3000, 3000, 3000, 3000), p.Data())
+		require.Equal(t, time.Duration(i)*10*time.Millisecond, p.Timestamp())
+		require.Equal(t, mixEpoch.Add(p.Timestamp()), p.StartTime())
+		require.Equal(t, 10*time.Millisecond, p.Duration())
+		require.Equal(t, "talkback", p.SourceID())
+	}
+}
+
+func TestMixer_GapsAndLatency(t *testing.T) {
+	par := codecpcm.NewCodecParameters(0, gomedia.PCM, 1, mixRate)
+	m := newTestMixer(t)
+	m.AddSource("b")
+
+	// b only starts 5ms in; until then it is silent.
+	out := pushAll(t, m,
+		constPacket("a", 0, 10, 100, par),
+		constPacket("b", 5*time.Millisecond, 5, 200, par),
+	)
+	require.Len(t, out, 1)
+	require.Equal(t, s16(100, 100, 100, 100, 100, 300, 300, 300, 300, 300), out[0].Data())
+
+	// a loses 10ms; the gap is filled with silence once it resumes.
+	out = pushAll(t, m,
+		constPacket("b", 10*time.Millisecond, 20, 200, par),
+		constPacket("a", 20*time.Millisecond, 10, 100, par),
+	)
+	require.Len(t, out, 2)
+	require.Equal(t, s16(200, 200, 200, 200, 200, 200, 200, 200, 200, 200), out[0].Data())
+	require.Equal(t, s16(300, 300, 300, 300, 300, 300, 300, 300, 300, 300), out[1].Data())
+
+	// b stalls: frames go out without it once a is more than 30ms ahead.
+	out = pushAll(t, m, constPacket("a", 30*time.Millisecond, 30, 100, par))
+	require.Empty(t, out)
+	out = pushAll(t, m, constPacket("a", 60*time.Millisecond, 10, 100, par))
+	require.Len(t, out, 1)
+	require.Equal(t, 30*time.Millisecond, out[0].Timestamp())
+	require.Equal(t, s16(100, 100, 100, 100, 100, 100, 100, 100, 100, 100), out[0].Data())
+
+	// A late packet of b is dropped where it was already mixed.
+	out = pushAll(t, m, constPacket("b", 30*time.Millisecond, 20, 200, par))
+	require.Len(t, out, 1)
+	require.Equal(t, s16(300, 300, 300, 300, 300, 300, 300, 300, 300, 300), out[0].Data())
+
+	m.RemoveSource("b")
+	out = m.Flush()
+	require.Len(t, out, 2)
+	require.Equal(t, 60*time.Millisecond, out[1].Timestamp())
+}
+
+func TestMixer_Errors(t *testing.T) {
+	_, err := NewMixer(Format{SampleFormat: gomedia.S16})
+	require.Error(t, err)
+
+	m := newTestMixer(t)
+	_, err = m.Push(constPacket("a", 0, 1, 0, codecpcm.NewCodecParameters(0, gomedia.PCM, 1, 8000)))
+	require.ErrorIs(t, err, ErrSampleRate)
+}
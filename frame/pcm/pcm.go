@@ -0,0 +1,71 @@
+// Package pcm processes raw audio: sample format conversion, channel
+// up/down-mixing, gain and N-way mixing of codec/pcm packets. Samples are
+// processed as float32 planes in [-1, 1], one slice per channel, and
+// converted back to the packed byte layout of the target format.
+package pcm
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ugparu/gomedia"
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported sample format")
+	ErrInvalidData       = errors.New("data is not a whole number of samples")
+)
+
+// Format describes raw audio. Layout gives both the channel count and the
+// speaker positions used for remixing.
+type Format struct {
+	SampleFormat gomedia.SampleFormat
+	Layout       gomedia.ChannelLayout
+	SampleRate   uint64
+}
+
+func (f Format) String() string {
+	return fmt.Sprintf("%v %v %dHz", f.SampleFormat, f.Layout, f.SampleRate)
+}
+
+// Channels returns the channel count of the layout.
+func (f Format) Channels() int {
+	return f.Layout.Count()
+}
+
+// FormatOf returns the format of par, taking the layout from a
+// ChannelLayout method when par has one (AAC) and from DefaultLayout
+// otherwise.
+func FormatOf(par gomedia.AudioCodecParameters) Format {
+	layout := DefaultLayout(int(par.Channels()))
+	if lp, ok := par.(interface{ ChannelLayout() gomedia.ChannelLayout }); ok && lp.ChannelLayout().Count() == int(par.Channels()) {
+		layout = lp.ChannelLayout()
+	}
+	return Format{SampleFormat: par.SampleFormat(), Layout: layout, SampleRate: par.SampleRate()}
+}
+
+// DefaultLayout returns the usual speaker layout for a channel count, as
+// used by FFmpeg for streams that do not signal one.
+func DefaultLayout(channels int) gomedia.ChannelLayout {
+	switch channels {
+	case 1:
+		return gomedia.ChMono
+	case 2: //nolint:mnd
+		return gomedia.ChStereo
+	case 3: //nolint:mnd
+		return gomedia.ChSurround
+	case 4: //nolint:mnd
+		return gomedia.ChStereo | gomedia.ChBackLeft | gomedia.ChBackRight
+	case 5: //nolint:mnd
+		return gomedia.ChSurround | gomedia.ChBackLeft | gomedia.ChBackRight
+	case 6: //nolint:mnd
+		return gomedia.ChSurround | gomedia.ChBackLeft | gomedia.ChBackRight | gomedia.ChLowFreq
+	default:
+		// Fill positions in bit order; good enough to keep the count.
+		var l gomedia.ChannelLayout
+		for bit := gomedia.ChFrontCenter; bit < gomedia.ChNr && l.Count() < channels; bit <<= 1 {
+			l |= bit
+		}
+		return l
+	}
+}
@@ -0,0 +1,170 @@
+package pcm
+
+import (
+	"math"
+
+	"github.com/ugparu/gomedia"
+)
+
+// channelOrder is the order of channels within a frame, as in WAV and
+// FFmpeg: planes returned by Decode follow it for the positions present in
+// the layout.
+var channelOrder = [...]gomedia.ChannelLayout{
+	gomedia.ChFrontLeft,
+	gomedia.ChFrontRight,
+	gomedia.ChFrontCenter,
+	gomedia.ChLowFreq,
+	gomedia.ChBackLeft,
+	gomedia.ChBackRight,
+	gomedia.ChBackCenter,
+	gomedia.ChSideLeft,
+	gomedia.ChSightRight,
+}
+
+// minus3dB is the usual level of a channel folded into two speakers.
+const minus3dB = 0.70710678
+
+// Positions returns the speaker positions of layout in channel order.
+func Positions(layout gomedia.ChannelLayout) []gomedia.ChannelLayout {
+	pos := make([]gomedia.ChannelLayout, 0, layout.Count())
+	for _, p := range channelOrder {
+		if layout&p != 0 {
+			pos = append(pos, p)
+		}
+	}
+	return pos
+}
+
+// MixMatrix returns the coefficients that turn channels laid out as from
+// into channels laid out as to: out[i] = sum of m[i][j] * in[j]. Shared
+// positions are copied, centre and surround channels missing from the
+// target are folded into the nearest speakers at -3 dB, stereo folds into
+// centre and LFE is dropped. Rows are normalised so a full-scale input
+// cannot clip.
+func MixMatrix(from, to gomedia.ChannelLayout) [][]float32 {
+	in, out := Positions(from), Positions(to)
+	outIdx := make(map[gomedia.ChannelLayout]int, len(out))
+	for i, p := range out {
+		outIdx[p] = i
+	}
+	m := make([][]float32, len(out))
+	for i := range m {
+		m[i] = make([]float32, len(in))
+	}
+
+	// route adds input j to the first group of targets that to has entirely
+	// and reports whether there was one.
+	route := func(j int, gain float32, groups ...[]gomedia.ChannelLayout) bool {
+		for _, g := range groups {
+			all := true
+			for _, p := range g {
+				if _, ok := outIdx[p]; !ok {
+					all = false
+				}
+			}
+			if !all {
+				continue
+			}
+			for _, p := range g {
+				m[outIdx[p]][j] += gain
+			}
+			return true
+		}
+		return false
+	}
+	var (
+		front  = []gomedia.ChannelLayout{gomedia.ChFrontLeft, gomedia.ChFrontRight}
+		center = []gomedia.ChannelLayout{gomedia.ChFrontCenter}
+		back   = []gomedia.ChannelLayout{gomedia.ChBackLeft, gomedia.ChBackRight}
+	)
+	for j, p := range in {
+		if i, ok := outIdx[p]; ok {
+			m[i][j] = 1
+			continue
+		}
+		switch p { //nolint:exhaustive // remaining positions are dropped
+		case gomedia.ChFrontCenter:
+			gain := float32(minus3dB)
+			if from == gomedia.ChMono {
+				gain = 1 // mono upmix keeps its level on both speakers
+			}
+			route(j, gain, front)
+		case gomedia.ChFrontLeft, gomedia.ChFrontRight:
+			route(j, minus3dB, center)
+		case gomedia.ChBackLeft:
+			if !route(j, 1, []gomedia.ChannelLayout{gomedia.ChSideLeft}) {
+				route(j, minus3dB, []gomedia.ChannelLayout{gomedia.ChFrontLeft}, center)
+			}
+		case gomedia.ChBackRight:
+			if !route(j, 1, []gomedia.ChannelLayout{gomedia.ChSightRight}) {
+				route(j, minus3dB, []gomedia.ChannelLayout{gomedia.ChFrontRight}, center)
+			}
+		case gomedia.ChSideLeft:
+			if !route(j, 1, []gomedia.ChannelLayout{gomedia.ChBackLeft}) {
+				route(j, minus3dB, []gomedia.ChannelLayout{gomedia.ChFrontLeft}, center)
+			}
+		case gomedia.ChSightRight:
+			if !route(j, 1, []gomedia.ChannelLayout{gomedia.ChBackRight}) {
+				route(j, minus3dB, []gomedia.ChannelLayout{gomedia.ChFrontRight}, center)
+			}
+		case gomedia.ChBackCenter:
+			route(j, minus3dB, back, front, center)
+		}
+	}
+
+	for _, row := range m {
+		var sum float32
+		for _, c := range row {
+			sum += c
+		}
+		if sum > 1 {
+			for j := range row {
+				row[j] /= sum
+			}
+		}
+	}
+	return m
+}
+
+// Remix converts planes laid out as from into planes laid out as to.
+// planes is returned as is when the layouts match.
+func Remix(planes [][]float32, from, to gomedia.ChannelLayout) [][]float32 {
+	if from == to {
+		return planes
+	}
+	m := MixMatrix(from, to)
+	var n int
+	if len(planes) > 0 {
+		n = len(planes[0])
+	}
+	out := make([][]float32, len(m))
+	for i, row := range m {
+		out[i] = make([]float32, n)
+		for j, c := range row {
+			if c == 0 {
+				continue
+			}
+			for k, v := range planes[j][:n] {
+				out[i][k] += c * v
+			}
+		}
+	}
+	return out
+}
+
+// Gain multiplies every sample of planes by g in place.
+func Gain(planes [][]float32, g float32) {
+	if g == 1 {
+		return
+	}
+	for _, plane := range planes {
+		for i := range plane {
+			plane[i] *= g
+		}
+	}
+}
+
+// DB returns the linear gain of a level in decibels.
+func DB(db float64) float32 {
+	return float32(math.Pow(10, db/20)) //nolint:mnd
+}
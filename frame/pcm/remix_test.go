@@ -0,0 +1,56 @@
+package pcm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ugparu/gomedia"
+)
+
+func TestMixMatrix(t *testing.T) {
+	surround51 := DefaultLayout(6)
+	tests := []struct {
+		name     string
+		from, to gomedia.ChannelLayout
+		want     [][]float32
+	}{
+		{"mono to stereo", gomedia.ChMono, gomedia.ChStereo, [][]float32{{1}, {1}}},
+		{"stereo to mono", gomedia.ChStereo, gomedia.ChMono, [][]float32{{0.5, 0.5}}},
+		{"identity", gomedia.ChStereo, gomedia.ChStereo, [][]float32{{1, 0}, {0, 1}}},
+		// FL FR FC LFE BL BR: centre and surrounds at -3 dB, LFE dropped,
+		// normalised by 1 + 2*0.7071.
+		{"5.1 to stereo", surround51, gomedia.ChStereo, [][]float32{
+			{0.41421357, 0, 0.29289323, 0, 0.29289323, 0},
+			{0, 0.41421357, 0.29289323, 0, 0, 0.29289323},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MixMatrix(tt.from, tt.to)
+			require.Len(t, got, len(tt.want))
+			for i := range got {
+				require.InDeltaSlice(t, tt.want[i], got[i], 1e-6, "row %d", i)
+			}
+		})
+	}
+}
+
+func TestRemix(t *testing.T) {
+	planes := [][]float32{{1, 0.5}, {-1, 0.5}}
+	require.Equal(t, [][]float32{{0, 0.5}}, Remix(planes, gomedia.ChStereo, gomedia.ChMono))
+	require.Equal(t, [][]float32{{0.25}, {0.25}}, Remix([][]float32{{0.25}}, gomedia.ChMono, gomedia.ChStereo))
+	require.Equal(t, planes, Remix(planes, gomedia.ChStereo, gomedia.ChStereo))
+}
+
+func TestGain(t *testing.T) {
+	planes := [][]float32{{0.5, -0.25}}
+	Gain(planes, DB(-6.0206))
+	require.InDeltaSlice(t, []float32{0.25, -0.125}, planes[0], 1e-4)
+}
+
+func TestPositions(t *testing.T) {
+	require.Equal(t, []gomedia.ChannelLayout{gomedia.ChFrontLeft, gomedia.ChFrontRight, gomedia.ChFrontCenter},
+		Positions(gomedia.ChSurround))
+	require.Equal(t, 8, DefaultLayout(8).Count())
+}
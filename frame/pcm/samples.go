@@ -0,0 +1,130 @@
+package pcm
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/ugparu/gomedia"
+)
+
+// Scale factors between integer samples and [-1, 1].
+const (
+	scaleU8  = 1 << 7
+	scaleS16 = 1 << 15
+	scaleS32 = 1 << 31
+)
+
+// Decode splits little-endian samples into one float32 plane per channel.
+// Planar formats store the channel planes one after another.
+func Decode(data []byte, sf gomedia.SampleFormat, channels int) ([][]float32, error) {
+	bps := sf.BytesPerSample()
+	if bps == 0 {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedFormat, sf)
+	}
+	if channels <= 0 || len(data)%(bps*channels) != 0 {
+		return nil, fmt.Errorf("%w: %d bytes of %d channel %v", ErrInvalidData, len(data), channels, sf)
+	}
+	n := len(data) / (bps * channels)
+	planes := make([][]float32, channels)
+	for ch := range planes {
+		planes[ch] = make([]float32, n)
+	}
+	if sf.IsPlanar() {
+		for ch, plane := range planes {
+			src := data[ch*n*bps:]
+			for i := range plane {
+				plane[i] = sample(src[i*bps:], sf)
+			}
+		}
+		return planes, nil
+	}
+	for i := range n {
+		frame := data[i*bps*channels:]
+		for ch, plane := range planes {
+			plane[i] = sample(frame[ch*bps:], sf)
+		}
+	}
+	return planes, nil
+}
+
+// Encode packs planes into little-endian samples of sf, clamping them to
+// [-1, 1]. All planes must have the same length.
+func Encode(planes [][]float32, sf gomedia.SampleFormat) ([]byte, error) {
+	bps := sf.BytesPerSample()
+	if bps == 0 {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedFormat, sf)
+	}
+	if len(planes) == 0 {
+		return nil, nil
+	}
+	n := len(planes[0])
+	data := make([]byte, n*bps*len(planes))
+	if sf.IsPlanar() {
+		for ch, plane := range planes {
+			dst := data[ch*n*bps:]
+			for i, v := range plane[:n] {
+				putSample(dst[i*bps:], sf, v)
+			}
+		}
+		return data, nil
+	}
+	for i := range n {
+		frame := data[i*bps*len(planes):]
+		for ch, plane := range planes {
+			putSample(frame[ch*bps:], sf, plane[i])
+		}
+	}
+	return data, nil
+}
+
+// ConvertFormat converts samples between formats, keeping the channels.
+// data is returned as is when the formats match.
+func ConvertFormat(data []byte, from, to gomedia.SampleFormat, channels int) ([]byte, error) {
+	if from == to {
+		return data, nil
+	}
+	planes, err := Decode(data, from, channels)
+	if err != nil {
+		return nil, err
+	}
+	return Encode(planes, to)
+}
+
+func sample(b []byte, sf gomedia.SampleFormat) float32 {
+	switch sf { //nolint:exhaustive // BytesPerSample filters unknown formats
+	case gomedia.U8, gomedia.U8P:
+		return float32(int(b[0])-scaleU8) / scaleU8
+	case gomedia.S16, gomedia.S16P:
+		return float32(int16(binary.LittleEndian.Uint16(b))) / scaleS16 //nolint:gosec
+	case gomedia.S32, gomedia.S32P:
+		return float32(float64(int32(binary.LittleEndian.Uint32(b))) / scaleS32) //nolint:gosec
+	case gomedia.U32:
+		return float32((float64(binary.LittleEndian.Uint32(b)) - scaleS32) / scaleS32)
+	case gomedia.FLT, gomedia.FLTP:
+		return math.Float32frombits(binary.LittleEndian.Uint32(b))
+	default:
+		return float32(math.Float64frombits(binary.LittleEndian.Uint64(b)))
+	}
+}
+
+func putSample(b []byte, sf gomedia.SampleFormat, v float32) {
+	v = max(-1, min(1, v))
+	switch sf { //nolint:exhaustive // BytesPerSample filters unknown formats
+	case gomedia.U8, gomedia.U8P:
+		b[0] = uint8(max(0, min(255, math.Round(float64(v)*scaleU8)+scaleU8))) //nolint:mnd
+	case gomedia.S16, gomedia.S16P:
+		s := max(math.MinInt16, min(math.MaxInt16, math.Round(float64(v)*scaleS16)))
+		binary.LittleEndian.PutUint16(b, uint16(int16(s))) //nolint:gosec
+	case gomedia.S32, gomedia.S32P:
+		s := max(math.MinInt32, min(math.MaxInt32, math.Round(float64(v)*scaleS32)))
+		binary.LittleEndian.PutUint32(b, uint32(int32(s))) //nolint:gosec
+	case gomedia.U32:
+		s := max(0, min(math.MaxUint32, math.Round(float64(v)*scaleS32)+scaleS32))
+		binary.LittleEndian.PutUint32(b, uint32(s))
+	case gomedia.FLT, gomedia.FLTP:
+		binary.LittleEndian.PutUint32(b, math.Float32bits(v))
+	default:
+		binary.LittleEndian.PutUint64(b, math.Float64bits(float64(v)))
+	}
+}
@@ -0,0 +1,70 @@
+package pcm
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ugparu/gomedia"
+)
+
+func s16(samples ...int16) []byte {
+	b := make([]byte, 0, 2*len(samples))
+	for _, s := range samples {
+		b = binary.LittleEndian.AppendUint16(b, uint16(s))
+	}
+	return b
+}
+
+func TestDecode_Interleaved(t *testing.T) {
+	planes, err := Decode(s16(16384, -32768, 0, 32767), gomedia.S16, 2)
+	require.NoError(t, err)
+	require.Equal(t, [][]float32{{0.5, 0}, {-1, 32767.0 / 32768}}, planes)
+
+	_, err = Decode(s16(1, 2, 3), gomedia.S16, 2)
+	require.ErrorIs(t, err, ErrInvalidData)
+	_, err = Decode(nil, gomedia.SampleFormat(0), 1)
+	require.ErrorIs(t, err, ErrUnsupportedFormat)
+}
+
+func TestDecode_Planar(t *testing.T) {
+	planes, err := Decode(s16(1, 2, 3, 4), gomedia.S16P, 2)
+	require.NoError(t, err)
+	require.Equal(t, [][]float32{{1.0 / 32768, 2.0 / 32768}, {3.0 / 32768, 4.0 / 32768}}, planes)
+}
+
+func TestConvertFormat_RoundTrip(t *testing.T) {
+	src := s16(0, 1000, -1000, 32767, -32768, 12345)
+	formats := []gomedia.SampleFormat{
+		gomedia.S16P, gomedia.S32, gomedia.S32P, gomedia.FLT, gomedia.FLTP,
+		gomedia.DBL, gomedia.DBLP, gomedia.U32,
+	}
+	for _, sf := range formats {
+		t.Run(sf.String(), func(t *testing.T) {
+			conv, err := ConvertFormat(src, gomedia.S16, sf, 2)
+			require.NoError(t, err)
+			require.Len(t, conv, len(src)/2*sf.BytesPerSample())
+			back, err := ConvertFormat(conv, sf, gomedia.S16, 2)
+			require.NoError(t, err)
+			require.Equal(t, src, back)
+		})
+	}
+}
+
+func TestConvertFormat_U8(t *testing.T) {
+	conv, err := ConvertFormat(s16(0, 256, -32768, 32767), gomedia.S16, gomedia.U8, 1)
+	require.NoError(t, err)
+	require.Equal(t, []byte{128, 129, 0, 255}, conv)
+}
+
+func TestEncode_Clamps(t *testing.T) {
+	data, err := Encode([][]float32{{2, -2, float32(math.Inf(1))}}, gomedia.S16)
+	require.NoError(t, err)
+	require.Equal(t, s16(32767, -32768, 32767), data)
+
+	data, err = Encode([][]float32{{1.5}}, gomedia.FLT)
+	require.NoError(t, err)
+	require.Equal(t, float32(1), math.Float32frombits(binary.LittleEndian.Uint32(data)))
+}
@@ -0,0 +1,48 @@
+package rgb
+
+// Scale resizes src into dst, mapping src.Rect onto dst.Rect. Every
+// destination pixel is the average of the source pixels it covers, which
+// keeps large downscales (4K to 360p) free of aliasing; when upscaling each
+// destination pixel covers less than one source pixel and the result is a
+// nearest-neighbour enlargement.
+func Scale(dst, src *RGB) {
+	dw, dh := dst.Rect.Dx(), dst.Rect.Dy()
+	sw, sh := src.Rect.Dx(), src.Rect.Dy()
+	if dw <= 0 || dh <= 0 || sw <= 0 || sh <= 0 {
+		return
+	}
+
+	// Column spans are shared by every row.
+	xs := make([]int, dw+1)
+	for x := range xs {
+		xs[x] = x * sw / dw
+	}
+
+	for y := range dh {
+		y0, y1 := y*sh/dh, (y+1)*sh/dh
+		if y1 == y0 {
+			y1 = y0 + 1
+		}
+		dRow := dst.Pix[y*dst.Stride:]
+		for x := range dw {
+			x0, x1 := xs[x], xs[x+1]
+			if x1 == x0 {
+				x1 = x0 + 1
+			}
+			var r, g, b, n int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride+x0*bytesPerPix : sy*src.Stride+x1*bytesPerPix]
+				for i := 0; i < len(row); i += bytesPerPix {
+					r += int(row[i])
+					g += int(row[i+1])
+					b += int(row[i+2])
+				}
+				n += x1 - x0
+			}
+			d := dRow[x*bytesPerPix : x*bytesPerPix+bytesPerPix]
+			d[0] = byte(r / n)
+			d[1] = byte(g / n)
+			d[2] = byte(b / n)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package rgb
+
+import (
+	"image"
+	"testing"
+)
+
+func TestScale_DownscaleAveragesBlocks(t *testing.T) {
+	src := NewRGB(image.Rect(0, 0, 4, 2))
+	// Left 2x2 block: red 0 and 100; right 2x2 block: constant blue 40.
+	for y := range 2 {
+		src.Set(0, y, Color{R: 0})
+		src.Set(1, y, Color{R: 100})
+		src.Set(2, y, Color{B: 40})
+		src.Set(3, y, Color{B: 40})
+	}
+	dst := NewRGB(image.Rect(0, 0, 2, 1))
+	Scale(dst, src)
+
+	if got := dst.RGBAt(0, 0); got != (Color{R: 50}) {
+		t.Errorf("left pixel = %+v, want R=50", got)
+	}
+	if got := dst.RGBAt(1, 0); got != (Color{B: 40}) {
+		t.Errorf("right pixel = %+v, want B=40", got)
+	}
+}
+
+func TestScale_UpscaleIsNearestNeighbour(t *testing.T) {
+	src := NewRGB(image.Rect(0, 0, 2, 1))
+	src.Set(0, 0, Color{R: 10})
+	src.Set(1, 0, Color{G: 20})
+	dst := NewRGB(image.Rect(0, 0, 4, 2))
+	Scale(dst, src)
+
+	for y := range 2 {
+		for x := range 4 {
+			want := Color{R: 10}
+			if x >= 2 {
+				want = Color{G: 20}
+			}
+			if got := dst.RGBAt(x, y); got != want {
+				t.Errorf("pixel (%d,%d) = %+v, want %+v", x, y, got, want)
+			}
+		}
+	}
+}
+
+func TestScale_SubImageSource(t *testing.T) {
+	full := NewRGB(image.Rect(0, 0, 4, 4))
+	for y := range 4 {
+		for x := range 4 {
+			full.Set(x, y, Color{R: byte(x), G: byte(y)})
+		}
+	}
+	sub, _ := full.SubImage(image.Rect(2, 2, 4, 4)).(*RGB)
+	dst := NewRGB(image.Rect(0, 0, 2, 2))
+	Scale(dst, sub)
+
+	if got := dst.RGBAt(1, 1); got != (Color{R: 3, G: 3}) {
+		t.Errorf("pixel (1,1) = %+v, want R=3 G=3", got)
+	}
+}
+
+func TestScale_EmptyIsNoop(t *testing.T) {
+	dst := NewRGB(image.Rect(0, 0, 2, 2))
+	Scale(dst, NewRGB(image.Rect(0, 0, 0, 0)))
+	for _, b := range dst.Pix {
+		if b != 0 {
+			t.Fatal("empty source must leave dst untouched")
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package yuv
+
+import "image"
+
+// ScaleI420 resizes the crop rectangle of src into dst, mapping it onto
+// dst.Rect. Every plane is scaled on its own with the area average of
+// rgb.Scale; chroma covers the crop rounded out to whole 2x2 blocks.
+func ScaleI420(dst, src *I420, crop image.Rectangle) {
+	crop = crop.Intersect(src.Rect)
+	dw, dh := dst.Rect.Dx(), dst.Rect.Dy()
+	if crop.Empty() || dw <= 0 || dh <= 0 {
+		return
+	}
+	scalePlane(dst.Y, dst.YStride, dw, dh,
+		src.Y[src.YOffset(crop.Min.X, crop.Min.Y):], src.YStride, crop.Dx(), crop.Dy(), 1)
+
+	cw, ch := chromaCrop(crop, src.Rect)
+	dcw, dch := chromaSize(dw, dh)
+	ci := src.COffset(crop.Min.X, crop.Min.Y)
+	scalePlane(dst.Cb, dst.CStride, dcw, dch, src.Cb[ci:], src.CStride, cw, ch, 1)
+	scalePlane(dst.Cr, dst.CStride, dcw, dch, src.Cr[ci:], src.CStride, cw, ch, 1)
+}
+
+// ScaleNV12 is ScaleI420 for NV12 frames; Cb and Cr stay interleaved.
+func ScaleNV12(dst, src *NV12, crop image.Rectangle) {
+	crop = crop.Intersect(src.Rect)
+	dw, dh := dst.Rect.Dx(), dst.Rect.Dy()
+	if crop.Empty() || dw <= 0 || dh <= 0 {
+		return
+	}
+	scalePlane(dst.Y, dst.YStride, dw, dh,
+		src.Y[src.YOffset(crop.Min.X, crop.Min.Y):], src.YStride, crop.Dx(), crop.Dy(), 1)
+
+	cw, ch := chromaCrop(crop, src.Rect)
+	dcw, dch := chromaSize(dw, dh)
+	scalePlane(dst.UV, dst.UVStride, dcw, dch,
+		src.UV[src.UVOffset(crop.Min.X, crop.Min.Y):], src.UVStride, cw, ch, 2) //nolint:mnd // Cb, Cr
+}
+
+// chromaCrop returns the size in chroma samples of the blocks covering crop
+// in a frame at r.
+func chromaCrop(crop, r image.Rectangle) (int, int) {
+	x0, y0 := crop.Min.X/2-r.Min.X/2, crop.Min.Y/2-r.Min.Y/2 //nolint:mnd
+	x1, y1 := (crop.Max.X+1)/2-r.Min.X/2, (crop.Max.Y+1)/2-r.Min.Y/2
+	return x1 - x0, y1 - y0
+}
+
+// scalePlane area-averages the sw x sh samples of src into the dw x dh
+// samples of dst. A sample is n interleaved bytes, averaged separately.
+func scalePlane(dst []byte, dStride, dw, dh int, src []byte, sStride, sw, sh, n int) {
+	xs := make([]int, dw+1)
+	for x := range xs {
+		xs[x] = x * sw / dw
+	}
+	var sum [2]int
+	for y := range dh {
+		y0, y1 := y*sh/dh, (y+1)*sh/dh
+		if y1 == y0 {
+			y1 = y0 + 1
+		}
+		dRow := dst[y*dStride:]
+		for x := range dw {
+			x0, x1 := xs[x], xs[x+1]
+			if x1 == x0 {
+				x1 = x0 + 1
+			}
+			sum = [2]int{}
+			for sy := y0; sy < y1; sy++ {
+				row := src[sy*sStride+x0*n : sy*sStride+x1*n]
+				for i := 0; i < len(row); i += n {
+					for c := range n {
+						sum[c] += int(row[i+c])
+					}
+				}
+			}
+			cnt := (x1 - x0) * (y1 - y0)
+			for c := range n {
+				dRow[x*n+c] = byte(sum[c] / cnt)
+			}
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package yuv
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestScaleI420_CropsAndAveragesPlanes(t *testing.T) {
+	src := NewI420(image.Rect(0, 0, 8, 4))
+	red := color.YCbCr{Y: 82, Cb: 90, Cr: 240}
+	blue := color.YCbCr{Y: 41, Cb: 240, Cr: 110}
+	fillI420(src, red, blue)
+	src.Y[src.YOffset(4, 0)] = 81 // averaged away with the other 3 samples of its block
+
+	// The right half (blue) at half size.
+	dst := NewI420(image.Rect(0, 0, 2, 2))
+	ScaleI420(dst, src, image.Rect(4, 0, 8, 4))
+	for y := range 2 {
+		for x := range 2 {
+			if got := dst.YCbCrAt(x, y); got != blue && (x != 0 || y != 0) {
+				t.Errorf("pixel %d,%d = %+v, want %+v", x, y, got, blue)
+			}
+		}
+	}
+	if got := dst.YCbCrAt(0, 0); got != (color.YCbCr{Y: 51, Cb: blue.Cb, Cr: blue.Cr}) {
+		t.Errorf("pixel 0,0 = %+v, want Y=51", got)
+	}
+}
+
+func TestScaleNV12_KeepsChromaInterleaved(t *testing.T) {
+	src := NewNV12(image.Rect(0, 0, 4, 4))
+	for y := range 4 {
+		for x := range 4 {
+			src.Y[src.YOffset(x, y)] = byte(10 * (x + y))
+			uv := src.UVOffset(x, y)
+			src.UV[uv], src.UV[uv+1] = 100, 200
+		}
+	}
+
+	dst := NewNV12(image.Rect(0, 0, 2, 2))
+	ScaleNV12(dst, src, src.Rect)
+	want := [][]byte{{10, 30}, {30, 50}}
+	for y := range 2 {
+		for x := range 2 {
+			if got := dst.YCbCrAt(x, y); got != (color.YCbCr{Y: want[y][x], Cb: 100, Cr: 200}) {
+				t.Errorf("pixel %d,%d = %+v, want Y=%d Cb=100 Cr=200", x, y, got, want[y][x])
+			}
+		}
+	}
+}
@@ -0,0 +1,270 @@
+// Package yuv provides pooled planar 4:2:0 video frames, the native output
+// of most decoders. Both frame types implement rgb.ReleasableImage, so they
+// travel through VideoDecoder.Images() unchanged; consumers that want the
+// planes type-assert to *I420 or *NV12, others can still call GetRGB.
+package yuv
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/ugparu/gomedia/frame/rgb"
+)
+
+const (
+	defaultPoolSize = 4
+	bytesPerRGBPix  = 3
+)
+
+// pool is a fixed-size free list of equally sized frames, like
+// rgb.FramePool: frames are not cleared by the GC, so there are no
+// allocations after warmup.
+type pool[T any] struct {
+	ch    chan T
+	alloc func() T
+}
+
+func newPool[T any](alloc func() T) *pool[T] {
+	p := &pool[T]{ch: make(chan T, defaultPoolSize), alloc: alloc}
+	for range defaultPoolSize {
+		p.ch <- alloc()
+	}
+	return p
+}
+
+func (p *pool[T]) get() T {
+	select {
+	case f := <-p.ch:
+		return f
+	default:
+		return p.alloc()
+	}
+}
+
+func (p *pool[T]) put(f T) {
+	select {
+	case p.ch <- f:
+	default:
+	}
+}
+
+// chromaSize returns the size of a 4:2:0 chroma plane, rounding odd
+// dimensions up like image.NewYCbCr.
+func chromaSize(w, h int) (int, int) {
+	return (w + 1) / 2, (h + 1) / 2 //nolint:mnd
+}
+
+// rgbCache holds the lazily converted RGB copy behind GetRGB. The buffer
+// survives pool round trips; only its validity is reset on Release.
+type rgbCache struct {
+	img   *rgb.RGB
+	valid bool
+}
+
+func (c *rgbCache) get(r image.Rectangle, convert func(dst *rgb.RGB)) *rgb.RGB {
+	if c.valid {
+		return c.img
+	}
+	if c.img == nil || c.img.Rect != r {
+		c.img = rgb.NewRGB(r)
+	}
+	convert(c.img)
+	c.valid = true
+	return c.img
+}
+
+// I420 is a planar Y, U, V frame with 4:2:0 chroma. The embedded
+// image.YCbCr gives direct plane access and lets &f.YCbCr be passed to any
+// API taking *image.YCbCr (image/jpeg encodes it without conversion).
+type I420 struct {
+	image.YCbCr
+	pool *I420Pool
+	rgb  rgbCache
+}
+
+// NewI420 allocates a heap-backed (not pooled) I420 frame. The Y, Cb and
+// Cr planes are consecutive in one buffer without row padding, so the frame
+// can be filled by a single copy starting at &Y[0].
+func NewI420(r image.Rectangle) *I420 {
+	w, h := r.Dx(), r.Dy()
+	cw, ch := chromaSize(w, h)
+	buf := make([]byte, w*h+2*cw*ch) //nolint:mnd // Cb and Cr
+	i0, i1 := w*h, w*h+cw*ch
+	return &I420{YCbCr: image.YCbCr{
+		Y:              buf[:i0:i0],
+		Cb:             buf[i0:i1:i1],
+		Cr:             buf[i1:],
+		YStride:        w,
+		CStride:        cw,
+		SubsampleRatio: image.YCbCrSubsampleRatio420,
+		Rect:           r,
+	}}
+}
+
+// Release returns the frame to its pool. No-op for heap-allocated frames.
+func (f *I420) Release() {
+	if f == nil {
+		return
+	}
+	f.rgb.valid = false
+	if f.pool != nil {
+		f.pool.p.put(f)
+	}
+}
+
+// GetRGB converts the frame to packed RGB on first use. The result is owned
+// by the frame and valid until Release.
+func (f *I420) GetRGB() *rgb.RGB {
+	return f.rgb.get(f.Rect, func(dst *rgb.RGB) {
+		for y := f.Rect.Min.Y; y < f.Rect.Max.Y; y++ {
+			out := dst.Pix[(y-f.Rect.Min.Y)*dst.Stride:]
+			for x := f.Rect.Min.X; x < f.Rect.Max.X; x++ {
+				ci := f.COffset(x, y)
+				r, g, b := color.YCbCrToRGB(f.Y[f.YOffset(x, y)], f.Cb[ci], f.Cr[ci])
+				o := bytesPerRGBPix * (x - f.Rect.Min.X)
+				out[o], out[o+1], out[o+2] = r, g, b
+			}
+		}
+	})
+}
+
+// I420Pool is a resolution-specific pool of I420 frames.
+type I420Pool struct {
+	p *pool[*I420]
+}
+
+// NewI420Pool creates a pool of w x h I420 frames.
+func NewI420Pool(w, h int) *I420Pool {
+	ip := &I420Pool{}
+	ip.p = newPool(func() *I420 {
+		f := NewI420(image.Rect(0, 0, w, h))
+		f.pool = ip
+		return f
+	})
+	return ip
+}
+
+// Get returns a pooled frame; its pixels hold whatever the previous user
+// left there.
+func (ip *I420Pool) Get() *I420 {
+	return ip.p.get()
+}
+
+// NV12 is a frame with a full-resolution Y plane followed by one plane of
+// interleaved Cb, Cr samples at half resolution in both directions, the
+// layout produced by most hardware decoders.
+type NV12 struct {
+	Y        []byte
+	UV       []byte
+	YStride  int
+	UVStride int
+	Rect     image.Rectangle
+	pool     *NV12Pool
+	rgb      rgbCache
+}
+
+// NewNV12 allocates a heap-backed (not pooled) NV12 frame. As with NewI420
+// the planes share one unpadded buffer starting at &Y[0].
+func NewNV12(r image.Rectangle) *NV12 {
+	w, h := r.Dx(), r.Dy()
+	cw, ch := chromaSize(w, h)
+	buf := make([]byte, w*h+2*cw*ch) //nolint:mnd // Cb and Cr
+	return &NV12{
+		Y:        buf[: w*h : w*h],
+		UV:       buf[w*h:],
+		YStride:  w,
+		UVStride: 2 * cw, //nolint:mnd
+		Rect:     r,
+	}
+}
+
+func (f *NV12) ColorModel() color.Model { return color.YCbCrModel }
+
+func (f *NV12) Bounds() image.Rectangle { return f.Rect }
+
+// YOffset returns the index of the Y sample of pixel (x, y).
+func (f *NV12) YOffset(x, y int) int {
+	return (y-f.Rect.Min.Y)*f.YStride + (x - f.Rect.Min.X)
+}
+
+// UVOffset returns the index of the Cb sample of pixel (x, y); Cr follows it.
+func (f *NV12) UVOffset(x, y int) int {
+	return (y/2-f.Rect.Min.Y/2)*f.UVStride + 2*(x/2-f.Rect.Min.X/2) //nolint:mnd
+}
+
+func (f *NV12) YCbCrAt(x, y int) color.YCbCr {
+	if !(image.Point{X: x, Y: y}.In(f.Rect)) {
+		return color.YCbCr{}
+	}
+	uv := f.UVOffset(x, y)
+	return color.YCbCr{Y: f.Y[f.YOffset(x, y)], Cb: f.UV[uv], Cr: f.UV[uv+1]}
+}
+
+func (f *NV12) At(x, y int) color.Color {
+	return f.YCbCrAt(x, y)
+}
+
+// YCbCr copies the frame into a new image.YCbCr with 4:2:0 subsampling,
+// for APIs that require one.
+func (f *NV12) YCbCr() *image.YCbCr {
+	dst := image.NewYCbCr(f.Rect, image.YCbCrSubsampleRatio420)
+	for y := f.Rect.Min.Y; y < f.Rect.Max.Y; y++ {
+		copy(dst.Y[dst.YOffset(f.Rect.Min.X, y):], f.Y[f.YOffset(f.Rect.Min.X, y):f.YOffset(f.Rect.Min.X, y)+f.Rect.Dx()])
+	}
+	for y := f.Rect.Min.Y; y < f.Rect.Max.Y; y += 2 {
+		for x := f.Rect.Min.X; x < f.Rect.Max.X; x += 2 {
+			ci, uv := dst.COffset(x, y), f.UVOffset(x, y)
+			dst.Cb[ci], dst.Cr[ci] = f.UV[uv], f.UV[uv+1]
+		}
+	}
+	return dst
+}
+
+// Release returns the frame to its pool. No-op for heap-allocated frames.
+func (f *NV12) Release() {
+	if f == nil {
+		return
+	}
+	f.rgb.valid = false
+	if f.pool != nil {
+		f.pool.p.put(f)
+	}
+}
+
+// GetRGB converts the frame to packed RGB on first use. The result is owned
+// by the frame and valid until Release.
+func (f *NV12) GetRGB() *rgb.RGB {
+	return f.rgb.get(f.Rect, func(dst *rgb.RGB) {
+		for y := f.Rect.Min.Y; y < f.Rect.Max.Y; y++ {
+			out := dst.Pix[(y-f.Rect.Min.Y)*dst.Stride:]
+			for x := f.Rect.Min.X; x < f.Rect.Max.X; x++ {
+				uv := f.UVOffset(x, y)
+				r, g, b := color.YCbCrToRGB(f.Y[f.YOffset(x, y)], f.UV[uv], f.UV[uv+1])
+				o := bytesPerRGBPix * (x - f.Rect.Min.X)
+				out[o], out[o+1], out[o+2] = r, g, b
+			}
+		}
+	})
+}
+
+// NV12Pool is a resolution-specific pool of NV12 frames.
+type NV12Pool struct {
+	p *pool[*NV12]
+}
+
+// NewNV12Pool creates a pool of w x h NV12 frames.
+func NewNV12Pool(w, h int) *NV12Pool {
+	np := &NV12Pool{}
+	np.p = newPool(func() *NV12 {
+		f := NewNV12(image.Rect(0, 0, w, h))
+		f.pool = np
+		return f
+	})
+	return np
+}
+
+// Get returns a pooled frame; its pixels hold whatever the previous user
+// left there.
+func (np *NV12Pool) Get() *NV12 {
+	return np.p.get()
+}
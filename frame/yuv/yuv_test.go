@@ -0,0 +1,127 @@
+package yuv
+
+import (
+	"image"
+	"image/color"
+	"testing"
+	"unsafe"
+)
+
+// fillI420 paints a frame whose left half is one colour and right half
+// another, with a 2-pixel aligned boundary so chroma is not mixed.
+func fillI420(f *I420, left, right color.YCbCr) {
+	b := f.Rect
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := left
+			if x >= b.Dx()/2 {
+				c = right
+			}
+			f.Y[f.YOffset(x, y)] = c.Y
+			ci := f.COffset(x, y)
+			f.Cb[ci], f.Cr[ci] = c.Cb, c.Cr
+		}
+	}
+}
+
+func TestI420_IsContiguous(t *testing.T) {
+	f := NewI420(image.Rect(0, 0, 5, 3))
+	if len(f.Y) != 15 || len(f.Cb) != 6 || len(f.Cr) != 6 {
+		t.Fatalf("plane sizes %d/%d/%d, want 15/6/6", len(f.Y), len(f.Cb), len(f.Cr))
+	}
+	if unsafe.Add(unsafe.Pointer(&f.Y[0]), 15) != unsafe.Pointer(&f.Cb[0]) {
+		t.Error("Cb does not follow Y")
+	}
+	if unsafe.Add(unsafe.Pointer(&f.Cb[0]), 6) != unsafe.Pointer(&f.Cr[0]) {
+		t.Error("Cr does not follow Cb")
+	}
+	if f.SubsampleRatio != image.YCbCrSubsampleRatio420 {
+		t.Errorf("subsample ratio = %v", f.SubsampleRatio)
+	}
+}
+
+func TestI420_GetRGB(t *testing.T) {
+	f := NewI420(image.Rect(0, 0, 4, 2))
+	red := color.YCbCr{Y: 82, Cb: 90, Cr: 240}
+	blue := color.YCbCr{Y: 41, Cb: 240, Cr: 110}
+	fillI420(f, red, blue)
+
+	img := f.GetRGB()
+	for _, tc := range []struct {
+		x    int
+		want color.YCbCr
+	}{{0, red}, {1, red}, {2, blue}, {3, blue}} {
+		r, g, b := color.YCbCrToRGB(tc.want.Y, tc.want.Cb, tc.want.Cr)
+		if got := img.RGBAt(tc.x, 1); got.R != r || got.G != g || got.B != b {
+			t.Errorf("pixel %d = %+v, want %d,%d,%d", tc.x, got, r, g, b)
+		}
+	}
+	if f.GetRGB() != img {
+		t.Error("GetRGB converted twice without Release")
+	}
+}
+
+func TestI420Pool_ReusesFramesAndInvalidatesRGB(t *testing.T) {
+	p := NewI420Pool(2, 2)
+	f := p.Get()
+	fillI420(f, color.YCbCr{Y: 10, Cb: 128, Cr: 128}, color.YCbCr{Y: 10, Cb: 128, Cr: 128})
+	first := f.GetRGB().RGBAt(0, 0)
+	f.Release()
+
+	var again *I420
+	for range defaultPoolSize + 1 {
+		if g := p.Get(); g == f {
+			again = g
+			break
+		}
+	}
+	if again == nil {
+		t.Fatal("released frame was not returned by the pool")
+	}
+	fillI420(again, color.YCbCr{Y: 200, Cb: 128, Cr: 128}, color.YCbCr{Y: 200, Cb: 128, Cr: 128})
+	if got := again.GetRGB().RGBAt(0, 0); got == first {
+		t.Errorf("stale RGB %+v after Release", got)
+	}
+}
+
+func TestNV12_AtAndYCbCr(t *testing.T) {
+	f := NewNV12(image.Rect(0, 0, 4, 2))
+	if f.UVStride != 4 || len(f.UV) != 4 {
+		t.Fatalf("UV stride %d len %d, want 4/4", f.UVStride, len(f.UV))
+	}
+	for i := range f.Y {
+		f.Y[i] = byte(i * 10)
+	}
+	copy(f.UV, []byte{1, 2, 3, 4})
+
+	if got := f.YCbCrAt(3, 1); got != (color.YCbCr{Y: 70, Cb: 3, Cr: 4}) {
+		t.Errorf("YCbCrAt(3,1) = %+v", got)
+	}
+	if got := f.YCbCrAt(4, 0); got != (color.YCbCr{}) {
+		t.Errorf("out of bounds = %+v", got)
+	}
+
+	ycc := f.YCbCr()
+	for y := range 2 {
+		for x := range 4 {
+			if got, want := ycc.YCbCrAt(x, y), f.YCbCrAt(x, y); got != want {
+				t.Errorf("YCbCr()(%d,%d) = %+v, want %+v", x, y, got, want)
+			}
+		}
+	}
+
+	r, g, b := color.YCbCrToRGB(70, 3, 4)
+	if got := f.GetRGB().RGBAt(3, 1); got.R != r || got.G != g || got.B != b {
+		t.Errorf("GetRGB(3,1) = %+v, want %d,%d,%d", got, r, g, b)
+	}
+}
+
+func TestNV12Pool_Get(t *testing.T) {
+	p := NewNV12Pool(6, 4)
+	f := p.Get()
+	if f.Bounds() != image.Rect(0, 0, 6, 4) {
+		t.Fatalf("bounds = %v", f.Bounds())
+	}
+	f.Release()
+	NewNV12(image.Rect(0, 0, 2, 2)).Release() // heap frame: no-op
+}
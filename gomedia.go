@@ -131,10 +131,12 @@ type Decoder[P Packet] interface {
 
 // VideoDecoder decodes VideoPacket frames into RGB images.
 // FPS is a throttling signal: send 0 to pause, -1 for native rate.
+// Output changes the crop and size of the emitted images.
 type VideoDecoder interface {
 	Decoder[VideoPacket]
 	Images() <-chan rgb.ReleasableImage
 	FPS() chan<- int
+	Output() chan<- VideoOutput
 }
 
 // AudioDecoder decodes AudioPacket frames into PCM samples.
@@ -156,6 +158,41 @@ type AudioEncoder interface {
 	Encode()
 }
 
+// VideoFrame is a raw image handed to a VideoEncoder together with the
+// timing and source the resulting packet should carry. The encoder takes
+// ownership of Image and releases it once encoded.
+type VideoFrame struct {
+	Image     rgb.ReleasableImage
+	Timestamp time.Duration
+	StartTime time.Time
+	SourceID  string
+}
+
+// VideoEncoderParameters configures a video encoder backend. Width and Height
+// come from the incoming frames; the rest from the encoder options.
+type VideoEncoderParameters struct {
+	Width  int
+	Height int
+	FPS    int
+	// Bitrate is the target bitrate in bits per second; 0 selects the
+	// backend's constant-quality mode.
+	Bitrate uint
+	// GOPSize is the key frame interval in frames; 0 means one key frame
+	// per second.
+	GOPSize     int
+	StreamIndex uint8
+}
+
+// VideoEncoder consumes raw frames on Frames() and produces encoded
+// VideoPackets on Packets() (inherited from Encoder).
+type VideoEncoder interface {
+	Encoder
+	Frames() chan<- VideoFrame
+	Encode()
+	Close()
+	Done() <-chan struct{}
+}
+
 // HLSMuxer is a single-source HLS muxer. It rotates segments on
 // segmentDuration and exposes the playlist/segment/fragment bytes via the
 // Get* methods. UpdateCodecParameters injects an HLS discontinuity.
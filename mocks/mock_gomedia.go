@@ -1571,6 +1571,20 @@ func (mr *MockVideoDecoderMockRecorder) Images() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Images", reflect.TypeOf((*MockVideoDecoder)(nil).Images))
 }
 
+// Output mocks base method.
+func (m *MockVideoDecoder) Output() chan<- gomedia.VideoOutput {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Output")
+	ret0, _ := ret[0].(chan<- gomedia.VideoOutput)
+	return ret0
+}
+
+// Output indicates an expected call of Output.
+func (mr *MockVideoDecoderMockRecorder) Output() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Output", reflect.TypeOf((*MockVideoDecoder)(nil).Output))
+}
+
 // Packets mocks base method.
 func (m *MockVideoDecoder) Packets() chan<- gomedia.VideoPacket {
 	m.ctrl.T.Helper()
@@ -1777,6 +1791,96 @@ func (mr *MockAudioEncoderMockRecorder) Samples() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Samples", reflect.TypeOf((*MockAudioEncoder)(nil).Samples))
 }
 
+// MockVideoEncoder is a mock of VideoEncoder interface.
+type MockVideoEncoder struct {
+	ctrl     *gomock.Controller
+	recorder *MockVideoEncoderMockRecorder
+	isgomock struct{}
+}
+
+// MockVideoEncoderMockRecorder is the mock recorder for MockVideoEncoder.
+type MockVideoEncoderMockRecorder struct {
+	mock *MockVideoEncoder
+}
+
+// NewMockVideoEncoder creates a new mock instance.
+func NewMockVideoEncoder(ctrl *gomock.Controller) *MockVideoEncoder {
+	mock := &MockVideoEncoder{ctrl: ctrl}
+	mock.recorder = &MockVideoEncoderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockVideoEncoder) EXPECT() *MockVideoEncoderMockRecorder {
+	return m.recorder
+}
+
+// Close mocks base method.
+func (m *MockVideoEncoder) Close() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Close")
+}
+
+// Close indicates an expected call of Close.
+func (mr *MockVideoEncoderMockRecorder) Close() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockVideoEncoder)(nil).Close))
+}
+
+// Done mocks base method.
+func (m *MockVideoEncoder) Done() <-chan struct{} {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Done")
+	ret0, _ := ret[0].(<-chan struct{})
+	return ret0
+}
+
+// Done indicates an expected call of Done.
+func (mr *MockVideoEncoderMockRecorder) Done() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Done", reflect.TypeOf((*MockVideoEncoder)(nil).Done))
+}
+
+// Encode mocks base method.
+func (m *MockVideoEncoder) Encode() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Encode")
+}
+
+// Encode indicates an expected call of Encode.
+func (mr *MockVideoEncoderMockRecorder) Encode() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Encode", reflect.TypeOf((*MockVideoEncoder)(nil).Encode))
+}
+
+// Frames mocks base method.
+func (m *MockVideoEncoder) Frames() chan<- gomedia.VideoFrame {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Frames")
+	ret0, _ := ret[0].(chan<- gomedia.VideoFrame)
+	return ret0
+}
+
+// Frames indicates an expected call of Frames.
+func (mr *MockVideoEncoderMockRecorder) Frames() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Frames", reflect.TypeOf((*MockVideoEncoder)(nil).Frames))
+}
+
+// Packets mocks base method.
+func (m *MockVideoEncoder) Packets() <-chan gomedia.Packet {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Packets")
+	ret0, _ := ret[0].(<-chan gomedia.Packet)
+	return ret0
+}
+
+// Packets indicates an expected call of Packets.
+func (mr *MockVideoEncoderMockRecorder) Packets() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Packets", reflect.TypeOf((*MockVideoEncoder)(nil).Packets))
+}
+
 // MockHLSMuxer is a mock of HLSMuxer interface.
 type MockHLSMuxer struct {
 	ctrl     *gomock.Controller
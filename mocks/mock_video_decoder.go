@@ -95,3 +95,79 @@ func (mr *MockInnerVideoDecoderMockRecorder) Init(codecPar any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Init", reflect.TypeOf((*MockInnerVideoDecoder)(nil).Init), codecPar)
 }
+
+// MockPixelFormatSetter is a mock of PixelFormatSetter interface.
+type MockPixelFormatSetter struct {
+	ctrl     *gomock.Controller
+	recorder *MockPixelFormatSetterMockRecorder
+	isgomock struct{}
+}
+
+// MockPixelFormatSetterMockRecorder is the mock recorder for MockPixelFormatSetter.
+type MockPixelFormatSetterMockRecorder struct {
+	mock *MockPixelFormatSetter
+}
+
+// NewMockPixelFormatSetter creates a new mock instance.
+func NewMockPixelFormatSetter(ctrl *gomock.Controller) *MockPixelFormatSetter {
+	mock := &MockPixelFormatSetter{ctrl: ctrl}
+	mock.recorder = &MockPixelFormatSetterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPixelFormatSetter) EXPECT() *MockPixelFormatSetterMockRecorder {
+	return m.recorder
+}
+
+// SetPixelFormat mocks base method.
+func (m *MockPixelFormatSetter) SetPixelFormat(pf gomedia.PixelFormat) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetPixelFormat", pf)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetPixelFormat indicates an expected call of SetPixelFormat.
+func (mr *MockPixelFormatSetterMockRecorder) SetPixelFormat(pf any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPixelFormat", reflect.TypeOf((*MockPixelFormatSetter)(nil).SetPixelFormat), pf)
+}
+
+// MockOutputSetter is a mock of OutputSetter interface.
+type MockOutputSetter struct {
+	ctrl     *gomock.Controller
+	recorder *MockOutputSetterMockRecorder
+	isgomock struct{}
+}
+
+// MockOutputSetterMockRecorder is the mock recorder for MockOutputSetter.
+type MockOutputSetterMockRecorder struct {
+	mock *MockOutputSetter
+}
+
+// NewMockOutputSetter creates a new mock instance.
+func NewMockOutputSetter(ctrl *gomock.Controller) *MockOutputSetter {
+	mock := &MockOutputSetter{ctrl: ctrl}
+	mock.recorder = &MockOutputSetterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOutputSetter) EXPECT() *MockOutputSetterMockRecorder {
+	return m.recorder
+}
+
+// SetOutput mocks base method.
+func (m *MockOutputSetter) SetOutput(out gomedia.VideoOutput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetOutput", out)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetOutput indicates an expected call of SetOutput.
+func (mr *MockOutputSetterMockRecorder) SetOutput(out any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetOutput", reflect.TypeOf((*MockOutputSetter)(nil).SetOutput), out)
+}
@@ -0,0 +1,82 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: video_encoder.go
+//
+// Generated by this command:
+//
+//	mockgen -source=video_encoder.go -destination=../mocks/mock_video_encoder.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+
+	gomedia "github.com/ugparu/gomedia"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockInnerVideoEncoder is a mock of InnerVideoEncoder interface.
+type MockInnerVideoEncoder struct {
+	ctrl     *gomock.Controller
+	recorder *MockInnerVideoEncoderMockRecorder
+	isgomock struct{}
+}
+
+// MockInnerVideoEncoderMockRecorder is the mock recorder for MockInnerVideoEncoder.
+type MockInnerVideoEncoderMockRecorder struct {
+	mock *MockInnerVideoEncoder
+}
+
+// NewMockInnerVideoEncoder creates a new mock instance.
+func NewMockInnerVideoEncoder(ctrl *gomock.Controller) *MockInnerVideoEncoder {
+	mock := &MockInnerVideoEncoder{ctrl: ctrl}
+	mock.recorder = &MockInnerVideoEncoderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockInnerVideoEncoder) EXPECT() *MockInnerVideoEncoderMockRecorder {
+	return m.recorder
+}
+
+// Close mocks base method.
+func (m *MockInnerVideoEncoder) Close() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Close")
+}
+
+// Close indicates an expected call of Close.
+func (mr *MockInnerVideoEncoderMockRecorder) Close() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockInnerVideoEncoder)(nil).Close))
+}
+
+// Encode mocks base method.
+func (m *MockInnerVideoEncoder) Encode(frame gomedia.VideoFrame) ([]gomedia.VideoPacket, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Encode", frame)
+	ret0, _ := ret[0].([]gomedia.VideoPacket)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Encode indicates an expected call of Encode.
+func (mr *MockInnerVideoEncoderMockRecorder) Encode(frame any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Encode", reflect.TypeOf((*MockInnerVideoEncoder)(nil).Encode), frame)
+}
+
+// Init mocks base method.
+func (m *MockInnerVideoEncoder) Init(params gomedia.VideoEncoderParameters) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Init", params)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Init indicates an expected call of Init.
+func (mr *MockInnerVideoEncoderMockRecorder) Init(params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Init", reflect.TypeOf((*MockInnerVideoEncoder)(nil).Init), params)
+}
@@ -0,0 +1,23 @@
+package gomedia
+
+// PixelFormat identifies the layout of decoded video frames.
+type PixelFormat uint8
+
+const (
+	RGB24 = PixelFormat(iota) // packed 8-bit R, G, B (rgb.RGB); the default
+	I420                      // planar Y, U, V with 4:2:0 chroma (yuv.I420)
+	NV12                      // planar Y and interleaved UV with 4:2:0 chroma (yuv.NV12)
+)
+
+func (pf PixelFormat) String() string {
+	switch pf {
+	case RGB24:
+		return "RGB24"
+	case I420:
+		return "I420"
+	case NV12:
+		return "NV12"
+	default:
+		return "?"
+	}
+}
@@ -0,0 +1,84 @@
+package reader
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ugparu/gomedia"
+)
+
+// EventType identifies what happened to a source.
+type EventType int
+
+const (
+	// EventConnected is sent when a demuxer started; Params holds the
+	// stream parameters.
+	EventConnected EventType = iota + 1
+	// EventCodecParametersChanged is sent when packets arrive with new
+	// codec parameters; Params holds the current ones.
+	EventCodecParametersChanged
+	// EventDisconnected is sent when a source goes offline: its demuxer
+	// failed to start or stopped delivering packets. Err holds the cause.
+	EventDisconnected
+	// EventReconnecting is sent before every reconnect attempt, to be made
+	// at NextAttempt.
+	EventReconnecting
+	// EventGaveUp is sent when the reconnect policy ran out of attempts and
+	// the source stopped; Err holds the last failure. RemoveURL still
+	// drops its stats.
+	EventGaveUp
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventConnected:
+		return "connected"
+	case EventCodecParametersChanged:
+		return "codec parameters changed"
+	case EventDisconnected:
+		return "disconnected"
+	case EventReconnecting:
+		return "reconnecting"
+	case EventGaveUp:
+		return "gave up"
+	default:
+		return fmt.Sprintf("EventType(%d)", int(t))
+	}
+}
+
+// Event reports a change in the health of a source.
+type Event struct {
+	Type EventType
+	URL  string
+	Time time.Time
+	// Params is set for EventConnected and EventCodecParametersChanged.
+	Params gomedia.CodecParametersPair
+	// Err is set for EventDisconnected and EventGaveUp.
+	Err error
+	// NextAttempt is set for EventReconnecting.
+	NextAttempt time.Time
+}
+
+// emit sends ev without blocking: events are dropped when the application
+// does not keep up, so that a slow consumer never stalls ingest.
+func (rdr *reader) emit(ev Event) {
+	ev.Time = time.Now()
+	select {
+	case rdr.events <- ev:
+	default:
+		rdr.log.Debugf(rdr, "Dropping %s event of %s", ev.Type, ev.URL)
+	}
+}
+
+func (rdr *reader) connected(src string, st *sourceStats, par gomedia.CodecParametersPair) {
+	st.setConnected(par)
+	rdr.emit(Event{Type: EventConnected, URL: src, Params: par})
+}
+
+// disconnected reports err unless the source is already known to be
+// offline, so a camera that stays down is reported once.
+func (rdr *reader) disconnected(src string, st *sourceStats, err error) {
+	if st.setDisconnected() {
+		rdr.emit(Event{Type: EventDisconnected, URL: src, Err: err})
+	}
+}
@@ -0,0 +1,68 @@
+package reader
+
+import (
+	"errors"
+	"net/url"
+	"os"
+
+	"github.com/ugparu/gomedia"
+	"github.com/ugparu/gomedia/format/mp4"
+	"github.com/ugparu/gomedia/utils/logger"
+)
+
+var errNotDemuxed = errors.New("file demuxer: Demux not called")
+
+// fileDemuxer plays the MP4 file of a file:// URL in real time, looping at
+// its end like a camera that never stops. Packets carry the URL as SourceID,
+// like those of the other schemes. Loops are seamless: timestamps keep
+// increasing and the source stays connected.
+type fileDemuxer struct {
+	gomedia.Demuxer
+	src  string
+	file *os.File
+}
+
+func newFileDemuxer(src string, _ logger.Logger) gomedia.Demuxer {
+	return &fileDemuxer{src: src}
+}
+
+// filePath returns the local path of a file:// URL. "file://dir/a.mp4" is
+// taken as the relative path dir/a.mp4.
+func filePath(src string) (string, error) {
+	u, err := url.Parse(src)
+	if err != nil {
+		return "", err
+	}
+	if u.Host != "" && u.Host != "localhost" {
+		return u.Host + u.Path, nil
+	}
+	return u.Path, nil
+}
+
+func (d *fileDemuxer) Demux() (gomedia.CodecParametersPair, error) {
+	p, err := filePath(d.src)
+	if err != nil {
+		return gomedia.CodecParametersPair{}, err
+	}
+	if d.file, err = os.Open(p); err != nil {
+		return gomedia.CodecParametersPair{}, err
+	}
+	d.Demuxer = mp4.NewPlaybackDemuxerFromReader(d.file, d.src, mp4.WithLoop())
+	return d.Demuxer.Demux()
+}
+
+func (d *fileDemuxer) ReadPacket() (gomedia.Packet, error) {
+	if d.Demuxer == nil {
+		return nil, errNotDemuxed
+	}
+	return d.Demuxer.ReadPacket()
+}
+
+func (d *fileDemuxer) Close() {
+	if d.Demuxer != nil {
+		d.Demuxer.Close()
+	}
+	if d.file != nil {
+		_ = d.file.Close()
+	}
+}
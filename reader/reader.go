@@ -3,7 +3,10 @@
 package reader
 
 import (
+	"errors"
 	"net/url"
+	"path"
+	"strings"
 	"sync"
 	"time"
 
@@ -19,43 +22,107 @@ const (
 
 type Option func(*reader)
 
+// DemuxerFactory opens a demuxer for url. It is called again on every
+// reconnect; log is the reader's logger. Close of the demuxer may be called
+// while ReadPacket blocks on another goroutine and must make it return.
+type DemuxerFactory func(url string, log logger.Logger) gomedia.Demuxer
+
 func WithLogger(l logger.Logger) Option {
 	return func(r *reader) { r.log = l }
 }
 
+// WithRTSPParams sets the options of the demuxers opened for rtsp:// and
+// rtsps:// URLs.
 func WithRTSPParams(params ...rtsp.DemuxerOption) Option {
 	return func(r *reader) { r.opts = params }
 }
 
-// reader fans packets from many RTSP demuxers (one per URL) into a single
+// WithScheme registers the demuxer factory for URLs of the given scheme,
+// e.g. "rtmp", replacing the built-in one if any.
+func WithScheme(scheme string, factory DemuxerFactory) Option {
+	return func(r *reader) { r.schemes[strings.ToLower(scheme)] = factory.forSource() }
+}
+
+// Reader is a gomedia.Reader that also reports the health of its sources.
+type Reader interface {
+	gomedia.Reader
+	// Events delivers connection and codec events of every source. It is
+	// buffered like Packets; events that do not fit are dropped.
+	Events() <-chan Event
+	// Stats returns the counters of a source added through AddURL.
+	Stats(url string) (Stats, bool)
+	// AddSource adds a source with settings of its own. Sources are
+	// identified by their URL, as for RemoveURL.
+	AddSource() chan<- Source
+	// Reconnect closes the connection of a source and opens it again
+	// without waiting, reporting false if url is not a source. A read
+	// blocked in the demuxer is interrupted by closing it.
+	Reconnect(url string) bool
+}
+
+// reader fans packets from many demuxers (one per URL) into a single
 // channel. Each demuxer runs in its own goroutine; Step only handles URL
 // add/remove.
 type reader struct {
 	lifecycle.AsyncManager[*reader]
 	log         logger.Logger
 	newDmx      func(string, ...rtsp.DemuxerOption) gomedia.Demuxer
+	schemes     map[string]sourceFactory
 	packets     chan gomedia.Packet
 	addURLCh    chan string
+	addSrcCh    chan Source
 	removeURLCh chan string
 	dmxStoppers map[string]chan struct{}
+	reconnects  map[string]chan struct{}
 	name        string
 	mu          sync.Mutex
 	opts        []rtsp.DemuxerOption
+
+	events  chan Event
+	stats   map[string]*sourceStats
+	statsMu sync.Mutex
+
+	policy     ReconnectPolicy
+	noVideo    time.Duration
+	noKeyframe time.Duration
 }
 
-func NewRTSP(chanSize int, opts ...Option) gomedia.Reader {
+// worker is the state of one source, owned by its repackPackets goroutine.
+type worker struct {
+	url       string
+	spec      Source
+	open      sourceFactory
+	stats     *sourceStats
+	backoff   backoff
+	watchdog  watchdog
+	reconnect <-chan struct{}
+	stop      <-chan struct{}
+}
+
+// New returns a reader that opens every added URL with the demuxer
+// registered for its scheme: rtsp:// and rtsps:// use rtsp.New, file:// plays
+// an MP4 file in a real-time loop through mp4.NewPlaybackDemuxerFromReader,
+// and WithScheme adds more.
+// URLs with an unknown scheme are logged and ignored.
+func New(chanSize int, opts ...Option) Reader {
 	rdr := &reader{
 		AsyncManager: nil,
 		log:          logger.Default,
 		newDmx:       rtsp.New,
 		packets:      make(chan gomedia.Packet, chanSize),
 		addURLCh:     make(chan string, chanSize),
+		addSrcCh:     make(chan Source, chanSize),
 		removeURLCh:  make(chan string, chanSize),
 		dmxStoppers:  make(map[string]chan struct{}),
+		reconnects:   make(map[string]chan struct{}),
 		name:         "READER",
 		mu:           sync.Mutex{},
 		opts:         nil,
+		events:       make(chan Event, chanSize),
+		stats:        make(map[string]*sourceStats),
+		policy:       DefaultReconnectPolicy(),
 	}
+	rdr.schemes = rdr.defaultSchemes()
 
 	for _, o := range opts {
 		o(rdr)
@@ -65,31 +132,52 @@ func NewRTSP(chanSize int, opts ...Option) gomedia.Reader {
 	return rdr
 }
 
-func (rdr *reader) repackPackets(src string, stopCh <-chan struct{}) {
+// NewRTSP returns a reader for RTSP cameras. It is New, kept for existing
+// callers; the other schemes are available as well.
+func NewRTSP(chanSize int, opts ...Option) Reader {
+	return New(chanSize, opts...)
+}
+
+func (rdr *reader) defaultSchemes() map[string]sourceFactory {
+	return map[string]sourceFactory{
+		"rtsp":  rdr.newRTSPDemuxer,
+		"rtsps": rdr.newRTSPDemuxer,
+		"file":  DemuxerFactory(newFileDemuxer).forSource(),
+	}
+}
+
+// newRTSPDemuxer applies the options of the source after those of
+// WithRTSPParams.
+func (rdr *reader) newRTSPDemuxer(spec Source, log logger.Logger) gomedia.Demuxer {
+	opts := append([]rtsp.DemuxerOption{rtsp.WithLogger(log)}, rdr.opts...)
+	return rdr.newDmx(spec.URL, append(opts, spec.rtspOptions()...)...)
+}
+
+func (rdr *reader) repackPackets(src *worker) {
 	defer func() {
 		if r := recover(); r != nil {
 			rdr.log.Errorf(rdr, "Panic in repackPackets: %v", r)
 		}
 	}()
 
-	recInterval := time.Second
 	videoOffsetHandler := new(offsetHandler)
 	audioOffsetHandler := new(offsetHandler)
 
-	opts := append([]rtsp.DemuxerOption{rtsp.WithLogger(rdr.log)}, rdr.opts...)
-	dmx := rdr.newDmx(src, opts...)
-	pars, err := dmx.Demux()
-	if err != nil {
-		rdr.log.Warningf(rdr, "Failed to start demuxer: %s", err.Error())
-	} else {
-		rdr.log.Infof(rdr, "Demuxer started. Video: %t, Audio: %t",
-			pars.VideoCodecParameters != nil, pars.AudioCodecParameters != nil)
+	conn := rdr.connect(src, 0)
+	if conn == nil {
+		rdr.forget(src)
+		return
 	}
+	defer func() {
+		if conn != nil {
+			conn.close(nil)
+		}
+	}()
 
 	var pktCnt float64
 	for {
 		select {
-		case <-stopCh:
+		case <-src.stop:
 			videoOffsetHandler.releaseLastPacket()
 			audioOffsetHandler.releaseLastPacket()
 			return
@@ -98,17 +186,30 @@ func (rdr *reader) repackPackets(src string, stopCh <-chan struct{}) {
 
 		rdr.log.Trace(rdr, "Trying to read new packet")
 
-		var pkt gomedia.Packet
-		pkt, err = dmx.ReadPacket()
-		if err != nil {
-			recInterval, dmx = rdr.handleReadError(dmx, src, recInterval, stopCh, videoOffsetHandler, audioOffsetHandler, err)
+		pkt, err := conn.dmx.ReadPacket()
+		if err = conn.err(err); err != nil {
+			if pkt != nil {
+				pkt.Release()
+			}
+			var ok bool
+			if conn, ok = rdr.handleReadError(conn, src, videoOffsetHandler, audioOffsetHandler, err); !ok {
+				videoOffsetHandler.releaseLastPacket()
+				audioOffsetHandler.releaseLastPacket()
+				rdr.forget(src)
+				return
+			}
 			continue
 		}
 
 		if pkt == nil {
 			continue
 		}
+		src.watchdog.seen(pkt, time.Now())
 		rdr.log.Tracef(rdr, "Read new packet %v", pkt)
+		if par, changed := src.stats.record(pkt); changed {
+			par.SourceID = src.url
+			rdr.emit(Event{Type: EventCodecParametersChanged, URL: src.url, Params: par})
+		}
 
 		pktCnt++
 
@@ -129,7 +230,7 @@ func (rdr *reader) repackPackets(src string, stopCh <-chan struct{}) {
 			select {
 			case rdr.packets <- videoOffsetHandler.lastPacket:
 				videoOffsetHandler.lastPacket = pkt
-			case <-stopCh:
+			case <-src.stop:
 				pkt.Release()
 				videoOffsetHandler.releaseLastPacket()
 				audioOffsetHandler.releaseLastPacket()
@@ -149,7 +250,7 @@ func (rdr *reader) repackPackets(src string, stopCh <-chan struct{}) {
 			select {
 			case rdr.packets <- audioOffsetHandler.lastPacket:
 				audioOffsetHandler.lastPacket = pkt
-			case <-stopCh:
+			case <-src.stop:
 				pkt.Release()
 				videoOffsetHandler.releaseLastPacket()
 				audioOffsetHandler.releaseLastPacket()
@@ -161,63 +262,163 @@ func (rdr *reader) repackPackets(src string, stopCh <-chan struct{}) {
 	}
 }
 
-// handleReadError tears down the failing demuxer, sleeps for recInterval, and
-// opens a new one. On repeated failure the interval doubles up to
-// maxReconnectInterval and further log messages are silenced to avoid spam.
-// Offset handlers are rewound on success so timestamps stay monotonic across
-// the reconnection boundary.
-func (rdr *reader) handleReadError(dmx gomedia.Demuxer, src string, recInterval time.Duration,
-	stopCh <-chan struct{}, videoHandler, audioHandler *offsetHandler, readErr error) (time.Duration, gomedia.Demuxer) {
-	if recInterval < maxReconnectInterval {
-		rdr.log.Warningf(rdr, "Packet read error: %s", readErr.Error())
-		rdr.log.Infof(rdr, "Restarting demuxer with %.fs interval", recInterval.Seconds())
+// handleReadError tears down the failing connection and opens a new one as
+// the reconnect policy says. Offset handlers are rewound on success so
+// timestamps stay monotonic across the reconnection boundary. It reports
+// false when the source was stopped or given up.
+func (rdr *reader) handleReadError(conn *connection, src *worker, videoHandler, audioHandler *offsetHandler,
+	readErr error) (*connection, bool) {
+	rdr.log.Debug(rdr, "Closing demuxer")
+	conn.close(readErr)
+	select {
+	case <-src.stop:
+		return nil, false
+	default:
 	}
 
-	rdr.log.Debug(rdr, "Closing demuxer")
-	dmx.Close()
+	wait := src.backoff.wait()
+	if errors.Is(readErr, ErrReconnectRequested) {
+		wait = 0
+	}
+	if !src.backoff.capped() {
+		rdr.log.Warningf(rdr, "Packet read error: %s", readErr.Error())
+		rdr.log.Infof(rdr, "Restarting demuxer in %v", wait.Round(time.Millisecond))
+	}
+	rdr.disconnected(src.url, src.stats, readErr)
+	src.stats.reconnecting()
+	rdr.emit(Event{Type: EventReconnecting, URL: src.url, NextAttempt: time.Now().Add(wait)})
 
-	select {
-	case <-time.After(recInterval):
-	case <-stopCh:
-		return recInterval, dmx
+	if conn = rdr.connect(src, wait); conn == nil {
+		return nil, false
 	}
+	videoHandler.RecalcForGap()
+	audioHandler.RecalcForGap()
+	return conn, true
+}
+
+// connect opens the source after wait, retrying failed attempts as the
+// reconnect policy says. Once the wait stops growing further log messages
+// are silenced to avoid spam. It returns nil when the source was stopped or
+// given up.
+func (rdr *reader) connect(src *worker, wait time.Duration) *connection {
+	for {
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-src.reconnect:
+			timer.Stop()
+		case <-src.stop:
+			timer.Stop()
+			return nil
+		}
 
-	rdr.log.Debug(rdr, "Creating new demuxer")
-	reconOpts := append([]rtsp.DemuxerOption{rtsp.WithLogger(rdr.log)}, rdr.opts...)
-	dmx = rdr.newDmx(src, reconOpts...)
+		rdr.log.Debug(rdr, "Creating new demuxer")
+		dmx := src.open(src.spec, rdr.log)
+
+		rdr.log.Debug(rdr, "Starting demuxing")
+		par, err := dmx.Demux()
+		if err == nil {
+			rdr.log.Infof(rdr, "Demuxer started. Video: %t, Audio: %t",
+				par.VideoCodecParameters != nil, par.AudioCodecParameters != nil)
+			rdr.connected(src.url, src.stats, par)
+			src.backoff.reset()
+			src.watchdog.reset(par, time.Now())
+			conn := &connection{dmx: dmx, done: make(chan struct{})}
+			go rdr.guard(src, conn)
+			return conn
+		}
 
-	rdr.log.Debug(rdr, "Starting demuxing")
-	par, err := dmx.Demux()
-	if err != nil {
-		if recInterval < maxReconnectInterval {
+		dmx.Close()
+		if !src.backoff.capped() {
 			rdr.log.Warningf(rdr, "Failed to start demuxer: %s", err.Error())
 		}
-		return rdr.updateReconnectInterval(recInterval), dmx
+		rdr.disconnected(src.url, src.stats, err)
+		wait = src.backoff.wait()
+		wasCapped := src.backoff.capped()
+		if !src.backoff.failed() {
+			rdr.log.Errorf(rdr, "Giving up %s after %d failed attempts", src.url, src.backoff.failures)
+			rdr.emit(Event{Type: EventGaveUp, URL: src.url, Err: err})
+			return nil
+		}
+		if !wasCapped && src.backoff.capped() {
+			rdr.log.Infof(rdr, "Max reconnect interval reached. Further attempts will be silent")
+		} else if !wasCapped {
+			rdr.log.Infof(rdr, "Restarting demuxer in %v", wait.Round(time.Millisecond))
+		}
+		src.stats.reconnecting()
+		rdr.emit(Event{Type: EventReconnecting, URL: src.url, NextAttempt: time.Now().Add(wait)})
 	}
+}
 
-	rdr.log.Infof(rdr, "Demuxer started. Video: %t, Audio: %t",
-		par.VideoCodecParameters != nil, par.AudioCodecParameters != nil)
+// guard closes conn from outside when the source is stopped, a reconnect is
+// requested or the stall watchdog fires, so that a ReadPacket blocked on a
+// connection that stays up but carries nothing returns.
+func (rdr *reader) guard(src *worker, conn *connection) {
+	var tick <-chan time.Time
+	if period := src.watchdog.period(); period > 0 {
+		ticker := time.NewTicker(period)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case <-conn.done:
+			return
+		case <-src.stop:
+			conn.close(nil)
+			return
+		case <-src.reconnect:
+			conn.close(ErrReconnectRequested)
+			return
+		case now := <-tick:
+			if err := src.watchdog.stalled(now); err != nil {
+				rdr.log.Warningf(rdr, "Closing stalled demuxer: %s", err.Error())
+				conn.close(err)
+				return
+			}
+		}
+	}
+}
 
-	videoHandler.RecalcForGap()
-	audioHandler.RecalcForGap()
+// connection is an open demuxer of a source. It is closed once, either by
+// the worker after a read error or by its guard.
+type connection struct {
+	dmx  gomedia.Demuxer
+	done chan struct{}
+	once sync.Once
+	why  error // cause given by the first close
+}
 
-	return time.Second, dmx
+func (c *connection) close(cause error) {
+	c.once.Do(func() {
+		c.why = cause
+		close(c.done)
+		c.dmx.Close()
+	})
 }
 
-func (rdr *reader) updateReconnectInterval(current time.Duration) time.Duration {
-	if current >= maxReconnectInterval {
-		return current
+// err returns the cause the connection was closed for, if any, instead of
+// readErr: the read failed, or returned late, because the guard closed it.
+func (c *connection) err(readErr error) error {
+	select {
+	case <-c.done:
+		if c.why != nil {
+			return c.why
+		}
+	default:
 	}
+	return readErr
+}
 
-	const scaleFactor = 2
-	newInterval := current * scaleFactor
-
-	if newInterval >= maxReconnectInterval {
-		newInterval = maxReconnectInterval
-		rdr.log.Infof(rdr, "Max reconnect interval reached. Further attempts will be silent")
+// forget drops a source that was given up, unless it was removed or added
+// again meanwhile. Its stats stay available until RemoveURL.
+func (rdr *reader) forget(src *worker) {
+	rdr.mu.Lock()
+	defer rdr.mu.Unlock()
+	if rdr.dmxStoppers[src.url] == src.stop {
+		delete(rdr.dmxStoppers, src.url)
+		delete(rdr.reconnects, src.url)
 	}
-
-	return newInterval
 }
 
 func (rdr *reader) Step(stopCh <-chan struct{}) (err error) {
@@ -226,32 +427,72 @@ func (rdr *reader) Step(stopCh <-chan struct{}) (err error) {
 	case <-stopCh:
 		return &lifecycle.BreakError{}
 	case src := <-rdr.addURLCh:
-		rdr.log.Infof(rdr, "Adding new URL %s", src)
-
-		parsedURL, err := url.Parse(src)
-		if err != nil {
-			rdr.log.Errorf(rdr, "Failed to parse URL %s: %s", src, err.Error())
-			return err
-		}
-		rdr.name = "READER " + parsedURL.Hostname()
-
-		rStopCh := make(chan struct{})
-		rdr.mu.Lock()
-		rdr.dmxStoppers[src] = rStopCh
-		rdr.mu.Unlock()
-		go rdr.repackPackets(src, rStopCh)
+		return rdr.add(Source{URL: src})
+	case spec := <-rdr.addSrcCh:
+		return rdr.add(spec)
 	case src := <-rdr.removeURLCh:
 		rdr.log.Infof(rdr, "Removing URL %s", src)
 		rdr.mu.Lock()
 		if dmxStopCh, ok := rdr.dmxStoppers[src]; ok {
 			close(dmxStopCh)
 			delete(rdr.dmxStoppers, src)
+			delete(rdr.reconnects, src)
 		}
 		rdr.mu.Unlock()
+		rdr.statsMu.Lock()
+		delete(rdr.stats, src)
+		rdr.statsMu.Unlock()
 	}
 	return
 }
 
+func (rdr *reader) add(spec Source) error {
+	src := spec.URL
+	rdr.log.Infof(rdr, "Adding new URL %s", src)
+
+	parsedURL, err := url.Parse(src)
+	if err != nil {
+		rdr.log.Errorf(rdr, "Failed to parse URL %s: %s", src, err.Error())
+		return err
+	}
+	open, ok := rdr.schemes[strings.ToLower(parsedURL.Scheme)]
+	if spec.Demuxer != nil {
+		open, ok = spec.Demuxer.forSource(), true
+	}
+	if !ok {
+		rdr.log.Errorf(rdr, "No demuxer for URL scheme %q of %s", parsedURL.Scheme, src)
+		return nil
+	}
+	if host := parsedURL.Hostname(); host != "" {
+		rdr.name = "READER " + host
+	} else {
+		rdr.name = "READER " + path.Base(parsedURL.Path)
+	}
+
+	st := newSourceStats(src)
+	rdr.statsMu.Lock()
+	rdr.stats[src] = st
+	rdr.statsMu.Unlock()
+
+	rStopCh := make(chan struct{})
+	reconnectCh := make(chan struct{}, 1)
+	rdr.mu.Lock()
+	rdr.dmxStoppers[src] = rStopCh
+	rdr.reconnects[src] = reconnectCh
+	rdr.mu.Unlock()
+	go rdr.repackPackets(&worker{
+		url:       src,
+		spec:      spec,
+		open:      open,
+		stats:     st,
+		backoff:   newBackoff(rdr.policy),
+		watchdog:  watchdog{noVideo: rdr.noVideo, noKeyframe: rdr.noKeyframe},
+		reconnect: reconnectCh,
+		stop:      rStopCh,
+	})
+	return nil
+}
+
 func (rdr *reader) Read() {
 	startFunc := func(*reader) error {
 		return nil
@@ -270,6 +511,7 @@ func (rdr *reader) Release() { //nolint: revive
 	for src, stopCh := range rdr.dmxStoppers {
 		close(stopCh)
 		delete(rdr.dmxStoppers, src)
+		delete(rdr.reconnects, src)
 	}
 
 	for {
@@ -296,6 +538,27 @@ func (rdr *reader) AddURL() chan<- string {
 	return rdr.addURLCh
 }
 
+func (rdr *reader) AddSource() chan<- Source {
+	return rdr.addSrcCh
+}
+
 func (rdr *reader) RemoveURL() chan<- string {
 	return rdr.removeURLCh
 }
+
+func (rdr *reader) Reconnect(src string) bool {
+	rdr.mu.Lock()
+	defer rdr.mu.Unlock()
+	ch, ok := rdr.reconnects[src]
+	if ok {
+		select {
+		case ch <- struct{}{}:
+		default: // a reconnect is already pending
+		}
+	}
+	return ok
+}
+
+func (rdr *reader) Events() <-chan Event {
+	return rdr.events
+}
@@ -1,7 +1,11 @@
 package reader
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"errors"
+	"os"
+	"path/filepath"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -13,7 +17,9 @@ import (
 	"github.com/ugparu/gomedia/codec"
 	"github.com/ugparu/gomedia/codec/aac"
 	"github.com/ugparu/gomedia/codec/h264"
+	"github.com/ugparu/gomedia/format/mp4"
 	"github.com/ugparu/gomedia/format/rtsp"
+	"github.com/ugparu/gomedia/tests"
 	"github.com/ugparu/gomedia/utils/lifecycle"
 	"github.com/ugparu/gomedia/utils/logger"
 )
@@ -82,10 +88,16 @@ func newTestReader(chanSize int, factory func(string, ...rtsp.DemuxerOption) gom
 		newDmx:      factory,
 		packets:     make(chan gomedia.Packet, chanSize),
 		addURLCh:    make(chan string, chanSize),
+		addSrcCh:    make(chan Source, chanSize),
 		removeURLCh: make(chan string, chanSize),
 		dmxStoppers: make(map[string]chan struct{}),
+		reconnects:  make(map[string]chan struct{}),
 		name:        "TEST_READER",
+		events:      make(chan Event, chanSize),
+		stats:       make(map[string]*sourceStats),
+		policy:      DefaultReconnectPolicy(),
 	}
+	rdr.schemes = rdr.defaultSchemes()
 	rdr.AsyncManager = lifecycle.NewFailSafeAsyncManager(rdr, rdr.log)
 	return rdr
 }
@@ -692,3 +704,515 @@ func TestReader_DemuxError_StillReconnects(t *testing.T) {
 	rdr.Close()
 	<-rdr.Done()
 }
+
+func TestReader_GivesUpAfterMaxAttempts(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	rdr := newTestReader(10, func(string, ...rtsp.DemuxerOption) gomedia.Demuxer {
+		calls.Add(1)
+		fd := newFakeDemuxer()
+		fd.demuxErr = errors.New("connection refused")
+		fd.readFunc = func() (gomedia.Packet, error) {
+			return nil, errors.New("not connected")
+		}
+		return fd
+	})
+	WithReconnectPolicy(ReconnectPolicy{Initial: 10 * time.Millisecond, MaxAttempts: 2})(rdr)
+	rdr.Read()
+	const src = "rtsp://test.local/stream"
+	rdr.AddURL() <- src
+
+	for {
+		ev := nextEvent(t, rdr)
+		if ev.Type == EventGaveUp {
+			require.EqualError(t, ev.Err, "connection refused")
+			break
+		}
+	}
+	require.Equal(t, int32(2), calls.Load())
+	require.Eventually(t, func() bool {
+		rdr.mu.Lock()
+		defer rdr.mu.Unlock()
+		return len(rdr.dmxStoppers) == 0
+	}, time.Second, 10*time.Millisecond)
+	require.False(t, rdr.Reconnect(src))
+	_, ok := rdr.Stats(src)
+	require.True(t, ok)
+
+	rdr.Close()
+	<-rdr.Done()
+}
+
+func TestReader_Reconnect(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	rdr := newTestReader(10, func(string, ...rtsp.DemuxerOption) gomedia.Demuxer {
+		calls.Add(1)
+		fd := newFakeDemuxer()
+		fd.demuxResult = gomedia.CodecParametersPair{VideoCodecParameters: testH264Params}
+		fd.readFunc = func() (gomedia.Packet, error) {
+			time.Sleep(5 * time.Millisecond)
+			return nil, nil
+		}
+		return fd
+	})
+	rdr.Read()
+	const src = "rtsp://test.local/stream"
+	rdr.AddURL() <- src
+	require.Equal(t, EventConnected, nextEvent(t, rdr).Type)
+
+	require.False(t, rdr.Reconnect("rtsp://other.local/stream"))
+	require.True(t, rdr.Reconnect(src))
+
+	ev := nextEvent(t, rdr)
+	require.Equal(t, EventDisconnected, ev.Type)
+	require.ErrorIs(t, ev.Err, ErrReconnectRequested)
+	ev = nextEvent(t, rdr)
+	require.Equal(t, EventReconnecting, ev.Type)
+	require.WithinDuration(t, time.Now(), ev.NextAttempt, 100*time.Millisecond)
+	require.Equal(t, EventConnected, nextEvent(t, rdr).Type)
+	require.Equal(t, int32(2), calls.Load())
+
+	rdr.Close()
+	<-rdr.Done()
+}
+
+func TestReader_StallWatchdog(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	rdr := newTestReader(10, func(string, ...rtsp.DemuxerOption) gomedia.Demuxer {
+		calls.Add(1)
+		fd := newFakeDemuxer()
+		fd.demuxResult = gomedia.CodecParametersPair{
+			VideoCodecParameters: testH264Params,
+			AudioCodecParameters: testAACParams,
+		}
+		// The session stays up but only audio keeps flowing.
+		var ts time.Duration
+		fd.readFunc = func() (gomedia.Packet, error) {
+			time.Sleep(10 * time.Millisecond)
+			ts += 10 * time.Millisecond
+			return makeAudioPacket(ts, 10*time.Millisecond), nil
+		}
+		return fd
+	})
+	WithStallWatchdog(100*time.Millisecond, 0)(rdr)
+	WithReconnectPolicy(ReconnectPolicy{Initial: 10 * time.Millisecond})(rdr)
+	rdr.Read()
+	go func() {
+		for {
+			select {
+			case pkt := <-rdr.Packets():
+				pkt.Release()
+			case <-rdr.Done():
+				return
+			}
+		}
+	}()
+	rdr.AddURL() <- "rtsp://test.local/stream"
+	require.Equal(t, EventConnected, nextEvent(t, rdr).Type)
+
+	start := time.Now()
+	ev := nextEvent(t, rdr)
+	require.Equal(t, EventDisconnected, ev.Type)
+	require.ErrorIs(t, ev.Err, ErrStalled)
+	require.Less(t, time.Since(start), time.Second)
+	require.Equal(t, EventReconnecting, nextEvent(t, rdr).Type)
+	require.Equal(t, EventConnected, nextEvent(t, rdr).Type)
+	require.Equal(t, int32(2), calls.Load())
+
+	rdr.Close()
+	<-rdr.Done()
+}
+
+func TestReader_AddSource(t *testing.T) {
+	t.Parallel()
+
+	type call struct {
+		url   string
+		nOpts int
+	}
+	calls := make(chan call, 2)
+	rdr := newTestReader(10, func(s string, opts ...rtsp.DemuxerOption) gomedia.Demuxer {
+		calls <- call{s, len(opts)}
+		return newFakeDemuxer()
+	})
+	WithRTSPParams(rtsp.WithRingBuffer(1 << 20))(rdr)
+	rdr.Read()
+
+	rdr.AddURL() <- "rtsp://plain.local/stream"
+	require.Equal(t, call{"rtsp://plain.local/stream", 2}, <-calls)
+
+	// Logger, WithRTSPParams, credentials, one header and the own options.
+	rdr.AddSource() <- Source{
+		URL:         "rtsp://cam.local/stream",
+		Username:    "admin",
+		Password:    "secret",
+		Headers:     map[string]string{"User-Agent": "cam-tool"},
+		RTSPOptions: []rtsp.DemuxerOption{rtsp.NoAudio()},
+	}
+	require.Equal(t, call{"rtsp://cam.local/stream", 5}, <-calls)
+
+	_, ok := rdr.Stats("rtsp://cam.local/stream")
+	require.True(t, ok)
+
+	rdr.Close()
+	<-rdr.Done()
+}
+
+func TestReader_AddSourceWithDemuxer(t *testing.T) {
+	t.Parallel()
+
+	rdr := newTestReader(10, nil)
+	rdr.Read()
+	const src = "custom://cam/1"
+	rdr.AddSource() <- Source{URL: src, Demuxer: func(s string, _ logger.Logger) gomedia.Demuxer {
+		fd := newFakeDemuxer()
+		fd.demuxResult = gomedia.CodecParametersPair{VideoCodecParameters: testH264Params}
+		return fd
+	}}
+
+	ev := nextEvent(t, rdr)
+	require.Equal(t, EventConnected, ev.Type)
+	require.Equal(t, src, ev.URL)
+
+	rdr.Close()
+	<-rdr.Done()
+}
+
+func TestReader_UnknownSchemeIgnored(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	rdr := newTestReader(10, func(string, ...rtsp.DemuxerOption) gomedia.Demuxer {
+		calls.Add(1)
+		return newFakeDemuxer()
+	})
+	rdr.Read()
+	rdr.AddURL() <- "rtmp://test.local/live"
+	rdr.AddURL() <- "rtsps://test.local/stream"
+
+	require.Eventually(t, func() bool { return calls.Load() == 1 }, 2*time.Second, 10*time.Millisecond)
+	rdr.mu.Lock()
+	require.Len(t, rdr.dmxStoppers, 1)
+	rdr.mu.Unlock()
+
+	rdr.Close()
+	<-rdr.Done()
+}
+
+func TestReader_WithScheme(t *testing.T) {
+	t.Parallel()
+
+	fd := newFakeDemuxer()
+	var gotURL atomic.Value
+	rdr := New(10, WithScheme("RTMP", func(src string, _ logger.Logger) gomedia.Demuxer {
+		gotURL.Store(src)
+		idx := 0
+		fd.readFunc = func() (gomedia.Packet, error) {
+			if idx < 3 {
+				idx++
+				return makeVideoPacket(time.Duration(idx) * 40 * time.Millisecond), nil
+			}
+			<-fd.closeCh
+			return nil, errors.New("closed")
+		}
+		return fd
+	})).(*reader)
+	rdr.Read()
+	rdr.AddURL() <- "rtmp://test.local/live"
+
+	pkts := receivePackets(rdr.Packets(), 2, 2*time.Second)
+	require.Len(t, pkts, 2)
+	require.Equal(t, "rtmp://test.local/live", gotURL.Load())
+
+	rdr.Close()
+	<-rdr.Done()
+}
+
+func TestReader_FileScheme(t *testing.T) {
+	t.Parallel()
+
+	raw, err := os.ReadFile("../tests/data/h264/parameters.json")
+	require.NoError(t, err)
+	var params tests.ParametersJSON
+	require.NoError(t, json.Unmarshal(raw, &params))
+	record, err := base64.StdEncoding.DecodeString(params.Video.Record)
+	require.NoError(t, err)
+	par, err := h264.NewCodecDataFromAVCDecoderConfRecord(record)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "loop.mp4")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	mux := mp4.NewMuxer(f)
+	require.NoError(t, mux.Mux(gomedia.CodecParametersPair{VideoCodecParameters: &par}))
+	for i := range 10 {
+		pkt := h264.NewPacket(i%5 == 0, time.Duration(i)*40*time.Millisecond, time.Now(),
+			[]byte{0, 0, 0, 2, 0x65, byte(i)}, "", &par)
+		pkt.SetDuration(40 * time.Millisecond)
+		require.NoError(t, mux.WritePacket(pkt))
+	}
+	require.NoError(t, mux.WriteTrailer())
+	require.NoError(t, f.Close())
+
+	src := "file://" + path
+	rdr := New(10).(*reader)
+	rdr.Read()
+	rdr.AddURL() <- src
+
+	// 15 packets cross the end of the 10-packet file: the loop is paced and
+	// seamless, not a disconnect followed by a reconnect.
+	start := time.Now()
+	pkts := receivePackets(rdr.Packets(), 15, 3*time.Second)
+	require.Len(t, pkts, 15)
+	require.GreaterOrEqual(t, time.Since(start), 400*time.Millisecond)
+	for i, pkt := range pkts {
+		require.Equal(t, src, pkt.SourceID())
+		require.Equal(t, time.Duration(i)*40*time.Millisecond, pkt.Timestamp())
+		pkt.Release()
+	}
+	stats, ok := rdr.Stats(src)
+	require.True(t, ok)
+	require.True(t, stats.Connected)
+	require.Zero(t, stats.Reconnects)
+
+	rdr.Close()
+	<-rdr.Done()
+}
+
+func TestFilePath(t *testing.T) {
+	for src, want := range map[string]string{
+		"file:///var/media/a.mp4":          "/var/media/a.mp4",
+		"file://localhost/var/media/a.mp4": "/var/media/a.mp4",
+		"file://media/a.mp4":               "media/a.mp4",
+	} {
+		got, err := filePath(src)
+		require.NoError(t, err)
+		require.Equal(t, want, got, src)
+	}
+}
+
+// nextEvent returns the next event of rdr or fails after a timeout.
+func TestReader_StallWatchdog_BlockedRead(t *testing.T) {
+	t.Parallel()
+
+	// The session stays up but no RTP arrives: ReadPacket blocks until the
+	// demuxer is closed.
+	var (
+		mu   sync.Mutex
+		dmxs []*fakeDemuxer
+	)
+	rdr := newTestReader(10, func(string, ...rtsp.DemuxerOption) gomedia.Demuxer {
+		fd := newFakeDemuxer()
+		fd.demuxResult = gomedia.CodecParametersPair{VideoCodecParameters: testH264Params}
+		mu.Lock()
+		dmxs = append(dmxs, fd)
+		mu.Unlock()
+		return fd
+	})
+	WithStallWatchdog(100*time.Millisecond, 0)(rdr)
+	WithReconnectPolicy(ReconnectPolicy{Initial: 10 * time.Millisecond})(rdr)
+	rdr.Read()
+	rdr.AddURL() <- "rtsp://test.local/stream"
+	require.Equal(t, EventConnected, nextEvent(t, rdr).Type)
+	mu.Lock()
+	first := dmxs[0]
+	mu.Unlock()
+
+	start := time.Now()
+	ev := nextEvent(t, rdr)
+	require.Equal(t, EventDisconnected, ev.Type)
+	require.ErrorIs(t, ev.Err, ErrStalled)
+	require.Less(t, time.Since(start), time.Second)
+	require.True(t, first.closed.Load())
+	require.Equal(t, EventReconnecting, nextEvent(t, rdr).Type)
+	require.Equal(t, EventConnected, nextEvent(t, rdr).Type)
+
+	rdr.Close()
+	<-rdr.Done()
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return dmxs[len(dmxs)-1].closed.Load()
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestReader_Reconnect_BlockedRead(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	rdr := newTestReader(10, func(string, ...rtsp.DemuxerOption) gomedia.Demuxer {
+		calls.Add(1)
+		fd := newFakeDemuxer()
+		fd.demuxResult = gomedia.CodecParametersPair{VideoCodecParameters: testH264Params}
+		return fd
+	})
+	rdr.Read()
+	const src = "rtsp://test.local/stream"
+	rdr.AddURL() <- src
+	require.Equal(t, EventConnected, nextEvent(t, rdr).Type)
+
+	require.True(t, rdr.Reconnect(src))
+	ev := nextEvent(t, rdr)
+	require.Equal(t, EventDisconnected, ev.Type)
+	require.ErrorIs(t, ev.Err, ErrReconnectRequested)
+	require.Equal(t, EventReconnecting, nextEvent(t, rdr).Type)
+	require.Equal(t, EventConnected, nextEvent(t, rdr).Type)
+	require.Equal(t, int32(2), calls.Load())
+
+	rdr.Close()
+	<-rdr.Done()
+}
+
+func nextEvent(t *testing.T, rdr *reader) Event {
+	t.Helper()
+	select {
+	case ev := <-rdr.Events():
+		return ev
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out waiting for event")
+		return Event{}
+	}
+}
+
+func TestReader_Events(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	rdr := newTestReader(10, func(string, ...rtsp.DemuxerOption) gomedia.Demuxer {
+		fd := newFakeDemuxer()
+		fd.demuxResult = gomedia.CodecParametersPair{VideoCodecParameters: testH264Params}
+		if calls.Add(1) == 1 {
+			sent := false
+			fd.readFunc = func() (gomedia.Packet, error) {
+				if !sent {
+					sent = true
+					return makeVideoPacket(0), nil
+				}
+				return nil, errors.New("connection reset")
+			}
+		}
+		return fd
+	})
+	rdr.Read()
+	const src = "rtsp://test.local/stream"
+	rdr.AddURL() <- src
+
+	ev := nextEvent(t, rdr)
+	require.Equal(t, EventConnected, ev.Type)
+	require.Equal(t, src, ev.URL)
+	require.Equal(t, testH264Params, ev.Params.VideoCodecParameters)
+
+	ev = nextEvent(t, rdr)
+	require.Equal(t, EventDisconnected, ev.Type)
+	require.EqualError(t, ev.Err, "connection reset")
+
+	ev = nextEvent(t, rdr)
+	require.Equal(t, EventReconnecting, ev.Type)
+	require.WithinDuration(t, time.Now().Add(time.Second), ev.NextAttempt, 200*time.Millisecond)
+
+	ev = nextEvent(t, rdr)
+	require.Equal(t, EventConnected, ev.Type)
+
+	stats, ok := rdr.Stats(src)
+	require.True(t, ok)
+	require.True(t, stats.Connected)
+	require.Equal(t, 1, stats.Reconnects)
+	require.Equal(t, uint64(1), stats.Packets)
+	require.Equal(t, uint64(2), stats.Bytes)
+
+	rdr.Close()
+	<-rdr.Done()
+}
+
+func TestReader_CodecParametersChangedEvent(t *testing.T) {
+	t.Parallel()
+
+	newPar := &h264.CodecParameters{BaseParameters: codec.BaseParameters{CodecType: gomedia.H264}}
+	fd := newFakeDemuxer()
+	fd.demuxResult = gomedia.CodecParametersPair{VideoCodecParameters: testH264Params}
+	idx := 0
+	fd.readFunc = func() (gomedia.Packet, error) {
+		idx++
+		switch idx {
+		case 1:
+			return makeVideoPacket(0), nil
+		case 2:
+			return h264.NewPacket(true, 40*time.Millisecond, time.Now(), []byte{0x00, 0x01}, "test", newPar), nil
+		}
+		<-fd.closeCh
+		return nil, errors.New("closed")
+	}
+	rdr := newTestReader(10, func(string, ...rtsp.DemuxerOption) gomedia.Demuxer { return fd })
+	rdr.Read()
+	rdr.AddURL() <- "rtsp://test.local/stream"
+
+	require.Equal(t, EventConnected, nextEvent(t, rdr).Type)
+	ev := nextEvent(t, rdr)
+	require.Equal(t, EventCodecParametersChanged, ev.Type)
+	require.Equal(t, newPar, ev.Params.VideoCodecParameters)
+
+	stats, ok := rdr.Stats("rtsp://test.local/stream")
+	require.True(t, ok)
+	require.Equal(t, 40*time.Millisecond, stats.KeyframeInterval)
+
+	rdr.Close()
+	<-rdr.Done()
+}
+
+func TestSourceStats_Record(t *testing.T) {
+	st := newSourceStats("src")
+	st.setConnected(gomedia.CodecParametersPair{VideoCodecParameters: testH264Params, AudioCodecParameters: testAACParams})
+
+	for i := range 5 {
+		_, changed := st.record(makeVideoPacket(time.Duration(i) * 40 * time.Millisecond))
+		require.False(t, changed)
+	}
+	st.record(makeVideoPacket(10 * time.Second)) // jump
+	st.record(makeAudioPacket(0, 20*time.Millisecond))
+	st.record(makeAudioPacket(20*time.Millisecond, 20*time.Millisecond))
+
+	st.windowStart = time.Now().Add(-2 * time.Second)
+	st.record(makeAudioPacket(40*time.Millisecond, 20*time.Millisecond))
+
+	s := st.snapshot()
+	require.Equal(t, uint64(9), s.Packets)
+	require.Equal(t, uint64(18), s.Bytes)
+	require.Equal(t, 1, s.TimestampGaps)
+	require.Equal(t, 10*time.Second-160*time.Millisecond, s.KeyframeInterval)
+	require.InDelta(t, 3, s.FPS, 0.1)
+	require.InDelta(t, 9*2*8/2.0, s.Bitrate, 1)
+	require.False(t, s.LastPacket.IsZero())
+}
+
+func TestSourceStats_DisconnectedOnce(t *testing.T) {
+	st := newSourceStats("src")
+	require.True(t, st.setDisconnected())
+	require.False(t, st.setDisconnected())
+	st.setConnected(gomedia.CodecParametersPair{})
+	require.True(t, st.setDisconnected())
+}
+
+func TestSourceStats_DisconnectZeroesRates(t *testing.T) {
+	st := newSourceStats("src")
+	st.setConnected(gomedia.CodecParametersPair{VideoCodecParameters: testH264Params})
+	st.record(makeVideoPacket(0))
+	st.windowStart = time.Now().Add(-2 * time.Second)
+	st.record(makeVideoPacket(40 * time.Millisecond))
+	require.NotZero(t, st.snapshot().FPS)
+
+	st.setDisconnected()
+	s := st.snapshot()
+	require.Zero(t, s.Bitrate)
+	require.Zero(t, s.FPS)
+
+	// The window restarts on reconnect rather than spanning the outage.
+	st.setConnected(gomedia.CodecParametersPair{VideoCodecParameters: testH264Params})
+	require.WithinDuration(t, time.Now(), st.windowStart, time.Second)
+	require.Zero(t, st.windowBytes)
+	require.Zero(t, st.windowFrames)
+}
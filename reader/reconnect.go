@@ -0,0 +1,174 @@
+package reader
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/ugparu/gomedia"
+)
+
+var (
+	// ErrStalled is the cause reported when the stall watchdog forces a
+	// reconnect.
+	ErrStalled = errors.New("source stalled")
+	// ErrReconnectRequested is the cause reported for a reconnect forced
+	// through Reconnect.
+	ErrReconnectRequested = errors.New("reconnect requested")
+)
+
+// ReconnectPolicy controls how a failed source is reopened. The wait before
+// an attempt starts at Initial and is multiplied by Multiplier after every
+// failed attempt, up to Max; a successful connection resets it. Jitter
+// randomises every wait by up to ±Jitter of it (0.2 is ±20%) so that many
+// cameras lost together do not reconnect in lockstep. After MaxAttempts
+// consecutive failed attempts, the first connection included, the source is
+// given up and EventGaveUp is sent; 0 retries forever.
+type ReconnectPolicy struct {
+	Initial     time.Duration
+	Max         time.Duration
+	Multiplier  float64
+	Jitter      float64
+	MaxAttempts int
+}
+
+// DefaultReconnectPolicy returns the policy used without
+// WithReconnectPolicy: 1s doubling up to 8s, no jitter, no give-up.
+func DefaultReconnectPolicy() ReconnectPolicy {
+	return ReconnectPolicy{
+		Initial:    time.Second,
+		Max:        maxReconnectInterval,
+		Multiplier: 2, //nolint:mnd
+	}
+}
+
+// WithReconnectPolicy sets the reconnect policy of every source. Zero
+// Initial, Max and Multiplier take their DefaultReconnectPolicy values.
+func WithReconnectPolicy(p ReconnectPolicy) Option {
+	return func(r *reader) {
+		def := DefaultReconnectPolicy()
+		if p.Initial <= 0 {
+			p.Initial = def.Initial
+		}
+		if p.Max <= 0 {
+			p.Max = max(def.Max, p.Initial)
+		}
+		if p.Multiplier < 1 {
+			p.Multiplier = def.Multiplier
+		}
+		p.Jitter = min(max(p.Jitter, 0), 1)
+		r.policy = p
+	}
+}
+
+// WithStallWatchdog forces a reconnect of a source whose connection carries
+// video but that delivered no video packet for noVideo, or no video key
+// frame for noKeyframe. Zero disables either check. The check runs on a timer
+// of its own and closes the demuxer, so a source whose session stays up but
+// carries no RTP at all is caught as well.
+func WithStallWatchdog(noVideo, noKeyframe time.Duration) Option {
+	return func(r *reader) {
+		r.noVideo = noVideo
+		r.noKeyframe = noKeyframe
+	}
+}
+
+// backoff tracks the reconnect attempts of one source.
+type backoff struct {
+	policy   ReconnectPolicy
+	interval time.Duration // next wait, before jitter
+	failures int           // consecutive failed attempts
+}
+
+func newBackoff(p ReconnectPolicy) backoff {
+	return backoff{policy: p, interval: p.Initial}
+}
+
+// wait returns the delay before the next attempt.
+func (b *backoff) wait() time.Duration {
+	d := b.interval
+	if j := b.policy.Jitter; j > 0 {
+		d = time.Duration(float64(d) * (1 + j*(2*rand.Float64()-1))) //nolint:gosec,mnd // jitter needs no crypto
+	}
+	return d
+}
+
+// failed records a failed attempt and reports whether another one is
+// allowed.
+func (b *backoff) failed() bool {
+	b.failures++
+	b.interval = min(b.policy.Max, time.Duration(float64(b.interval)*b.policy.Multiplier))
+	return b.policy.MaxAttempts <= 0 || b.failures < b.policy.MaxAttempts
+}
+
+func (b *backoff) reset() {
+	b.interval = b.policy.Initial
+	b.failures = 0
+}
+
+// capped reports whether the wait stopped growing; further failures are
+// not logged.
+func (b *backoff) capped() bool {
+	return b.interval >= b.policy.Max
+}
+
+// watchdog detects a connection that stays up but stopped delivering video.
+// The worker feeds it while the guard of the connection checks it.
+type watchdog struct {
+	noVideo    time.Duration
+	noKeyframe time.Duration
+
+	mu        sync.Mutex
+	video     bool // the connection carries video
+	lastVideo time.Time
+	lastKey   time.Time
+}
+
+// reset starts watching a new connection.
+func (w *watchdog) reset(par gomedia.CodecParametersPair, now time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.video = par.VideoCodecParameters != nil
+	w.lastVideo, w.lastKey = now, now
+}
+
+func (w *watchdog) seen(pkt gomedia.Packet, now time.Time) {
+	vPkt, ok := pkt.(gomedia.VideoPacket)
+	if !ok {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.lastVideo = now
+	if vPkt.IsKeyFrame() {
+		w.lastKey = now
+	}
+}
+
+// stalled returns ErrStalled with the reason when a limit was exceeded.
+func (w *watchdog) stalled(now time.Time) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.video {
+		return nil
+	}
+	if d := now.Sub(w.lastVideo); w.noVideo > 0 && d >= w.noVideo {
+		return fmt.Errorf("%w: no video for %v", ErrStalled, d.Round(time.Millisecond))
+	}
+	if d := now.Sub(w.lastKey); w.noKeyframe > 0 && d >= w.noKeyframe {
+		return fmt.Errorf("%w: no key frame for %v", ErrStalled, d.Round(time.Millisecond))
+	}
+	return nil
+}
+
+// period returns how often stalled is checked, a quarter of the shortest
+// limit, or 0 when the watchdog is disabled.
+func (w *watchdog) period() time.Duration {
+	limit := w.noVideo
+	if w.noKeyframe > 0 && (limit <= 0 || w.noKeyframe < limit) {
+		limit = w.noKeyframe
+	}
+	return max(limit, 0) / 4 //nolint:mnd
+}
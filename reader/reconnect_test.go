@@ -0,0 +1,82 @@
+package reader
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/ugparu/gomedia"
+	"github.com/ugparu/gomedia/codec/h264"
+)
+
+func TestBackoff_Policy(t *testing.T) {
+	t.Parallel()
+
+	b := newBackoff(ReconnectPolicy{
+		Initial:     100 * time.Millisecond,
+		Max:         350 * time.Millisecond,
+		Multiplier:  2,
+		MaxAttempts: 3,
+	})
+	require.Equal(t, 100*time.Millisecond, b.wait())
+	require.True(t, b.failed())
+	require.Equal(t, 200*time.Millisecond, b.wait())
+	require.True(t, b.failed())
+	require.Equal(t, 350*time.Millisecond, b.wait())
+	require.True(t, b.capped())
+	require.False(t, b.failed())
+
+	b.reset()
+	require.Equal(t, 100*time.Millisecond, b.wait())
+	require.True(t, b.failed())
+}
+
+func TestBackoff_Jitter(t *testing.T) {
+	t.Parallel()
+
+	b := newBackoff(ReconnectPolicy{Initial: time.Second, Max: time.Second, Multiplier: 2, Jitter: 0.2})
+	for range 100 {
+		require.InDelta(t, float64(time.Second), float64(b.wait()), float64(200*time.Millisecond))
+	}
+}
+
+func TestWithReconnectPolicy_Defaults(t *testing.T) {
+	t.Parallel()
+
+	rdr := &reader{}
+	WithReconnectPolicy(ReconnectPolicy{Jitter: 3, MaxAttempts: 5})(rdr)
+	want := DefaultReconnectPolicy()
+	want.Jitter = 1
+	want.MaxAttempts = 5
+	require.Equal(t, want, rdr.policy)
+}
+
+func TestWatchdog_Stalled(t *testing.T) {
+	t.Parallel()
+
+	start := time.Now()
+	w := watchdog{noVideo: time.Second, noKeyframe: 2500 * time.Millisecond}
+	w.reset(gomedia.CodecParametersPair{VideoCodecParameters: testH264Params}, start)
+	require.NoError(t, w.stalled(start.Add(500*time.Millisecond)))
+
+	// Audio does not feed the video checks.
+	w.seen(makeAudioPacket(0, 20*time.Millisecond), start.Add(900*time.Millisecond))
+	require.ErrorIs(t, w.stalled(start.Add(time.Second)), ErrStalled)
+
+	delta := h264.NewPacket(false, 0, start, []byte{0x00, 0x01}, "test", testH264Params)
+	for i := 1; i <= 3; i++ {
+		now := start.Add(time.Duration(i) * 900 * time.Millisecond)
+		w.seen(delta, now)
+		err := w.stalled(now)
+		if i < 3 {
+			require.NoError(t, err)
+		} else {
+			require.ErrorIs(t, err, ErrStalled)
+			require.Contains(t, err.Error(), "key frame")
+		}
+	}
+
+	// Sources without video are never stalled.
+	w.reset(gomedia.CodecParametersPair{AudioCodecParameters: testAACParams}, start)
+	require.NoError(t, w.stalled(start.Add(time.Hour)))
+}
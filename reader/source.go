@@ -0,0 +1,54 @@
+package reader
+
+import (
+	"github.com/ugparu/gomedia"
+	"github.com/ugparu/gomedia/format/rtsp"
+	"github.com/ugparu/gomedia/utils/logger"
+)
+
+// Source is a URL to read with settings of its own, sent to AddSource. A
+// plain URL sent to AddURL is a Source with only URL set.
+type Source struct {
+	URL string
+	// Username and Password, when Username is set, replace the user info
+	// of an rtsp:// or rtsps:// URL, so that the URL identifying the source
+	// in RemoveURL, Stats and events carries no secret.
+	Username string
+	Password string
+	// Headers are sent with every RTSP request; "User-Agent" replaces the
+	// default one.
+	Headers map[string]string
+	// Transport selects how an RTSP camera delivers RTP, interleaved on
+	// the control connection by default.
+	Transport rtsp.Transport
+	// RTSPOptions apply after those of WithRTSPParams, e.g. rtsp.NoAudio()
+	// or rtsp.WithRingBuffer for this camera only.
+	RTSPOptions []rtsp.DemuxerOption
+	// Demuxer, when set, opens the source instead of the factory
+	// registered for its scheme.
+	Demuxer DemuxerFactory
+}
+
+// sourceFactory opens a demuxer for a source and its settings.
+type sourceFactory func(spec Source, log logger.Logger) gomedia.Demuxer
+
+// forSource adapts f, which only takes the URL of a source.
+func (f DemuxerFactory) forSource() sourceFactory {
+	return func(spec Source, log logger.Logger) gomedia.Demuxer {
+		return f(spec.URL, log)
+	}
+}
+
+func (spec Source) rtspOptions() []rtsp.DemuxerOption {
+	opts := make([]rtsp.DemuxerOption, 0, len(spec.Headers)+len(spec.RTSPOptions)+2)
+	if spec.Username != "" {
+		opts = append(opts, rtsp.WithCredentials(spec.Username, spec.Password))
+	}
+	if spec.Transport != rtsp.TransportTCP {
+		opts = append(opts, rtsp.WithTransport(spec.Transport))
+	}
+	for k, v := range spec.Headers {
+		opts = append(opts, rtsp.WithHeader(k, v))
+	}
+	return append(opts, spec.RTSPOptions...)
+}
@@ -0,0 +1,166 @@
+package reader
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ugparu/gomedia"
+)
+
+const (
+	// rateWindow is the period over which Bitrate and FPS are measured.
+	rateWindow = time.Second
+	// maxTSJump is the largest difference between a packet timestamp and
+	// the end of the previous packet that is not counted as a gap.
+	maxTSJump = time.Second
+)
+
+// Stats is a snapshot of the counters of one source. Packets are counted
+// as the demuxer delivers them, across reconnects.
+type Stats struct {
+	URL        string
+	Connected  bool
+	Reconnects int
+	Packets    uint64
+	Bytes      uint64
+	// Bitrate in bit/s and FPS in video packets per second, measured over
+	// the last complete second.
+	Bitrate float64
+	FPS     float64
+	// KeyframeInterval is the time between the last two video key frames.
+	KeyframeInterval time.Duration
+	// TimestampGaps counts timestamp jumps of more than a second, forward
+	// or backward, within video or audio, including wraps and resets.
+	TimestampGaps int
+	LastPacket    time.Time
+}
+
+// sourceStats holds the live counters of a source. It is written by the
+// source goroutine and read by Stats.
+type sourceStats struct {
+	mu    sync.Mutex
+	stats Stats
+	// known is false until the first connection attempt finished.
+	known bool
+
+	params gomedia.CodecParametersPair
+
+	windowStart  time.Time
+	windowBytes  uint64
+	windowFrames int
+
+	lastKey  time.Duration
+	hasKey   bool
+	videoEnd time.Duration
+	audioEnd time.Duration
+	hasVideo bool
+	hasAudio bool
+}
+
+func newSourceStats(src string) *sourceStats {
+	return &sourceStats{stats: Stats{URL: src}}
+}
+
+func (st *sourceStats) snapshot() Stats {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.stats
+}
+
+func (st *sourceStats) setConnected(par gomedia.CodecParametersPair) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.known = true
+	st.stats.Connected = true
+	st.params = par
+	// The new session starts its own timeline and rate window.
+	st.hasVideo, st.hasAudio, st.hasKey = false, false, false
+	st.windowStart, st.windowBytes, st.windowFrames = time.Now(), 0, 0
+}
+
+// setDisconnected marks the source offline and reports whether it was not
+// already known to be. Rates are zeroed: they are only recomputed when
+// packets arrive, so an offline source would otherwise keep its last ones.
+func (st *sourceStats) setDisconnected() bool {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	changed := !st.known || st.stats.Connected
+	st.known = true
+	st.stats.Connected = false
+	st.stats.Bitrate, st.stats.FPS = 0, 0
+	return changed
+}
+
+func (st *sourceStats) reconnecting() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.stats.Reconnects++
+}
+
+// record accounts a packet as delivered by the demuxer and reports whether
+// it carries codec parameters different from the known ones, returning the
+// updated parameters.
+func (st *sourceStats) record(pkt gomedia.Packet) (gomedia.CodecParametersPair, bool) {
+	now := time.Now()
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.stats.Packets++
+	st.stats.Bytes += uint64(pkt.Len()) //nolint:gosec
+	st.stats.LastPacket = now
+	st.windowBytes += uint64(pkt.Len()) //nolint:gosec
+
+	var changed bool
+	switch p := pkt.(type) {
+	case gomedia.VideoPacket:
+		st.windowFrames++
+		if p.IsKeyFrame() {
+			if st.hasKey && p.Timestamp() > st.lastKey {
+				st.stats.KeyframeInterval = p.Timestamp() - st.lastKey
+			}
+			st.lastKey, st.hasKey = p.Timestamp(), true
+		}
+		st.checkGap(&st.videoEnd, &st.hasVideo, p)
+		if par := p.CodecParameters(); par != st.params.VideoCodecParameters {
+			st.params.VideoCodecParameters = par
+			changed = true
+		}
+	case gomedia.AudioPacket:
+		st.checkGap(&st.audioEnd, &st.hasAudio, p)
+		if par := p.CodecParameters(); par != st.params.AudioCodecParameters {
+			st.params.AudioCodecParameters = par
+			changed = true
+		}
+	}
+
+	switch elapsed := now.Sub(st.windowStart); {
+	case st.windowStart.IsZero():
+		st.windowStart = now
+	case elapsed >= rateWindow:
+		st.stats.Bitrate = float64(st.windowBytes*8) / elapsed.Seconds() //nolint:mnd
+		st.stats.FPS = float64(st.windowFrames) / elapsed.Seconds()
+		st.windowStart, st.windowBytes, st.windowFrames = now, 0, 0
+	}
+	return st.params, changed
+}
+
+func (st *sourceStats) checkGap(end *time.Duration, has *bool, pkt gomedia.Packet) {
+	if *has {
+		if d := pkt.Timestamp() - *end; d > maxTSJump || d < -maxTSJump {
+			st.stats.TimestampGaps++
+		}
+	}
+	*end, *has = pkt.Timestamp()+pkt.Duration(), true
+}
+
+// Stats returns the counters of a source added through AddURL and whether
+// it is known.
+func (rdr *reader) Stats(src string) (Stats, bool) {
+	rdr.statsMu.Lock()
+	st, ok := rdr.stats[src]
+	rdr.statsMu.Unlock()
+	if !ok {
+		return Stats{}, false
+	}
+	return st.snapshot(), true
+}
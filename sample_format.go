@@ -69,7 +69,7 @@ func (sf SampleFormat) String() string {
 // (as opposed to samples interleaved across channels in one buffer).
 func (sf SampleFormat) IsPlanar() bool {
 	switch sf { //nolint: exhaustive // other formats are not planar
-	case S16P, S32P, FLTP, DBLP:
+	case U8P, S16P, S32P, FLTP, DBLP:
 		return true
 	default:
 		return false
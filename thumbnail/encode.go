@@ -0,0 +1,75 @@
+package thumbnail
+
+import (
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"strings"
+
+	"github.com/ugparu/gomedia"
+	"github.com/ugparu/gomedia/frame/rgb"
+	"github.com/ugparu/gomedia/frame/yuv"
+)
+
+// Encode scales img to the size in req and writes it in req.Format.
+func Encode(w io.Writer, img image.Image, req Request) error {
+	img = resize(img, req.Width, req.Height)
+	switch req.Format {
+	case PNG:
+		enc := png.Encoder{CompressionLevel: png.BestSpeed}
+		return enc.Encode(w, img)
+	case WebP:
+		return encodeWebP(w, img)
+	default:
+		if f, ok := img.(*yuv.I420); ok {
+			// image/jpeg encodes *image.YCbCr without colour conversion.
+			img = &f.YCbCr
+		}
+		var opts *jpeg.Options
+		if req.Quality > 0 {
+			opts = &jpeg.Options{Quality: req.Quality}
+		}
+		return jpeg.Encode(w, img, opts)
+	}
+}
+
+// resize returns img scaled with rgb.Scale, or img itself when no size is
+// requested or it already has it.
+func resize(img image.Image, w, h int) image.Image {
+	b := img.Bounds()
+	_, size := gomedia.VideoOutput{Width: w, Height: h}.Resolve(b)
+	if size == b.Size() {
+		return img
+	}
+
+	var src *rgb.RGB
+	switch i := img.(type) {
+	case rgb.ReleasableImage:
+		src = i.GetRGB()
+	default:
+		src = rgb.NewRGB(image.Rectangle{Max: b.Size()})
+		for y := range b.Dy() {
+			for x := range b.Dx() {
+				src.Set(x, y, img.At(b.Min.X+x, b.Min.Y+y))
+			}
+		}
+	}
+	dst := rgb.NewRGB(image.Rectangle{Max: size})
+	rgb.Scale(dst, src)
+	return dst
+}
+
+// FileName is the default Periodic.FileName: the source ID with every
+// character outside [A-Za-z0-9._-] replaced by '_', so that
+// "rtsp://cam1/main" becomes "rtsp___cam1_main".
+func FileName(sourceID string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, sourceID)
+}
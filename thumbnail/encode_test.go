@@ -0,0 +1,134 @@
+package thumbnail
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/image/webp"
+
+	"github.com/ugparu/gomedia/frame/rgb"
+	"github.com/ugparu/gomedia/frame/yuv"
+)
+
+func gradient(w, h int) *rgb.RGB {
+	img := rgb.NewRGB(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			img.Set(x, y, rgb.Color{R: uint8(x * 255 / w), G: uint8(y * 255 / h), B: uint8((x + y) % 256)}) //nolint:gosec
+		}
+	}
+	return img
+}
+
+func requireSamePixels(t *testing.T, want, got image.Image) {
+	t.Helper()
+	require.Equal(t, want.Bounds().Size(), got.Bounds().Size())
+	wb, gb := want.Bounds(), got.Bounds()
+	for y := range wb.Dy() {
+		for x := range wb.Dx() {
+			wr, wg, wbl, wa := want.At(wb.Min.X+x, wb.Min.Y+y).RGBA()
+			gr, gg, gbl, ga := got.At(gb.Min.X+x, gb.Min.Y+y).RGBA()
+			require.Equal(t, [4]uint32{wr, wg, wbl, wa}, [4]uint32{gr, gg, gbl, ga}, "pixel %d,%d", x, y)
+		}
+	}
+}
+
+func TestEncodeWebP_Lossless(t *testing.T) {
+	tests := map[string]image.Image{
+		"gradient": gradient(37, 21),
+		"solid":    rgb.NewRGB(image.Rect(0, 0, 8, 8)),
+		"1x1":      gradient(1, 1),
+	}
+	noise := rgb.NewRGB(image.Rect(0, 0, 64, 48))
+	r := rand.New(rand.NewPCG(1, 2)) //nolint:gosec
+	for i := range noise.Pix {
+		noise.Pix[i] = uint8(r.UintN(256)) //nolint:gosec
+	}
+	tests["noise"] = noise
+	alpha := image.NewNRGBA(image.Rect(0, 0, 5, 3))
+	for i := range alpha.Pix {
+		alpha.Pix[i] = uint8(i * 17) //nolint:gosec
+	}
+	tests["alpha"] = alpha
+
+	for name, img := range tests {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, Encode(&buf, img, Request{Format: WebP}))
+			require.Equal(t, "RIFF", buf.String()[:4])
+			require.Zero(t, buf.Len()%2)
+
+			got, err := webp.Decode(&buf)
+			require.NoError(t, err)
+			requireSamePixels(t, img, got)
+		})
+	}
+}
+
+func TestCodeLengths_Limited(t *testing.T) {
+	// Fibonacci weights give a maximally deep Huffman tree.
+	hist := make([]uint32, 30)
+	a, b := uint32(1), uint32(1)
+	for i := range hist {
+		hist[i] = a
+		a, b = b, a+b
+	}
+	lengths := codeLengths(hist, 7)
+	var kraft float64
+	for _, l := range lengths {
+		require.LessOrEqual(t, l, uint8(7))
+		require.NotZero(t, l)
+		kraft += 1 / float64(uint(1)<<l)
+	}
+	require.InDelta(t, 1.0, kraft, 1e-9)
+}
+
+func TestEncode_ResizesJPEGAndPNG(t *testing.T) {
+	src := gradient(64, 32)
+
+	var buf bytes.Buffer
+	require.NoError(t, Encode(&buf, src, Request{Format: JPEG, Width: 32, Quality: 90}))
+	img, err := jpeg.Decode(&buf)
+	require.NoError(t, err)
+	require.Equal(t, image.Pt(32, 16), img.Bounds().Size())
+
+	buf.Reset()
+	require.NoError(t, Encode(&buf, src, Request{Format: PNG, Height: 8}))
+	img, err = png.Decode(&buf)
+	require.NoError(t, err)
+	require.Equal(t, image.Pt(16, 8), img.Bounds().Size())
+}
+
+func TestEncode_I420JPEG(t *testing.T) {
+	f := yuv.NewI420(image.Rect(0, 0, 16, 16))
+	for i := range f.Y {
+		f.Y[i] = 100
+	}
+	for i := range f.Cb {
+		f.Cb[i], f.Cr[i] = 128, 128
+	}
+	var buf bytes.Buffer
+	require.NoError(t, Encode(&buf, f, Request{}))
+	img, err := jpeg.Decode(&buf)
+	require.NoError(t, err)
+	c, ok := img.At(4, 4).(color.YCbCr)
+	require.True(t, ok)
+	require.InDelta(t, 100, int(c.Y), 2)
+}
+
+func TestFileName(t *testing.T) {
+	require.Equal(t, "rtsp___cam1_main", FileName("rtsp://cam1/main"))
+	require.Equal(t, "cam-1.hd", FileName("cam-1.hd"))
+}
+
+func TestFormat(t *testing.T) {
+	require.Equal(t, ".webp", WebP.Extension())
+	require.Equal(t, "image/png", PNG.ContentType())
+	require.Equal(t, "JPEG", JPEG.String())
+}
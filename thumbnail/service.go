@@ -0,0 +1,334 @@
+package thumbnail
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ugparu/gomedia"
+	"github.com/ugparu/gomedia/decoder"
+	"github.com/ugparu/gomedia/frame/rgb"
+	"github.com/ugparu/gomedia/utils"
+	"github.com/ugparu/gomedia/utils/lifecycle"
+	"github.com/ugparu/gomedia/utils/logger"
+)
+
+// service keeps a heap copy of the last key frame of every added source, so
+// no ring-buffer slot stays pinned. The frame is decoded by a short-lived
+// inner decoder on the first request and the RGB result is reused until
+// the next key frame arrives.
+type service struct {
+	lifecycle.AsyncManager[*service]
+	config
+	decoders map[gomedia.CodecType]func() decoder.InnerVideoDecoder
+
+	inpPktCh chan gomedia.Packet
+	addSrcCh chan string
+	rmSrcCh  chan string
+
+	mu      sync.Mutex
+	sources map[string]*source
+
+	periodicStop chan struct{}
+	wg           sync.WaitGroup
+}
+
+type source struct {
+	key        gomedia.VideoPacket
+	gen        uint64   // incremented on every new key frame
+	decoded    *rgb.RGB // key decoded; nil until requested
+	decodedGen uint64
+	writtenGen uint64 // gen of the last periodic file
+}
+
+// New returns a thumbnail service for the sources added through AddSource.
+// decoders selects the inner decoders used for snapshots, e.g. the
+// decoder/video/cpu decoders or decoder/video/mjpeg for cgo-free builds.
+func New(chanSize int, decoders map[gomedia.CodecType]func() decoder.InnerVideoDecoder, opts ...Option) Service {
+	s := &service{
+		config:   config{log: logger.Default},
+		decoders: decoders,
+		inpPktCh: make(chan gomedia.Packet, chanSize),
+		addSrcCh: make(chan string, chanSize),
+		rmSrcCh:  make(chan string, chanSize),
+		sources:  map[string]*source{},
+	}
+	for _, o := range opts {
+		o(&s.config)
+	}
+	s.AsyncManager = lifecycle.NewFailSafeAsyncManager(s, s.log)
+	return s
+}
+
+func (s *service) Write() {
+	// FailSafeAsyncManager.Start never returns an error.
+	_ = s.Start(func(s *service) error {
+		if p := s.periodic; p != nil && p.Interval > 0 {
+			s.periodicStop = make(chan struct{})
+			s.wg.Add(1)
+			go s.runPeriodic(*p)
+		}
+		return nil
+	})
+}
+
+func (s *service) Step(stopCh <-chan struct{}) error {
+	select {
+	case <-stopCh:
+		return &lifecycle.BreakError{}
+	case url := <-s.addSrcCh:
+		s.addSource(url)
+	case url := <-s.rmSrcCh:
+		s.mu.Lock()
+		if src, ok := s.sources[url]; ok {
+			s.log.Infof(s, "Removing source %s", url)
+			if src.key != nil {
+				src.key.Release()
+			}
+			delete(s.sources, url)
+		}
+		s.mu.Unlock()
+	case pkt := <-s.inpPktCh:
+		if pkt == nil {
+			return &utils.NilPacketError{}
+		}
+		defer pkt.Release()
+		if vPkt, ok := pkt.(gomedia.VideoPacket); ok && vPkt.IsKeyFrame() {
+			s.addPending()
+			s.storeKey(vPkt)
+		}
+	}
+	return nil
+}
+
+func (s *service) addSource(url string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.sources[url]; !ok {
+		s.log.Infof(s, "Adding source %s", url)
+		s.sources[url] = &source{}
+	}
+}
+
+// addPending adds the sources still queued in addSrcCh, so that a key frame
+// sent right after AddSource is not dropped as coming from an unknown
+// source when select picks the packet first.
+func (s *service) addPending() {
+	for {
+		select {
+		case url := <-s.addSrcCh:
+			s.addSource(url)
+		default:
+			return
+		}
+	}
+}
+
+func (s *service) storeKey(pkt gomedia.VideoPacket) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	src, ok := s.sources[pkt.SourceID()]
+	if !ok {
+		return
+	}
+	if src.key != nil {
+		src.key.Release()
+	}
+	src.key, _ = pkt.Clone(true).(gomedia.VideoPacket)
+	src.gen++
+	src.decoded = nil
+}
+
+func (s *service) Snapshot(sourceID string, req Request) ([]byte, error) {
+	img, _, err := s.frame(sourceID)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err = Encode(&buf, img, req); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// frame returns the decoded last key frame of sourceID and its generation.
+// The image is shared between callers and must not be modified.
+func (s *service) frame(sourceID string) (*rgb.RGB, uint64, error) {
+	s.mu.Lock()
+	src, ok := s.sources[sourceID]
+	if !ok || src.key == nil {
+		s.mu.Unlock()
+		return nil, 0, fmt.Errorf("%w: %s", ErrNoKeyFrame, sourceID)
+	}
+	if src.decoded != nil {
+		img, gen := src.decoded, src.decodedGen
+		s.mu.Unlock()
+		return img, gen, nil
+	}
+	key, _ := src.key.Clone(false).(gomedia.VideoPacket)
+	gen := src.gen
+	s.mu.Unlock()
+
+	img, err := s.decode(key)
+	key.Release()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	s.mu.Lock()
+	if src.gen == gen {
+		src.decoded, src.decodedGen = img, gen
+	}
+	s.mu.Unlock()
+	return img, gen, nil
+}
+
+func (s *service) decode(pkt gomedia.VideoPacket) (*rgb.RGB, error) {
+	par := pkt.CodecParameters()
+	newDecoder, ok := s.decoders[par.Type()]
+	if !ok {
+		return nil, fmt.Errorf("unsupported video codec %v", par.Type())
+	}
+	dec := newDecoder()
+	defer dec.Close()
+	if err := dec.Init(par); err != nil {
+		return nil, err
+	}
+	img, err := dec.Decode(pkt)
+	if errors.Is(err, decoder.ErrNeedMoreData) {
+		// Decoders with output delay hand the picture out once they get
+		// another packet; repeating the key frame is enough.
+		img, err = dec.Decode(pkt)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer img.Release()
+	return img.GetRGB().Clone(), nil
+}
+
+func (s *service) runPeriodic(p Periodic) {
+	defer s.wg.Done()
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.periodicStop:
+			return
+		case <-ticker.C:
+			s.writeSnapshots(p)
+		}
+	}
+}
+
+// writeSnapshots refreshes the file of every source whose key frame changed
+// since the last run.
+func (s *service) writeSnapshots(p Periodic) {
+	fileName := p.FileName
+	if fileName == nil {
+		fileName = FileName
+	}
+
+	s.mu.Lock()
+	var ids []string
+	for id, src := range s.sources {
+		if src.key != nil && src.gen != src.writtenGen {
+			ids = append(ids, id)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, id := range ids {
+		img, gen, err := s.frame(id)
+		if err != nil {
+			s.log.Warningf(s, "Can not snapshot %s: %v", id, err)
+			continue
+		}
+		var buf bytes.Buffer
+		if err = Encode(&buf, img, p.Request); err != nil {
+			s.log.Warningf(s, "Can not encode snapshot of %s: %v", id, err)
+			continue
+		}
+		path := filepath.Join(p.Dir, fileName(id)+p.Request.Format.Extension())
+		if err = writeFileAtomic(path, buf.Bytes()); err != nil {
+			s.log.Warningf(s, "Can not write snapshot of %s: %v", id, err)
+			continue
+		}
+		s.mu.Lock()
+		if src, ok := s.sources[id]; ok {
+			src.writtenGen = gen
+		}
+		s.mu.Unlock()
+	}
+}
+
+// writeFileAtomic writes data next to path and renames it into place.
+func writeFileAtomic(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err = f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err = os.Rename(f.Name(), path); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return nil
+}
+
+func (s *service) Release() { //nolint:revive // required by lifecycle.AsyncInstance interface
+	if s.periodicStop != nil {
+		close(s.periodicStop)
+		s.wg.Wait()
+	}
+	s.mu.Lock()
+	for url, src := range s.sources {
+		if src.key != nil {
+			src.key.Release()
+		}
+		delete(s.sources, url)
+	}
+	s.mu.Unlock()
+	for {
+		select {
+		case pkt, ok := <-s.inpPktCh:
+			if !ok {
+				return
+			}
+			if pkt != nil {
+				pkt.Release()
+			}
+		default:
+			close(s.inpPktCh)
+			return
+		}
+	}
+}
+
+func (s *service) String() string {
+	return fmt.Sprintf("THUMBNAIL %s", s.name)
+}
+
+func (s *service) Packets() chan<- gomedia.Packet {
+	return s.inpPktCh
+}
+
+func (s *service) AddSource() chan<- string {
+	return s.addSrcCh
+}
+
+func (s *service) RemoveSource() chan<- string {
+	return s.rmSrcCh
+}
@@ -0,0 +1,144 @@
+package thumbnail
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ugparu/gomedia"
+	"github.com/ugparu/gomedia/codec/mjpeg"
+	"github.com/ugparu/gomedia/decoder"
+	"github.com/ugparu/gomedia/frame/rgb"
+)
+
+const testSource = "rtsp://cam/main"
+
+// fakeDecoder returns a frame of the stream size whose first byte is the
+// first byte of the packet, and counts the decodes.
+type fakeDecoder struct {
+	decodes *atomic.Int32
+	w, h    int
+}
+
+func (d *fakeDecoder) Init(par gomedia.VideoCodecParameters) error {
+	d.w, d.h = int(par.Width()), int(par.Height()) //nolint:gosec
+	return nil
+}
+
+func (d *fakeDecoder) Feed(gomedia.VideoPacket) error { return nil }
+
+func (d *fakeDecoder) Decode(pkt gomedia.VideoPacket) (rgb.ReleasableImage, error) {
+	d.decodes.Add(1)
+	img := rgb.NewRGB(image.Rect(0, 0, d.w, d.h))
+	img.Pix[0] = pkt.Data()[0]
+	return img, nil
+}
+
+func (d *fakeDecoder) Close() {}
+
+func newTestService(t *testing.T, opts ...Option) (Service, *atomic.Int32) {
+	t.Helper()
+	decodes := &atomic.Int32{}
+	s := New(16, map[gomedia.CodecType]func() decoder.InnerVideoDecoder{ //nolint:mnd
+		gomedia.MJPEG: func() decoder.InnerVideoDecoder { return &fakeDecoder{decodes: decodes} },
+	}, opts...)
+	s.Write()
+	t.Cleanup(s.Close)
+	return s, decodes
+}
+
+// sendKey feeds a key frame and waits for Step to store it.
+func sendKey(t *testing.T, s Service, source string, marker byte) {
+	t.Helper()
+	par := mjpeg.NewCodecParameters(64, 32, 25) //nolint:mnd
+	s.Packets() <- mjpeg.NewPacket(true, 0, time.Now(), []byte{marker, 0xd8}, source, par)
+	s.Packets() <- mjpeg.NewPacket(false, 0, time.Now(), []byte{0, 0xd8}, source, par)
+	time.Sleep(50 * time.Millisecond) //nolint:mnd
+}
+
+func decodePNG(t *testing.T, data []byte) image.Image {
+	t.Helper()
+	img, err := png.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	return img
+}
+
+func TestService_Snapshot(t *testing.T) {
+	s, decodes := newTestService(t)
+	s.AddSource() <- testSource
+	time.Sleep(50 * time.Millisecond) //nolint:mnd
+
+	_, err := s.Snapshot(testSource, Request{Format: PNG})
+	require.ErrorIs(t, err, ErrNoKeyFrame)
+	_, err = s.Snapshot("other", Request{})
+	require.ErrorIs(t, err, ErrNoKeyFrame)
+
+	sendKey(t, s, testSource, 7) //nolint:mnd
+	require.Zero(t, decodes.Load(), "decoded before any request")
+
+	data, err := s.Snapshot(testSource, Request{Format: PNG})
+	require.NoError(t, err)
+	img := decodePNG(t, data)
+	require.Equal(t, image.Rect(0, 0, 64, 32), img.Bounds())
+	r, _, _, _ := img.At(0, 0).RGBA()
+	require.Equal(t, uint32(7*0x101), r)
+
+	data, err = s.Snapshot(testSource, Request{Format: PNG, Width: 16})
+	require.NoError(t, err)
+	require.Equal(t, image.Rect(0, 0, 16, 8), decodePNG(t, data).Bounds())
+	require.Equal(t, int32(1), decodes.Load(), "same key frame decoded twice")
+
+	sendKey(t, s, testSource, 9) //nolint:mnd
+	data, err = s.Snapshot(testSource, Request{Format: PNG})
+	require.NoError(t, err)
+	r, _, _, _ = decodePNG(t, data).At(0, 0).RGBA()
+	require.Equal(t, uint32(9*0x101), r)
+	require.Equal(t, int32(2), decodes.Load())
+
+	s.RemoveSource() <- testSource
+	time.Sleep(50 * time.Millisecond) //nolint:mnd
+	_, err = s.Snapshot(testSource, Request{})
+	require.ErrorIs(t, err, ErrNoKeyFrame)
+}
+
+func TestService_IgnoresUnknownSources(t *testing.T) {
+	s, _ := newTestService(t)
+	sendKey(t, s, testSource, 1)
+	_, err := s.Snapshot(testSource, Request{})
+	require.ErrorIs(t, err, ErrNoKeyFrame)
+}
+
+func TestService_Periodic(t *testing.T) {
+	dir := t.TempDir()
+	s, decodes := newTestService(t, WithPeriodic(Periodic{
+		Dir:      dir,
+		Interval: 20 * time.Millisecond, //nolint:mnd
+		Request:  Request{Format: PNG, Height: 16},
+	}))
+	s.AddSource() <- testSource
+	sendKey(t, s, testSource, 3) //nolint:mnd
+
+	path := filepath.Join(dir, FileName(testSource)+".png")
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(path)
+		return err == nil
+	}, time.Second, 10*time.Millisecond) //nolint:mnd
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, image.Rect(0, 0, 32, 16), decodePNG(t, data).Bounds())
+
+	// Unchanged key frames are not rewritten.
+	time.Sleep(100 * time.Millisecond) //nolint:mnd
+	require.Equal(t, int32(1), decodes.Load())
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1, "temporary files left behind")
+}
@@ -0,0 +1,115 @@
+// Package thumbnail turns video streams into still images. Encode converts
+// a decoded frame to JPEG, PNG or lossless WebP in pure Go, and New returns
+// a Writer-style Service that keeps the last key frame of every added
+// source and decodes it only when a snapshot is requested, optionally
+// writing periodic snapshot files (e.g. for a camera grid).
+package thumbnail
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ugparu/gomedia"
+	"github.com/ugparu/gomedia/utils/logger"
+)
+
+// ErrNoKeyFrame is returned by Service.Snapshot for sources that are not
+// added or have not delivered a video key frame yet.
+var ErrNoKeyFrame = errors.New("no key frame received")
+
+// Format is the image container of a snapshot.
+type Format uint8
+
+const (
+	JPEG = Format(iota)
+	PNG
+	WebP // lossless
+)
+
+func (f Format) String() string {
+	switch f {
+	case JPEG:
+		return "JPEG"
+	case PNG:
+		return "PNG"
+	case WebP:
+		return "WebP"
+	default:
+		return "?"
+	}
+}
+
+// Extension returns the file extension including the dot.
+func (f Format) Extension() string {
+	switch f {
+	case PNG:
+		return ".png"
+	case WebP:
+		return ".webp"
+	default:
+		return ".jpg"
+	}
+}
+
+// ContentType returns the MIME type, for serving snapshots over HTTP.
+func (f Format) ContentType() string {
+	switch f {
+	case PNG:
+		return "image/png"
+	case WebP:
+		return "image/webp"
+	default:
+		return "image/jpeg"
+	}
+}
+
+// Request selects the format and size of a snapshot.
+type Request struct {
+	Format Format
+	// Width and Height of the image; 0 for both keeps the frame size, one
+	// of them 0 keeps the aspect ratio.
+	Width  int
+	Height int
+	// Quality is the JPEG quality (1-100); 0 selects jpeg.DefaultQuality.
+	Quality int
+}
+
+// Service is a Writer that taps a packet stream (any Reader fan-out) for
+// key frames. Snapshot is safe to call from any goroutine.
+type Service interface {
+	gomedia.Writer
+	Snapshot(sourceID string, req Request) ([]byte, error)
+}
+
+// Periodic configures snapshot files written every Interval for each source
+// with a new key frame. Files are replaced atomically, so readers never see
+// a partial image.
+type Periodic struct {
+	Dir      string
+	Interval time.Duration
+	Request  Request
+	// FileName maps a source to a file name inside Dir without extension;
+	// defaults to FileName.
+	FileName func(sourceID string) string
+}
+
+type Option func(*config)
+
+type config struct {
+	log      logger.Logger
+	name     string
+	periodic *Periodic
+}
+
+func WithLogger(l logger.Logger) Option {
+	return func(c *config) { c.log = l }
+}
+
+func WithName(name string) Option {
+	return func(c *config) { c.name = name }
+}
+
+// WithPeriodic enables periodic snapshot files.
+func WithPeriodic(p Periodic) Option {
+	return func(c *config) { c.periodic = &p }
+}
@@ -0,0 +1,344 @@
+package thumbnail
+
+import (
+	"container/heap"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"io"
+
+	"github.com/ugparu/gomedia/frame/rgb"
+)
+
+// Lossless WebP (VP8L) encoder. It applies the subtract-green transform and
+// codes every pixel as a literal with one set of canonical prefix codes; no
+// backward references or colour cache. That is far from cwebp's ratio but
+// keeps the encoder small and exact, which is what thumbnails need.
+
+const (
+	vp8lSignature     = 0x2f
+	vp8lMaxSize       = 1 << 14
+	vp8lSubtractGreen = 2
+
+	greenAlphabet    = 256 + 24 // literals + length prefixes
+	literalAlphabet  = 256
+	distanceAlphabet = 40
+
+	maxCodeLength           = 15
+	maxCodeLengthCodeLength = 7
+	numCodeLengthCodes      = 19
+)
+
+var codeLengthCodeOrder = [numCodeLengthCodes]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+var errWebPTooLarge = errors.New("webp: image larger than 16384x16384")
+
+type bitWriter struct {
+	buf []byte
+	acc uint64
+	n   uint
+}
+
+// write appends the n low bits of v, least significant first.
+func (w *bitWriter) write(v uint32, n uint) {
+	w.acc |= uint64(v) << w.n
+	w.n += n
+	for w.n >= 8 { //nolint:mnd
+		w.buf = append(w.buf, byte(w.acc))
+		w.acc >>= 8
+		w.n -= 8
+	}
+}
+
+func (w *bitWriter) bytes() []byte {
+	if w.n > 0 {
+		w.buf = append(w.buf, byte(w.acc))
+		w.acc, w.n = 0, 0
+	}
+	return w.buf
+}
+
+// prefixCode holds bit-reversed canonical codes ready to be written LSB
+// first. A code with a single symbol is written with zero bits, as decoders
+// treat it.
+type prefixCode struct {
+	codes []uint16
+	bits  []uint8
+}
+
+func (c *prefixCode) put(w *bitWriter, symbol int) {
+	w.write(uint32(c.codes[symbol]), uint(c.bits[symbol]))
+}
+
+// writePrefixCode writes the code for hist to w and returns it. Up to two
+// literal symbols use the "simple" form; the rest get a normal code whose
+// lengths are themselves coded with a code-length code.
+func writePrefixCode(w *bitWriter, hist []uint32) *prefixCode {
+	var used []int
+	for s, n := range hist {
+		if n > 0 {
+			used = append(used, s)
+		}
+	}
+	code := &prefixCode{codes: make([]uint16, len(hist)), bits: make([]uint8, len(hist))}
+
+	if len(used) <= 2 && (len(used) == 0 || used[len(used)-1] < literalAlphabet) { //nolint:mnd
+		if len(used) == 0 {
+			used = []int{0}
+		}
+		w.write(1, 1) // simple code
+		w.write(uint32(len(used)-1), 1)
+		if used[0] < 2 { //nolint:mnd
+			w.write(0, 1)
+			w.write(uint32(used[0]), 1)
+		} else {
+			w.write(1, 1)
+			w.write(uint32(used[0]), 8) //nolint:mnd
+		}
+		if len(used) == 2 { //nolint:mnd
+			w.write(uint32(used[1]), 8) //nolint:mnd
+			code.codes[used[1]], code.bits[used[0]], code.bits[used[1]] = 1, 1, 1
+		}
+		return code
+	}
+
+	lengths := codeLengths(hist, maxCodeLength)
+	assignCodes(code, lengths)
+
+	clHist := make([]uint32, numCodeLengthCodes)
+	for _, l := range lengths {
+		clHist[l]++
+	}
+	clLengths := codeLengths(clHist, maxCodeLengthCodeLength)
+	clCode := &prefixCode{codes: make([]uint16, numCodeLengthCodes), bits: make([]uint8, numCodeLengthCodes)}
+	assignCodes(clCode, clLengths)
+
+	n := numCodeLengthCodes
+	for n > 4 && clLengths[codeLengthCodeOrder[n-1]] == 0 { //nolint:mnd
+		n--
+	}
+	w.write(0, 1)           // normal code
+	w.write(uint32(n-4), 4) //nolint:mnd
+	for _, s := range codeLengthCodeOrder[:n] {
+		w.write(uint32(clLengths[s]), 3) //nolint:mnd
+	}
+	w.write(0, 1) // lengths for the whole alphabet follow
+	for _, l := range lengths {
+		clCode.put(w, int(l))
+	}
+	return code
+}
+
+// assignCodes derives canonical codes from lengths, as in DEFLATE, and
+// reverses them for the LSB-first bit stream.
+func assignCodes(c *prefixCode, lengths []uint8) {
+	var used int
+	for _, l := range lengths {
+		if l > 0 {
+			used++
+		}
+	}
+	if used == 1 {
+		return // zero-bit code
+	}
+	var count [maxCodeLength + 1]int
+	for _, l := range lengths {
+		count[l]++
+	}
+	count[0] = 0
+	var next [maxCodeLength + 2]int
+	for l := 1; l <= maxCodeLength; l++ {
+		next[l+1] = (next[l] + count[l]) << 1
+	}
+	for s, l := range lengths {
+		if l == 0 {
+			continue
+		}
+		code := next[l]
+		next[l]++
+		var rev uint16
+		for range l {
+			rev = rev<<1 | uint16(code&1) //nolint:gosec
+			code >>= 1
+		}
+		c.codes[s], c.bits[s] = rev, l
+	}
+}
+
+type hNode struct {
+	weight      uint32
+	left, right int // children; -1 for leaves
+	symbol      int
+}
+
+type nodeHeap struct {
+	nodes []hNode
+	idx   []int
+}
+
+func (h *nodeHeap) Len() int { return len(h.idx) }
+func (h *nodeHeap) Less(i, j int) bool {
+	a, b := h.nodes[h.idx[i]], h.nodes[h.idx[j]]
+	if a.weight != b.weight {
+		return a.weight < b.weight
+	}
+	return h.idx[i] < h.idx[j]
+}
+func (h *nodeHeap) Swap(i, j int) { h.idx[i], h.idx[j] = h.idx[j], h.idx[i] }
+func (h *nodeHeap) Push(x any)    { h.idx = append(h.idx, x.(int)) } //nolint:forcetypeassert
+func (h *nodeHeap) Pop() any {
+	x := h.idx[len(h.idx)-1]
+	h.idx = h.idx[:len(h.idx)-1]
+	return x
+}
+
+// codeLengths builds Huffman code lengths no longer than maxLen. When the
+// tree is too deep the rarest symbols are made more frequent and the tree is
+// rebuilt, converging to a balanced tree.
+func codeLengths(hist []uint32, maxLen int) []uint8 {
+	counts := append([]uint32(nil), hist...)
+	lengths := make([]uint8, len(hist))
+	for minCount := uint32(1); ; minCount *= 2 {
+		h := &nodeHeap{}
+		for s, n := range counts {
+			if n > 0 {
+				h.nodes = append(h.nodes, hNode{weight: max(n, minCount), left: -1, right: -1, symbol: s})
+				h.idx = append(h.idx, len(h.nodes)-1)
+			}
+		}
+		switch len(h.idx) {
+		case 0:
+			return lengths
+		case 1:
+			lengths[h.nodes[0].symbol] = 1
+			return lengths
+		}
+		heap.Init(h)
+		for h.Len() > 1 {
+			a, b := heap.Pop(h).(int), heap.Pop(h).(int) //nolint:forcetypeassert
+			h.nodes = append(h.nodes, hNode{weight: h.nodes[a].weight + h.nodes[b].weight, left: a, right: b})
+			heap.Push(h, len(h.nodes)-1)
+		}
+
+		clear(lengths)
+		tooDeep := false
+		var walk func(n, depth int)
+		walk = func(n, depth int) {
+			nd := h.nodes[n]
+			if nd.left < 0 {
+				if depth > maxLen {
+					tooDeep = true
+				}
+				lengths[nd.symbol] = uint8(depth) //nolint:gosec
+				return
+			}
+			walk(nd.left, depth+1)
+			walk(nd.right, depth+1)
+		}
+		walk(len(h.nodes)-1, 0)
+		if !tooDeep {
+			return lengths
+		}
+	}
+}
+
+// argbPixels returns the pixels of img as 0xAARRGGBB and whether any of
+// them is not opaque.
+func argbPixels(img image.Image) ([]uint32, bool) {
+	b := img.Bounds()
+	pix := make([]uint32, 0, b.Dx()*b.Dy())
+	if src, ok := img.(*rgb.RGB); ok {
+		for y := range b.Dy() {
+			row := src.Pix[y*src.Stride : y*src.Stride+3*b.Dx()]
+			for x := 0; x < len(row); x += 3 {
+				pix = append(pix, 0xff000000|uint32(row[x])<<16|uint32(row[x+1])<<8|uint32(row[x+2]))
+			}
+		}
+		return pix, false
+	}
+	hasAlpha := false
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA) //nolint:forcetypeassert
+			hasAlpha = hasAlpha || c.A != 0xff
+			pix = append(pix, uint32(c.A)<<24|uint32(c.R)<<16|uint32(c.G)<<8|uint32(c.B))
+		}
+	}
+	return pix, hasAlpha
+}
+
+// encodeWebP writes img as a lossless WebP file.
+func encodeWebP(out io.Writer, img image.Image) error {
+	b := img.Bounds()
+	if b.Dx() > vp8lMaxSize || b.Dy() > vp8lMaxSize {
+		return errWebPTooLarge
+	}
+	if b.Empty() {
+		return errors.New("webp: empty image")
+	}
+	pix, hasAlpha := argbPixels(img)
+
+	green := make([]uint32, greenAlphabet)
+	red := make([]uint32, literalAlphabet)
+	blue := make([]uint32, literalAlphabet)
+	alpha := make([]uint32, literalAlphabet)
+	for i, p := range pix {
+		g := p >> 8 & 0xff
+		r := (p>>16 - g) & 0xff
+		bl := (p - g) & 0xff
+		pix[i] = p&0xff00ff00 | r<<16 | bl
+		green[g]++
+		red[r]++
+		blue[bl]++
+		alpha[p>>24]++
+	}
+
+	w := &bitWriter{}
+	w.write(vp8lSignature, 8)     //nolint:mnd
+	w.write(uint32(b.Dx()-1), 14) //nolint:mnd,gosec
+	w.write(uint32(b.Dy()-1), 14) //nolint:mnd,gosec
+	if hasAlpha {
+		w.write(1, 1)
+	} else {
+		w.write(0, 1)
+	}
+	w.write(0, 3) //nolint:mnd // version
+
+	w.write(1, 1)                 // transform present
+	w.write(vp8lSubtractGreen, 2) //nolint:mnd
+	w.write(0, 1)                 // no more transforms
+
+	w.write(0, 1) // no colour cache
+	w.write(0, 1) // no meta prefix codes
+
+	gc := writePrefixCode(w, green)
+	rc := writePrefixCode(w, red)
+	bc := writePrefixCode(w, blue)
+	ac := writePrefixCode(w, alpha)
+	writePrefixCode(w, make([]uint32, distanceAlphabet))
+
+	for _, p := range pix {
+		gc.put(w, int(p>>8&0xff))
+		rc.put(w, int(p>>16&0xff))
+		bc.put(w, int(p&0xff))
+		ac.put(w, int(p>>24))
+	}
+	data := w.bytes()
+
+	const chunkHeader = 8
+	padded := len(data) + len(data)&1
+	hdr := make([]byte, 0, 12+chunkHeader) //nolint:mnd
+	hdr = append(hdr, "RIFF"...)
+	hdr = binary.LittleEndian.AppendUint32(hdr, uint32(4+chunkHeader+padded)) //nolint:mnd,gosec
+	hdr = append(hdr, "WEBPVP8L"...)
+	hdr = binary.LittleEndian.AppendUint32(hdr, uint32(len(data))) //nolint:gosec
+	if _, err := out.Write(hdr); err != nil {
+		return err
+	}
+	if len(data)&1 == 1 {
+		data = append(data, 0)
+	}
+	_, err := out.Write(data)
+	return err
+}
@@ -0,0 +1,405 @@
+package transcode
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ugparu/gomedia"
+	"github.com/ugparu/gomedia/codec/g726"
+	"github.com/ugparu/gomedia/codec/pcm"
+	"github.com/ugparu/gomedia/decoder"
+	decadpcm "github.com/ugparu/gomedia/decoder/adpcm"
+	decpcm "github.com/ugparu/gomedia/decoder/pcm"
+	"github.com/ugparu/gomedia/encoder"
+	encadpcm "github.com/ugparu/gomedia/encoder/adpcm"
+	encpcm "github.com/ugparu/gomedia/encoder/pcm"
+	framepcm "github.com/ugparu/gomedia/frame/pcm"
+	"github.com/ugparu/gomedia/utils"
+	"github.com/ugparu/gomedia/utils/lifecycle"
+	"github.com/ugparu/gomedia/utils/logger"
+)
+
+// maxAudioTSDrift bounds how far the evenly spaced output clock of a source
+// may drift from its input before it is re-anchored, as in the encoder
+// package: above encoder buffering, below any stream gap.
+const maxAudioTSDrift = time.Second
+
+var ErrNoAudioEncoder = errors.New("no audio encoder for target codec, use WithAudioEncoder")
+
+// WithAudioDecoders adds or replaces the inner decoders used by NewAudio,
+// e.g. decoder/aac.NewAacDecoder and decoder/opus.NewOpusDecoder. G.711,
+// G.722 and G.726 are decoded without any.
+func WithAudioDecoders(decoders map[gomedia.CodecType]func() decoder.InnerAudioDecoder) Option {
+	return func(c *config) {
+		if c.audioDecoders == nil {
+			c.audioDecoders = map[gomedia.CodecType]func() decoder.InnerAudioDecoder{}
+		}
+		for ct, fn := range decoders {
+			c.audioDecoders[ct] = fn
+		}
+	}
+}
+
+// WithAudioEncoder sets the inner encoder used by NewAudio. It is required
+// for targets without a pure-Go encoder, such as AAC or Opus.
+func WithAudioEncoder(newEncoder func() encoder.InnerAudioEncoder) Option {
+	return func(c *config) { c.audioEncoder = newEncoder }
+}
+
+func defaultAudioDecoders() map[gomedia.CodecType]func() decoder.InnerAudioDecoder {
+	return map[gomedia.CodecType]func() decoder.InnerAudioDecoder{
+		gomedia.PCMAlaw: decpcm.NewALAWDecoder,
+		gomedia.PCMUlaw: decpcm.NewULAWDecoder,
+		gomedia.G722:    decadpcm.NewG722Decoder,
+		gomedia.G726:    decadpcm.NewG726Decoder,
+	}
+}
+
+func defaultAudioEncoder(target gomedia.CodecType) func() encoder.InnerAudioEncoder {
+	switch target { //nolint:exhaustive // other targets need WithAudioEncoder
+	case gomedia.PCMAlaw:
+		return encpcm.NewAlawEncoder
+	case gomedia.PCMUlaw:
+		return encpcm.NewUlawEncoder
+	case gomedia.G722:
+		return encadpcm.NewG722Encoder
+	case gomedia.G726:
+		return func() encoder.InnerAudioEncoder { return encadpcm.NewG726Encoder(32000, g726.RFC3551) } //nolint:mnd
+	}
+	return nil
+}
+
+// audioTranscoder re-encodes the audio of every added source into one
+// codec. Decoding, format conversion, resampling and encoding run inline in
+// Step, so the output keeps the input order relative to video.
+type audioTranscoder struct {
+	lifecycle.AsyncManager[*audioTranscoder]
+	config
+	target   gomedia.CodecType
+	rate     uint64
+	channels uint8
+
+	inpPktCh chan gomedia.Packet
+	outPktCh chan gomedia.Packet
+	addSrcCh chan string
+	rmSrcCh  chan string
+
+	// sources holds every added source; the value stays nil until its
+	// first audio packet.
+	sources map[string]*audioSource
+}
+
+// NewAudio returns a transcoder that converts the audio of every source
+// added through AddSource to target at the given sample rate and channel
+// count; a rate or channel count of 0 keeps the source's. Codecs with a
+// fixed clock (G.711, G.722, G.726, Opus) resample to it on their own.
+// Target gomedia.PCM produces raw S16 packets.
+//
+// Audio that already matches the target is forwarded as is, and input may
+// switch codec at any time. Video and the packets of other sources pass
+// through untouched unless WithPassthrough(false) is given.
+func NewAudio(chanSize int, target gomedia.CodecType, rate uint64, channels uint8, opts ...Option) (Transcoder, error) {
+	t := &audioTranscoder{
+		config: config{
+			log:           logger.Default,
+			passthrough:   true,
+			audioDecoders: defaultAudioDecoders(),
+			audioEncoder:  defaultAudioEncoder(target),
+		},
+		target:   target,
+		rate:     rate,
+		channels: channels,
+		inpPktCh: make(chan gomedia.Packet, chanSize),
+		outPktCh: make(chan gomedia.Packet, chanSize),
+		addSrcCh: make(chan string, chanSize),
+		rmSrcCh:  make(chan string, chanSize),
+		sources:  map[string]*audioSource{},
+	}
+	for _, o := range opts {
+		o(&t.config)
+	}
+	if target != gomedia.PCM && t.audioEncoder == nil {
+		return nil, fmt.Errorf("%w: %v", ErrNoAudioEncoder, target)
+	}
+	t.AsyncManager = lifecycle.NewFailSafeAsyncManager(t, t.log)
+	return t, nil
+}
+
+func (t *audioTranscoder) Write() {
+	// FailSafeAsyncManager.Start never returns an error.
+	_ = t.Start(func(*audioTranscoder) error { return nil })
+}
+
+func (t *audioTranscoder) Step(stopCh <-chan struct{}) error {
+	select {
+	case <-stopCh:
+		return &lifecycle.BreakError{}
+	case url := <-t.addSrcCh:
+		if _, ok := t.sources[url]; !ok {
+			t.log.Infof(t, "Adding source %s", url)
+			t.sources[url] = nil
+		}
+	case url := <-t.rmSrcCh:
+		if src, ok := t.sources[url]; ok {
+			t.log.Infof(t, "Removing source %s", url)
+			src.close()
+			delete(t.sources, url)
+		}
+	case pkt := <-t.inpPktCh:
+		if pkt == nil {
+			return &utils.NilPacketError{}
+		}
+		aPkt, isAudio := pkt.(gomedia.AudioPacket)
+		if _, ok := t.sources[pkt.SourceID()]; !ok || !isAudio {
+			if !t.passthrough {
+				pkt.Release()
+				return nil
+			}
+			return t.emit(pkt, stopCh)
+		}
+		return t.processPacket(aPkt, stopCh)
+	}
+	return nil
+}
+
+func (t *audioTranscoder) processPacket(pkt gomedia.AudioPacket, stopCh <-chan struct{}) error {
+	par := pkt.CodecParameters()
+	if t.matches(par) {
+		return t.emit(pkt, stopCh)
+	}
+	defer pkt.Release()
+
+	src := t.sources[pkt.SourceID()]
+	if src == nil {
+		src = &audioSource{}
+		t.sources[pkt.SourceID()] = src
+	}
+	out, err := src.transcode(t, pkt)
+	if err != nil {
+		// A broken packet or an unsupported codec must not stall the
+		// other sources and the video.
+		t.log.Errorf(t, "Transcoding %s audio of %s: %v", par.Type(), pkt.SourceID(), err)
+		return nil
+	}
+	for i, p := range out {
+		if err = t.emit(p, stopCh); err != nil {
+			for _, rest := range out[i+1:] {
+				rest.Release()
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// matches reports whether audio with par can be forwarded without
+// transcoding.
+func (t *audioTranscoder) matches(par gomedia.AudioCodecParameters) bool {
+	return par.Type() == t.target &&
+		(t.rate == 0 || par.SampleRate() == t.rate) &&
+		(t.channels == 0 || par.Channels() == t.channels) &&
+		(t.target != gomedia.PCM || par.SampleFormat() == gomedia.S16)
+}
+
+func (t *audioTranscoder) emit(pkt gomedia.Packet, stopCh <-chan struct{}) error {
+	select {
+	case t.outPktCh <- pkt:
+		return nil
+	case <-stopCh:
+		pkt.Release()
+		return &lifecycle.BreakError{}
+	}
+}
+
+func (t *audioTranscoder) Release() { //nolint:revive // required by lifecycle.AsyncInstance interface
+	for url, src := range t.sources {
+		src.close()
+		delete(t.sources, url)
+	}
+	for {
+		select {
+		case pkt, ok := <-t.inpPktCh:
+			if !ok {
+				goto drained
+			}
+			if pkt != nil {
+				pkt.Release()
+			}
+		default:
+			close(t.inpPktCh)
+			goto drained
+		}
+	}
+drained:
+	close(t.outPktCh)
+}
+
+func (t *audioTranscoder) String() string {
+	return fmt.Sprintf("AUDIO_TRANSCODER %s", t.name)
+}
+
+func (t *audioTranscoder) Packets() chan<- gomedia.Packet {
+	return t.inpPktCh
+}
+
+func (t *audioTranscoder) Output() <-chan gomedia.Packet {
+	return t.outPktCh
+}
+
+func (t *audioTranscoder) AddSource() chan<- string {
+	return t.addSrcCh
+}
+
+func (t *audioTranscoder) RemoveSource() chan<- string {
+	return t.rmSrcCh
+}
+
+// audioSource is the transcoding pipeline of one source. Every stage is
+// rebuilt when the parameters it depends on change.
+type audioSource struct {
+	inPar  gomedia.AudioCodecParameters
+	dec    decoder.InnerAudioDecoder
+	decPar *pcm.CodecParameters
+
+	conv    *framepcm.Converter
+	res     *utils.Resampler
+	resFrom uint64 // input rate of res
+
+	encPar *pcm.CodecParameters
+	enc    encoder.InnerAudioEncoder
+
+	ts    time.Duration
+	hasTS bool
+}
+
+// transcode returns the packets produced for pkt, which stays owned by the
+// caller.
+func (src *audioSource) transcode(t *audioTranscoder, pkt gomedia.AudioPacket) ([]gomedia.AudioPacket, error) {
+	if err := src.update(t, pkt.CodecParameters()); err != nil {
+		return nil, err
+	}
+
+	raw := pkt
+	if src.dec != nil {
+		data, _, err := src.dec.Decode(pkt.Data(), nil)
+		if err != nil || len(data) == 0 {
+			return nil, err
+		}
+		raw = pcm.NewPacket(data, pkt.Timestamp(), pkt.SourceID(), pkt.StartTime(), src.decPar, pkt.Duration())
+	}
+
+	converted, err := src.conv.Convert(raw)
+	if err != nil {
+		return nil, err
+	}
+	inRate := converted.CodecParameters().SampleRate()
+	outRate, outChannels := inRate, converted.CodecParameters().Channels()
+	if t.rate != 0 {
+		outRate = t.rate
+	}
+	data := converted.Data()
+	if outRate != inRate {
+		if src.res == nil || src.resFrom != inRate {
+			if outChannels > 2 { //nolint:mnd
+				return nil, fmt.Errorf("cannot resample %d channels", outChannels)
+			}
+			if src.res, err = utils.NewPcmS16leResampler(int(outChannels), int(inRate), int(outRate)); err != nil { //nolint:gosec
+				return nil, err
+			}
+			src.resFrom = inRate
+		}
+		if data, err = src.res.Resample(data); err != nil {
+			return nil, err
+		}
+	}
+
+	if src.encPar == nil || src.encPar.SampleRate() != outRate || src.encPar.Channels() != outChannels {
+		src.encPar = pcm.NewCodecParameters(pkt.CodecParameters().StreamIndex(), gomedia.PCM, outChannels, outRate)
+		src.closeEncoder()
+	}
+	samples := len(data) / (2 * int(outChannels)) //nolint:mnd
+	resampled := pcm.NewPacket(data, pkt.Timestamp(), pkt.SourceID(), pkt.StartTime(), src.encPar,
+		time.Duration(samples)*time.Second/time.Duration(outRate)) //nolint:gosec
+
+	var out []gomedia.AudioPacket
+	if t.target == gomedia.PCM {
+		out = []gomedia.AudioPacket{resampled}
+	} else {
+		if src.enc == nil {
+			src.enc = t.audioEncoder()
+			if err = src.enc.Init(src.encPar); err != nil {
+				src.enc = nil
+				return nil, err
+			}
+		}
+		if out, err = src.enc.Encode(resampled); err != nil {
+			return nil, err
+		}
+	}
+	for _, p := range out {
+		src.stamp(p, pkt.Timestamp())
+		p.SetSourceID(pkt.SourceID())
+	}
+	return out, nil
+}
+
+// stamp spaces the output evenly, re-anchoring to the input timestamp when
+// the two diverge (see the encoder package).
+func (src *audioSource) stamp(p gomedia.AudioPacket, in time.Duration) {
+	if !src.hasTS || in-src.ts > maxAudioTSDrift || src.ts-in > maxAudioTSDrift {
+		src.ts, src.hasTS = in, true
+	}
+	p.SetTimestamp(src.ts)
+	src.ts += p.Duration()
+}
+
+// update rebuilds the decoder and converter for new input parameters.
+func (src *audioSource) update(t *audioTranscoder, par gomedia.AudioCodecParameters) error {
+	if par == src.inPar {
+		return nil
+	}
+	if src.dec != nil {
+		src.dec.Close()
+		src.dec = nil
+	}
+	src.inPar = nil
+
+	if par.Type() != gomedia.PCM {
+		newDec, ok := t.audioDecoders[par.Type()]
+		if !ok {
+			return fmt.Errorf("no decoder for %v", par.Type())
+		}
+		dec := newDec()
+		if err := dec.Init(par); err != nil {
+			return err
+		}
+		src.dec = dec
+		src.decPar = pcm.NewCodecParameters(par.StreamIndex(), gomedia.PCM, par.Channels(), par.SampleRate())
+	}
+	var layout gomedia.ChannelLayout
+	if t.channels != 0 {
+		layout = framepcm.DefaultLayout(int(t.channels))
+	}
+	src.conv = framepcm.NewConverter(framepcm.Format{SampleFormat: gomedia.S16, Layout: layout})
+	src.inPar = par
+	return nil
+}
+
+func (src *audioSource) closeEncoder() {
+	if src.enc != nil {
+		src.enc.Close()
+		src.enc = nil
+	}
+}
+
+func (src *audioSource) close() {
+	if src == nil {
+		return
+	}
+	if src.dec != nil {
+		src.dec.Close()
+		src.dec = nil
+	}
+	src.closeEncoder()
+}
@@ -0,0 +1,135 @@
+package transcode
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ugparu/gomedia"
+	"github.com/ugparu/gomedia/codec/g722"
+	"github.com/ugparu/gomedia/codec/mjpeg"
+	"github.com/ugparu/gomedia/codec/pcm"
+	"github.com/ugparu/gomedia/encoder"
+)
+
+func newTestAudioTranscoder(t *testing.T, target gomedia.CodecType, rate uint64, channels uint8, opts ...Option) Transcoder {
+	t.Helper()
+	tr, err := NewAudio(16, target, rate, channels, opts...) //nolint:mnd
+	require.NoError(t, err)
+	tr.Write()
+	t.Cleanup(tr.Close)
+	return tr
+}
+
+// alawPacket returns 100ms of A-law silence.
+func alawPacket(par *pcm.CodecParameters, source string, ts time.Duration) gomedia.Packet {
+	data := make([]byte, 800) //nolint:mnd
+	for i := range data {
+		data[i] = 0xd5
+	}
+	return pcm.NewPacket(data, ts, source, time.Time{}, par, 100*time.Millisecond)
+}
+
+func TestAudioTranscoder_ALawToULaw(t *testing.T) {
+	tr := newTestAudioTranscoder(t, gomedia.PCMUlaw, 0, 0)
+	addSource(tr, testSource)
+
+	par := pcm.NewCodecParameters(1, gomedia.PCMAlaw, 1, 8000) //nolint:mnd
+	for i := range 3 {
+		tr.Packets() <- alawPacket(par, testSource, time.Duration(i)*100*time.Millisecond)
+	}
+
+	got := collect(t, tr, 3)[testSource] //nolint:mnd
+	for i, pkt := range got {
+		ap, ok := pkt.(gomedia.AudioPacket)
+		require.True(t, ok)
+		require.Equal(t, gomedia.PCMUlaw, ap.CodecParameters().Type())
+		require.Equal(t, uint8(1), ap.CodecParameters().StreamIndex())
+		require.Equal(t, time.Duration(i)*100*time.Millisecond, pkt.Timestamp())
+		require.Len(t, pkt.Data(), 800)
+	}
+}
+
+func TestAudioTranscoder_ToRawPCM(t *testing.T) {
+	tr := newTestAudioTranscoder(t, gomedia.PCM, 16000, 2) //nolint:mnd
+	addSource(tr, testSource)
+
+	tr.Packets() <- alawPacket(pcm.NewCodecParameters(1, gomedia.PCMAlaw, 1, 8000), testSource, 0) //nolint:mnd
+	pkt := collect(t, tr, 1)[testSource][0].(gomedia.AudioPacket)
+	par := pkt.CodecParameters()
+	require.Equal(t, gomedia.PCM, par.Type())
+	require.Equal(t, uint64(16000), par.SampleRate())
+	require.Equal(t, uint8(2), par.Channels())
+	require.InDelta(t, 100*time.Millisecond, pkt.Duration(), float64(time.Millisecond))
+	require.InDelta(t, 1600*4, len(pkt.Data()), 16) //nolint:mnd
+}
+
+func TestAudioTranscoder_CodecChange(t *testing.T) {
+	tr := newTestAudioTranscoder(t, gomedia.PCMAlaw, 0, 0)
+	addSource(tr, testSource)
+
+	tr.Packets() <- alawPacket(pcm.NewCodecParameters(0, gomedia.PCMUlaw, 1, 8000), testSource, 0) //nolint:mnd
+	// 100ms of G.722 takes 800 code bytes.
+	g722Par := g722.NewCodecParameters(0)
+	tr.Packets() <- g722.NewPacket(make([]byte, 800), 100*time.Millisecond, testSource, time.Time{}, g722Par, 100*time.Millisecond) //nolint:mnd
+
+	got := collect(t, tr, 2)[testSource] //nolint:mnd
+	for _, pkt := range got {
+		require.Equal(t, gomedia.PCMAlaw, pkt.(gomedia.AudioPacket).CodecParameters().Type())
+	}
+	require.Equal(t, 100*time.Millisecond, got[1].Timestamp())
+}
+
+func TestAudioTranscoder_PassesThrough(t *testing.T) {
+	tr := newTestAudioTranscoder(t, gomedia.PCMUlaw, 0, 0)
+	addSource(tr, testSource)
+
+	video := videoPacket(mjpeg.NewCodecParameters(1280, 720, 25), testSource, 0) //nolint:mnd
+	tr.Packets() <- video
+	ulaw := alawPacket(pcm.NewCodecParameters(0, gomedia.PCMUlaw, 1, 8000), testSource, 0) //nolint:mnd
+	tr.Packets() <- ulaw
+	other := alawPacket(pcm.NewCodecParameters(0, gomedia.PCMAlaw, 1, 8000), "other", 0) //nolint:mnd
+	tr.Packets() <- other
+
+	got := collect(t, tr, 3) //nolint:mnd
+	require.Equal(t, []gomedia.Packet{video, ulaw}, got[testSource])
+	require.Equal(t, []gomedia.Packet{other}, got["other"])
+}
+
+func TestAudioTranscoder_WithoutPassthrough(t *testing.T) {
+	tr := newTestAudioTranscoder(t, gomedia.PCMUlaw, 0, 0, WithPassthrough(false))
+	addSource(tr, testSource)
+
+	tr.Packets() <- videoPacket(mjpeg.NewCodecParameters(1280, 720, 25), testSource, 0)            //nolint:mnd
+	tr.Packets() <- alawPacket(pcm.NewCodecParameters(0, gomedia.PCMAlaw, 1, 8000), testSource, 0) //nolint:mnd
+
+	got := collect(t, tr, 1)
+	_, ok := got[testSource][0].(gomedia.AudioPacket)
+	require.True(t, ok)
+}
+
+func TestAudioTranscoder_UnsupportedInputIsDropped(t *testing.T) {
+	tr := newTestAudioTranscoder(t, gomedia.PCMUlaw, 0, 0)
+	addSource(tr, testSource)
+
+	tr.Packets() <- alawPacket(pcm.NewCodecParameters(0, gomedia.OPUS, 1, 48000), testSource, 0)   //nolint:mnd
+	tr.Packets() <- alawPacket(pcm.NewCodecParameters(0, gomedia.PCMAlaw, 1, 8000), testSource, 0) //nolint:mnd
+
+	got := collect(t, tr, 1)[testSource]
+	require.Equal(t, gomedia.PCMUlaw, got[0].(gomedia.AudioPacket).CodecParameters().Type())
+	select {
+	case pkt := <-tr.Output():
+		t.Fatalf("unexpected packet %v", pkt)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestNewAudio_NeedsEncoder(t *testing.T) {
+	_, err := NewAudio(1, gomedia.AAC, 48000, 2) //nolint:mnd
+	require.ErrorIs(t, err, ErrNoAudioEncoder)
+
+	tr, err := NewAudio(1, gomedia.AAC, 48000, 2, WithAudioEncoder(func() encoder.InnerAudioEncoder { return nil })) //nolint:mnd
+	require.NoError(t, err)
+	require.NotNil(t, tr)
+}
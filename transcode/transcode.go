@@ -0,0 +1,16 @@
+// Package transcode provides pipeline stages that sit between a
+// gomedia.Reader and gomedia.Writers and re-encode part of the stream: they
+// are fed like a Writer and emit the resulting packets on Output(), ready to
+// be forwarded to any number of writers.
+package transcode
+
+import (
+	"github.com/ugparu/gomedia"
+)
+
+// Transcoder is a Writer whose processed packets are read back from Output.
+// Output is closed after the transcoder has been closed and drained.
+type Transcoder interface {
+	gomedia.Writer
+	Output() <-chan gomedia.Packet
+}
@@ -0,0 +1,480 @@
+package transcode
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ugparu/gomedia"
+	"github.com/ugparu/gomedia/decoder"
+	"github.com/ugparu/gomedia/encoder"
+	"github.com/ugparu/gomedia/frame/rgb"
+	"github.com/ugparu/gomedia/utils"
+	"github.com/ugparu/gomedia/utils/lifecycle"
+	"github.com/ugparu/gomedia/utils/logger"
+)
+
+const (
+	defaultFPS = 25
+	// maxPendingTimings bounds the packet timings waiting for a decoded
+	// image. The decoder may swallow frames (decode errors, restarts), so
+	// without a bound the queue would grow and timestamps drift.
+	maxPendingTimings = 64
+)
+
+// Rendition describes one lower-resolution output derived from every
+// source. Dimensions are rounded down to even numbers.
+type Rendition struct {
+	// Width and Height of the output. If one of them is 0 it is derived
+	// from the source aspect ratio.
+	Width  int
+	Height int
+	// FPS caps the output frame rate; 0 keeps the source rate.
+	FPS int
+	// Bitrate in bits per second; 0 lets the encoder choose by quality.
+	Bitrate uint
+}
+
+// RenditionSourceID is the default SourceID of a rendition's packets: the
+// source ID with "_<height>p" appended, e.g. "rtsp://cam/main_360p", or
+// "_<width>w" for renditions given by width only.
+func RenditionSourceID(sourceID string, r Rendition) string {
+	if r.Height <= 0 {
+		return fmt.Sprintf("%s_%dw", sourceID, r.Width)
+	}
+	return fmt.Sprintf("%s_%dp", sourceID, r.Height)
+}
+
+type Option func(*config)
+
+type config struct {
+	log         logger.Logger
+	name        string
+	passthrough bool
+	sourceIDFn  func(sourceID string, r Rendition) string
+
+	audioDecoders map[gomedia.CodecType]func() decoder.InnerAudioDecoder
+	audioEncoder  func() encoder.InnerAudioEncoder
+}
+
+func WithLogger(l logger.Logger) Option {
+	return func(c *config) { c.log = l }
+}
+
+func WithName(name string) Option {
+	return func(c *config) { c.name = name }
+}
+
+// WithPassthrough controls whether input packets are forwarded unchanged on
+// Output alongside the transcoded ones (default true).
+func WithPassthrough(enabled bool) Option {
+	return func(c *config) { c.passthrough = enabled }
+}
+
+// WithSourceIDFunc overrides how rendition SourceIDs are derived
+// (default RenditionSourceID).
+func WithSourceIDFunc(fn func(sourceID string, r Rendition) string) Option {
+	return func(c *config) { c.sourceIDFn = fn }
+}
+
+// videoTranscoder decodes the video of every added source once, scales the
+// frames to each rendition and re-encodes them. Audio of a transcoded source
+// is shared (Clone(false)) onto every rendition so each one is a complete
+// stream for the HLS and WebRTC writers.
+type videoTranscoder struct {
+	lifecycle.AsyncManager[*videoTranscoder]
+	config
+	chanSize   int
+	renditions []Rendition
+	decoders   map[gomedia.CodecType]func() decoder.InnerVideoDecoder
+	newEncoder func() encoder.InnerVideoEncoder
+
+	inpPktCh chan gomedia.Packet
+	outPktCh chan gomedia.Packet
+	addSrcCh chan string
+	rmSrcCh  chan string
+
+	// sources holds every added source; the value stays nil until its
+	// first video packet reveals the resolution.
+	sources map[string]*videoSource
+	wg      sync.WaitGroup
+}
+
+// NewVideo returns a transcoder producing the given renditions for every
+// source added through AddSource; packets of other sources are only passed
+// through. Renditions that are not smaller than a source are skipped for it.
+// decoders and newEncoder select the backends, e.g. the decoder/video/cpu
+// decoders and encoder/video/cpu.NewFFmpegCPUEncoder.
+func NewVideo(chanSize int, renditions []Rendition,
+	decoders map[gomedia.CodecType]func() decoder.InnerVideoDecoder,
+	newEncoder func() encoder.InnerVideoEncoder, opts ...Option) Transcoder {
+	t := &videoTranscoder{
+		config: config{
+			log:         logger.Default,
+			passthrough: true,
+			sourceIDFn:  RenditionSourceID,
+		},
+		chanSize:   chanSize,
+		renditions: renditions,
+		decoders:   decoders,
+		newEncoder: newEncoder,
+		inpPktCh:   make(chan gomedia.Packet, chanSize),
+		outPktCh:   make(chan gomedia.Packet, chanSize),
+		addSrcCh:   make(chan string, chanSize),
+		rmSrcCh:    make(chan string, chanSize),
+		sources:    map[string]*videoSource{},
+	}
+	for _, o := range opts {
+		o(&t.config)
+	}
+	t.AsyncManager = lifecycle.NewFailSafeAsyncManager(t, t.log)
+	return t
+}
+
+func (t *videoTranscoder) Write() {
+	// FailSafeAsyncManager.Start never returns an error.
+	_ = t.Start(func(*videoTranscoder) error { return nil })
+}
+
+func (t *videoTranscoder) Step(stopCh <-chan struct{}) error {
+	select {
+	case <-stopCh:
+		return &lifecycle.BreakError{}
+	case url := <-t.addSrcCh:
+		if _, ok := t.sources[url]; !ok {
+			t.log.Infof(t, "Adding source %s", url)
+			t.sources[url] = nil
+		}
+	case url := <-t.rmSrcCh:
+		if src, ok := t.sources[url]; ok {
+			t.log.Infof(t, "Removing source %s", url)
+			t.stopSource(src)
+			delete(t.sources, url)
+		}
+	case pkt := <-t.inpPktCh:
+		if pkt == nil {
+			return &utils.NilPacketError{}
+		}
+		if err := t.processPacket(pkt, stopCh); err != nil {
+			return err
+		}
+		if !t.passthrough {
+			pkt.Release()
+			return nil
+		}
+		return t.emit(pkt, stopCh)
+	}
+	return nil
+}
+
+func (t *videoTranscoder) processPacket(pkt gomedia.Packet, stopCh <-chan struct{}) error {
+	src, ok := t.sources[pkt.SourceID()]
+	if !ok {
+		return nil
+	}
+	switch p := pkt.(type) {
+	case gomedia.VideoPacket:
+		if src == nil || !src.sameSize(p.CodecParameters()) {
+			t.stopSource(src)
+			src = t.startSource(pkt.SourceID(), p.CodecParameters())
+			t.sources[pkt.SourceID()] = src
+		}
+		return src.feed(p, stopCh)
+	case gomedia.AudioPacket:
+		if src == nil {
+			return nil
+		}
+		for _, r := range src.renditions {
+			clone := p.Clone(false)
+			clone.SetSourceID(r.id)
+			if err := t.emit(clone, stopCh); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (t *videoTranscoder) emit(pkt gomedia.Packet, stopCh <-chan struct{}) error {
+	select {
+	case t.outPktCh <- pkt:
+		return nil
+	case <-stopCh:
+		pkt.Release()
+		return &lifecycle.BreakError{}
+	}
+}
+
+// startSource builds the decoder and one scaler/encoder per applicable
+// rendition for a source with the given parameters.
+func (t *videoTranscoder) startSource(id string, par gomedia.VideoCodecParameters) *videoSource {
+	src := &videoSource{
+		id:       id,
+		codecPar: par,
+		width:    int(par.Width()),  //nolint:gosec
+		height:   int(par.Height()), //nolint:gosec
+		done:     make(chan struct{}),
+	}
+	fps := int(par.FPS()) //nolint:gosec
+	if fps <= 0 {
+		fps = defaultFPS
+	}
+
+	for _, r := range t.renditions {
+		w, h, ok := renditionSize(r, src.width, src.height)
+		if !ok {
+			t.log.Debugf(t, "Skipping rendition %+v for %dx%d source %s", r, src.width, src.height, id)
+			continue
+		}
+		outFPS := fps
+		if r.FPS > 0 && r.FPS < fps {
+			outFPS = r.FPS
+		}
+		outID := t.sourceIDFn(id, r)
+		enc := encoder.NewVideoEncoder(t.chanSize, outFPS, t.newEncoder,
+			encoder.VideoWithName(outID),
+			encoder.VideoWithLogger(t.log),
+			encoder.VideoWithBitrate(r.Bitrate),
+			encoder.VideoWithStreamIndex(par.StreamIndex()))
+		enc.Encode()
+		src.renditions = append(src.renditions, &videoRendition{
+			id:       outID,
+			pool:     rgb.NewFramePool(w, h),
+			enc:      enc,
+			frameDur: time.Second / time.Duration(outFPS),
+		})
+	}
+	if len(src.renditions) == 0 {
+		return src
+	}
+
+	t.log.Infof(t, "Transcoding %dx%d source %s into %d renditions", src.width, src.height, id, len(src.renditions))
+	src.dec = decoder.NewVideo(t.chanSize, -1, t.decoders,
+		decoder.VideoWithName(id), decoder.VideoWithLogger(t.log))
+	src.dec.Decode()
+
+	src.imagesDone = make(chan struct{})
+	go src.scaleImages()
+	for _, r := range src.renditions {
+		t.wg.Add(1)
+		go func() {
+			defer t.wg.Done()
+			for pkt := range r.enc.Packets() {
+				select {
+				case t.outPktCh <- pkt:
+				case <-src.done:
+					pkt.Release()
+				}
+			}
+		}()
+	}
+	return src
+}
+
+// stopSource tears a source pipeline down. The scaler goroutine is joined
+// before the encoders close, since closing an encoder closes its input.
+func (t *videoTranscoder) stopSource(src *videoSource) {
+	if src == nil {
+		return
+	}
+	close(src.done)
+	if src.dec == nil {
+		return
+	}
+	src.dec.Close()
+	<-src.imagesDone
+	for _, r := range src.renditions {
+		r.enc.Close()
+	}
+}
+
+// renditionSize resolves the output size of r for a w x h source and
+// reports whether it is a downscale.
+func renditionSize(r Rendition, w, h int) (int, int, bool) {
+	outW, outH := r.Width, r.Height
+	switch {
+	case w <= 0 || h <= 0 || (outW <= 0 && outH <= 0):
+		return 0, 0, false
+	case outW <= 0:
+		outW = w * outH / h
+	case outH <= 0:
+		outH = h * outW / w
+	}
+	outW, outH = outW&^1, outH&^1
+	if outW < 2 || outH < 2 || outW > w || outH > h || (outW == w && outH == h) {
+		return 0, 0, false
+	}
+	return outW, outH, true
+}
+
+func (t *videoTranscoder) Release() { //nolint:revive // required by lifecycle.AsyncInstance interface
+	for url, src := range t.sources {
+		t.stopSource(src)
+		delete(t.sources, url)
+	}
+	t.wg.Wait()
+	for {
+		select {
+		case pkt, ok := <-t.inpPktCh:
+			if !ok {
+				goto drained
+			}
+			if pkt != nil {
+				pkt.Release()
+			}
+		default:
+			close(t.inpPktCh)
+			goto drained
+		}
+	}
+drained:
+	close(t.outPktCh)
+}
+
+func (t *videoTranscoder) String() string {
+	return fmt.Sprintf("VIDEO_TRANSCODER %s", t.name)
+}
+
+func (t *videoTranscoder) Packets() chan<- gomedia.Packet {
+	return t.inpPktCh
+}
+
+func (t *videoTranscoder) Output() <-chan gomedia.Packet {
+	return t.outPktCh
+}
+
+func (t *videoTranscoder) AddSource() chan<- string {
+	return t.addSrcCh
+}
+
+func (t *videoTranscoder) RemoveSource() chan<- string {
+	return t.rmSrcCh
+}
+
+type frameTiming struct {
+	ts    time.Duration
+	start time.Time
+}
+
+// videoSource is the decode side of one transcoded source. The decoder does
+// not carry timestamps through, so the timing of every packet fed to it is
+// queued and matched to the decoded images in presentation order.
+type videoSource struct {
+	id            string
+	codecPar      gomedia.VideoCodecParameters
+	width, height int
+	hasKey        bool
+	dec           gomedia.VideoDecoder
+	renditions    []*videoRendition
+	done          chan struct{}
+	imagesDone    chan struct{}
+
+	mu      sync.Mutex
+	timings []frameTiming // sorted by ts
+}
+
+type videoRendition struct {
+	id       string
+	pool     *rgb.FramePool
+	enc      gomedia.VideoEncoder
+	frameDur time.Duration
+	lastTS   time.Duration
+	hasLast  bool
+}
+
+func (src *videoSource) sameSize(par gomedia.VideoCodecParameters) bool {
+	return int(par.Width()) == src.width && int(par.Height()) == src.height //nolint:gosec
+}
+
+// feed hands a clone of pkt to the decoder, mirroring the decoder's own
+// rule of ignoring everything before the first key frame after a codec
+// change so that queued timings stay aligned with the decoded images.
+func (src *videoSource) feed(pkt gomedia.VideoPacket, stopCh <-chan struct{}) error {
+	if src.dec == nil {
+		return nil
+	}
+	if pkt.CodecParameters() != src.codecPar {
+		src.codecPar = pkt.CodecParameters()
+		src.hasKey = false
+		src.mu.Lock()
+		src.timings = src.timings[:0]
+		src.mu.Unlock()
+	}
+	if !src.hasKey && !pkt.IsKeyFrame() {
+		return nil
+	}
+	src.hasKey = true
+
+	clone, _ := pkt.Clone(false).(gomedia.VideoPacket)
+	src.pushTiming(frameTiming{ts: pkt.Timestamp(), start: pkt.StartTime()})
+	select {
+	case src.dec.Packets() <- clone:
+		return nil
+	case <-stopCh:
+		clone.Release()
+		return &lifecycle.BreakError{}
+	}
+}
+
+func (src *videoSource) pushTiming(ft frameTiming) {
+	src.mu.Lock()
+	defer src.mu.Unlock()
+	i, _ := slices.BinarySearchFunc(src.timings, ft.ts, func(e frameTiming, ts time.Duration) int {
+		return cmp.Compare(e.ts, ts)
+	})
+	src.timings = slices.Insert(src.timings, i, ft)
+	if len(src.timings) > maxPendingTimings {
+		src.timings = src.timings[1:]
+	}
+}
+
+func (src *videoSource) popTiming() (frameTiming, bool) {
+	src.mu.Lock()
+	defer src.mu.Unlock()
+	if len(src.timings) == 0 {
+		return frameTiming{}, false
+	}
+	ft := src.timings[0]
+	src.timings = src.timings[1:]
+	return ft, true
+}
+
+// scaleImages runs until the decoder output closes or the source stops,
+// scaling each decoded image into every rendition due for a frame.
+func (src *videoSource) scaleImages() {
+	defer close(src.imagesDone)
+	for img := range src.dec.Images() {
+		ft, ok := src.popTiming()
+		if !ok {
+			img.Release()
+			continue
+		}
+		if !src.encode(img.GetRGB(), ft) {
+			img.Release()
+			return
+		}
+		img.Release()
+	}
+}
+
+func (src *videoSource) encode(img *rgb.RGB, ft frameTiming) bool {
+	for _, r := range src.renditions {
+		if r.hasLast && ft.ts > r.lastTS && ft.ts-r.lastTS < r.frameDur-r.frameDur/4 {
+			continue // frame rate cap
+		}
+		r.lastTS, r.hasLast = ft.ts, true
+
+		dst := r.pool.Get()
+		rgb.Scale(dst, img)
+		frame := gomedia.VideoFrame{Image: dst, Timestamp: ft.ts, StartTime: ft.start, SourceID: r.id}
+		select {
+		case r.enc.Frames() <- frame:
+		case <-src.done:
+			dst.Release()
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,207 @@
+package transcode
+
+import (
+	"image"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ugparu/gomedia"
+	"github.com/ugparu/gomedia/codec/mjpeg"
+	"github.com/ugparu/gomedia/codec/pcm"
+	"github.com/ugparu/gomedia/decoder"
+	"github.com/ugparu/gomedia/encoder"
+	"github.com/ugparu/gomedia/frame/rgb"
+)
+
+const testSource = "rtsp://cam/main"
+
+// fakeDecoder returns a blank frame of the stream size for every packet.
+type fakeDecoder struct {
+	w, h int
+}
+
+func (d *fakeDecoder) Init(par gomedia.VideoCodecParameters) error {
+	d.w, d.h = int(par.Width()), int(par.Height()) //nolint:gosec
+	return nil
+}
+
+func (d *fakeDecoder) Feed(gomedia.VideoPacket) error { return nil }
+
+func (d *fakeDecoder) Decode(gomedia.VideoPacket) (rgb.ReleasableImage, error) {
+	return rgb.NewRGB(image.Rect(0, 0, d.w, d.h)), nil
+}
+
+func (d *fakeDecoder) Close() {}
+
+// fakeEncoder emits one MJPEG packet per frame and records the frame sizes
+// it was initialised with.
+type fakeEncoder struct {
+	mu    *sync.Mutex
+	sizes *[]image.Point
+	par   *mjpeg.CodecParameters
+}
+
+func (e *fakeEncoder) Init(params gomedia.VideoEncoderParameters) error {
+	e.mu.Lock()
+	*e.sizes = append(*e.sizes, image.Pt(params.Width, params.Height))
+	e.mu.Unlock()
+	e.par = mjpeg.NewCodecParameters(uint(params.Width), uint(params.Height), uint(params.FPS)) //nolint:gosec
+	return nil
+}
+
+func (e *fakeEncoder) Encode(frame gomedia.VideoFrame) ([]gomedia.VideoPacket, error) {
+	if frame.Image.Bounds().Dx() != int(e.par.Width()) { //nolint:gosec
+		panic("frame not scaled to rendition size")
+	}
+	return []gomedia.VideoPacket{
+		mjpeg.NewPacket(true, frame.Timestamp, frame.StartTime, []byte{0xff, 0xd8}, frame.SourceID, e.par),
+	}, nil
+}
+
+func (e *fakeEncoder) Close() {}
+
+func newTestTranscoder(t *testing.T, renditions []Rendition, opts ...Option) (Transcoder, *[]image.Point) {
+	t.Helper()
+	var mu sync.Mutex
+	sizes := &[]image.Point{}
+	tr := NewVideo(16, renditions, //nolint:mnd
+		map[gomedia.CodecType]func() decoder.InnerVideoDecoder{
+			gomedia.MJPEG: func() decoder.InnerVideoDecoder { return &fakeDecoder{} },
+		},
+		func() encoder.InnerVideoEncoder { return &fakeEncoder{mu: &mu, sizes: sizes} },
+		opts...)
+	tr.Write()
+	t.Cleanup(tr.Close)
+	return tr, sizes
+}
+
+// addSource registers id and gives Step time to take it before any packet,
+// since the two channels are not ordered relative to each other.
+func addSource(tr Transcoder, id string) {
+	tr.AddSource() <- id
+	time.Sleep(50 * time.Millisecond) //nolint:mnd
+}
+
+func videoPacket(par *mjpeg.CodecParameters, source string, ts time.Duration) gomedia.Packet {
+	return mjpeg.NewPacket(true, ts, time.Now(), []byte{0xff, 0xd8}, source, par)
+}
+
+// collect reads Output until want packets arrived, grouped by SourceID.
+func collect(t *testing.T, tr Transcoder, want int) map[string][]gomedia.Packet {
+	t.Helper()
+	got := map[string][]gomedia.Packet{}
+	for range want {
+		select {
+		case pkt := <-tr.Output():
+			got[pkt.SourceID()] = append(got[pkt.SourceID()], pkt)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out, got %v", got)
+		}
+	}
+	return got
+}
+
+func TestVideoTranscoder_ProducesRenditions(t *testing.T) {
+	tr, sizes := newTestTranscoder(t, []Rendition{{Height: 360}, {Width: 320, Height: 180}})
+	addSource(tr, testSource)
+
+	par := mjpeg.NewCodecParameters(1280, 720, 25) //nolint:mnd
+	for i := range 3 {
+		tr.Packets() <- videoPacket(par, testSource, time.Duration(i)*40*time.Millisecond)
+	}
+
+	// 3 passthrough + 3 per rendition.
+	got := collect(t, tr, 9) //nolint:mnd
+	require.Len(t, got[testSource], 3)
+	for _, id := range []string{testSource + "_360p", testSource + "_180p"} {
+		pkts := got[id]
+		require.Len(t, pkts, 3, id)
+		for i, pkt := range pkts {
+			require.Equal(t, time.Duration(i)*40*time.Millisecond, pkt.Timestamp(), id)
+		}
+	}
+	require.ElementsMatch(t, []image.Point{{640, 360}, {320, 180}}, *sizes)
+}
+
+func TestVideoTranscoder_AudioFollowsRenditions(t *testing.T) {
+	tr, _ := newTestTranscoder(t, []Rendition{{Height: 360}})
+	addSource(tr, testSource)
+
+	tr.Packets() <- videoPacket(mjpeg.NewCodecParameters(1280, 720, 25), testSource, 0) //nolint:mnd
+	apar := pcm.NewCodecParameters(1, gomedia.PCMAlaw, 1, 8000)                         //nolint:mnd
+	tr.Packets() <- pcm.NewPacket([]byte{1, 2}, 0, testSource, time.Now(), apar, 20*time.Millisecond)
+
+	got := collect(t, tr, 4) //nolint:mnd
+	require.Len(t, got[testSource], 2)
+	require.Len(t, got[testSource+"_360p"], 2)
+	var audio int
+	for _, pkt := range got[testSource+"_360p"] {
+		if _, ok := pkt.(gomedia.AudioPacket); ok {
+			audio++
+		}
+	}
+	require.Equal(t, 1, audio)
+}
+
+func TestVideoTranscoder_UnknownSourcePassesThrough(t *testing.T) {
+	tr, sizes := newTestTranscoder(t, []Rendition{{Height: 360}})
+
+	tr.Packets() <- videoPacket(mjpeg.NewCodecParameters(1280, 720, 25), "other", 0) //nolint:mnd
+	got := collect(t, tr, 1)
+	require.Len(t, got["other"], 1)
+
+	select {
+	case pkt := <-tr.Output():
+		t.Fatalf("unexpected packet %v", pkt)
+	case <-time.After(100 * time.Millisecond):
+	}
+	require.Empty(t, *sizes)
+}
+
+func TestVideoTranscoder_WithoutPassthrough(t *testing.T) {
+	tr, _ := newTestTranscoder(t, []Rendition{{Height: 360}}, WithPassthrough(false),
+		WithSourceIDFunc(func(src string, r Rendition) string { return src + "/low" }))
+	addSource(tr, testSource)
+
+	tr.Packets() <- videoPacket(mjpeg.NewCodecParameters(1280, 720, 25), testSource, 0) //nolint:mnd
+	got := collect(t, tr, 1)
+	require.Len(t, got[testSource+"/low"], 1)
+}
+
+func TestVideoTranscoder_CloseClosesOutput(t *testing.T) {
+	tr, _ := newTestTranscoder(t, []Rendition{{Height: 360}})
+	addSource(tr, testSource)
+	tr.Packets() <- videoPacket(mjpeg.NewCodecParameters(1280, 720, 25), testSource, 0) //nolint:mnd
+	collect(t, tr, 2)                                                                   //nolint:mnd
+
+	tr.Close()
+	for range tr.Output() { //nolint:revive // drain until closed
+	}
+}
+
+func TestRenditionSize(t *testing.T) {
+	tests := []struct {
+		r      Rendition
+		w, h   int
+		ow, oh int
+		ok     bool
+	}{
+		{Rendition{Height: 360}, 1920, 1080, 640, 360, true},
+		{Rendition{Width: 640}, 1920, 1080, 640, 360, true},
+		{Rendition{Height: 361}, 1920, 1080, 640, 360, true},
+		{Rendition{Height: 1080}, 1920, 1080, 0, 0, false},
+		{Rendition{Height: 2160}, 1920, 1080, 0, 0, false},
+		{Rendition{}, 1920, 1080, 0, 0, false},
+	}
+	for _, tt := range tests {
+		ow, oh, ok := renditionSize(tt.r, tt.w, tt.h)
+		require.Equal(t, tt.ok, ok, "%+v", tt.r)
+		if ok {
+			require.Equal(t, tt.ow, ow, "%+v", tt.r)
+			require.Equal(t, tt.oh, oh, "%+v", tt.r)
+		}
+	}
+}
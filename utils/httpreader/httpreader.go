@@ -0,0 +1,204 @@
+// Package httpreader exposes a remote HTTP resource as an io.ReadSeeker and
+// io.ReaderAt backed by Range requests, so container demuxers can read
+// archive files from HTTP servers or pre-signed object-storage URLs without
+// downloading them first.
+package httpreader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const defaultBlockSize = 256 << 10 // one Range request covers many small samples
+
+// ErrRangeNotSupported is returned by New when the server ignores Range
+// requests and answers with the full body.
+var ErrRangeNotSupported = errors.New("httpreader: server does not support range requests")
+
+// Option is a functional option for configuring a Reader.
+type Option func(*Reader)
+
+// WithClient sets the HTTP client used for requests (default http.DefaultClient).
+func WithClient(c *http.Client) Option {
+	return func(r *Reader) { r.client = c }
+}
+
+// WithHeader adds a header to every request, e.g. Authorization.
+func WithHeader(key, value string) Option {
+	return func(r *Reader) { r.header.Add(key, value) }
+}
+
+// WithBlockSize sets how many bytes each Range request fetches. Reads inside
+// the most recently fetched block are served from memory; larger reads are
+// fetched in a single request of their own size.
+func WithBlockSize(n int) Option {
+	return func(r *Reader) {
+		if n > 0 {
+			r.blockSize = n
+		}
+	}
+}
+
+// Reader reads a remote resource through HTTP Range requests. ReadAt is safe
+// for concurrent use; Read and Seek share a cursor and are not.
+type Reader struct {
+	ctx       context.Context
+	client    *http.Client
+	url       string
+	header    http.Header
+	blockSize int
+	size      int64
+	offset    int64
+
+	mu         sync.Mutex
+	block      []byte
+	blockStart int64
+}
+
+// New probes url with a one-byte Range request to learn the resource size
+// and confirm range support. ctx bounds every request made by the Reader.
+func New(ctx context.Context, url string, opts ...Option) (*Reader, error) {
+	r := &Reader{
+		ctx:       ctx,
+		client:    http.DefaultClient,
+		url:       url,
+		header:    make(http.Header),
+		blockSize: defaultBlockSize,
+	}
+	for _, o := range opts {
+		o(r)
+	}
+
+	resp, err := r.get(0, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if r.size, err = totalSize(resp.Header.Get("Content-Range")); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Size returns the total length of the remote resource.
+func (r *Reader) Size() int64 { return r.size }
+
+func (r *Reader) get(first, last int64) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(r.ctx, http.MethodGet, r.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range r.header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", first, last))
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		return resp, nil
+	case http.StatusOK:
+		resp.Body.Close()
+		return nil, ErrRangeNotSupported
+	case http.StatusRequestedRangeNotSatisfiable:
+		resp.Body.Close()
+		return nil, io.EOF
+	default:
+		resp.Body.Close()
+		return nil, fmt.Errorf("httpreader: GET %s: unexpected status %s", r.url, resp.Status)
+	}
+}
+
+// totalSize extracts the complete length from "bytes first-last/total".
+func totalSize(contentRange string) (int64, error) {
+	_, total, ok := strings.Cut(contentRange, "/")
+	if !ok || total == "*" {
+		return 0, fmt.Errorf("httpreader: unusable Content-Range %q", contentRange)
+	}
+	size, err := strconv.ParseInt(total, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("httpreader: unusable Content-Range %q: %w", contentRange, err)
+	}
+	return size, nil
+}
+
+// fetch reads [off, off+n) from the server into a new slice.
+func (r *Reader) fetch(off int64, n int) ([]byte, error) {
+	resp, err := r.get(off, off+int64(n)-1)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	buf := make([]byte, n)
+	read, err := io.ReadFull(resp.Body, buf)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		err = nil
+	}
+	return buf[:read], err
+}
+
+// ReadAt implements io.ReaderAt.
+func (r *Reader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("httpreader: negative offset")
+	}
+	if off >= r.size {
+		return 0, io.EOF
+	}
+	want := int(min(int64(len(p)), r.size-off))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if off < r.blockStart || off+int64(want) > r.blockStart+int64(len(r.block)) {
+		n := int(min(int64(max(want, r.blockSize)), r.size-off))
+		block, err := r.fetch(off, n)
+		if err != nil {
+			return 0, err
+		}
+		r.block, r.blockStart = block, off
+	}
+	n := copy(p, r.block[off-r.blockStart:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Read implements io.Reader.
+func (r *Reader) Read(p []byte) (int, error) {
+	n, err := r.ReadAt(p, r.offset)
+	r.offset += int64(n)
+	if n > 0 && errors.Is(err, io.EOF) {
+		err = nil
+	}
+	return n, err
+}
+
+// Seek implements io.Seeker.
+func (r *Reader) Seek(offset int64, whence int) (int64, error) {
+	var abs int64
+	switch whence {
+	case io.SeekStart:
+		abs = offset
+	case io.SeekCurrent:
+		abs = r.offset + offset
+	case io.SeekEnd:
+		abs = r.size + offset
+	default:
+		return 0, errors.New("httpreader: invalid whence")
+	}
+	if abs < 0 {
+		return 0, errors.New("httpreader: negative position")
+	}
+	r.offset = abs
+	return abs, nil
+}
@@ -0,0 +1,88 @@
+package httpreader
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newRangeServer(t *testing.T, data []byte, requests *atomic.Int32) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests.Add(1)
+		http.ServeContent(w, req, "clip.mp4", time.Time{}, bytes.NewReader(data))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestReader_ReadSeek(t *testing.T) {
+	t.Parallel()
+	data := make([]byte, 10000)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	var requests atomic.Int32
+	srv := newRangeServer(t, data, &requests)
+
+	r, err := New(context.Background(), srv.URL, WithBlockSize(4096))
+	require.NoError(t, err)
+	require.Equal(t, int64(len(data)), r.Size())
+
+	got, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, data, got)
+
+	pos, err := r.Seek(-100, io.SeekEnd)
+	require.NoError(t, err)
+	require.Equal(t, int64(9900), pos)
+	tail := make([]byte, 200)
+	n, err := io.ReadFull(r, tail)
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	require.Equal(t, data[9900:], tail[:n])
+}
+
+func TestReader_ReadAtServedFromBlock(t *testing.T) {
+	t.Parallel()
+	data := bytes.Repeat([]byte("0123456789"), 1000)
+	var requests atomic.Int32
+	srv := newRangeServer(t, data, &requests)
+
+	r, err := New(context.Background(), srv.URL, WithBlockSize(1000))
+	require.NoError(t, err)
+	probe := requests.Load()
+
+	buf := make([]byte, 10)
+	for off := int64(2000); off < 2990; off += 10 {
+		_, err = r.ReadAt(buf, off)
+		require.NoError(t, err)
+		require.Equal(t, data[off:off+10], buf)
+	}
+	require.Equal(t, probe+1, requests.Load(), "reads within one block need a single request")
+
+	big := make([]byte, 5000)
+	_, err = r.ReadAt(big, 4000)
+	require.NoError(t, err)
+	require.Equal(t, data[4000:9000], big)
+
+	_, err = r.ReadAt(buf, int64(len(data)))
+	require.ErrorIs(t, err, io.EOF)
+}
+
+func TestNew_RangeNotSupported(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("whole body"))
+	}))
+	t.Cleanup(srv.Close)
+
+	_, err := New(context.Background(), srv.URL)
+	require.ErrorIs(t, err, ErrRangeNotSupported)
+}
@@ -126,3 +126,36 @@ func SplitNALUs(b []byte, dst [][]byte) (nalus [][]byte, typ int) {
 	// If none of the formats match, consider it as a single raw NALU.
 	return append(nalus, b), naluRaw
 }
+
+// SplitAnnexB splits start-code framed data (as produced by encoders) into
+// NALUs. Unlike SplitNALUs it never tries the AVCC interpretation, which is
+// ambiguous for a leading 00 00 00 01 start code.
+func SplitAnnexB(b []byte, dst [][]byte) [][]byte {
+	for pos := 0; pos < len(b); pos++ {
+		if startCodeLength, found := isStartCode(b, pos); found {
+			if startCodeLength == 3 { //nolint:mnd
+				return parseANNEXB(b[pos:], 1, 0, dst[:0])
+			}
+			return parseANNEXB(b[pos:], 0, 1, dst[:0])
+		}
+	}
+	return dst[:0]
+}
+
+// AnnexBToAVCC rewrites start-code framed data as 4-byte length-prefixed
+// NALUs, the layout carried by gomedia H.264/H.265 packets.
+func AnnexBToAVCC(b []byte) []byte {
+	nalus := SplitAnnexB(b, nil)
+	size := 0
+	for _, nalu := range nalus {
+		size += MinNaluSize + len(nalu)
+	}
+	out := make([]byte, size)
+	pos := 0
+	for _, nalu := range nalus {
+		pio.PutU32BE(out[pos:], uint32(len(nalu))) //nolint:gosec
+		pos += MinNaluSize
+		pos += copy(out[pos:], nalu)
+	}
+	return out
+}
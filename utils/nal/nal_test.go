@@ -112,6 +112,35 @@ func TestSplitNALUs_AnnexB_SingleNALU(t *testing.T) {
 	assert.Equal(t, []byte{0x65, 0xAA, 0xBB, 0xCC}, nalus[0])
 }
 
+// SplitAnnexB / AnnexBToAVCC
+
+func TestSplitAnnexB_4ByteStartCode(t *testing.T) {
+	// The input SplitNALUs misreads as AVCC is split on start codes here.
+	b := []byte{
+		0x00, 0x00, 0x00, 0x01, 0x67, 0x42, 0xAA, 0xBB, 0xCC,
+		0x00, 0x00, 0x01, 0x68, 0xCE, 0xDD,
+		0x00, 0x00, 0x00, 0x01, 0x65, 0x88, 0x84,
+	}
+	nalus := SplitAnnexB(b, nil)
+	assert.Equal(t, [][]byte{
+		{0x67, 0x42, 0xAA, 0xBB, 0xCC},
+		{0x68, 0xCE, 0xDD},
+		{0x65, 0x88, 0x84},
+	}, nalus)
+	assert.Empty(t, SplitAnnexB([]byte{0x67, 0x42, 0xAA}, nil))
+}
+
+func TestAnnexBToAVCC(t *testing.T) {
+	b := []byte{
+		0x00, 0x00, 0x00, 0x01, 0x67, 0x42, 0xAA, 0xBB, 0xCC,
+		0x00, 0x00, 0x01, 0x68, 0xCE, 0xDD,
+	}
+	assert.Equal(t, []byte{
+		0x00, 0x00, 0x00, 0x05, 0x67, 0x42, 0xAA, 0xBB, 0xCC,
+		0x00, 0x00, 0x00, 0x03, 0x68, 0xCE, 0xDD,
+	}, AnnexBToAVCC(b))
+}
+
 // SplitNALUs — AVCC format
 
 func TestSplitNALUs_AVCC_SingleNALU(t *testing.T) {
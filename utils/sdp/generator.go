@@ -79,7 +79,7 @@ func marshalMedia(m Media) []string {
 	lines = append(lines, fmt.Sprintf("m=%s 0 RTP/AVP %d", av, pt))
 
 	// a=rtpmap:<pt> <encoding>/<clock>[/<channels>]
-	enc := rtpmapEncoding(m.Type)
+	enc := rtpmapEncoding(m)
 	if enc != "" {
 		if isAudio(m.Type) {
 			ch := m.ChannelCount
@@ -173,7 +173,7 @@ func defaultAVType(ct gomedia.CodecType) string {
 
 func isAudio(ct gomedia.CodecType) bool {
 	switch ct {
-	case gomedia.AAC, gomedia.OPUS, gomedia.PCM, gomedia.PCMAlaw, gomedia.PCMUlaw:
+	case gomedia.AAC, gomedia.OPUS, gomedia.PCM, gomedia.PCMAlaw, gomedia.PCMUlaw, gomedia.G722, gomedia.G726:
 		return true
 	default:
 		return false
@@ -189,8 +189,8 @@ func isVideo(ct gomedia.CodecType) bool {
 	}
 }
 
-func rtpmapEncoding(ct gomedia.CodecType) string {
-	switch ct {
+func rtpmapEncoding(m Media) string {
+	switch m.Type {
 	case gomedia.AAC:
 		return "MPEG4-GENERIC"
 	case gomedia.OPUS:
@@ -201,6 +201,18 @@ func rtpmapEncoding(ct gomedia.CodecType) string {
 		return "PCMA"
 	case gomedia.PCMUlaw:
 		return "PCMU"
+	case gomedia.G722:
+		return "G722"
+	case gomedia.G726:
+		bitrate := m.Bitrate
+		if bitrate == 0 {
+			bitrate = 32000
+		}
+		enc := fmt.Sprintf("G726-%d", bitrate/1000)
+		if m.AAL2 {
+			enc = "AAL2-" + enc
+		}
+		return enc
 	case gomedia.H264:
 		return "H264"
 	case gomedia.H265:
@@ -221,7 +233,7 @@ func defaultTimeScale(ct gomedia.CodecType) int {
 		return 48000
 	case gomedia.AAC:
 		return 48000
-	case gomedia.PCM, gomedia.PCMAlaw, gomedia.PCMUlaw:
+	case gomedia.PCM, gomedia.PCMAlaw, gomedia.PCMUlaw, gomedia.G722, gomedia.G726:
 		return 8000
 	default:
 		return 90000
@@ -246,6 +258,8 @@ func defaultPayloadType(ct gomedia.CodecType) int {
 		return 0
 	case gomedia.PCMAlaw:
 		return 8
+	case gomedia.G722:
+		return 9
 	default:
 		return 96
 	}
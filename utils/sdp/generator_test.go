@@ -2,6 +2,7 @@ package sdp
 
 import (
 	"bytes"
+	"strings"
 	"testing"
 
 	"github.com/ugparu/gomedia"
@@ -200,3 +201,35 @@ func TestGenerate_ParseRoundtrip_G711(t *testing.T) {
 		})
 	}
 }
+
+func TestGenerate_ParseRoundtrip_G722G726(t *testing.T) {
+	in := []Media{
+		{AVType: "audio", Type: gomedia.G722, Control: "trackID=1"},
+		{AVType: "audio", Type: gomedia.G726, PayloadType: 97, Bitrate: 24000, AAL2: true, Control: "trackID=2"},
+	}
+	out := Generate(Session{}, in)
+	if !strings.Contains(out, "a=rtpmap:9 G722/8000/1") || !strings.Contains(out, "a=rtpmap:97 AAL2-G726-24/8000/1") {
+		t.Fatalf("unexpected rtpmap lines:\n%s", out)
+	}
+	_, medias := Parse(out)
+	if len(medias) != 2 {
+		t.Fatalf("expected 2 medias, got %d", len(medias))
+	}
+	if medias[0].Type != gomedia.G722 || medias[0].PayloadType != 9 || medias[0].TimeScale != 8000 {
+		t.Fatalf("G722 media: %+v", medias[0])
+	}
+	if m := medias[1]; m.Type != gomedia.G726 || m.Bitrate != 24000 || !m.AAL2 || m.ChannelCount != 1 {
+		t.Fatalf("G726 media: %+v", m)
+	}
+}
+
+func TestGenerate_ParseRoundtrip_L16(t *testing.T) {
+	out := Generate(Session{}, []Media{{AVType: "audio", Type: gomedia.PCM, TimeScale: 16000, ChannelCount: 1}})
+	if !strings.Contains(out, "a=rtpmap:96 L16/16000/1") {
+		t.Fatalf("unexpected rtpmap line:\n%s", out)
+	}
+	_, medias := Parse(out)
+	if len(medias) != 1 || medias[0].Type != gomedia.PCM || medias[0].TimeScale != 16000 || medias[0].ChannelCount != 1 {
+		t.Fatalf("L16 media: %+v", medias)
+	}
+}
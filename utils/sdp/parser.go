@@ -33,6 +33,10 @@ type Media struct {
 	IndexLength        int
 	Width              int
 	Height             int
+	// Bitrate is taken from encoding names that carry it (G726-32 is
+	// 32000); AAL2 marks G.726 code words packed most significant first.
+	Bitrate int
+	AAL2    bool
 }
 
 func parseMediaDescription(fields []string) (*Media, bool) {
@@ -58,14 +62,29 @@ func parseMediaDescription(fields []string) (*Media, bool) {
 
 		// RFC 3551 static payload types; dynamic types (>=96) are resolved later via rtpmap.
 		const (
-			pcmu = 0
-			pcma = 8
+			pcmu      = 0
+			pcma      = 8
+			g722      = 9
+			l16Stereo = 10
+			l16Mono   = 11
 		)
 		switch media.PayloadType {
 		case pcmu:
 			media.Type = gomedia.PCMUlaw
 		case pcma:
 			media.Type = gomedia.PCMAlaw
+		case g722:
+			// RFC 3551 §4.5.2: the RTP clock stays at 8000 for G.722.
+			media.Type = gomedia.G722
+			media.TimeScale = 8000
+			media.ChannelCount = 1
+		case l16Stereo, l16Mono:
+			media.Type = gomedia.PCM
+			media.TimeScale = 44100
+			media.ChannelCount = 1
+			if media.PayloadType == l16Stereo {
+				media.ChannelCount = 2
+			}
 		}
 
 		return &media, true
@@ -79,7 +98,14 @@ func parseCodecType(media *Media, key string, keyval []string) {
 	case "MPEG4-GENERIC":
 		media.Type = gomedia.AAC
 	case "L16":
+		// RFC 3551 §4.5.11: "L16/<rate>[/<channels>]", mono when omitted.
 		media.Type = gomedia.PCM
+		media.ChannelCount = 1
+		if len(keyval) > 2 { //nolint:mnd
+			if i, err := strconv.Atoi(keyval[2]); err == nil {
+				media.ChannelCount = i
+			}
+		}
 	case "OPUS":
 		media.Type = gomedia.OPUS
 		// RFC 7587: rtpmap for Opus is "opus/48000/2" — channel count lives in the third slash-separated field.
@@ -102,6 +128,11 @@ func parseCodecType(media *Media, key string, keyval []string) {
 		media.ChannelCount = 1
 	case "MJPEG":
 		media.Type = gomedia.MJPEG
+	case "G722":
+		media.Type = gomedia.G722
+		media.ChannelCount = 1
+	default:
+		parseG726(media, strings.ToUpper(key))
 	}
 
 	if len(keyval) > 1 {
@@ -111,6 +142,22 @@ func parseCodecType(media *Media, key string, keyval []string) {
 	}
 }
 
+// parseG726 handles the G726-<kbps> names of RFC 3551 §4.5.4 and their
+// AAL2-G726-<kbps> variants.
+func parseG726(media *Media, name string) {
+	aal2 := strings.HasPrefix(name, "AAL2-")
+	kbps, ok := strings.CutPrefix(strings.TrimPrefix(name, "AAL2-"), "G726-")
+	if !ok {
+		return
+	}
+	if n, err := strconv.Atoi(kbps); err == nil {
+		media.Type = gomedia.G726
+		media.ChannelCount = 1
+		media.Bitrate = n * 1000 //nolint:mnd
+		media.AAL2 = aal2
+	}
+}
+
 func parseAttributeKeyValue(media *Media, key, val string) {
 	switch key {
 	case "config":
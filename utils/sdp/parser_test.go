@@ -99,6 +99,8 @@ func TestParse_CodecTypeDetection(t *testing.T) {
 		{"PCMA", "a=rtpmap:8 PCMA/8000", gomedia.PCMAlaw},
 		{"PCMU", "a=rtpmap:0 PCMU/8000", gomedia.PCMUlaw},
 		{"L16", "a=rtpmap:96 L16/44100", gomedia.PCM},
+		{"G722", "a=rtpmap:9 G722/8000", gomedia.G722},
+		{"G726", "a=rtpmap:97 G726-32/8000", gomedia.G726},
 		{"JPEG", "a=rtpmap:26 JPEG/90000", gomedia.MJPEG},
 		{"MJPEG", "a=rtpmap:26 MJPEG/90000", gomedia.MJPEG},
 	}
@@ -135,6 +137,54 @@ func TestParse_StaticPayloadType_PCMA(t *testing.T) {
 	assert.Equal(t, 8, medias[0].PayloadType)
 }
 
+func TestParse_StaticPayloadType_G722(t *testing.T) {
+	_, medias := Parse("v=0\r\nm=audio 0 RTP/AVP 9\r\n")
+	require.Equal(t, 1, len(medias))
+	assert.Equal(t, gomedia.G722, medias[0].Type)
+	assert.Equal(t, 8000, medias[0].TimeScale)
+	assert.Equal(t, 1, medias[0].ChannelCount)
+}
+
+func TestParse_G726Bitrate(t *testing.T) {
+	tests := []struct {
+		rtpmap  string
+		bitrate int
+		aal2    bool
+	}{
+		{"a=rtpmap:97 G726-16/8000", 16000, false},
+		{"a=rtpmap:97 g726-40/8000", 40000, false},
+		{"a=rtpmap:97 AAL2-G726-24/8000", 24000, true},
+	}
+	for _, tt := range tests {
+		_, medias := Parse("v=0\r\nm=audio 0 RTP/AVP 97\r\n" + tt.rtpmap)
+		require.Equal(t, 1, len(medias))
+		assert.Equal(t, gomedia.G726, medias[0].Type, tt.rtpmap)
+		assert.Equal(t, tt.bitrate, medias[0].Bitrate, tt.rtpmap)
+		assert.Equal(t, tt.aal2, medias[0].AAL2, tt.rtpmap)
+		assert.Equal(t, 8000, medias[0].TimeScale, tt.rtpmap)
+	}
+}
+
+func TestParse_L16(t *testing.T) {
+	tests := []struct {
+		sdp      string
+		rate     int
+		channels int
+	}{
+		{"m=audio 0 RTP/AVP 10\r\n", 44100, 2},
+		{"m=audio 0 RTP/AVP 11\r\n", 44100, 1},
+		{"m=audio 0 RTP/AVP 96\r\na=rtpmap:96 L16/16000\r\n", 16000, 1},
+		{"m=audio 0 RTP/AVP 96\r\na=rtpmap:96 L16/48000/2\r\n", 48000, 2},
+	}
+	for _, tt := range tests {
+		_, medias := Parse("v=0\r\n" + tt.sdp)
+		require.Equal(t, 1, len(medias))
+		assert.Equal(t, gomedia.PCM, medias[0].Type, tt.sdp)
+		assert.Equal(t, tt.rate, medias[0].TimeScale, tt.sdp)
+		assert.Equal(t, tt.channels, medias[0].ChannelCount, tt.sdp)
+	}
+}
+
 // Parse — OPUS channel count
 
 func TestParse_OPUS_ChannelCount(t *testing.T) {
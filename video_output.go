@@ -0,0 +1,40 @@
+package gomedia
+
+import "image"
+
+// VideoOutput describes the frames a VideoDecoder emits. Crop selects a
+// region of the decoded picture; the zero rectangle (or one that misses the
+// picture) keeps the whole picture. Width and Height give the size the
+// cropped region is scaled to: both 0 keep the cropped size, one of them 0
+// follows the cropped aspect ratio.
+type VideoOutput struct {
+	Crop   image.Rectangle
+	Width  int
+	Height int
+}
+
+// IsZero reports whether o leaves frames untouched.
+func (o VideoOutput) IsZero() bool {
+	return o.Crop.Empty() && o.Width <= 0 && o.Height <= 0
+}
+
+// Resolve returns the source region and the output size for a decoded
+// picture with bounds frame.
+func (o VideoOutput) Resolve(frame image.Rectangle) (image.Rectangle, image.Point) {
+	crop := o.Crop.Intersect(frame)
+	if crop.Empty() {
+		crop = frame
+	}
+	cw, ch := crop.Dx(), crop.Dy()
+
+	w, h := o.Width, o.Height
+	switch {
+	case w <= 0 && h <= 0:
+		w, h = cw, ch
+	case w <= 0:
+		w = max(1, (h*cw+ch/2)/ch) //nolint:mnd // round to nearest
+	case h <= 0:
+		h = max(1, (w*ch+cw/2)/cw) //nolint:mnd
+	}
+	return crop, image.Pt(w, h)
+}
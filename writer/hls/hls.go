@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/ugparu/gomedia"
+	"github.com/ugparu/gomedia/format/fmp4"
 	"github.com/ugparu/gomedia/format/hls"
 	"github.com/ugparu/gomedia/utils"
 	"github.com/ugparu/gomedia/utils/lifecycle"
@@ -72,14 +73,91 @@ func WithMinPlaylistDuration(d time.Duration) Option {
 	return func(h *hlsWriter) { h.minPlaylistDuration = d }
 }
 
+// WithDVRWindow keeps d of segments behind the live edge in every playlist so
+// viewers can rewind. See hls.WithDVRWindow.
+func WithDVRWindow(d time.Duration) Option {
+	return func(h *hlsWriter) { h.dvrWindow = d }
+}
+
+// WithEventPlaylist serves #EXT-X-PLAYLIST-TYPE:EVENT playlists that keep
+// every segment of a source until it is removed. See hls.WithEventPlaylist.
+func WithEventPlaylist(enabled bool) Option {
+	return func(h *hlsWriter) { h.eventPlaylist = enabled }
+}
+
+// WithSpillDir writes segments behind the live edge to files in dir instead
+// of keeping them in memory. See hls.WithSpillDir.
+func WithSpillDir(dir string) Option {
+	return func(h *hlsWriter) { h.spillDir = dir }
+}
+
+// WithEncryption protects every source's segments with method, taking keys
+// from keys. The provider is shared by all sources. See hls.WithEncryption.
+func WithEncryption(method hls.EncryptionMethod, keys hls.KeyProvider) Option {
+	return func(h *hlsWriter) {
+		h.encryption = method
+		h.keys = keys
+	}
+}
+
+// WithKeyRotation asks the key provider for a new key every n segments. See
+// hls.WithKeyRotation.
+func WithKeyRotation(n uint64) Option {
+	return func(h *hlsWriter) { h.keyRotation = n }
+}
+
+// WithSeparateAudio delivers audio as its own playlist instead of muxing it
+// into every video rendition. The master playlist advertises it as an
+// #EXT-X-MEDIA:TYPE=AUDIO rendition in group "audio", which every video
+// variant references, plus an audio-only variant. The audio of the first
+// source that has any is used for the whole group; sources are expected to
+// be renditions of the same content, so the audio of the others is dropped.
+// Audio-only sources are served this way too. All muxers share one
+// hls.Timeline, so the audio wraps its timestamps together with the video.
+func WithSeparateAudio(enabled bool) Option {
+	return func(h *hlsWriter) { h.separateAudio = enabled }
+}
+
+// audioGroupID is the GROUP-ID of the shared audio rendition.
+const audioGroupID = "audio"
+
+// WithIFramePlaylists advertises an #EXT-X-I-FRAME-STREAM-INF playlist next
+// to every video variant, served by IFrameStreamer.GetIFrameM3u8 under
+// "iframe_" + the index name. Players use it for trick play and scrubbing
+// thumbnails. Not available with hls.EncryptionAES128. See
+// hls.IFramePlaylister.
+func WithIFramePlaylists(enabled bool) Option {
+	return func(h *hlsWriter) { h.iframePlaylists = enabled }
+}
+
 // masterVariant is one rendition of the master playlist: its #EXT-X-STREAM-INF
 // line, the playlist URI line, and the muxer they describe. The muxer is kept
 // so the master can be assembled per request and skip renditions that have
 // nothing playable in them.
 type masterVariant struct {
-	mux   gomedia.HLSMuxer
-	entry string
-	uri   string
+	mux    gomedia.HLSMuxer
+	entry  string
+	uri    string
+	iframe string // #EXT-X-I-FRAME-STREAM-INF line, "" → none
+}
+
+// Event is an in-band event (emsg) for the rendition of SourceID.
+type Event struct {
+	SourceID string
+	fmp4.Event
+}
+
+// EventStreamer is implemented by the writer returned from New. Events sent
+// to Events() are embedded in the part currently being filled for their
+// source; events for unknown sources are dropped.
+type EventStreamer interface {
+	Events() chan<- Event
+}
+
+// IFrameStreamer is implemented by the writer returned from New. It serves
+// the I-frame playlists advertised under WithIFramePlaylists.
+type IFrameStreamer interface {
+	GetIFrameM3u8(uid string) (string, error)
 }
 
 // hlsWriter fans media packets to one HLS muxer per source URL and publishes
@@ -96,22 +174,36 @@ type hlsWriter struct {
 	inpPktCh chan gomedia.Packet
 	addSrcCh chan string
 	rmSrcCh  chan string
-
-	muxerIDs      map[string]gomedia.HLSMuxer
-	muxerURLs     map[string]gomedia.HLSMuxer
-	muxerUIDs     map[string]string
-	codPars       map[string]*gomedia.CodecParametersPair
-	sortedURLs    []string
-	mu            sync.RWMutex
-	variants           []masterVariant
-	indexName          string
-	mediaName          string
-	partHoldBack       float64
-	version            int
-	keyframeSplit      bool
+	eventCh  chan Event
+
+	muxerIDs            map[string]gomedia.HLSMuxer
+	muxerURLs           map[string]gomedia.HLSMuxer
+	muxerUIDs           map[string]string
+	codPars             map[string]*gomedia.CodecParametersPair
+	sortedURLs          []string
+	mu                  sync.RWMutex
+	variants            []masterVariant
+	indexName           string
+	mediaName           string
+	partHoldBack        float64
+	version             int
+	keyframeSplit       bool
 	fragmentDuration    time.Duration // 0 → muxer default (495ms)
 	maxSegmentDuration  time.Duration // 0 → muxer default (no cap)
 	minPlaylistDuration time.Duration // 0 → muxer default (count-based eviction)
+	dvrWindow           time.Duration // 0 → no time-shift
+	eventPlaylist       bool
+	spillDir            string // "" → archived segments stay in memory
+	encryption          hls.EncryptionMethod
+	keys                hls.KeyProvider
+	keyRotation         uint64 // 0 → one key per source
+	separateAudio       bool
+	audioURL            string           // source whose audio is the shared rendition, "" → none yet
+	audioMux            gomedia.HLSMuxer // muxer of the shared audio rendition
+	audioUID            string
+	audioEntry          string        // #EXT-X-MEDIA line of the audio rendition
+	timeline            *hls.Timeline // epoch shared by all muxers when audio is separate
+	iframePlaylists     bool
 }
 
 func New(id uint64, segCnt uint8, segDur time.Duration, chanSize int, partHoldBack float64, opts ...Option) gomedia.HLSStreamer {
@@ -125,6 +217,7 @@ func New(id uint64, segCnt uint8, segDur time.Duration, chanSize int, partHoldBa
 		inpPktCh: make(chan gomedia.Packet, chanSize),
 		addSrcCh: make(chan string, chanSize),
 		rmSrcCh:  make(chan string, chanSize),
+		eventCh:  make(chan Event, chanSize),
 
 		muxerIDs:     map[string]gomedia.HLSMuxer{},
 		muxerURLs:    make(map[string]gomedia.HLSMuxer),
@@ -142,6 +235,9 @@ func New(id uint64, segCnt uint8, segDur time.Duration, chanSize int, partHoldBa
 	for _, o := range opts {
 		o(hwr)
 	}
+	if hwr.separateAudio {
+		hwr.timeline = hls.NewTimeline()
+	}
 
 	hwr.log.Infof(hwr, "Initialized HLS writer with %d segments, %.2f seconds per segment, part hold back %.2f", segCnt, segDur.Seconds(), partHoldBack)
 	hwr.AsyncManager = lifecycle.NewFailSafeAsyncManager(hwr, hwr.log)
@@ -180,6 +276,14 @@ func (hlsw *hlsWriter) checkCodPar(url string, codecPar gomedia.CodecParameters)
 	}
 
 	par := hlsw.codPars[url]
+	if hlsw.separateAudio {
+		if _, isAudio := codecPar.(gomedia.AudioCodecParameters); isAudio {
+			return hlsw.updateAudio(url)
+		}
+		videoOnly := *par
+		videoOnly.AudioCodecParameters = nil
+		par = &videoOnly
+	}
 	if par.VideoCodecParameters == nil {
 		return hlsw.recalcManifest()
 	}
@@ -191,18 +295,7 @@ func (hlsw *hlsWriter) checkCodPar(url string, codecPar gomedia.CodecParameters)
 		}
 		hlsw.muxerUIDs[url] = generateUID()
 	} else {
-		muxOpts := []hls.MuxerOption{hls.WithMediaName(hlsw.mediaName), hls.WithVersion(hlsw.version), hls.WithKeyframeSplit(hlsw.keyframeSplit)}
-		if hlsw.fragmentDuration > 0 {
-			muxOpts = append(muxOpts, hls.WithFragmentDuration(hlsw.fragmentDuration))
-		}
-		if hlsw.maxSegmentDuration > 0 {
-			muxOpts = append(muxOpts, hls.WithMaxSegmentDuration(hlsw.maxSegmentDuration))
-		}
-		if hlsw.minPlaylistDuration > 0 {
-			muxOpts = append(muxOpts, hls.WithMinPlaylistDuration(hlsw.minPlaylistDuration))
-		}
-		mux = hls.NewHLSMuxer(hlsw.segmentDuration, hlsw.segmentCount, hlsw.partHoldBack, hlsw.log, muxOpts...)
-		if err = mux.Mux(*par); err != nil {
+		if mux, err = hlsw.newMuxer(*par); err != nil {
 			return
 		}
 		hlsw.muxerURLs[url] = mux
@@ -212,6 +305,63 @@ func (hlsw *hlsWriter) checkCodPar(url string, codecPar gomedia.CodecParameters)
 	return hlsw.recalcManifest()
 }
 
+// updateAudio (re)starts the shared audio rendition after the audio codec
+// parameters of url changed. The first source with audio provides the
+// rendition; changes on other sources are ignored.
+func (hlsw *hlsWriter) updateAudio(url string) (err error) {
+	if hlsw.audioURL == "" {
+		hlsw.audioURL = url
+	}
+	if url != hlsw.audioURL {
+		return nil
+	}
+	par := gomedia.CodecParametersPair{SourceID: url, AudioCodecParameters: hlsw.codPars[url].AudioCodecParameters}
+	if hlsw.audioMux != nil {
+		if err = hlsw.audioMux.UpdateCodecParameters(par); err != nil {
+			return
+		}
+	} else if hlsw.audioMux, err = hlsw.newMuxer(par); err != nil {
+		hlsw.audioMux = nil
+		return
+	}
+	hlsw.audioUID = generateUID()
+	return hlsw.recalcManifest()
+}
+
+// newMuxer creates and starts an HLS muxer for par with the writer's options.
+func (hlsw *hlsWriter) newMuxer(par gomedia.CodecParametersPair) (gomedia.HLSMuxer, error) {
+	muxOpts := []hls.MuxerOption{hls.WithMediaName(hlsw.mediaName), hls.WithVersion(hlsw.version), hls.WithKeyframeSplit(hlsw.keyframeSplit)}
+	if hlsw.fragmentDuration > 0 {
+		muxOpts = append(muxOpts, hls.WithFragmentDuration(hlsw.fragmentDuration))
+	}
+	if hlsw.maxSegmentDuration > 0 {
+		muxOpts = append(muxOpts, hls.WithMaxSegmentDuration(hlsw.maxSegmentDuration))
+	}
+	if hlsw.minPlaylistDuration > 0 {
+		muxOpts = append(muxOpts, hls.WithMinPlaylistDuration(hlsw.minPlaylistDuration))
+	}
+	if hlsw.dvrWindow > 0 {
+		muxOpts = append(muxOpts, hls.WithDVRWindow(hlsw.dvrWindow))
+	}
+	if hlsw.timeline != nil {
+		muxOpts = append(muxOpts, hls.WithTimeline(hlsw.timeline))
+	}
+	if hlsw.eventPlaylist {
+		muxOpts = append(muxOpts, hls.WithEventPlaylist(true))
+	}
+	if hlsw.spillDir != "" {
+		muxOpts = append(muxOpts, hls.WithSpillDir(hlsw.spillDir))
+	}
+	if hlsw.encryption != hls.EncryptionNone {
+		muxOpts = append(muxOpts, hls.WithEncryption(hlsw.encryption, hlsw.keys), hls.WithKeyRotation(hlsw.keyRotation))
+	}
+	mux := hls.NewHLSMuxer(hlsw.segmentDuration, hlsw.segmentCount, hlsw.partHoldBack, hlsw.log, muxOpts...)
+	if err := mux.Mux(par); err != nil {
+		return nil, err
+	}
+	return mux, nil
+}
+
 func (hlsw *hlsWriter) removeSrc(url string) error {
 	hlsw.log.Infof(hlsw, "Removing source %s", url)
 
@@ -225,6 +375,21 @@ func (hlsw *hlsWriter) removeSrc(url string) error {
 	if idx := slices.Index(hlsw.sortedURLs, url); idx != -1 {
 		hlsw.sortedURLs = slices.Delete(hlsw.sortedURLs, idx, idx+1)
 	}
+
+	if url == hlsw.audioURL {
+		if hlsw.audioMux != nil {
+			hlsw.audioMux.Close()
+		}
+		hlsw.audioURL, hlsw.audioMux, hlsw.audioUID = "", nil, ""
+		for _, next := range hlsw.sortedURLs {
+			if hlsw.codPars[next].AudioCodecParameters != nil {
+				if err := hlsw.updateAudio(next); err != nil {
+					return err
+				}
+				break
+			}
+		}
+	}
 	return hlsw.recalcManifest()
 }
 
@@ -251,6 +416,25 @@ func (hlsw *hlsWriter) recalcManifest() (err error) {
 
 	clear(hlsw.muxerIDs)
 	hlsw.variants = hlsw.variants[:0]
+	hlsw.audioEntry = ""
+
+	var sharedAudio gomedia.AudioCodecParameters
+	if hlsw.audioMux != nil {
+		sharedAudio = hlsw.codPars[hlsw.audioURL].AudioCodecParameters
+		hlsw.muxerIDs[hlsw.audioUID] = hlsw.audioMux
+		uri := fmt.Sprintf("%d/%s/%s", hlsw.id, hlsw.audioUID, hlsw.indexName)
+		hlsw.audioEntry = fmt.Sprintf(
+			"#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID=\"%s\",NAME=\"%s\",DEFAULT=YES,AUTOSELECT=YES,URI=\"%s\"",
+			audioGroupID, audioGroupID, uri)
+
+		// Audio-only variant: lets players drop to audio on a starved link
+		// and carries audio-only sources.
+		var entry string
+		if entry, err = hls.StreamInf(gomedia.CodecParametersPair{AudioCodecParameters: sharedAudio}, audioGroupID); err != nil {
+			return err
+		}
+		hlsw.variants = append(hlsw.variants, masterVariant{mux: hlsw.audioMux, entry: entry, uri: uri})
+	}
 
 	for _, url := range hlsw.sortedURLs {
 		mux, ok := hlsw.muxerURLs[url]
@@ -262,14 +446,30 @@ func (hlsw *hlsWriter) recalcManifest() (err error) {
 		hlsw.muxerIDs[uid] = mux
 
 		var entry string
-		if entry, err = mux.GetMasterEntry(); err != nil {
+		if sharedAudio != nil {
+			pair := gomedia.CodecParametersPair{
+				VideoCodecParameters: hlsw.codPars[url].VideoCodecParameters,
+				AudioCodecParameters: sharedAudio,
+			}
+			entry, err = hls.StreamInf(pair, audioGroupID)
+		} else {
+			entry, err = mux.GetMasterEntry()
+		}
+		if err != nil {
 			return err
 		}
-		hlsw.variants = append(hlsw.variants, masterVariant{
+		variant := masterVariant{
 			mux:   mux,
 			entry: entry,
 			uri:   fmt.Sprintf("%d/%s/%s", hlsw.id, uid, hlsw.indexName),
-		})
+		}
+		if _, ok := mux.(hls.IFramePlaylister); ok && hlsw.iframePlaylists && hlsw.encryption != hls.EncryptionAES128 {
+			uri := fmt.Sprintf("%d/%s/iframe_%s", hlsw.id, uid, hlsw.indexName)
+			if variant.iframe, err = hls.IFrameStreamInf(*hlsw.codPars[url], uri); err != nil {
+				return err
+			}
+		}
+		hlsw.variants = append(hlsw.variants, variant)
 	}
 
 	return
@@ -290,6 +490,17 @@ func (hlsw *hlsWriter) Step(stopCh <-chan struct{}) (err error) {
 		// Sources are auto-created on first packet via checkCodPar.
 	case url := <-hlsw.rmSrcCh:
 		return hlsw.removeSrc(url)
+	case ev := <-hlsw.eventCh:
+		mux, ok := hlsw.muxerURLs[ev.SourceID]
+		if !ok && hlsw.audioMux != nil && ev.SourceID == hlsw.audioURL {
+			mux, ok = hlsw.audioMux, true
+		}
+		if !ok {
+			return nil
+		}
+		if evWriter, ok := mux.(hls.EventWriter); ok {
+			return evWriter.WriteEvent(ev.Event)
+		}
 	case inpPkt := <-hlsw.inpPktCh:
 		hlsw.log.Tracef(hlsw, "Received packet %v", inpPkt)
 
@@ -311,6 +522,9 @@ func (hlsw *hlsWriter) Step(stopCh <-chan struct{}) (err error) {
 		}
 
 		mux, ok := hlsw.muxerURLs[inpPkt.SourceID()]
+		if _, isAudio := inpPkt.(gomedia.AudioPacket); isAudio && hlsw.separateAudio {
+			mux, ok = hlsw.audioMux, hlsw.audioMux != nil && inpPkt.SourceID() == hlsw.audioURL
+		}
 		if !ok {
 			inpPkt.Release()
 			return
@@ -349,6 +563,10 @@ func (hlsw *hlsWriter) GetMasterPlaylist() (string, error) {
 
 	var builder strings.Builder
 	fmt.Fprintf(&builder, "#EXTM3U\n#EXT-X-VERSION:%d\n", hlsw.version)
+	if hlsw.audioEntry != "" {
+		builder.WriteString(hlsw.audioEntry)
+		builder.WriteByte('\n')
+	}
 	for _, variant := range hlsw.variants {
 		if playable > 0 && !variant.mux.HasPlayableSegments() {
 			continue
@@ -358,6 +576,13 @@ func (hlsw *hlsWriter) GetMasterPlaylist() (string, error) {
 		builder.WriteString(variant.uri)
 		builder.WriteByte('\n')
 	}
+	for _, variant := range hlsw.variants {
+		if variant.iframe == "" || (playable > 0 && !variant.mux.HasPlayableSegments()) {
+			continue
+		}
+		builder.WriteString(variant.iframe)
+		builder.WriteByte('\n')
+	}
 	return builder.String(), nil
 }
 
@@ -387,6 +612,19 @@ func (hlsw *hlsWriter) GetIndexM3u8(ctx context.Context, uid string, needMSN int
 	return mux.GetIndexM3u8(ctx, needMSN, needPart)
 }
 
+// GetIFrameM3u8 returns the I-frame playlist of the rendition uid.
+func (hlsw *hlsWriter) GetIFrameM3u8(uid string) (string, error) {
+	mux, err := hlsw.lookupMuxer(uid)
+	if err != nil {
+		return "", err
+	}
+	iframes, ok := mux.(hls.IFramePlaylister)
+	if !ok {
+		return "", errors.New("rendition has no I-frame playlist")
+	}
+	return iframes.GetIFrameM3u8()
+}
+
 func (hlsw *hlsWriter) GetInit(uid string) ([]byte, error) {
 	mux, err := hlsw.lookupMuxer(uid)
 	if err != nil {
@@ -425,6 +663,9 @@ func (hlsw *hlsWriter) Release() { //nolint: revive
 	for _, mux := range hlsw.muxerURLs {
 		mux.Close()
 	}
+	if hlsw.audioMux != nil {
+		hlsw.audioMux.Close()
+	}
 	for {
 		select {
 		case pkt, ok := <-hlsw.inpPktCh:
@@ -449,6 +690,10 @@ func (hlsw *hlsWriter) Packets() chan<- gomedia.Packet {
 	return hlsw.inpPktCh
 }
 
+func (hlsw *hlsWriter) Events() chan<- Event {
+	return hlsw.eventCh
+}
+
 func (hlsw *hlsWriter) RemoveSource() chan<- string {
 	return hlsw.rmSrcCh
 }
@@ -689,6 +689,112 @@ func TestAudioOnly_NoMasterPlaylistEntry(t *testing.T) {
 	assert.NotContains(t, master, "#EXT-X-STREAM-INF:")
 }
 
+// Separate audio rendition
+
+// syntheticPackets returns dur worth of interleaved 40ms IDR frames and AAC
+// frames from sourceID. Audio only when videoCp is nil.
+func syntheticPackets(sourceID string, videoCp *h264.CodecParameters, audioCp *aac.CodecParameters, dur time.Duration) []gomedia.Packet {
+	nal := make([]byte, 104)
+	nal[3], nal[4] = 100, 0x65
+	frame := 1024 * time.Second / time.Duration(audioCp.SampleRate())
+	var result []gomedia.Packet
+	var ats time.Duration
+	for vts := time.Duration(0); vts < dur; vts += 40 * time.Millisecond {
+		if videoCp != nil {
+			pkt := h264.NewPacket(true, vts, time.Time{}, nal, sourceID, videoCp)
+			pkt.SetDuration(40 * time.Millisecond)
+			result = append(result, pkt)
+		}
+		for ; ats < vts+40*time.Millisecond; ats += frame {
+			result = append(result, aac.NewPacket(make([]byte, 64), ats, sourceID, time.Time{}, audioCp, frame))
+		}
+	}
+	return result
+}
+
+func TestSeparateAudio_SharedRendition(t *testing.T) {
+	w := newWriter(t, 1, 5, time.Second, WithSeparateAudio(true))
+	_, vCp, aCp := loadTestCodecPair(t, "src1")
+	sendPackets(t, w, syntheticPackets("src1", vCp, aCp, 3*time.Second))
+
+	var master string
+	require.Eventually(t, func() bool {
+		var err error
+		master, err = w.GetMasterPlaylist()
+		require.NoError(t, err)
+		return strings.Count(master, "#EXT-X-STREAM-INF:") == 2
+	}, 5*time.Second, 5*time.Millisecond)
+
+	assert.Contains(t, master, `#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="audio"`)
+	lines := strings.Split(master, "\n")
+	var audioOnly, video string
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "#EXT-X-STREAM-INF:") {
+			continue
+		}
+		assert.Contains(t, line, `AUDIO="audio"`)
+		if strings.Contains(line, "RESOLUTION=") {
+			video = line
+		} else {
+			audioOnly = line
+		}
+	}
+	assert.Contains(t, video, "avc1.")
+	assert.Contains(t, video, "mp4a.")
+	assert.Contains(t, audioOnly, "mp4a.")
+	assert.NotContains(t, audioOnly, "avc1.")
+
+	// The rendition URI is served by its own audio-only muxer.
+	uids := extractUIDs(t, master)
+	require.Len(t, uids, 3)
+	audioIndex := waitForSegment(t, w, uids[0], 3*time.Second)
+	assert.Contains(t, audioIndex, "#EXT-X-MAP:")
+}
+
+func TestSeparateAudio_AudioOnlySource(t *testing.T) {
+	w := newWriter(t, 1, 5, time.Second, WithSeparateAudio(true))
+	_, _, aCp := loadTestCodecPair(t, "audio-only")
+	sendPackets(t, w, syntheticPackets("audio-only", nil, aCp, 3*time.Second))
+
+	master := waitForMaster(t, w, 3*time.Second)
+	assert.Contains(t, master, "#EXT-X-MEDIA:TYPE=AUDIO")
+	assert.Equal(t, 1, strings.Count(master, "#EXT-X-STREAM-INF:"), master)
+	assert.NotContains(t, master, "RESOLUTION=")
+}
+
+// I-frame playlists
+
+func TestIFramePlaylists_AdvertisedAndServed(t *testing.T) {
+	w := newWriter(t, 1, 5, time.Second, WithIFramePlaylists(true), WithKeyframeSplit(true))
+	_, vCp, aCp := loadTestCodecPair(t, "src1")
+	sendPackets(t, w, syntheticPackets("src1", vCp, aCp, 3*time.Second))
+
+	master := waitForMaster(t, w, 3*time.Second)
+	uid := extractUIDs(t, master)[0]
+	assert.Contains(t, master, "#EXT-X-I-FRAME-STREAM-INF:BANDWIDTH=")
+	assert.Contains(t, master, fmt.Sprintf(`URI="1/%s/iframe_index.m3u8"`, uid))
+
+	waitForSegment(t, w, uid, 3*time.Second)
+	iframes, ok := w.(IFrameStreamer)
+	require.True(t, ok)
+	m, err := iframes.GetIFrameM3u8(uid)
+	require.NoError(t, err)
+	assert.Contains(t, m, "#EXT-X-I-FRAMES-ONLY\n")
+	assert.Contains(t, m, "#EXT-X-BYTERANGE:")
+
+	_, err = iframes.GetIFrameM3u8("deadbeef")
+	require.Error(t, err)
+}
+
+func TestIFramePlaylists_OffByDefault(t *testing.T) {
+	w := newWriter(t, 1, 5, time.Second)
+	_, vCp, aCp := loadTestCodecPair(t, "src1")
+	sendPackets(t, w, syntheticPackets("src1", vCp, aCp, time.Second))
+
+	master := waitForMaster(t, w, 3*time.Second)
+	assert.NotContains(t, master, "#EXT-X-I-FRAME-STREAM-INF:")
+}
+
 // GetSegment with context cancellation
 
 func TestGetSegment_ContextCancelled(t *testing.T) {